@@ -8,20 +8,27 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"runtime/debug"
 	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/cloudwego/eino-ext/devops"
 
+	"github.com/PGshen/thinking-map/server/internal/agent/llmmodel"
+	"github.com/PGshen/thinking-map/server/internal/agent/tool/mcp"
 	"github.com/PGshen/thinking-map/server/internal/config"
 	"github.com/PGshen/thinking-map/server/internal/global"
 	"github.com/PGshen/thinking-map/server/internal/model"
 	"github.com/PGshen/thinking-map/server/internal/pkg/database"
 	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/PGshen/thinking-map/server/internal/pkg/queue"
 	"github.com/PGshen/thinking-map/server/internal/pkg/sse"
 	"github.com/PGshen/thinking-map/server/internal/pkg/validator"
 	"github.com/PGshen/thinking-map/server/internal/repository"
@@ -31,6 +38,9 @@ import (
 	"go.uber.org/zap"
 )
 
+// shutdownTimeout 关闭HTTP服务器时等待请求结束的时长，超时后直接关闭剩余连接（如SSE长连接）
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	defer func() {
 		if r := recover(); r != nil {
@@ -85,9 +95,18 @@ func main() {
 		&model.ThinkingMap{},
 		&model.ThinkingNode{},
 		&model.RAGRecord{},
+		&model.AgentCheckpoint{},
+		&model.AgentUsage{},
+		&model.KBDocument{},
+		&model.KBChunk{},
+		&model.NodeChange{},
+		&model.NodeOperation{},
 	); err != nil {
 		logger.Fatal("Failed to migrate database", zap.Error(err))
 	}
+	if err := repository.CreateKnowledgeBaseIndexes(db); err != nil {
+		logger.Fatal("Failed to create knowledge base indexes", zap.Error(err))
+	}
 
 	// 初始化 Redis
 	redisClientRaw, err := database.NewClient(&cfg.Redis)
@@ -112,12 +131,57 @@ func main() {
 	// 初始化 RAG Record 仓库
 	global.InitRAGRecordRepository(repository.NewRAGRecordRepository(db))
 
+	// 初始化思维导图仓库
+	global.InitThinkingMapRepository(repository.NewThinkingMapRepository(db))
+
 	// 初始化全局消息管理器
 	global.InitMessageManager(repository.NewMessageRepository(db), repository.NewThinkingNodeRepository(db), repository.NewRAGRecordRepository(db), db)
 
 	// 初始化全局节点操作器
 	global.InitNodeOperator(repository.NewThinkingNodeRepository(db), repository.NewThinkingMapRepository(db))
 
+	// 初始化节点操作策略
+	global.InitNodePolicy(repository.NewThinkingNodeRepository(db), repository.NewThinkingMapRepository(db), repository.NewNodeChangeRepository(db), redisClient, cfg.NodePolicy)
+
+	// 初始化节点结论摘要生成器
+	global.InitNodeAbstractor(repository.NewThinkingNodeRepository(db))
+
+	// 初始化节点操作日志
+	global.InitNodeOperationLog(repository.NewNodeOperationRepository(db))
+
+	// 初始化Agent运行注册表
+	global.InitRunRegistry()
+
+	// 初始化运行检查点存储，任务重试时从检查点恢复
+	global.InitCheckpointStore(repository.NewAgentCheckpointRepository(db))
+
+	// 初始化预算管理器，统计模型调用的token用量并限制运行预算
+	global.InitBudgetManager(repository.NewAgentUsageRepository(db), cfg.Budget)
+
+	// 初始化知识库，配置了嵌入模型时同时使用向量检索
+	embedder, err := llmmodel.NewDefaultEmbedder(context.Background())
+	if err != nil {
+		logger.Warn("Failed to create embedder, knowledge base falls back to full-text search", zap.Error(err))
+	}
+	global.InitKnowledgeBase(repository.NewKnowledgeBaseRepository(db), embedder)
+
+	// 连接配置的MCP服务器，将其工具注册到工具注册表
+	if err := mcp.Load(context.Background()); err != nil {
+		logger.Warn("Failed to load mcp tools", zap.Error(err))
+	}
+	defer mcp.Close()
+
+	// 初始化自动驾驶状态存储
+	global.InitAutopilotStore(redisClient, cfg.Autopilot)
+
+	// 初始化任务队列，workers > 0 时本进程同时消费任务
+	global.InitJobQueue(redisClient, cfg.Queue)
+	var worker *queue.Worker
+	if cfg.Queue.Workers > 0 {
+		worker = service.NewJobWorker(db, cfg.Queue.Workers)
+		worker.Start(context.Background())
+	}
+
 	// 解析 JWT 配置
 	expireDuration, err := time.ParseDuration(cfg.JWT.Expire)
 	if err != nil {
@@ -131,7 +195,24 @@ func main() {
 	}
 
 	r := router.SetupRouter(db, redisClient, jwtConfig)
-	if err := r.Run(addr); err != nil {
-		logger.Fatal("Failed to start HTTP server", zap.Error(err))
+	srv := &http.Server{Addr: addr, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("Failed to start HTTP server", zap.Error(err))
+		}
+	}()
+
+	// 等待退出信号，先停止worker（执行中的任务中断后重新入队），再关闭HTTP服务器
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	logger.Info("Server shutting down")
+	if worker != nil {
+		worker.Stop()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Error("Failed to shut down HTTP server", zap.Error(err))
 	}
 }
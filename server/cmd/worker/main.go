@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/llmmodel"
+	"github.com/PGshen/thinking-map/server/internal/agent/tool/mcp"
+	"github.com/PGshen/thinking-map/server/internal/config"
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/pkg/database"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/PGshen/thinking-map/server/internal/pkg/sse"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+	"github.com/PGshen/thinking-map/server/internal/service"
+
+	"go.uber.org/zap"
+)
+
+// worker 独立消费Agent运行任务，可与HTTP服务分开扩容
+// SSE事件通过redis事件总线推送到持有连接的服务实例
+func main() {
+	// 加载配置
+	cfg, err := config.LoadConfig("configs/config.yaml")
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// 初始化日志
+	if err = logger.Init(&cfg.Log); err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer logger.Sync()
+
+	// worker数量，环境变量WORKERS优先
+	workers := cfg.Queue.Workers
+	if workersEnv := os.Getenv("WORKERS"); workersEnv != "" {
+		if n, err2 := strconv.Atoi(workersEnv); err2 == nil {
+			workers = n
+		}
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+
+	// 初始化数据库
+	db, err := database.NewPostgresDB(&cfg.Database)
+	if err != nil {
+		logger.Fatal("Failed to connect to database", zap.Error(err))
+	}
+
+	// 初始化 Redis
+	redisClient, err := database.NewClient(&cfg.Redis)
+	if err != nil {
+		logger.Fatal("Failed to connect to Redis", zap.Error(err))
+	}
+
+	// 初始化分布式SSE组件
+	serverID := fmt.Sprintf("worker-%d", time.Now().UnixMicro())
+	connManager := sse.NewRedisConnectionManager(redisClient, serverID)
+	eventBus := sse.NewRedisEventBus(redisClient, connManager, serverID)
+	global.InitBroker(eventBus, connManager, serverID, 10*time.Second, 60*time.Second)
+
+	global.InitRAGRecordRepository(repository.NewRAGRecordRepository(db))
+	global.InitThinkingMapRepository(repository.NewThinkingMapRepository(db))
+	global.InitMessageManager(repository.NewMessageRepository(db), repository.NewThinkingNodeRepository(db), repository.NewRAGRecordRepository(db), db)
+	global.InitNodeOperator(repository.NewThinkingNodeRepository(db), repository.NewThinkingMapRepository(db))
+	global.InitNodePolicy(repository.NewThinkingNodeRepository(db), repository.NewThinkingMapRepository(db), repository.NewNodeChangeRepository(db), redisClient, cfg.NodePolicy)
+	global.InitNodeAbstractor(repository.NewThinkingNodeRepository(db))
+	global.InitNodeOperationLog(repository.NewNodeOperationRepository(db))
+	global.InitRunRegistry()
+	global.InitCheckpointStore(repository.NewAgentCheckpointRepository(db))
+	global.InitBudgetManager(repository.NewAgentUsageRepository(db), cfg.Budget)
+	embedder, err := llmmodel.NewDefaultEmbedder(context.Background())
+	if err != nil {
+		logger.Warn("Failed to create embedder, knowledge base falls back to full-text search", zap.Error(err))
+	}
+	global.InitKnowledgeBase(repository.NewKnowledgeBaseRepository(db), embedder)
+
+	// 连接配置的MCP服务器，将其工具注册到工具注册表
+	if err := mcp.Load(context.Background()); err != nil {
+		logger.Warn("Failed to load mcp tools", zap.Error(err))
+	}
+	defer mcp.Close()
+	global.InitAutopilotStore(redisClient, cfg.Autopilot)
+	global.InitJobQueue(redisClient, cfg.Queue)
+
+	worker := service.NewJobWorker(db, workers)
+	worker.Start(context.Background())
+
+	// 等待退出信号，执行中的任务中断后重新入队
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	logger.Info("Worker shutting down")
+	worker.Stop()
+}
@@ -0,0 +1,250 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// Budget 运行预算，零值表示不限制
+type Budget struct {
+	MaxTokens   int           `yaml:"max_tokens" mapstructure:"max_tokens" json:"maxTokens"`
+	MaxDuration time.Duration `yaml:"max_duration" mapstructure:"max_duration" json:"maxDuration"`
+	MaxCost     float64       `yaml:"max_cost" mapstructure:"max_cost" json:"maxCost"` // 估算费用，单位与模型配置档的价格一致
+}
+
+// BudgetConfig 预算配置
+// Run 为单次运行的预算；Map、User 为 Window 时间窗口内同一思维导图、同一用户所有运行的累计预算（不含时长）
+type BudgetConfig struct {
+	Run    Budget        `yaml:"run"`
+	Map    Budget        `yaml:"map"`
+	User   Budget        `yaml:"user"`
+	Window time.Duration `yaml:"window"`
+}
+
+// Usage 资源用量
+type Usage struct {
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	TotalTokens      int     `json:"totalTokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// Add 累加用量
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+	u.Cost += other.Cost
+}
+
+// PriceFunc 根据模型名称和token用量估算费用
+type PriceFunc func(modelName string, promptTokens, completionTokens int) float64
+
+// BudgetLimit 一项预算限制，Used 为运行开始前已经消耗的用量（如思维导图的累计用量）
+type BudgetLimit struct {
+	Scope  string // run | map | user
+	Budget Budget
+	Used   Usage
+}
+
+// BudgetTracker 统计一次运行的用量并检查预算，并发安全
+type BudgetTracker struct {
+	mu         sync.Mutex
+	limits     []BudgetLimit
+	price      PriceFunc
+	startTime  time.Time
+	usage      Usage
+	exceeded   string
+	onExceeded func(reason string)
+	notifyOnce sync.Once
+}
+
+// NewBudgetTracker 创建预算跟踪器，price为空时不估算费用
+func NewBudgetTracker(price PriceFunc, limits ...BudgetLimit) *BudgetTracker {
+	return &BudgetTracker{
+		limits:    limits,
+		price:     price,
+		startTime: time.Now(),
+	}
+}
+
+// OnExceeded 设置预算首次耗尽时的回调，用于通知前端
+func (t *BudgetTracker) OnExceeded(fn func(reason string)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onExceeded = fn
+}
+
+// AddUsage 记录一次模型调用的token用量
+func (t *BudgetTracker) AddUsage(modelName string, promptTokens, completionTokens, totalTokens int) {
+	usage := Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      totalTokens,
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	if t.price != nil {
+		usage.Cost = t.price(modelName, usage.PromptTokens, usage.CompletionTokens)
+	}
+	t.mu.Lock()
+	t.usage.Add(usage)
+	t.mu.Unlock()
+	t.Exceeded()
+}
+
+// Usage 本次运行的累计用量
+func (t *BudgetTracker) Usage() Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.usage
+}
+
+// Elapsed 本次运行已经持续的时间
+func (t *BudgetTracker) Elapsed() time.Duration {
+	return time.Since(t.startTime)
+}
+
+// Exceeded 检查预算是否耗尽，返回耗尽原因，未耗尽时返回空字符串
+func (t *BudgetTracker) Exceeded() string {
+	t.mu.Lock()
+	if t.exceeded == "" {
+		t.exceeded = t.check()
+	}
+	reason, onExceeded := t.exceeded, t.onExceeded
+	t.mu.Unlock()
+	if reason != "" && onExceeded != nil {
+		t.notifyOnce.Do(func() { onExceeded(reason) })
+	}
+	return reason
+}
+
+func (t *BudgetTracker) check() string {
+	elapsed := time.Since(t.startTime)
+	for _, limit := range t.limits {
+		used := limit.Used
+		used.Add(t.usage)
+		if limit.Budget.MaxTokens > 0 && used.TotalTokens >= limit.Budget.MaxTokens {
+			return fmt.Sprintf("%s token budget exhausted: %d/%d", limit.Scope, used.TotalTokens, limit.Budget.MaxTokens)
+		}
+		if limit.Budget.MaxCost > 0 && used.Cost >= limit.Budget.MaxCost {
+			return fmt.Sprintf("%s cost budget exhausted: %.4f/%.4f", limit.Scope, used.Cost, limit.Budget.MaxCost)
+		}
+		if limit.Budget.MaxDuration > 0 && elapsed >= limit.Budget.MaxDuration {
+			return fmt.Sprintf("%s time budget exhausted: %s/%s", limit.Scope, elapsed.Round(time.Second), limit.Budget.MaxDuration)
+		}
+	}
+	return ""
+}
+
+type budgetTrackerKey struct{}
+
+// WithBudgetTracker 将预算跟踪器放入上下文，运行中的模型调用用量计入该跟踪器
+func WithBudgetTracker(ctx context.Context, tracker *BudgetTracker) context.Context {
+	return context.WithValue(ctx, budgetTrackerKey{}, tracker)
+}
+
+// GetBudgetTracker 获取上下文中的预算跟踪器
+func GetBudgetTracker(ctx context.Context) (*BudgetTracker, bool) {
+	tracker, ok := ctx.Value(budgetTrackerKey{}).(*BudgetTracker)
+	return tracker, ok && tracker != nil
+}
+
+// BudgetExceeded 上下文中的预算是否耗尽，返回耗尽原因
+func BudgetExceeded(ctx context.Context) string {
+	if tracker, ok := GetBudgetTracker(ctx); ok {
+		return tracker.Exceeded()
+	}
+	return ""
+}
+
+var usageCallbackOnce sync.Once
+
+// RegisterUsageCallback 注册全局模型回调，将每次模型调用的token用量计入上下文中的预算跟踪器
+func RegisterUsageCallback() {
+	usageCallbackOnce.Do(func() {
+		callbacks.AppendGlobalHandlers(UsageCallbackHandler())
+	})
+}
+
+// UsageCallbackHandler 统计模型token用量的回调
+func UsageCallbackHandler() callbacks.Handler {
+	return callbacks.NewHandlerBuilder().
+		OnEndFn(func(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
+			tracker, ok := GetBudgetTracker(ctx)
+			if !ok || info == nil || info.Component != components.ComponentOfChatModel {
+				return ctx
+			}
+			if out := model.ConvCallbackOutput(output); out != nil {
+				if usage := callbackTokenUsage(out); usage != nil {
+					tracker.AddUsage(callbackModelName(out), usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens)
+				}
+			}
+			return ctx
+		}).
+		OnEndWithStreamOutputFn(func(ctx context.Context, info *callbacks.RunInfo, output *schema.StreamReader[callbacks.CallbackOutput]) context.Context {
+			tracker, ok := GetBudgetTracker(ctx)
+			if !ok || info == nil || info.Component != components.ComponentOfChatModel {
+				output.Close()
+				return ctx
+			}
+			go func() {
+				defer output.Close()
+				// 流式输出的用量一般在最后一个分片中
+				var usage *model.TokenUsage
+				var modelName string
+				for {
+					chunk, err := output.Recv()
+					if err != nil {
+						break
+					}
+					out := model.ConvCallbackOutput(chunk)
+					if out == nil {
+						continue
+					}
+					if chunkUsage := callbackTokenUsage(out); chunkUsage != nil {
+						usage = chunkUsage
+					}
+					if name := callbackModelName(out); name != "" {
+						modelName = name
+					}
+				}
+				if usage != nil {
+					tracker.AddUsage(modelName, usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens)
+				}
+			}()
+			return ctx
+		}).
+		Build()
+}
+
+// callbackTokenUsage 获取模型回调输出中的token用量，回调由图节点注入时用量在消息的ResponseMeta中
+func callbackTokenUsage(out *model.CallbackOutput) *model.TokenUsage {
+	if out.TokenUsage != nil {
+		return out.TokenUsage
+	}
+	if out.Message != nil && out.Message.ResponseMeta != nil && out.Message.ResponseMeta.Usage != nil {
+		usage := out.Message.ResponseMeta.Usage
+		return &model.TokenUsage{
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+			TotalTokens:      usage.TotalTokens,
+		}
+	}
+	return nil
+}
+
+func callbackModelName(out *model.CallbackOutput) string {
+	if out.Config != nil {
+		return out.Config.Model
+	}
+	return ""
+}
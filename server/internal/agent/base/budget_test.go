@@ -0,0 +1,71 @@
+package base
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudgetTracker(t *testing.T) {
+	price := func(modelName string, promptTokens, completionTokens int) float64 {
+		return float64(promptTokens+completionTokens) / 1000
+	}
+	var notified []string
+	tracker := NewBudgetTracker(price,
+		BudgetLimit{Scope: "run", Budget: Budget{MaxTokens: 1000}},
+		BudgetLimit{Scope: "map", Budget: Budget{MaxCost: 1}, Used: Usage{TotalTokens: 500, Cost: 0.5}},
+	)
+	tracker.OnExceeded(func(reason string) {
+		notified = append(notified, reason)
+	})
+
+	tracker.AddUsage("m", 200, 100, 0)
+	assert.Empty(t, tracker.Exceeded())
+	assert.Equal(t, Usage{PromptTokens: 200, CompletionTokens: 100, TotalTokens: 300, Cost: 0.3}, tracker.Usage())
+
+	// 思维导图的累计费用先耗尽
+	tracker.AddUsage("m", 200, 0, 0)
+	assert.Contains(t, tracker.Exceeded(), "map cost budget exhausted")
+	tracker.AddUsage("m", 1000, 0, 0)
+	assert.Contains(t, tracker.Exceeded(), "map cost budget exhausted")
+	assert.Len(t, notified, 1)
+
+	tracker = NewBudgetTracker(nil, BudgetLimit{Scope: "run", Budget: Budget{MaxDuration: time.Millisecond}})
+	time.Sleep(2 * time.Millisecond)
+	assert.Contains(t, tracker.Exceeded(), "run time budget exhausted")
+	assert.Empty(t, BudgetExceeded(context.Background()))
+	assert.NotEmpty(t, BudgetExceeded(WithBudgetTracker(context.Background(), tracker)))
+}
+
+func TestUsageCallbackHandler(t *testing.T) {
+	handler := UsageCallbackHandler()
+	tracker := NewBudgetTracker(nil)
+	ctx := WithBudgetTracker(context.Background(), tracker)
+	info := &callbacks.RunInfo{Component: components.ComponentOfChatModel}
+
+	handler.OnEnd(ctx, info, &model.CallbackOutput{TokenUsage: &model.TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}})
+	// 图节点注入的回调，用量在消息中
+	msg := schema.AssistantMessage("ok", nil)
+	msg.ResponseMeta = &schema.ResponseMeta{Usage: &schema.TokenUsage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5}}
+	handler.OnEnd(ctx, info, msg)
+	// 非模型组件不统计
+	handler.OnEnd(ctx, &callbacks.RunInfo{Component: components.ComponentOfTool}, &model.CallbackOutput{TokenUsage: &model.TokenUsage{TotalTokens: 100}})
+	assert.Equal(t, 20, tracker.Usage().TotalTokens)
+
+	// 流式输出取最后一个分片中的用量
+	stream := schema.StreamReaderFromArray([]callbacks.CallbackOutput{
+		&model.CallbackOutput{Message: schema.AssistantMessage("a", nil)},
+		&model.CallbackOutput{TokenUsage: &model.TokenUsage{PromptTokens: 7, CompletionTokens: 3, TotalTokens: 10}},
+	})
+	handler.OnEndWithStreamOutput(ctx, info, stream)
+	require.Eventually(t, func() bool {
+		return tracker.Usage().TotalTokens == 30
+	}, time.Second, 10*time.Millisecond)
+}
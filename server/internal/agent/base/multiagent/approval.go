@@ -0,0 +1,168 @@
+package multiagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// PlanApprovalStatus 计划审批状态
+type PlanApprovalStatus string
+
+const (
+	PlanApprovalAwaiting PlanApprovalStatus = "awaiting_approval"
+	PlanApprovalApproved PlanApprovalStatus = "approved"
+	PlanApprovalRejected PlanApprovalStatus = "rejected"
+)
+
+// PlanReviewAction 用户对计划的处理
+type PlanReviewAction string
+
+const (
+	PlanReviewApprove PlanReviewAction = "approve"
+	PlanReviewEdit    PlanReviewAction = "edit"
+	PlanReviewReject  PlanReviewAction = "reject"
+)
+
+var (
+	ErrPlanAwaitingApproval    = errors.New("plan awaiting approval")
+	ErrPlanNotAwaitingApproval = errors.New("plan is not awaiting approval")
+	ErrInvalidPlanReview       = errors.New("invalid plan review")
+)
+
+// PlanApprovalError 计划等待审批时中断运行返回的错误
+type PlanApprovalError struct {
+	RunID string
+	Plan  *TaskPlan
+}
+
+func (e *PlanApprovalError) Error() string {
+	return fmt.Sprintf("run %s: %s", e.RunID, ErrPlanAwaitingApproval)
+}
+
+func (e *PlanApprovalError) Unwrap() error {
+	return ErrPlanAwaitingApproval
+}
+
+type planApprovalKey struct{}
+
+// WithPlanApproval 开启计划审批：计划创建和更新后中断运行，等待用户审批后从检查点恢复
+// 需要同时配置 CheckpointStore 和 WithCheckpointID，否则不生效
+func WithPlanApproval(ctx context.Context) context.Context {
+	return context.WithValue(ctx, planApprovalKey{}, true)
+}
+
+// planApprovalEnabled 判断本次运行是否需要审批计划
+func planApprovalEnabled(ctx context.Context, config *MultiAgentConfig) bool {
+	enabled, _ := ctx.Value(planApprovalKey{}).(bool)
+	if !enabled || config.CheckpointStore == nil {
+		return false
+	}
+	_, ok := GetCheckpointID(ctx)
+	return ok
+}
+
+// PlanApprovalHandler 计划审批节点，保存检查点后中断运行
+type PlanApprovalHandler struct {
+	config *MultiAgentConfig
+}
+
+func NewPlanApprovalHandler(config *MultiAgentConfig) *PlanApprovalHandler {
+	return &PlanApprovalHandler{
+		config: config,
+	}
+}
+
+func (h *PlanApprovalHandler) Await(ctx context.Context, input *schema.Message, state *MultiAgentState) (*schema.Message, error) {
+	if state.CurrentPlan == nil {
+		return nil, fmt.Errorf("no current plan to approve")
+	}
+	state.PlanApprovalStatus = PlanApprovalAwaiting
+	saveCheckpoint(ctx, h.config, planApprovalNodeKey, state)
+	runID, _ := GetCheckpointID(ctx)
+	plan := (&PlanUpdateHandler{}).clonePlan(state.CurrentPlan)
+	return nil, &PlanApprovalError{RunID: runID, Plan: plan}
+}
+
+// PlanReview 用户对等待审批计划的处理，编辑时使用与计划更新相同的操作格式
+type PlanReview struct {
+	Action     PlanReviewAction `json:"action"`
+	Operations []*OperationData `json:"operations,omitempty"`
+	Reason     string           `json:"reason,omitempty"`
+}
+
+// ReviewPlan 处理等待审批的计划
+// 通过或编辑后将检查点标记为已审批，之后调用 Resume 继续执行；拒绝时删除检查点
+func ReviewPlan(ctx context.Context, store CheckpointStore, runID string, review PlanReview) (*TaskPlan, error) {
+	checkpoint, err := store.Load(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	state := &MultiAgentState{}
+	if err := state.FromJSON(checkpoint.State); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal checkpoint state: %w", err)
+	}
+	if checkpoint.NodeKey != planApprovalNodeKey || state.PlanApprovalStatus != PlanApprovalAwaiting || state.CurrentPlan == nil {
+		return nil, ErrPlanNotAwaitingApproval
+	}
+
+	switch review.Action {
+	case PlanReviewReject:
+		if err := store.Delete(ctx, runID); err != nil {
+			return nil, err
+		}
+		state.CurrentPlan.Status = ExecutionStatusCancelled
+		return state.CurrentPlan, nil
+	case PlanReviewEdit:
+		if len(review.Operations) > 0 {
+			planUpdate := &PlanUpdate{UpdateReason: review.Reason, Operations: review.Operations}
+			if err := (&PlanUpdateHandler{}).applyPlanUpdate(state, planUpdate); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidPlanReview, err)
+			}
+		}
+	case PlanReviewApprove:
+	default:
+		return nil, fmt.Errorf("%w: unknown action %s", ErrInvalidPlanReview, review.Action)
+	}
+
+	state.PlanApprovalStatus = PlanApprovalApproved
+	data, err := state.ToJSON()
+	if err != nil {
+		return nil, err
+	}
+	err = store.Save(ctx, &Checkpoint{
+		RunID:     runID,
+		NodeKey:   planApprovalNodeKey,
+		State:     data,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return state.CurrentPlan, nil
+}
+
+// planApprovalBranch 计划创建或更新后，需要审批时进入审批节点，预算耗尽时直接进入最终回答
+func planApprovalBranch(config *MultiAgentConfig) *compose.GraphBranch {
+	return compose.NewGraphBranch(func(ctx context.Context, input *schema.Message) (string, error) {
+		var exceeded bool
+		err := compose.ProcessState(ctx, func(ctx context.Context, state *MultiAgentState) error {
+			exceeded = syncBudget(ctx, state)
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		if exceeded {
+			return toFinalAnswerNodeKey, nil
+		}
+		if planApprovalEnabled(ctx, config) {
+			return planApprovalNodeKey, nil
+		}
+		return planExecutionNodeKey, nil
+	}, map[string]bool{planExecutionNodeKey: true, planApprovalNodeKey: true, toFinalAnswerNodeKey: true})
+}
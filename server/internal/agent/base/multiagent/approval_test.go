@@ -0,0 +1,80 @@
+package multiagent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiAgent_PlanApproval(t *testing.T) {
+	store := NewMemoryCheckpointStore()
+	host := &scriptedChatModel{replies: []string{
+		`{"execution_completed": false, "overall_quality": 0.9, "plan_needs_update": false, "confidence": 0.9}`,
+		"final report",
+	}}
+	writer := &scriptedChatModel{}
+	agent := newCheckpointTestAgent(t, host, writer, store)
+	ctx := WithPlanApproval(context.Background())
+
+	seedCheckpoint(t, store, "run-1",
+		&PlanStep{ID: "step1", Name: "outline", Description: "write outline", AssignedSpecialist: "writer", Status: StepStatusPending},
+		&PlanStep{ID: "step2", Name: "body", Description: "write body", AssignedSpecialist: "writer", Status: StepStatusPending},
+	)
+
+	// 计划更新后中断，等待审批
+	_, err := agent.Resume(ctx, "run-1")
+	var approvalErr *PlanApprovalError
+	require.ErrorAs(t, err, &approvalErr)
+	assert.ErrorIs(t, err, ErrPlanAwaitingApproval)
+	assert.Equal(t, "run-1", approvalErr.RunID)
+	assert.Len(t, approvalErr.Plan.Steps, 2)
+	assert.Equal(t, 0, writer.calls())
+
+	// 未审批时恢复仍然中断
+	_, err = agent.Resume(ctx, "run-1")
+	require.ErrorIs(t, err, ErrPlanAwaitingApproval)
+
+	// 用户删除step1后通过
+	plan, err := ReviewPlan(context.Background(), store, "run-1", PlanReview{
+		Action:     PlanReviewEdit,
+		Operations: []*OperationData{{Type: "remove", StepID: "step1"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, plan.Steps, 1)
+	assert.Equal(t, "step2", plan.Steps[0].ID)
+	_, err = ReviewPlan(context.Background(), store, "run-1", PlanReview{Action: PlanReviewApprove})
+	assert.ErrorIs(t, err, ErrPlanNotAwaitingApproval)
+
+	result, err := agent.Resume(ctx, "run-1")
+	require.NoError(t, err)
+	assert.Equal(t, "final report", result.Content)
+	require.Equal(t, 1, writer.calls())
+	assert.Contains(t, writer.inputs[0][len(writer.inputs[0])-1].Content, "write body")
+}
+
+func TestReviewPlan_Reject(t *testing.T) {
+	ctx := WithPlanApproval(context.Background())
+	store := NewMemoryCheckpointStore()
+	writer := &scriptedChatModel{}
+	agent := newCheckpointTestAgent(t, &scriptedChatModel{}, writer, store)
+
+	seedCheckpoint(t, store, "run-1",
+		&PlanStep{ID: "step1", Name: "outline", Description: "write outline", AssignedSpecialist: "writer", Status: StepStatusPending},
+	)
+	_, err := agent.Resume(ctx, "run-1")
+	require.ErrorIs(t, err, ErrPlanAwaitingApproval)
+
+	_, err = ReviewPlan(ctx, store, "run-1", PlanReview{Action: "unknown"})
+	assert.ErrorIs(t, err, ErrInvalidPlanReview)
+	_, err = ReviewPlan(ctx, store, "run-1", PlanReview{Action: PlanReviewEdit, Operations: []*OperationData{{Type: "remove", StepID: "missing"}}})
+	assert.ErrorIs(t, err, ErrInvalidPlanReview)
+
+	plan, err := ReviewPlan(ctx, store, "run-1", PlanReview{Action: PlanReviewReject})
+	require.NoError(t, err)
+	assert.Equal(t, ExecutionStatusCancelled, plan.Status)
+	_, err = agent.Resume(ctx, "run-1")
+	assert.ErrorIs(t, err, ErrCheckpointNotFound)
+	assert.Equal(t, 0, writer.calls())
+}
@@ -0,0 +1,23 @@
+package multiagent
+
+import (
+	"context"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/base"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// syncBudget 将上下文中预算跟踪器的用量同步到状态，预算耗尽时返回true
+func syncBudget(ctx context.Context, state *MultiAgentState) bool {
+	tracker, ok := base.GetBudgetTracker(ctx)
+	if !ok {
+		return state.BudgetExceeded != ""
+	}
+	state.Usage = tracker.Usage()
+	if reason := tracker.Exceeded(); reason != "" && state.BudgetExceeded == "" {
+		state.BudgetExceeded = reason
+		logger.Info("budget exhausted, force final answer", zap.String("reason", reason))
+	}
+	return state.BudgetExceeded != ""
+}
@@ -0,0 +1,31 @@
+package multiagent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/base"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiAgent_BudgetExceeded(t *testing.T) {
+	store := NewMemoryCheckpointStore()
+	host := &scriptedChatModel{replies: []string{"final report"}}
+	writer := &scriptedChatModel{}
+	agent := newCheckpointTestAgent(t, host, writer, store)
+
+	tracker := base.NewBudgetTracker(nil, base.BudgetLimit{Scope: "run", Budget: base.Budget{MaxTokens: 100}})
+	tracker.AddUsage("test", 80, 40, 0)
+	ctx := base.WithBudgetTracker(context.Background(), tracker)
+
+	seedCheckpoint(t, store, "run-1",
+		&PlanStep{ID: "step1", Name: "outline", Description: "write outline", AssignedSpecialist: "writer", Status: StepStatusPending},
+	)
+	// 预算耗尽，跳过计划执行直接给出最终回答
+	result, err := agent.Resume(ctx, "run-1")
+	require.NoError(t, err)
+	assert.Equal(t, "final report", result.Content)
+	assert.Equal(t, 0, writer.calls())
+	assert.Equal(t, 1, host.calls())
+}
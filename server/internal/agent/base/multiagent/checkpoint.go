@@ -0,0 +1,214 @@
+package multiagent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
+)
+
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+// Checkpoint 一次运行在某个节点结束后的状态快照
+type Checkpoint struct {
+	RunID     string    `json:"runID"`
+	NodeKey   string    `json:"nodeKey"` // 刚执行完成的节点
+	State     []byte    `json:"state"`   // MultiAgentState 的JSON
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CheckpointStore 检查点存储
+type CheckpointStore interface {
+	// Save 保存检查点
+	Save(ctx context.Context, checkpoint *Checkpoint) error
+	// Load 加载运行最近的检查点，不存在时返回 ErrCheckpointNotFound
+	Load(ctx context.Context, runID string) (*Checkpoint, error)
+	// Delete 删除运行的所有检查点
+	Delete(ctx context.Context, runID string) error
+}
+
+// MemoryCheckpointStore 内存检查点存储，只保留每个运行最近的检查点，用于测试
+type MemoryCheckpointStore struct {
+	mu          sync.Mutex
+	checkpoints map[string]*Checkpoint
+}
+
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: make(map[string]*Checkpoint)}
+}
+
+func (s *MemoryCheckpointStore) Save(ctx context.Context, checkpoint *Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp := *checkpoint
+	s.checkpoints[checkpoint.RunID] = &cp
+	return nil
+}
+
+func (s *MemoryCheckpointStore) Load(ctx context.Context, runID string) (*Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp, ok := s.checkpoints[runID]
+	if !ok {
+		return nil, ErrCheckpointNotFound
+	}
+	loaded := *cp
+	return &loaded, nil
+}
+
+func (s *MemoryCheckpointStore) Delete(ctx context.Context, runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.checkpoints, runID)
+	return nil
+}
+
+type checkpointIDKey struct{}
+
+// WithCheckpointID 指定运行的检查点ID
+// 配置了 CheckpointStore 时，每个节点结束后以该ID保存检查点；存在该ID的检查点时从检查点恢复执行
+func WithCheckpointID(ctx context.Context, runID string) context.Context {
+	return context.WithValue(ctx, checkpointIDKey{}, runID)
+}
+
+// GetCheckpointID 获取上下文中的检查点ID
+func GetCheckpointID(ctx context.Context) (string, bool) {
+	runID, ok := ctx.Value(checkpointIDKey{}).(string)
+	return runID, ok && runID != ""
+}
+
+// saveCheckpoint 节点结束后保存检查点，保存失败不影响执行
+func saveCheckpoint(ctx context.Context, config *MultiAgentConfig, nodeKey string, state *MultiAgentState) {
+	if config.CheckpointStore == nil {
+		return
+	}
+	runID, ok := GetCheckpointID(ctx)
+	if !ok {
+		return
+	}
+	data, err := state.ToJSON()
+	if err != nil {
+		logger.Warn("marshal checkpoint state failed", zap.String("runID", runID), zap.Error(err))
+		return
+	}
+	err = config.CheckpointStore.Save(ctx, &Checkpoint{
+		RunID:     runID,
+		NodeKey:   nodeKey,
+		State:     data,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		logger.Warn("save checkpoint failed", zap.String("runID", runID), zap.String("node", nodeKey), zap.Error(err))
+	}
+}
+
+// clearCheckpoint 运行完成后删除检查点
+func clearCheckpoint(ctx context.Context, config *MultiAgentConfig) {
+	if config.CheckpointStore == nil {
+		return
+	}
+	runID, ok := GetCheckpointID(ctx)
+	if !ok {
+		return
+	}
+	if err := config.CheckpointStore.Delete(ctx, runID); err != nil {
+		logger.Warn("delete checkpoint failed", zap.String("runID", runID), zap.Error(err))
+	}
+}
+
+// withCheckpoint 在节点的后置处理完成后保存检查点
+func withCheckpoint(config *MultiAgentConfig, nodeKey string, post compose.StatePostHandler[*schema.Message, *MultiAgentState]) compose.StatePostHandler[*schema.Message, *MultiAgentState] {
+	return func(ctx context.Context, output *schema.Message, state *MultiAgentState) (*schema.Message, error) {
+		output, err := post(ctx, output, state)
+		if err != nil {
+			return output, err
+		}
+		saveCheckpoint(ctx, config, nodeKey, state)
+		return output, nil
+	}
+}
+
+// loadCheckpointState 加载检查点中的状态，没有可恢复的检查点时返回nil
+func loadCheckpointState(ctx context.Context, config *MultiAgentConfig) *MultiAgentState {
+	if config.CheckpointStore == nil {
+		return nil
+	}
+	runID, ok := GetCheckpointID(ctx)
+	if !ok {
+		return nil
+	}
+	checkpoint, err := config.CheckpointStore.Load(ctx, runID)
+	if err != nil {
+		if !errors.Is(err, ErrCheckpointNotFound) {
+			logger.Warn("load checkpoint failed", zap.String("runID", runID), zap.Error(err))
+		}
+		return nil
+	}
+	state := &MultiAgentState{}
+	if err := state.FromJSON(checkpoint.State); err != nil {
+		logger.Warn("unmarshal checkpoint state failed", zap.String("runID", runID), zap.Error(err))
+		return nil
+	}
+	if resumeTarget(ctx, config, checkpoint.NodeKey, state) == "" {
+		return nil
+	}
+	// 中断时执行中的步骤重新执行，已完成的步骤跳过
+	if state.CurrentPlan != nil {
+		for _, step := range state.CurrentPlan.Steps {
+			if step.Status == StepStatusRunning {
+				step.Status = StepStatusPending
+			}
+		}
+	}
+	state.resumeFrom = checkpoint.NodeKey
+	logger.Info("resume from checkpoint", zap.String("runID", runID), zap.String("node", checkpoint.NodeKey))
+	return state
+}
+
+// resumeTarget 根据检查点所在节点确定恢复后执行的下一个节点
+func resumeTarget(ctx context.Context, config *MultiAgentConfig, nodeKey string, state *MultiAgentState) string {
+	switch nodeKey {
+	case conversationAnalyzerNodeKey:
+		return toComplexityBranchNodeKey
+	case planCreationNodeKey, planUpdateNodeKey:
+		if state.CurrentPlan == nil {
+			return ""
+		}
+		if planApprovalEnabled(ctx, config) {
+			return planApprovalNodeKey
+		}
+		return planExecutionNodeKey
+	case planApprovalNodeKey:
+		// 审批通过后继续执行，仍在等待审批时再次中断
+		switch state.PlanApprovalStatus {
+		case PlanApprovalApproved:
+			return planExecutionNodeKey
+		case PlanApprovalAwaiting:
+			return planApprovalNodeKey
+		}
+		return ""
+	case parallelSpecialistsNodeKey:
+		return resultCollectorNodeKey
+	case resultCollectorNodeKey:
+		return toFeedbackProcessorNodeKey
+	case feedbackProcessorNodeKey:
+		if state.CurrentPlan == nil {
+			return ""
+		}
+		return reflectionResumeNodeKey
+	}
+	for _, specialist := range config.Specialists {
+		if specialist.Name == nodeKey {
+			return resultCollectorNodeKey
+		}
+	}
+	return ""
+}
+
+// reflectionResumeNodeKey 从反馈节点恢复时，需要重新做反思决策
+const reflectionResumeNodeKey = "reflection_resume"
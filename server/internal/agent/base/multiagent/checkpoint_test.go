@@ -0,0 +1,150 @@
+package multiagent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// scriptedChatModel 按顺序返回预设回复的模型
+type scriptedChatModel struct {
+	mu      sync.Mutex
+	replies []string
+	inputs  [][]*schema.Message
+}
+
+func (m *scriptedChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inputs = append(m.inputs, input)
+	reply := "ok"
+	if len(m.inputs) <= len(m.replies) {
+		reply = m.replies[len(m.inputs)-1]
+	}
+	return schema.AssistantMessage(reply, nil), nil
+}
+
+func (m *scriptedChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := m.Generate(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{msg}), nil
+}
+
+func (m *scriptedChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+func (m *scriptedChatModel) calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.inputs)
+}
+
+func newCheckpointTestAgent(t *testing.T, host model.ToolCallingChatModel, writer model.BaseChatModel, store CheckpointStore) *MultiAgent {
+	t.Helper()
+	return newTestAgent(t, &MultiAgentConfig{
+		Name: "checkpoint-test",
+		Host: Host{Model: host},
+		Specialists: []*Specialist{
+			{Name: "writer", IntendedUse: "Writing", ChatModel: writer},
+		},
+		MaxRounds:       20,
+		CheckpointStore: store,
+	})
+}
+
+func newTestAgent(t *testing.T, config *MultiAgentConfig) *MultiAgent {
+	t.Helper()
+	if logger.Log == nil {
+		logger.Log = zap.NewNop()
+	}
+	agent, err := NewMultiAgent(context.Background(), config)
+	require.NoError(t, err)
+	return agent
+}
+
+// seedCheckpoint 构造计划更新后中断的检查点
+func seedCheckpoint(t *testing.T, store CheckpointStore, runID string, steps ...*PlanStep) {
+	t.Helper()
+	state := &MultiAgentState{
+		RoundNumber:      1,
+		StartTime:        time.Now(),
+		OriginalMessages: []*schema.Message{schema.UserMessage("write a report")},
+		ConversationContext: &ConversationContext{
+			IsIndependentTopic: true,
+			UserIntent:         "write a report",
+			Complexity:         TaskComplexityComplex,
+		},
+		CurrentPlan: &TaskPlan{
+			ID:    "plan-1",
+			Name:  "report",
+			Steps: steps,
+		},
+		ExecutionStatus: ExecutionStatusPlanning,
+		MaxRounds:       20,
+		ShouldContinue:  true,
+	}
+	data, err := state.ToJSON()
+	require.NoError(t, err)
+	require.NoError(t, store.Save(context.Background(), &Checkpoint{RunID: runID, NodeKey: planUpdateNodeKey, State: data, CreatedAt: time.Now()}))
+}
+
+func TestMemoryCheckpointStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryCheckpointStore()
+
+	_, err := store.Load(ctx, "run-1")
+	assert.ErrorIs(t, err, ErrCheckpointNotFound)
+
+	require.NoError(t, store.Save(ctx, &Checkpoint{RunID: "run-1", NodeKey: planCreationNodeKey, State: []byte(`{}`)}))
+	require.NoError(t, store.Save(ctx, &Checkpoint{RunID: "run-1", NodeKey: resultCollectorNodeKey, State: []byte(`{}`)}))
+	cp, err := store.Load(ctx, "run-1")
+	require.NoError(t, err)
+	assert.Equal(t, resultCollectorNodeKey, cp.NodeKey)
+
+	require.NoError(t, store.Delete(ctx, "run-1"))
+	_, err = store.Load(ctx, "run-1")
+	assert.ErrorIs(t, err, ErrCheckpointNotFound)
+}
+
+func TestMultiAgent_ResumeFromCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryCheckpointStore()
+	host := &scriptedChatModel{replies: []string{
+		`{"execution_completed": false, "overall_quality": 0.9, "plan_needs_update": false, "confidence": 0.9}`,
+		"final report",
+	}}
+	writer := &scriptedChatModel{replies: []string{"report body"}}
+	agent := newCheckpointTestAgent(t, host, writer, store)
+
+	_, err := agent.Resume(ctx, "missing")
+	assert.ErrorIs(t, err, ErrCheckpointNotFound)
+
+	// step1已完成，step2执行中被中断
+	seedCheckpoint(t, store, "run-1",
+		&PlanStep{ID: "step1", Name: "outline", Description: "write outline", AssignedSpecialist: "writer", Status: StepStatusCompleted},
+		&PlanStep{ID: "step2", Name: "body", Description: "write body", AssignedSpecialist: "writer", Status: StepStatusRunning, Dependencies: []string{"step1"}},
+	)
+	result, err := agent.Resume(ctx, "run-1")
+	require.NoError(t, err)
+	assert.Equal(t, "final report", result.Content)
+
+	// 只执行了未完成的step2，没有重新分析和规划
+	require.Equal(t, 1, writer.calls())
+	assert.Contains(t, writer.inputs[0][len(writer.inputs[0])-1].Content, "write body")
+	assert.Equal(t, 2, host.calls())
+
+	// 完成后删除检查点
+	_, err = store.Load(ctx, "run-1")
+	assert.ErrorIs(t, err, ErrCheckpointNotFound)
+}
@@ -30,6 +30,9 @@ const (
 	planUpdateNodeKey           = "plan_update"
 	toFinalAnswerNodeKey        = "to_final_answer"
 	finalAnswerNodeKey          = "final_answer"
+	resumeNodeKey               = "resume"
+	parallelSpecialistsNodeKey  = "parallel_specialists"
+	planApprovalNodeKey         = "plan_approval"
 )
 
 // NewMultiAgent creates a new multi-agent system
@@ -45,6 +48,10 @@ func NewMultiAgent(ctx context.Context, config *MultiAgentConfig, agentOptions .
 	// Create the graph with state
 	graph := compose.NewGraph[[]*schema.Message, *schema.Message](
 		compose.WithGenLocalState(func(ctx context.Context) *MultiAgentState {
+			// 存在检查点时从检查点恢复状态
+			if state := loadCheckpointState(ctx, config); state != nil {
+				return state
+			}
 			return &MultiAgentState{
 				RoundNumber:     1,
 				StartTime:       time.Now(),
@@ -62,9 +69,7 @@ func NewMultiAgent(ctx context.Context, config *MultiAgentConfig, agentOptions .
 		compose.WithStatePreHandler(func(ctx context.Context, input []*schema.Message, state *MultiAgentState) ([]*schema.Message, error) {
 			return conversationAnalyzer.PreHandler(ctx, input, state)
 		}),
-		compose.WithStatePostHandler(func(ctx context.Context, output *schema.Message, state *MultiAgentState) (*schema.Message, error) {
-			return conversationAnalyzer.PostHandler(ctx, output, state)
-		}),
+		compose.WithStatePostHandler(withCheckpoint(config, conversationAnalyzerNodeKey, conversationAnalyzer.PostHandler)),
 		compose.WithNodeName("conversation_analyzer"),
 	)
 	if err != nil {
@@ -93,11 +98,12 @@ func NewMultiAgent(ctx context.Context, config *MultiAgentConfig, agentOptions .
 			compose.WithStatePreHandler(func(ctx context.Context, input []*schema.Message, state *MultiAgentState) ([]*schema.Message, error) {
 				// Build direct answer prompt
 				prompt := buildDirectAnswerPrompt(state)
-				return prompt, nil
+				return withHostPrompt(config, HostPromptDirectAnswer, prompt), nil
 			}),
 			compose.WithStatePostHandler(func(ctx context.Context, output *schema.Message, state *MultiAgentState) (*schema.Message, error) {
 				state.FinalAnswer = output
 				state.IsCompleted = true
+				clearCheckpoint(ctx, config)
 				return output, nil
 			}),
 			compose.WithNodeName("direct_answer"),
@@ -108,11 +114,12 @@ func NewMultiAgent(ctx context.Context, config *MultiAgentConfig, agentOptions .
 			compose.WithStatePreHandler(func(ctx context.Context, input []*schema.Message, state *MultiAgentState) ([]*schema.Message, error) {
 				// Build direct answer prompt
 				prompt := buildDirectAnswerPrompt(state)
-				return prompt, nil
+				return withHostPrompt(config, HostPromptDirectAnswer, prompt), nil
 			}),
 			compose.WithStatePostHandler(func(ctx context.Context, output *schema.Message, state *MultiAgentState) (*schema.Message, error) {
 				state.FinalAnswer = output
 				state.IsCompleted = true
+				clearCheckpoint(ctx, config)
 				return output, nil
 			}),
 			compose.WithNodeName("direct_answer"),
@@ -128,15 +135,31 @@ func NewMultiAgent(ctx context.Context, config *MultiAgentConfig, agentOptions .
 		compose.WithStatePreHandler(func(ctx context.Context, input []*schema.Message, state *MultiAgentState) ([]*schema.Message, error) {
 			return planCreationHandler.PreHandler(ctx, input, state)
 		}),
-		compose.WithStatePostHandler(func(ctx context.Context, output *schema.Message, state *MultiAgentState) (*schema.Message, error) {
-			return planCreationHandler.PostHandler(ctx, output, state)
-		}),
+		compose.WithStatePostHandler(withCheckpoint(config, planCreationNodeKey, planCreationHandler.PostHandler)),
 		compose.WithNodeName("plan_creation"),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to add plan creation node: %w", err)
 	}
 
+	// Add plan approval node, interrupts the run until the user reviews the plan
+	planApprovalHandler := NewPlanApprovalHandler(config)
+	err = graph.AddLambdaNode(planApprovalNodeKey,
+		compose.InvokableLambda(func(ctx context.Context, input *schema.Message) (*schema.Message, error) {
+			var result *schema.Message
+			err := compose.ProcessState(ctx, func(ctx context.Context, state *MultiAgentState) error {
+				var err error
+				result, err = planApprovalHandler.Await(ctx, input, state)
+				return err
+			})
+			return result, err
+		}),
+		compose.WithNodeName("plan_approval"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add plan approval node: %w", err)
+	}
+
 	// Add plan execution node
 	planExecutionHandler := NewPlanExecutionHandler(config)
 	err = graph.AddLambdaNode(planExecutionNodeKey,
@@ -170,11 +193,31 @@ func NewMultiAgent(ctx context.Context, config *MultiAgentConfig, agentOptions .
 
 	// Add specialist nodes
 	for _, specialist := range config.Specialists {
-		if err = addSpecialist(graph, specialist); err != nil {
+		if err = addSpecialist(graph, config, specialist); err != nil {
 			return nil, fmt.Errorf("failed to add specialist node %s: %w", specialist.Name, err)
 		}
 	}
 
+	// Add parallel specialists node, runs independent steps concurrently when MaxParallelism > 1
+	parallelSpecialistsHandler := NewParallelSpecialistsHandler(config)
+	err = graph.AddLambdaNode(parallelSpecialistsNodeKey,
+		compose.InvokableLambdaWithOption(func(ctx context.Context, input []*schema.Message, opts ...specialistHandlerOption) (*schema.Message, error) {
+			result, err := parallelSpecialistsHandler.Execute(ctx, input, opts...)
+			if err != nil {
+				return nil, err
+			}
+			_ = compose.ProcessState(ctx, func(ctx context.Context, state *MultiAgentState) error {
+				saveCheckpoint(ctx, config, parallelSpecialistsNodeKey, state)
+				return nil
+			})
+			return result, nil
+		}),
+		compose.WithNodeName("parallel_specialists"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add parallel specialists node: %w", err)
+	}
+
 	// Add result collector node
 	resultCollectorHandler := NewResultCollectorHandler(config)
 	err = graph.AddLambdaNode(resultCollectorNodeKey,
@@ -184,7 +227,11 @@ func NewMultiAgent(ctx context.Context, config *MultiAgentConfig, agentOptions .
 				// Convert single message to slice for ResultCollector
 				messages := []*schema.Message{input}
 				result, err = resultCollectorHandler.ResultCollector(ctx, messages, state)
-				return err
+				if err != nil {
+					return err
+				}
+				saveCheckpoint(ctx, config, resultCollectorNodeKey, state)
+				return nil
 			})
 			return result, err
 		}),
@@ -201,7 +248,7 @@ func NewMultiAgent(ctx context.Context, config *MultiAgentConfig, agentOptions .
 	feedbackProcessorHandler := NewFeedbackProcessorHandler(config)
 	err = graph.AddChatModelNode(feedbackProcessorNodeKey, config.Host.Model,
 		compose.WithStatePreHandler(feedbackProcessorHandler.PreHandler),
-		compose.WithStatePostHandler(feedbackProcessorHandler.PostHandler),
+		compose.WithStatePostHandler(withCheckpoint(config, feedbackProcessorNodeKey, feedbackProcessorHandler.PostHandler)),
 		compose.WithNodeName("feedback_processor"),
 	)
 	if err != nil {
@@ -213,6 +260,7 @@ func NewMultiAgent(ctx context.Context, config *MultiAgentConfig, agentOptions .
 	reflectionBranch := compose.NewGraphBranch(func(ctx context.Context, input *schema.Message) (string, error) {
 		var result string
 		err = compose.ProcessState(ctx, func(ctx context.Context, state *MultiAgentState) error {
+			syncBudget(ctx, state)
 			result = reflectionBranchHandler.evaluateReflectionDecision(state)
 			return err
 		})
@@ -230,7 +278,7 @@ func NewMultiAgent(ctx context.Context, config *MultiAgentConfig, agentOptions .
 	planUpdateHandler := NewPlanUpdateHandler(config)
 	err = graph.AddChatModelNode(planUpdateNodeKey, config.Host.Model,
 		compose.WithStatePreHandler(planUpdateHandler.PreHandler),
-		compose.WithStatePostHandler(planUpdateHandler.PostHandler),
+		compose.WithStatePostHandler(withCheckpoint(config, planUpdateNodeKey, planUpdateHandler.PostHandler)),
 		compose.WithNodeName("plan_update"),
 	)
 	if err != nil {
@@ -244,15 +292,83 @@ func NewMultiAgent(ctx context.Context, config *MultiAgentConfig, agentOptions .
 	finalAnswerHandler := NewFinalAnswerHandler(config)
 	err = graph.AddChatModelNode(finalAnswerNodeKey, config.Host.Model,
 		compose.WithStatePreHandler(finalAnswerHandler.PreHandler),
-		compose.WithStatePostHandler(finalAnswerHandler.PostHandler),
+		compose.WithStatePostHandler(func(ctx context.Context, output *schema.Message, state *MultiAgentState) (*schema.Message, error) {
+			output, err := finalAnswerHandler.PostHandler(ctx, output, state)
+			if err == nil {
+				clearCheckpoint(ctx, config)
+			}
+			return output, err
+		}),
 		compose.WithNodeName("final_answer"),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to add final answer node: %w", err)
 	}
 
+	// Add resume node, continue from the node after the checkpoint
+	err = graph.AddLambdaNode(resumeNodeKey,
+		compose.InvokableLambda(func(ctx context.Context, input []*schema.Message) (*schema.Message, error) {
+			var resumeFrom string
+			_ = compose.ProcessState(ctx, func(ctx context.Context, state *MultiAgentState) error {
+				resumeFrom = state.resumeFrom
+				return nil
+			})
+			return &schema.Message{
+				Role:    schema.Assistant,
+				Content: fmt.Sprintf("Resume from checkpoint after %s.", resumeFrom),
+			}, nil
+		}),
+		compose.WithNodeName("resume"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add resume node: %w", err)
+	}
+
+	startBranch := compose.NewGraphBranch(func(ctx context.Context, input []*schema.Message) (string, error) {
+		result := conversationAnalyzerNodeKey
+		err := compose.ProcessState(ctx, func(ctx context.Context, state *MultiAgentState) error {
+			if state.resumeFrom != "" {
+				result = resumeNodeKey
+			}
+			return nil
+		})
+		return result, err
+	}, map[string]bool{conversationAnalyzerNodeKey: true, resumeNodeKey: true})
+
+	resumeBranch := compose.NewGraphBranch(func(ctx context.Context, input *schema.Message) (string, error) {
+		var result string
+		err := compose.ProcessState(ctx, func(ctx context.Context, state *MultiAgentState) error {
+			result = resumeTarget(ctx, config, state.resumeFrom, state)
+			switch result {
+			case reflectionResumeNodeKey:
+				syncBudget(ctx, state)
+				result = reflectionBranchHandler.evaluateReflectionDecision(state)
+			case planApprovalNodeKey, planExecutionNodeKey:
+				// 预算耗尽时不再执行计划，直接给出最终回答
+				if syncBudget(ctx, state) {
+					result = toFinalAnswerNodeKey
+				}
+			}
+			state.resumeFrom = ""
+			if result == "" {
+				return fmt.Errorf("no resume target")
+			}
+			return nil
+		})
+		return result, err
+	}, map[string]bool{
+		toComplexityBranchNodeKey:  true,
+		planExecutionNodeKey:       true,
+		planApprovalNodeKey:        true,
+		resultCollectorNodeKey:     true,
+		toFeedbackProcessorNodeKey: true,
+		toPlanUpdateNodeKey:        true,
+		toFinalAnswerNodeKey:       true,
+	})
+
 	// Define edges
-	graph.AddEdge(compose.START, conversationAnalyzerNodeKey)
+	graph.AddBranch(compose.START, startBranch)
+	graph.AddBranch(resumeNodeKey, resumeBranch)
 	graph.AddEdge(conversationAnalyzerNodeKey, toComplexityBranchNodeKey)
 
 	// Complexity branch - directly from conversation analyzer
@@ -261,8 +377,9 @@ func NewMultiAgent(ctx context.Context, config *MultiAgentConfig, agentOptions .
 	// Direct answer path
 	graph.AddEdge(directAnswerNodeKey, compose.END)
 
-	// Plan and execute path
-	graph.AddEdge(planCreationNodeKey, planExecutionNodeKey)
+	// Plan and execute path, optionally waiting for plan approval
+	graph.AddBranch(planCreationNodeKey, planApprovalBranch(config))
+	graph.AddEdge(planApprovalNodeKey, planExecutionNodeKey)
 
 	// Plan execution branch
 	graph.AddEdge(planExecutionNodeKey, toSpecialistBranchNodeKey)
@@ -272,6 +389,7 @@ func NewMultiAgent(ctx context.Context, config *MultiAgentConfig, agentOptions .
 	for _, specialist := range config.Specialists {
 		graph.AddEdge(specialist.Name, resultCollectorNodeKey)
 	}
+	graph.AddEdge(parallelSpecialistsNodeKey, resultCollectorNodeKey)
 
 	// Result collector goes to feedback processor
 	graph.AddEdge(resultCollectorNodeKey, toFeedbackProcessorNodeKey)
@@ -282,7 +400,7 @@ func NewMultiAgent(ctx context.Context, config *MultiAgentConfig, agentOptions .
 
 	// Plan update loop
 	graph.AddEdge(toPlanUpdateNodeKey, planUpdateNodeKey)
-	graph.AddEdge(planUpdateNodeKey, planExecutionNodeKey)
+	graph.AddBranch(planUpdateNodeKey, planApprovalBranch(config))
 
 	// Final answer
 	graph.AddEdge(toFinalAnswerNodeKey, finalAnswerNodeKey)
@@ -321,16 +439,15 @@ func addDefaultSpecialist(ctx context.Context, config *MultiAgentConfig, agentOp
 	return nil
 }
 
-func addSpecialist(graph *compose.Graph[[]*schema.Message, *schema.Message], specialist *Specialist) error {
+func addSpecialist(graph *compose.Graph[[]*schema.Message, *schema.Message], config *MultiAgentConfig, specialist *Specialist) error {
 	specialistHandler := NewSpecialistHandler(specialist)
+	postHandler := withCheckpoint(config, specialist.Name, specialistHandler.PostHandler)
 	if specialist.ReactAgent != nil {
 		if err := graph.AddGraphNode(specialist.Name, specialist.ReactAgent.Graph,
 			compose.WithStatePreHandler(func(ctx context.Context, input []*schema.Message, state *MultiAgentState) ([]*schema.Message, error) {
 				return specialistHandler.PreHandler(ctx, input, state)
 			}),
-			compose.WithStatePostHandler(func(ctx context.Context, output *schema.Message, state *MultiAgentState) (*schema.Message, error) {
-				return specialistHandler.PostHandler(ctx, output, state)
-			}),
+			compose.WithStatePostHandler(postHandler),
 			compose.WithNodeName(specialist.Name)); err != nil {
 			return err
 		}
@@ -342,9 +459,7 @@ func addSpecialist(graph *compose.Graph[[]*schema.Message, *schema.Message], spe
 		if err := graph.AddLambdaNode(specialist.Name, lambda, compose.WithStatePreHandler(func(ctx context.Context, input []*schema.Message, state *MultiAgentState) ([]*schema.Message, error) {
 			return specialistHandler.PreHandler(ctx, input, state)
 		}),
-			compose.WithStatePostHandler(func(ctx context.Context, output *schema.Message, state *MultiAgentState) (*schema.Message, error) {
-				return specialistHandler.PostHandler(ctx, output, state)
-			}),
+			compose.WithStatePostHandler(postHandler),
 			compose.WithNodeName(specialist.Name)); err != nil {
 			return err
 		}
@@ -353,9 +468,7 @@ func addSpecialist(graph *compose.Graph[[]*schema.Message, *schema.Message], spe
 			compose.WithStatePreHandler(func(ctx context.Context, input []*schema.Message, state *MultiAgentState) ([]*schema.Message, error) {
 				return specialistHandler.PreHandler(ctx, input, state)
 			}),
-			compose.WithStatePostHandler(func(ctx context.Context, output *schema.Message, state *MultiAgentState) (*schema.Message, error) {
-				return specialistHandler.PostHandler(ctx, output, state)
-			}),
+			compose.WithStatePostHandler(postHandler),
 			compose.WithNodeName(specialist.Name),
 		); err != nil {
 			return err
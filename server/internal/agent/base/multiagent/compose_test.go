@@ -8,12 +8,11 @@ package multiagent
 import (
 	"context"
 	"fmt"
-	"os"
 	"testing"
 	"time"
 
 	"github.com/PGshen/thinking-map/server/internal/agent/base"
-	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/PGshen/thinking-map/server/internal/agent/testutil"
 	"github.com/cloudwego/eino/callbacks"
 	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/compose"
@@ -45,14 +44,9 @@ var callbackForTest = ub.NewHandlerHelper().ChatModel(&ub.ModelCallbackHandler{
 	},
 }).Handler()
 
-// 注意：这需要设置OPENAI_API_KEY环境变量
-var chatModel, err = openai.NewChatModel(context.Background(), &openai.ChatModelConfig{
-	APIKey: os.Getenv("OPENAI_API_KEY"),
-	Model:  "gpt-4o-mini",
-})
-
-// createTestConfig 创建测试用的配置
-func createTestConfig() *MultiAgentConfig {
+// createTestConfig 创建测试用的配置，模型默认从 testdata/cassettes 回放合成的录制文件
+func createTestConfig(tb testing.TB) *MultiAgentConfig {
+	chatModel := testutil.NewChatModel(tb)
 	return &MultiAgentConfig{
 		Name:        "test-multi-agent",
 		Description: "Test  Multi-Agent System",
@@ -81,7 +75,7 @@ func createTestConfig() *MultiAgentConfig {
 
 func TestNewMultiAgent_Success(t *testing.T) {
 	ctx := context.Background()
-	config := createTestConfig()
+	config := createTestConfig(t)
 
 	// 测试成功创建
 	agent, err := NewMultiAgent(ctx, config)
@@ -135,7 +129,7 @@ func TestNewMultiAgent_InvalidConfig(t *testing.T) {
 
 func TestMultiAgent_BasicExxecution(t *testing.T) {
 	ctx := context.Background()
-	config := createTestConfig()
+	config := createTestConfig(t)
 
 	agent, err := NewMultiAgent(ctx, config)
 	require.NoError(t, err)
@@ -160,7 +154,7 @@ func TestMultiAgent_BasicExxecution(t *testing.T) {
 
 func TestMultiAgent_StreamExecution(t *testing.T) {
 	ctx := context.Background()
-	config := createTestConfig()
+	config := createTestConfig(t)
 
 	agent, err := NewMultiAgent(ctx, config)
 	require.NoError(t, err)
@@ -199,7 +193,7 @@ func TestMultiAgent_StreamExecution(t *testing.T) {
 
 func TestMultiAgent_ComplexTask(t *testing.T) {
 	ctx := context.Background()
-	config := createTestConfig()
+	config := createTestConfig(t)
 
 	multiAgent, err := NewMultiAgent(ctx, config)
 	require.NoError(t, err)
@@ -239,7 +233,7 @@ func TestMultiAgent_ComplexTask(t *testing.T) {
 
 func TestMultiAgent_MultiRoundConversation(t *testing.T) {
 	ctx := context.Background()
-	config := createTestConfig()
+	config := createTestConfig(t)
 
 	multiAgent, err := NewMultiAgent(ctx, config)
 	require.NoError(t, err)
@@ -303,7 +297,7 @@ func TestMultiAgent_ContextTimeout(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 	defer cancel()
 
-	config := createTestConfig()
+	config := createTestConfig(t)
 
 	agent, err := NewMultiAgent(ctx, config)
 	if err != nil {
@@ -333,7 +327,7 @@ func TestMultiAgent_ContextTimeout(t *testing.T) {
 // BenchmarkMultiAgent_Generate 性能基准测试
 func BenchmarkMultiAgent_Generate(b *testing.B) {
 	ctx := context.Background()
-	config := createTestConfig()
+	config := createTestConfig(b)
 
 	agent, err := NewMultiAgent(ctx, config)
 	if err != nil {
@@ -45,7 +45,8 @@ type Specialist struct {
 
 // SessionConfig represents session management configuration
 type SessionConfig struct {
-	HistoryLength     int            `yaml:"history_length" json:"history_length"`
+	HistoryLength int `yaml:"history_length" json:"history_length"`
+	// Deprecated: 未生效，上下文的token预算由模型配置档的 context_budget 指定，保留字段以兼容已有配置
 	ContextWindow     int            `yaml:"context_window" json:"context_window"`
 	ContextProcessing map[string]any `yaml:"context_processing,omitempty" json:"context_processing,omitempty"`
 	IntentAnalysis    map[string]any `yaml:"intent_analysis,omitempty" json:"intent_analysis,omitempty"`
@@ -61,6 +62,10 @@ type MultiAgentConfig struct {
 	PromptTemplates map[string]string `yaml:"prompt_templates,omitempty" json:"prompt_templates,omitempty"`
 	Session         SessionConfig     `yaml:"session" json:"session"`
 	MaxRounds       int               `yaml:"max_rounds" json:"max_rounds"`
+	// MaxParallelism 同时执行的最大步骤数，大于1时依赖已满足的步骤并行分派给专家
+	MaxParallelism int `yaml:"max_parallelism" json:"max_parallelism"`
+	// CheckpointStore 检查点存储，为空时不保存检查点
+	CheckpointStore CheckpointStore `yaml:"-" json:"-"`
 }
 
 // Validate validates the configuration
@@ -31,8 +31,9 @@ func TestIsIndependentTopicContextControl(t *testing.T) {
 		message := buildDirectAnswerPrompt(state)
 		assert.NotNil(t, message)
 
-		// 应该包含完整的上下文信息
-		assert.True(t, contains(message[0].Content, "测试意图") && contains(message[0].Content, "测试摘要"), "应该包含用户意图和上下文摘要")
+		// 原始消息之后的提示应该包含完整的上下文信息
+		prompt := message[len(message)-1]
+		assert.True(t, contains(prompt.Content, "测试意图") && contains(prompt.Content, "测试摘要"), "应该包含用户意图和上下文摘要")
 	})
 
 	t.Run("DirectAnswer_WithoutContext", func(t *testing.T) {
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
@@ -48,7 +50,7 @@ func (h *ConversationAnalyzerHandler) PreHandler(ctx context.Context, input []*s
 
 	// Build conversation analysis prompt
 	prompt := buildConversationAnalysisPrompt(input)
-	return []*schema.Message{prompt}, nil
+	return withHostPrompt(h.config, HostPromptConversationAnalysis, []*schema.Message{prompt}), nil
 }
 
 // PostHandler processes conversation analysis results
@@ -138,6 +140,10 @@ func NewComplexityBranchHandler(config *MultiAgentConfig) *ComplexityBranchHandl
 
 // Evaluate determines the branch based on task complexity
 func (h *ComplexityBranchHandler) Evaluate(ctx context.Context, state *MultiAgentState) (string, error) {
+	// 预算耗尽时不再规划，直接回答
+	if syncBudget(ctx, state) {
+		return directAnswerNodeKey, nil
+	}
 	if state.ConversationContext == nil {
 		return directAnswerNodeKey, nil
 	}
@@ -180,7 +186,7 @@ func (h *PlanCreationHandler) PreHandler(ctx context.Context, input []*schema.Me
 	state.AddExecutionRecord(record)
 
 	prompt := buildPlanCreationPrompt(state, h.config)
-	return []*schema.Message{prompt}, nil
+	return withHostPrompt(h.config, HostPromptPlanCreation, []*schema.Message{prompt}), nil
 }
 
 // PostHandler processes plan creation results
@@ -313,39 +319,55 @@ func (h *SpecialistHandler) PostHandler(ctx context.Context, output *schema.Mess
 	if currentStep == nil {
 		return nil, fmt.Errorf("no current step found for specialist %s", h.specialist.Name)
 	}
+	result := finishStep(state, currentStep, output, nil)
+
+	// 与并行模式一致按步骤ID记录结果，同一专家执行多个步骤时结果互不覆盖
+	state.UpdateSpecialistResult(currentStep.ID, result)
+
+	return output, nil
+}
+
+// finishStep records the specialist output of a step and updates its status and execution record
+func finishStep(state *MultiAgentState, step *PlanStep, output *schema.Message, err error) *StepResult {
 	// Create step result
 	result := &StepResult{
-		Success:      true,
-		Target:       fmt.Sprintf("%s:%s", currentStep.Name, currentStep.Description),
+		Success:      err == nil,
+		Target:       fmt.Sprintf("%s:%s", step.Name, step.Description),
 		Output:       output,
 		Confidence:   0.8, // TODO: implement confidence calculation
 		QualityScore: 0.8, // TODO: implement quality scoring
 	}
-
-	// Update state using unified method
-	state.UpdateSpecialistResult(h.specialist.Name, result)
+	if err != nil {
+		result.Error = err.Error()
+	}
 
 	// Update the existing execution record
 	if len(state.ExecutionHistory) > 0 {
 		// Find the most recent record for this step
 		for i := len(state.ExecutionHistory) - 1; i >= 0; i-- {
 			record := state.ExecutionHistory[i]
-			if record.StepID == state.CurrentStep && record.Action == ActionTypeExecute {
+			if record.StepID == step.ID && record.Action == ActionTypeExecute {
 				// Update the existing record
 				record.Output = output
 				record.EndTime = time.Now()
 				record.Duration = record.EndTime.Sub(record.StartTime)
 				record.Status = ExecutionStatusCompleted
+				if err != nil {
+					record.Status = ExecutionStatusFailed
+					record.Error = err.Error()
+				}
 				break
 			}
 		}
 	}
 
 	// Update step status
-	currentStep.Status = StepStatusCompleted
-	currentStep.Result = result
-
-	return output, nil
+	step.Status = StepStatusCompleted
+	if err != nil {
+		step.Status = StepStatusFailed
+	}
+	step.Result = result
+	return result
 }
 
 // PlanExecutionHandler handles plan execution coordination
@@ -369,6 +391,26 @@ func (h *PlanExecutionHandler) Execute(ctx context.Context, input *schema.Messag
 	// Set execution state
 	state.SetExecutionStatus(ExecutionStatusExecuting)
 	state.ClearSpecialistResults() // Clear previous round results
+	state.CurrentSteps = nil
+
+	// 并行模式下同时分派所有依赖已满足的步骤
+	if h.config.MaxParallelism > 1 {
+		readySteps := findReadySteps(state, h.config.MaxParallelism)
+		if len(readySteps) > 1 {
+			var ids, names []string
+			for _, step := range readySteps {
+				step.Status = StepStatusRunning
+				ids = append(ids, step.ID)
+				names = append(names, step.Name)
+			}
+			state.CurrentSteps = ids
+			state.SetCurrentStep(ids[0])
+			return &schema.Message{
+				Role:    schema.Assistant,
+				Content: fmt.Sprintf("Executing steps in parallel: %s", strings.Join(names, ", ")),
+			}, nil
+		}
+	}
 
 	// Find the next step to execute
 	nextStep := findNextStep(state)
@@ -445,6 +487,9 @@ func NewSpecialistBranchHandler(config *MultiAgentConfig) *SpecialistBranchHandl
 
 // Evaluate determines which specialist should handle the current step
 func (h *SpecialistBranchHandler) Evaluate(ctx context.Context, state *MultiAgentState) (string, error) {
+	if len(state.CurrentSteps) > 1 {
+		return parallelSpecialistsNodeKey, nil // Multiple steps dispatched, run them in parallel
+	}
 	if state.CurrentStep == "" {
 		return generalSpecialistNodeKey, nil // No current step, go to common specialist
 	}
@@ -480,6 +525,7 @@ func buildSpecialistBranchMap(specialists []*Specialist) map[string]bool {
 	for _, specialist := range specialists {
 		branchMap[specialist.Name] = true
 	}
+	branchMap[parallelSpecialistsNodeKey] = true
 
 	return branchMap
 }
@@ -515,7 +561,7 @@ func (h *ResultCollectorHandler) ResultCollector(ctx context.Context, input []*s
 
 	// Collect all results
 	var results []*schema.Message
-	for _, result := range state.SpecialistResults {
+	for _, result := range orderedSpecialistResults(state) {
 		if result.Success && result.Output != nil {
 			// Add specialist name as context
 			msg := &schema.Message{
@@ -541,6 +587,31 @@ func (h *ResultCollectorHandler) ResultCollector(ctx context.Context, input []*s
 	return finalResult, nil
 }
 
+// orderedSpecialistResults returns specialist results in plan step order, so that parallel results are merged deterministically
+func orderedSpecialistResults(state *MultiAgentState) []*StepResult {
+	results := make([]*StepResult, 0, len(state.SpecialistResults))
+	seen := make(map[string]bool)
+	if state.CurrentPlan != nil {
+		for _, step := range state.CurrentPlan.Steps {
+			if result, ok := state.SpecialistResults[step.ID]; ok {
+				results = append(results, result)
+				seen[step.ID] = true
+			}
+		}
+	}
+	keys := make([]string, 0, len(state.SpecialistResults))
+	for key := range state.SpecialistResults {
+		if !seen[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		results = append(results, state.SpecialistResults[key])
+	}
+	return results
+}
+
 type FeedbackProcessorHandler struct {
 	config *MultiAgentConfig
 }
@@ -565,7 +636,7 @@ func (h *FeedbackProcessorHandler) PreHandler(ctx context.Context, input []*sche
 	}
 	state.AddExecutionRecord(record)
 
-	return buildFeedbackPrompt(state), nil
+	return withHostPrompt(h.config, HostPromptFeedback, buildFeedbackPrompt(state)), nil
 }
 
 func (h *FeedbackProcessorHandler) PostHandler(ctx context.Context, output *schema.Message, state *MultiAgentState) (*schema.Message, error) {
@@ -657,6 +728,14 @@ func (h *ReflectionBranchHandler) evaluateReflectionDecision(state *MultiAgentSt
 		}
 	}()
 
+	// 预算耗尽，强制进入最终回答
+	if state.BudgetExceeded != "" {
+		logger.Info("budget exhausted, force completion")
+		state.SetExecutionStatus(ExecutionStatusCompleted)
+		decision = toFinalAnswerNodeKey
+		return
+	}
+
 	// Get latest feedback from feedback history
 	if len(state.FeedbackHistory) == 0 {
 		// No feedback available, default to continue execution
@@ -731,7 +810,7 @@ func (h *PlanUpdateHandler) PreHandler(ctx context.Context, input []*schema.Mess
 	}
 	state.AddExecutionRecord(record)
 
-	return buildPlanUpdatePrompt(state), nil
+	return withHostPrompt(h.config, HostPromptPlanUpdate, buildPlanUpdatePrompt(state)), nil
 }
 
 func (h *PlanUpdateHandler) PostHandler(ctx context.Context, output *schema.Message, state *MultiAgentState) (*schema.Message, error) {
@@ -775,6 +854,16 @@ func (h *PlanUpdateHandler) processPlanUpdate(output *schema.Message, state *Mul
 		return fmt.Errorf("failed to parse plan update operations: %w", err)
 	}
 
+	if err := h.applyPlanUpdate(state, &planUpdate); err != nil {
+		return err
+	}
+
+	state.RoundNumber++
+	return nil
+}
+
+// applyPlanUpdate applies the update operations to a copy of the current plan and replaces it
+func (h *PlanUpdateHandler) applyPlanUpdate(state *MultiAgentState, planUpdate *PlanUpdate) error {
 	if state.CurrentPlan == nil {
 		return fmt.Errorf("no current plan to update")
 	}
@@ -813,7 +902,7 @@ func (h *PlanUpdateHandler) processPlanUpdate(output *schema.Message, state *Mul
 		operationDataList = append(operationDataList, *opData)
 	}
 	// save update info
-	updatedPlan.PlanUpdate = &planUpdate
+	updatedPlan.PlanUpdate = planUpdate
 
 	// Add old plan to history
 	state.AddPlanToHistory(state.CurrentPlan)
@@ -824,8 +913,6 @@ func (h *PlanUpdateHandler) processPlanUpdate(output *schema.Message, state *Mul
 	// Only clear specialist results for steps that were modified/removed
 	// This preserves results for completed and unmodified steps
 	h.selectiveClearSpecialistResults(state, operationDataList)
-
-	state.RoundNumber++
 	return nil
 }
 
@@ -1156,7 +1243,7 @@ func (h *FinalAnswerHandler) PreHandler(ctx context.Context, input []*schema.Mes
 
 	// Build final answer prompt
 	prompt := buildFinalAnswerPrompt(state)
-	return []*schema.Message{prompt}, nil
+	return withHostPrompt(h.config, HostPromptFinalAnswer, []*schema.Message{prompt}), nil
 }
 
 func (h *FinalAnswerHandler) PostHandler(ctx context.Context, output *schema.Message, state *MultiAgentState) (*schema.Message, error) {
@@ -26,6 +26,15 @@ type MessageHandler interface {
 
 // createMessageHandlerOption 创建通用的消息处理器option
 func createMessageHandlerOption(handler MessageHandler, nodeKey ...string) base.AgentOption {
+	return base.WithComposeOptions(messageHandlerComposeOption(handler, nodeKey...))
+}
+
+func messageHandlerComposeOption(handler MessageHandler, nodeKey ...string) compose.Option {
+	return compose.WithCallbacks(newMessageCallbackHandler(handler)).DesignateNodeWithPath(compose.NewNodePath(nodeKey...))
+}
+
+// newMessageCallbackHandler 将消息处理器转换为模型回调
+func newMessageCallbackHandler(handler MessageHandler) callbacks.Handler {
 	cmHandler := &ub.ModelCallbackHandler{
 		OnEnd: func(ctx context.Context, runInfo *callbacks.RunInfo, output *model.CallbackOutput) context.Context {
 			ctx, _ = handler.OnMessage(ctx, output.Message)
@@ -40,9 +49,7 @@ func createMessageHandlerOption(handler MessageHandler, nodeKey ...string) base.
 			return ctx
 		},
 	}
-	cb := ub.NewHandlerHelper().ChatModel(cmHandler).Handler()
-	option := base.WithComposeOptions(compose.WithCallbacks(cb).DesignateNodeWithPath(compose.NewNodePath(nodeKey...)))
-	return option
+	return ub.NewHandlerHelper().ChatModel(cmHandler).Handler()
 }
 
 // WithConversationAnalyzer 为对话分析节点添加消息处理器
@@ -75,9 +82,12 @@ func WithFinalAnswerHandler(handler MessageHandler) base.AgentOption {
 	return createMessageHandlerOption(handler, finalAnswerNodeKey)
 }
 
-// WithSpecialistHandler 为指定专家节点添加消息处理器
+// WithSpecialistHandler 为指定专家节点添加消息处理器，并行执行时同样生效
 func WithSpecialistHandler(specialistName string, handler MessageHandler) base.AgentOption {
-	return createMessageHandlerOption(handler, specialistName, "reasoning")
+	return base.WithComposeOptions(
+		messageHandlerComposeOption(handler, specialistName, "reasoning"),
+		compose.WithLambdaOption(specialistHandlerOption{name: specialistName, handler: handler}).DesignateNode(parallelSpecialistsNodeKey),
+	)
 }
 
 type PlanHandler interface {
@@ -119,6 +129,15 @@ func WithPlanHandler(handler PlanHandler) base.AgentOption {
 	peHandler := callbacks.NewHandlerBuilder().OnEndFn(func(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
 		// 计划执行结束
 		compose.ProcessState(ctx, func(ctx context.Context, state *MultiAgentState) error {
+			// 并行执行时通知所有分派的步骤
+			if len(state.CurrentSteps) > 1 {
+				for _, stepID := range state.CurrentSteps {
+					if step := findStep(state, stepID); step != nil {
+						ctx, _ = handler.OnPlanStepStatusUpdate(ctx, state.CurrentPlan, step)
+					}
+				}
+				return nil
+			}
 			var currentStep *PlanStep
 			for _, step := range state.CurrentPlan.Steps {
 				if step.ID == state.CurrentStep {
@@ -3,8 +3,10 @@ package multiagent
 import (
 	"context"
 	"fmt"
+	"sync"
 	"testing"
 
+	"github.com/PGshen/thinking-map/server/internal/agent/base/react"
 	"github.com/cloudwego/eino/schema"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -43,7 +45,7 @@ func (m *mockConversationAnalyzer) OnStreamMessage(ctx context.Context, message
 // TestWithConversationAnalyzer_BasicFunctionality 测试基本功能
 func TestWithConversationAnalyzer_BasicFunctionality(t *testing.T) {
 	ctx := context.Background()
-	config := createTestConfig()
+	config := createTestConfig(t)
 
 	// 创建mock analyzer
 	mockAnalyzer := &mockConversationAnalyzer{}
@@ -77,7 +79,7 @@ func TestWithConversationAnalyzer_BasicFunctionality(t *testing.T) {
 // TestWithConversationAnalyzer_StreamFunctionality 测试流式功能
 func TestWithConversationAnalyzer_StreamFunctionality(t *testing.T) {
 	ctx := context.Background()
-	config := createTestConfig()
+	config := createTestConfig(t)
 
 	// 创建mock analyzer
 	mockAnalyzer := &mockConversationAnalyzer{}
@@ -124,7 +126,7 @@ func TestWithConversationAnalyzer_StreamFunctionality(t *testing.T) {
 
 func TestWithConversationAnalyzer_MultiRoundConversation(t *testing.T) {
 	ctx := context.Background()
-	config := createTestConfig()
+	config := createTestConfig(t)
 	mockAnalyzer := &mockConversationAnalyzer{}
 
 	enhancedMultiAgent, err := NewMultiAgent(ctx, config)
@@ -188,7 +190,7 @@ func TestWithConversationAnalyzer_MultiRoundConversation(t *testing.T) {
 // TestWithConversationAnalyzer_MultipleMessages 测试多消息场景
 func TestWithConversationAnalyzer_MultipleMessages(t *testing.T) {
 	ctx := context.Background()
-	config := createTestConfig()
+	config := createTestConfig(t)
 
 	// 创建mock analyzer
 	mockAnalyzer := &mockConversationAnalyzer{}
@@ -231,7 +233,7 @@ func TestWithConversationAnalyzer_MultipleMessages(t *testing.T) {
 // TestWithConversationAnalyzer_ErrorHandling 测试错误处理
 func TestWithConversationAnalyzer_ErrorHandling(t *testing.T) {
 	ctx := context.Background()
-	config := createTestConfig()
+	config := createTestConfig(t)
 
 	// 创建会返回错误的mock analyzer
 	errorAnalyzer := &errorConversationAnalyzer{}
@@ -277,7 +279,7 @@ func (e *errorConversationAnalyzer) OnStreamMessage(ctx context.Context, message
 // TestWithConversationAnalyzer_ContextPropagation 测试上下文传播
 func TestWithConversationAnalyzer_ContextPropagation(t *testing.T) {
 	ctx := context.Background()
-	config := createTestConfig()
+	config := createTestConfig(t)
 
 	// 创建会修改context的mock analyzer
 	contextAnalyzer := &contextModifyingAnalyzer{}
@@ -368,6 +370,19 @@ func (m *mockMessageHandler) OnStreamMessage(ctx context.Context, message *schem
 	return ctx, m.errorToReturn
 }
 
+// useReactAgents 主持人和专家改用（与主持人相同模型的）ReAct Agent，直接回答和专家的消息处理器作用于其中的reasoning节点
+func useReactAgents(t *testing.T, config *MultiAgentConfig) {
+	ctx := context.Background()
+	hostAgent, err := react.NewAgent(ctx, react.ReactAgentConfig{ToolCallingModel: config.Host.Model})
+	require.NoError(t, err)
+	config.Host.ReactAgent = hostAgent
+	for _, specialist := range config.Specialists {
+		agent, err := react.NewAgent(ctx, react.ReactAgentConfig{ToolCallingModel: config.Host.Model})
+		require.NoError(t, err)
+		specialist.ReactAgent = agent
+	}
+}
+
 func TestWithDirectAnswerHandler(t *testing.T) {
 	mockHandler := &mockMessageHandler{}
 	option := WithDirectAnswerHandler(mockHandler)
@@ -376,7 +391,8 @@ func TestWithDirectAnswerHandler(t *testing.T) {
 	assert.NotNil(t, option)
 
 	// 创建测试agent并应用option
-	config := createTestConfig()
+	config := createTestConfig(t)
+	useReactAgents(t, config)
 	agent, err := NewMultiAgent(context.Background(), config)
 	assert.NoError(t, err)
 
@@ -397,7 +413,7 @@ func TestWithPlanCreationHandler(t *testing.T) {
 	assert.NotNil(t, option)
 
 	// 创建测试agent并应用option
-	config := createTestConfig()
+	config := createTestConfig(t)
 	agent, err := NewMultiAgent(context.Background(), config)
 	assert.NoError(t, err)
 
@@ -418,7 +434,7 @@ func TestWithFeedbackProcessorHandler(t *testing.T) {
 	assert.NotNil(t, option)
 
 	// 创建测试agent并应用option
-	config := createTestConfig()
+	config := createTestConfig(t)
 	agent, err := NewMultiAgent(context.Background(), config)
 	assert.NoError(t, err)
 
@@ -439,7 +455,7 @@ func TestWithPlanUpdateHandler(t *testing.T) {
 	assert.NotNil(t, option)
 
 	// 创建测试agent并应用option
-	config := createTestConfig()
+	config := createTestConfig(t)
 	agent, err := NewMultiAgent(context.Background(), config)
 	assert.NoError(t, err)
 
@@ -460,7 +476,7 @@ func TestWithFinalAnswerHandler(t *testing.T) {
 	assert.NotNil(t, option)
 
 	// 创建测试agent并应用option
-	config := createTestConfig()
+	config := createTestConfig(t)
 	agent, err := NewMultiAgent(context.Background(), config)
 	assert.NoError(t, err)
 
@@ -481,7 +497,8 @@ func TestWithSpecialistHandler(t *testing.T) {
 	assert.NotNil(t, option)
 
 	// 创建测试agent并应用option
-	config := createTestConfig()
+	config := createTestConfig(t)
+	useReactAgents(t, config)
 	agent, err := NewMultiAgent(context.Background(), config)
 	assert.NoError(t, err)
 
@@ -499,3 +516,75 @@ func TestAllHandlerInterfaces(t *testing.T) {
 	// 验证所有接口实现
 	var _ MessageHandler = (*mockMessageHandler)(nil)
 }
+
+// recordingPlanHandler 记录计划回调，回调可能在流式解析的goroutine中执行
+type recordingPlanHandler struct {
+	mu            sync.Mutex
+	created       []string
+	statusUpdates []string
+	opEnds        int
+}
+
+func (h *recordingPlanHandler) OnPlanStepCreate(ctx context.Context, plan *TaskPlan, step *PlanStep) (context.Context, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.created = append(h.created, step.ID)
+	return ctx, nil
+}
+
+func (h *recordingPlanHandler) OnPlanStepUpdate(ctx context.Context, plan *TaskPlan, step *PlanStep) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *recordingPlanHandler) OnPlanStepStatusUpdate(ctx context.Context, plan *TaskPlan, step *PlanStep) (context.Context, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.statusUpdates = append(h.statusUpdates, step.ID)
+	return ctx, nil
+}
+
+func (h *recordingPlanHandler) OnPlanStepDelete(ctx context.Context, plan *TaskPlan, step *PlanStep) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *recordingPlanHandler) OnPlanOpEnd(ctx context.Context, plan *TaskPlan) (context.Context, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.opEnds++
+	return ctx, nil
+}
+
+// TestWithPlanHandler 执行一个两步计划，验证计划解析和步骤执行的回调
+func TestWithPlanHandler(t *testing.T) {
+	ctx := context.Background()
+	config := createTestConfig(t)
+	agent, err := NewMultiAgent(ctx, config)
+	require.NoError(t, err)
+
+	handler := &recordingPlanHandler{}
+	input := []*schema.Message{
+		{Role: schema.User, Content: "Please make a plan to learn Go concurrency step by step."},
+	}
+	stream, err := agent.Stream(ctx, input, WithPlanHandler(handler))
+	require.NoError(t, err)
+	var chunks []*schema.Message
+	for {
+		chunk, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		chunks = append(chunks, chunk)
+	}
+	result, err := schema.ConcatMessages(chunks)
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.Content)
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	require.NotEmpty(t, handler.created, "plan steps should be parsed from the plan creation stream")
+	assert.Equal(t, 1, handler.opEnds)
+	// 每个计划步骤执行时都通知状态变化
+	for _, stepID := range handler.created {
+		assert.Contains(t, handler.statusUpdates, stepID)
+	}
+}
@@ -0,0 +1,192 @@
+package multiagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/base"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// specialistHandlerOption 并行执行时专家的消息处理器，通过lambda option传给并行节点
+type specialistHandlerOption struct {
+	name    string
+	handler MessageHandler
+}
+
+// parallelTask 一个并行执行的计划步骤
+type parallelTask struct {
+	step       *PlanStep
+	specialist *Specialist
+	prompt     []*schema.Message
+	output     *schema.Message
+	err        error
+}
+
+// ParallelSpecialistsHandler 并行执行多个依赖已满足的计划步骤
+type ParallelSpecialistsHandler struct {
+	config *MultiAgentConfig
+}
+
+func NewParallelSpecialistsHandler(config *MultiAgentConfig) *ParallelSpecialistsHandler {
+	return &ParallelSpecialistsHandler{
+		config: config,
+	}
+}
+
+// Execute 将当前批次的步骤同时分派给各自的专家，全部结束后写回状态
+func (h *ParallelSpecialistsHandler) Execute(ctx context.Context, input []*schema.Message, opts ...specialistHandlerOption) (*schema.Message, error) {
+	handlers := make(map[string]MessageHandler)
+	for _, opt := range opts {
+		handlers[opt.name] = opt.handler
+	}
+
+	// 1. 准备每个步骤的输入
+	var tasks []*parallelTask
+	err := compose.ProcessState(ctx, func(ctx context.Context, state *MultiAgentState) error {
+		for _, stepID := range state.CurrentSteps {
+			step := findStep(state, stepID)
+			if step == nil {
+				return fmt.Errorf("step %s not found", stepID)
+			}
+			specialist := h.findSpecialist(step.AssignedSpecialist)
+			if specialist == nil {
+				return fmt.Errorf("no specialist available for step %s", stepID)
+			}
+			state.AddExecutionRecord(&ExecutionRecord{
+				StepID:    step.ID,
+				Action:    ActionTypeExecute,
+				Input:     input,
+				StartTime: time.Now(),
+				Status:    ExecutionStatusRunning,
+			})
+			tasks = append(tasks, &parallelTask{
+				step:       step,
+				specialist: specialist,
+				prompt:     buildSpecialistPrompt(specialist, step, state),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 并发执行，不持有状态锁
+	limit := h.config.MaxParallelism
+	if limit <= 0 || limit > len(tasks) {
+		limit = len(tasks)
+	}
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for _, task := range tasks {
+		wg.Add(1)
+		go func(task *parallelTask) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			task.output, task.err = runSpecialist(ctx, task.specialist, task.prompt, handlers[task.specialist.Name])
+		}(task)
+	}
+	wg.Wait()
+
+	// 3. 写回结果，单个步骤失败不影响其他步骤
+	var errs []error
+	var names []string
+	err = compose.ProcessState(ctx, func(ctx context.Context, state *MultiAgentState) error {
+		for _, task := range tasks {
+			result := finishStep(state, task.step, task.output, task.err)
+			state.UpdateSpecialistResult(task.step.ID, result)
+			if task.err != nil {
+				errs = append(errs, fmt.Errorf("step %s: %w", task.step.ID, task.err))
+				continue
+			}
+			names = append(names, task.step.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(errs) == len(tasks) {
+		return nil, errors.Join(errs...)
+	}
+
+	return &schema.Message{
+		Role:    schema.Assistant,
+		Content: fmt.Sprintf("Executed %d steps in parallel: %s", len(names), strings.Join(names, ", ")),
+	}, nil
+}
+
+// findSpecialist 查找步骤指定的专家，找不到时使用通用专家
+func (h *ParallelSpecialistsHandler) findSpecialist(name string) *Specialist {
+	var general *Specialist
+	for _, specialist := range h.config.Specialists {
+		if specialist.Name == name {
+			return specialist
+		}
+		if specialist.Name == generalSpecialistNodeKey {
+			general = specialist
+		}
+	}
+	return general
+}
+
+// runSpecialist 在图外直接调用专家
+func runSpecialist(ctx context.Context, specialist *Specialist, input []*schema.Message, handler MessageHandler) (*schema.Message, error) {
+	switch {
+	case specialist.ReactAgent != nil:
+		// 流式执行，使消息处理器与串行执行时一样收到推理节点的流式输出
+		var opts []base.AgentOption
+		if handler != nil {
+			opts = append(opts, base.WithComposeOptions(compose.WithCallbacks(newMessageCallbackHandler(handler)).DesignateNode("reasoning")))
+		}
+		sr, err := specialist.ReactAgent.Stream(ctx, input, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return schema.ConcatMessageStream(sr)
+	case specialist.Invokable != nil:
+		return specialist.Invokable(ctx, input)
+	case specialist.Streamable != nil:
+		sr, err := specialist.Streamable(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		return schema.ConcatMessageStream(sr)
+	case specialist.ChatModel != nil:
+		return specialist.ChatModel.Generate(ctx, input)
+	}
+	return nil, fmt.Errorf("specialist %s is not runnable", specialist.Name)
+}
+
+// findReadySteps 查找依赖已满足的待执行步骤，最多返回limit个
+func findReadySteps(state *MultiAgentState, limit int) []*PlanStep {
+	var steps []*PlanStep
+	for _, step := range state.CurrentPlan.Steps {
+		if len(steps) >= limit {
+			break
+		}
+		if step.Status == StepStatusPending && areDependenciesCompleted(step, state) {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+func findStep(state *MultiAgentState, stepID string) *PlanStep {
+	if state.CurrentPlan == nil {
+		return nil
+	}
+	for _, step := range state.CurrentPlan.Steps {
+		if step.ID == stepID {
+			return step
+		}
+	}
+	return nil
+}
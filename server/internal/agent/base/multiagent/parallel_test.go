@@ -0,0 +1,134 @@
+package multiagent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// barrierChatModel 等待指定数量的调用同时到达后才返回，用于验证并发执行
+type barrierChatModel struct {
+	scriptedChatModel
+	wait    int
+	reached int
+	release chan struct{}
+	active  int
+	peak    int
+}
+
+func newBarrierChatModel(wait int) *barrierChatModel {
+	return &barrierChatModel{wait: wait, release: make(chan struct{})}
+}
+
+func (m *barrierChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.mu.Lock()
+	m.inputs = append(m.inputs, input)
+	m.active++
+	if m.active > m.peak {
+		m.peak = m.active
+	}
+	m.mu.Unlock()
+
+	m.mu.Lock()
+	m.reached++
+	if m.reached == m.wait {
+		close(m.release)
+	}
+	m.mu.Unlock()
+	select {
+	case <-m.release:
+	case <-time.After(2 * time.Second):
+	}
+
+	m.mu.Lock()
+	m.active--
+	m.mu.Unlock()
+	return schema.AssistantMessage("done", nil), nil
+}
+
+func TestMultiAgent_ParallelSteps(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryCheckpointStore()
+	host := &scriptedChatModel{replies: []string{
+		`{"execution_completed": false, "overall_quality": 0.9, "plan_needs_update": false, "confidence": 0.9}`,
+		`{"execution_completed": false, "overall_quality": 0.9, "plan_needs_update": false, "confidence": 0.9}`,
+		"final report",
+	}}
+	researcher := newBarrierChatModel(3)
+	agent := newTestAgent(t, &MultiAgentConfig{
+		Name: "parallel-test",
+		Host: Host{Model: host},
+		Specialists: []*Specialist{
+			{Name: "researcher", IntendedUse: "Research", ChatModel: researcher},
+		},
+		MaxRounds:       20,
+		MaxParallelism:  3,
+		CheckpointStore: store,
+	})
+
+	// 三个相互独立的调研步骤和一个依赖它们的汇总步骤
+	seedCheckpoint(t, store, "run-1",
+		&PlanStep{ID: "a", Name: "research a", Description: "research topic a", AssignedSpecialist: "researcher", Status: StepStatusPending},
+		&PlanStep{ID: "b", Name: "research b", Description: "research topic b", AssignedSpecialist: "researcher", Status: StepStatusPending},
+		&PlanStep{ID: "c", Name: "research c", Description: "research topic c", AssignedSpecialist: "researcher", Status: StepStatusPending},
+		&PlanStep{ID: "d", Name: "summary", Description: "summarize topics", AssignedSpecialist: "researcher", Status: StepStatusPending, Dependencies: []string{"a", "b", "c"}},
+	)
+	result, err := agent.Resume(ctx, "run-1")
+	require.NoError(t, err)
+	assert.Equal(t, "final report", result.Content)
+
+	// 独立步骤同时执行，依赖步骤在之后单独执行
+	assert.Equal(t, 3, researcher.peak)
+	assert.Equal(t, 4, researcher.calls())
+	assert.Equal(t, 3, host.calls())
+
+	// 反馈提示中按计划顺序合并了并行步骤的结果
+	feedbackPrompt := host.inputs[0][len(host.inputs[0])-1].Content
+	ia := strings.Index(feedbackPrompt, "research a:research topic a")
+	ib := strings.Index(feedbackPrompt, "research b:research topic b")
+	ic := strings.Index(feedbackPrompt, "research c:research topic c")
+	require.True(t, ia >= 0 && ib >= 0 && ic >= 0, feedbackPrompt)
+	assert.True(t, ia < ib && ib < ic)
+}
+
+func TestFindReadySteps(t *testing.T) {
+	state := &MultiAgentState{CurrentPlan: &TaskPlan{Steps: []*PlanStep{
+		{ID: "a", Status: StepStatusCompleted},
+		{ID: "b", Status: StepStatusPending, Dependencies: []string{"a"}},
+		{ID: "c", Status: StepStatusPending},
+		{ID: "d", Status: StepStatusPending, Dependencies: []string{"b"}},
+		{ID: "e", Status: StepStatusPending},
+	}}}
+
+	var ids []string
+	for _, step := range findReadySteps(state, 2) {
+		ids = append(ids, step.ID)
+	}
+	assert.Equal(t, []string{"b", "c"}, ids)
+	assert.Len(t, findReadySteps(state, 10), 3)
+}
+
+func TestSpecialistHandler_ResultKeyedByStepID(t *testing.T) {
+	state := &MultiAgentState{
+		CurrentStep: "b",
+		CurrentPlan: &TaskPlan{Steps: []*PlanStep{
+			{ID: "a", Name: "research a", AssignedSpecialist: "researcher", Status: StepStatusCompleted},
+			{ID: "b", Name: "research b", AssignedSpecialist: "researcher", Status: StepStatusRunning},
+		}},
+		SpecialistResults: map[string]*StepResult{"a": {Success: true}},
+	}
+	handler := NewSpecialistHandler(&Specialist{Name: "researcher"})
+	_, err := handler.PostHandler(context.Background(), schema.AssistantMessage("result two", nil), state)
+	require.NoError(t, err)
+
+	require.Contains(t, state.SpecialistResults, "a")
+	require.Contains(t, state.SpecialistResults, "b")
+	assert.NotContains(t, state.SpecialistResults, "researcher")
+	assert.Equal(t, "result two", state.SpecialistResults["b"].Output.Content)
+}
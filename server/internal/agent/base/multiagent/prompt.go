@@ -7,6 +7,31 @@ import (
 	"github.com/cloudwego/eino/schema"
 )
 
+// Host.Prompts 中各阶段附加提示的键
+const (
+	HostPromptConversationAnalysis = "conversation_analysis"
+	HostPromptDirectAnswer         = "direct_answer"
+	HostPromptPlanCreation         = "plan_creation"
+	HostPromptFeedback             = "feedback"
+	HostPromptPlanUpdate           = "plan_update"
+	HostPromptFinalAnswer          = "final_answer"
+)
+
+// withHostPrompt 在主持人的输入前加上系统提示和该阶段的附加提示
+func withHostPrompt(config *MultiAgentConfig, stage string, messages []*schema.Message) []*schema.Message {
+	parts := make([]string, 0, 2)
+	if config.Host.SystemPrompt != "" {
+		parts = append(parts, config.Host.SystemPrompt)
+	}
+	if prompt := config.Host.Prompts[stage]; prompt != "" {
+		parts = append(parts, prompt)
+	}
+	if len(parts) == 0 {
+		return messages
+	}
+	return append([]*schema.Message{schema.SystemMessage(strings.Join(parts, "\n\n"))}, messages...)
+}
+
 func buildConversationAnalysisPrompt(messages []*schema.Message) *schema.Message {
 	prompt := `Analyze the following conversation and extract key information:
 
@@ -0,0 +1,194 @@
+package multiagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/base"
+	"github.com/PGshen/thinking-map/server/internal/agent/base/react"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// HostSpec 主持人的声明式配置
+type HostSpec struct {
+	Model        string            `yaml:"model" json:"model"` // 模型配置档名称
+	Tools        []string          `yaml:"tools,omitempty" json:"tools,omitempty"`
+	MaxSteps     int               `yaml:"max_steps,omitempty" json:"max_steps,omitempty"`
+	SystemPrompt string            `yaml:"system_prompt" json:"system_prompt"`
+	Prompts      map[string]string `yaml:"prompts,omitempty" json:"prompts,omitempty"`
+	Thinking     ThinkingConfig    `yaml:"thinking" json:"thinking"`
+	Planning     PlanningConfig    `yaml:"planning" json:"planning"`
+}
+
+// SpecialistSpec 专家的声明式配置
+// 配置了工具或最大步数时以ReAct Agent运行，否则直接调用模型
+type SpecialistSpec struct {
+	Name         string   `yaml:"name" json:"name"`
+	IntendedUse  string   `yaml:"intended_use" json:"intended_use"`
+	SystemPrompt string   `yaml:"system_prompt" json:"system_prompt"`
+	Model        string   `yaml:"model" json:"model"` // 模型配置档名称
+	Tools        []string `yaml:"tools,omitempty" json:"tools,omitempty"`
+	MaxSteps     int      `yaml:"max_steps,omitempty" json:"max_steps,omitempty"`
+}
+
+// MultiAgentSpec 多智能体系统的声明式配置，可以从YAML文件加载
+type MultiAgentSpec struct {
+	Name            string            `yaml:"name" json:"name"`
+	Description     string            `yaml:"description,omitempty" json:"description,omitempty"`
+	Host            HostSpec          `yaml:"host" json:"host"`
+	Specialists     []*SpecialistSpec `yaml:"specialists" json:"specialists"`
+	PromptTemplates map[string]string `yaml:"prompt_templates,omitempty" json:"prompt_templates,omitempty"`
+	Session         SessionConfig     `yaml:"session" json:"session"`
+	MaxRounds       int               `yaml:"max_rounds" json:"max_rounds"`
+	MaxParallelism  int               `yaml:"max_parallelism" json:"max_parallelism"`
+}
+
+// SpecResolver 解析声明式配置中引用的模型和工具
+type SpecResolver struct {
+	// ChatModel 根据模型配置档创建模型，profile为空时由owner（"host"或专家名称）决定使用的配置档
+	ChatModel func(ctx context.Context, profile, owner string) (model.ToolCallingChatModel, error)
+	// Tools 根据名称获取工具
+	Tools func(ctx context.Context, names []string) ([]tool.BaseTool, error)
+}
+
+// SpecOwnerHost 解析主持人模型时的owner
+const SpecOwnerHost = "host"
+
+// LoadSpec 从YAML文件加载声明式配置
+func LoadSpec(path string) (*MultiAgentSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read multi agent spec: %w", err)
+	}
+	return ParseSpec(data)
+}
+
+// ParseSpec 解析YAML格式的声明式配置
+func ParseSpec(data []byte) (*MultiAgentSpec, error) {
+	spec := &MultiAgentSpec{}
+	if err := yaml.Unmarshal(data, spec); err != nil {
+		return nil, fmt.Errorf("failed to parse multi agent spec: %w", err)
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// Validate 校验声明式配置
+func (spec *MultiAgentSpec) Validate() error {
+	if len(spec.Name) == 0 {
+		return errors.New("multi agent spec name is empty")
+	}
+	names := make(map[string]bool)
+	for i, specialist := range spec.Specialists {
+		if specialist == nil || len(specialist.Name) == 0 {
+			return fmt.Errorf("specialist %d has empty name", i)
+		}
+		if len(specialist.IntendedUse) == 0 {
+			return fmt.Errorf("specialist %s has empty intended use", specialist.Name)
+		}
+		if names[specialist.Name] {
+			return fmt.Errorf("duplicate specialist: %s", specialist.Name)
+		}
+		names[specialist.Name] = true
+	}
+	return nil
+}
+
+// Build 根据声明式配置创建模型和工具，生成 MultiAgentConfig
+func (spec *MultiAgentSpec) Build(ctx context.Context, resolver SpecResolver, opts ...base.AgentOption) (*MultiAgentConfig, error) {
+	if resolver.ChatModel == nil {
+		return nil, errors.New("spec resolver has no chat model")
+	}
+	hostModel, hostAgent, err := buildSpecAgent(ctx, resolver, spec.Host.Model, SpecOwnerHost, spec.Host.Tools, spec.Host.MaxSteps, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build host: %w", err)
+	}
+	config := &MultiAgentConfig{
+		Name:        spec.Name,
+		Description: spec.Description,
+		Host: Host{
+			Model:        hostModel,
+			ReactAgent:   hostAgent,
+			SystemPrompt: spec.Host.SystemPrompt,
+			Prompts:      spec.Host.Prompts,
+			Thinking:     spec.Host.Thinking,
+			Planning:     spec.Host.Planning,
+		},
+		PromptTemplates: spec.PromptTemplates,
+		Session:         spec.Session,
+		MaxRounds:       spec.MaxRounds,
+		MaxParallelism:  spec.MaxParallelism,
+	}
+	for _, s := range spec.Specialists {
+		cm, agent, err := buildSpecAgent(ctx, resolver, s.Model, s.Name, s.Tools, s.MaxSteps, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build specialist %s: %w", s.Name, err)
+		}
+		specialist := &Specialist{
+			Name:         s.Name,
+			IntendedUse:  s.IntendedUse,
+			SystemPrompt: s.SystemPrompt,
+			ReactAgent:   agent,
+		}
+		if agent == nil {
+			specialist.ChatModel = cm
+		}
+		config.Specialists = append(config.Specialists, specialist)
+	}
+	return config, nil
+}
+
+// buildSpecAgent 创建模型，配置了工具或最大步数时同时创建ReAct Agent
+func buildSpecAgent(ctx context.Context, resolver SpecResolver, profile, owner string, toolNames []string, maxSteps int, opts ...base.AgentOption) (model.ToolCallingChatModel, *react.ReactAgent, error) {
+	cm, err := resolver.ChatModel(ctx, profile, owner)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(toolNames) == 0 && maxSteps <= 0 {
+		return cm, nil, nil
+	}
+	var tools []tool.BaseTool
+	bound := cm
+	if len(toolNames) > 0 {
+		if resolver.Tools == nil {
+			return nil, nil, errors.New("spec resolver has no tools")
+		}
+		tools, err = resolver.Tools(ctx, toolNames)
+		if err != nil {
+			return nil, nil, err
+		}
+		toolInfos := make([]*schema.ToolInfo, 0, len(tools))
+		for _, t := range tools {
+			info, err := t.Info(ctx)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get tool info: %w", err)
+			}
+			toolInfos = append(toolInfos, info)
+		}
+		// 主持人的模型节点也需要绑定工具；工具组可能为空（如未配置MCP服务器）
+		if len(toolInfos) > 0 {
+			if bound, err = cm.WithTools(toolInfos); err != nil {
+				return nil, nil, fmt.Errorf("failed to bind tools: %w", err)
+			}
+		}
+	}
+	agent, err := react.NewAgent(ctx, react.ReactAgentConfig{
+		ToolCallingModel: cm,
+		ToolsConfig: compose.ToolsNodeConfig{
+			Tools: tools,
+		},
+		MaxStep: maxSteps,
+	}, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bound, agent, nil
+}
@@ -0,0 +1,116 @@
+package multiagent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSpec = `
+name: spec-test
+max_rounds: 10
+max_parallelism: 2
+host:
+  model: fast
+  system_prompt: You coordinate reviewers.
+  prompts:
+    plan_creation: Always include an evaluation step.
+specialists:
+  - name: writer
+    intended_use: Writing
+    system_prompt: You write.
+  - name: evaluator
+    intended_use: Evaluate the result
+    model: strong
+    tools: [echo]
+    max_steps: 3
+`
+
+func TestParseSpec(t *testing.T) {
+	spec, err := ParseSpec([]byte(testSpec))
+	require.NoError(t, err)
+	assert.Equal(t, "spec-test", spec.Name)
+	assert.Equal(t, "fast", spec.Host.Model)
+	require.Len(t, spec.Specialists, 2)
+	assert.Equal(t, []string{"echo"}, spec.Specialists[1].Tools)
+	assert.Equal(t, 3, spec.Specialists[1].MaxSteps)
+
+	_, err = ParseSpec([]byte("name: x\nspecialists:\n  - name: a\n"))
+	assert.Error(t, err)
+	_, err = ParseSpec([]byte("name: x\nspecialists:\n  - {name: a, intended_use: u}\n  - {name: a, intended_use: u}\n"))
+	assert.Error(t, err)
+}
+
+func TestMultiAgentSpec_Build(t *testing.T) {
+	ctx := context.Background()
+	spec, err := ParseSpec([]byte(testSpec))
+	require.NoError(t, err)
+
+	models := map[string]*scriptedChatModel{}
+	echo, err := utils.InferTool("echo", "echo input", func(ctx context.Context, in struct{ Text string }) (string, error) {
+		return in.Text, nil
+	})
+	require.NoError(t, err)
+	resolver := SpecResolver{
+		ChatModel: func(ctx context.Context, profile, owner string) (model.ToolCallingChatModel, error) {
+			m := &scriptedChatModel{}
+			models[owner+"/"+profile] = m
+			return m, nil
+		},
+		Tools: func(ctx context.Context, names []string) ([]tool.BaseTool, error) {
+			if len(names) != 1 || names[0] != "echo" {
+				return nil, errors.New("unknown tool")
+			}
+			return []tool.BaseTool{echo}, nil
+		},
+	}
+	config, err := spec.Build(ctx, resolver)
+	require.NoError(t, err)
+
+	assert.Contains(t, models, "host/fast")
+	assert.Contains(t, models, "writer/")
+	assert.Contains(t, models, "evaluator/strong")
+	assert.Nil(t, config.Host.ReactAgent)
+	assert.Equal(t, 2, config.MaxParallelism)
+	require.Len(t, config.Specialists, 2)
+	// 没有工具的专家直接调用模型，有工具的专家以ReAct Agent运行
+	assert.NotNil(t, config.Specialists[0].ChatModel)
+	assert.Nil(t, config.Specialists[0].ReactAgent)
+	require.NotNil(t, config.Specialists[1].ReactAgent)
+	assert.Equal(t, 3, config.Specialists[1].ReactAgent.Config.MaxStep)
+
+	_, err = NewMultiAgent(ctx, config)
+	require.NoError(t, err)
+
+	resolver.Tools = func(ctx context.Context, names []string) ([]tool.BaseTool, error) {
+		return nil, errors.New("unknown tool")
+	}
+	_, err = spec.Build(ctx, resolver)
+	assert.Error(t, err)
+}
+
+func TestWithHostPrompt(t *testing.T) {
+	config := &MultiAgentConfig{Host: Host{
+		SystemPrompt: "system",
+		Prompts:      map[string]string{HostPromptPlanCreation: "plan"},
+	}}
+	input := []*schema.Message{schema.UserMessage("task")}
+
+	messages := withHostPrompt(config, HostPromptPlanCreation, input)
+	require.Len(t, messages, 2)
+	assert.Equal(t, schema.System, messages[0].Role)
+	assert.Equal(t, "system\n\nplan", messages[0].Content)
+
+	messages = withHostPrompt(config, HostPromptFeedback, input)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "system", messages[0].Content)
+
+	assert.Equal(t, input, withHostPrompt(&MultiAgentConfig{}, HostPromptFeedback, input))
+}
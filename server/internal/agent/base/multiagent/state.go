@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/PGshen/thinking-map/server/internal/agent/base"
 	"github.com/cloudwego/eino/schema"
 )
 
@@ -17,15 +18,17 @@ type MultiAgentState struct {
 	OriginalMessages    []*schema.Message    `json:"original_messages"`
 
 	// Task Planning
-	CurrentPlan *TaskPlan   `json:"current_plan,omitempty"`
-	PlanHistory []*TaskPlan `json:"plan_history,omitempty"`
+	CurrentPlan        *TaskPlan          `json:"current_plan,omitempty"`
+	PlanHistory        []*TaskPlan        `json:"plan_history,omitempty"`
+	PlanApprovalStatus PlanApprovalStatus `json:"plan_approval_status,omitempty"`
 
 	// Execution Status
 	ExecutionStatus  ExecutionStatus    `json:"execution_status"`
 	CurrentStep      string             `json:"current_step,omitempty"`
+	CurrentSteps     []string           `json:"current_steps,omitempty"` // 并行执行中的步骤
 	ExecutionHistory []*ExecutionRecord `json:"execution_history,omitempty"`
 
-	// Specialist Results
+	// Specialist Results，按步骤ID记录本轮执行的结果
 	SpecialistResults map[string]*StepResult `json:"specialist_results,omitempty"`
 
 	// Collected Results
@@ -40,11 +43,18 @@ type MultiAgentState struct {
 	ShouldContinue bool `json:"should_continue"`
 	IsCompleted    bool `json:"is_completed"`
 
+	// Budget 本次运行的用量，预算耗尽时 BudgetExceeded 记录原因并强制进入最终回答
+	Usage          base.Usage `json:"usage"`
+	BudgetExceeded string     `json:"budget_exceeded,omitempty"`
+
 	// Final Answer
 	FinalAnswer *schema.Message `json:"final_answer,omitempty"`
 
 	// Metadata
 	Metadata map[string]any `json:"metadata,omitempty"`
+
+	// resumeFrom 从检查点恢复时检查点所在的节点
+	resumeFrom string
 }
 
 // ToJSON serializes the state to JSON
@@ -124,11 +134,11 @@ func (es *MultiAgentState) AddExecutionRecord(record *ExecutionRecord) {
 }
 
 // 专家结果管理方法
-func (es *MultiAgentState) UpdateSpecialistResult(specialist string, result *StepResult) {
+func (es *MultiAgentState) UpdateSpecialistResult(stepID string, result *StepResult) {
 	if es.SpecialistResults == nil {
 		es.SpecialistResults = make(map[string]*StepResult)
 	}
-	es.SpecialistResults[specialist] = result
+	es.SpecialistResults[stepID] = result
 }
 
 func (es *MultiAgentState) ClearSpecialistResults() {
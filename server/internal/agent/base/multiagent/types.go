@@ -2,6 +2,7 @@ package multiagent
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/PGshen/thinking-map/server/internal/agent/base"
@@ -207,6 +208,17 @@ func (ema *MultiAgent) Stream(ctx context.Context, input []*schema.Message, opts
 	return ema.Runnable.Stream(ctx, input, options...)
 }
 
+// Resume 从检查点恢复运行，检查点不存在时返回 ErrCheckpointNotFound
+func (ema *MultiAgent) Resume(ctx context.Context, runID string, opts ...base.AgentOption) (*schema.Message, error) {
+	if ema.Config.CheckpointStore == nil {
+		return nil, errors.New("checkpoint store is not configured")
+	}
+	if _, err := ema.Config.CheckpointStore.Load(ctx, runID); err != nil {
+		return nil, err
+	}
+	return ema.Generate(WithCheckpointID(ctx, runID), nil, opts...)
+}
+
 // ExportGraph exports the underlying graph
 func (ema *MultiAgent) ExportGraph() (compose.AnyGraph, []compose.GraphAddNodeOpt) {
 	return ema.Graph, ema.GraphAddNodeOpts
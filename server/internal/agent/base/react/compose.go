@@ -283,8 +283,14 @@ func (a *ReactAgent) decisionBranchHandler(ctx context.Context, msgsStream *sche
 			return nil
 		}
 
+		// Sync budget usage, an exhausted budget forces a final answer just like reaching max iterations
+		if tracker, ok := base.GetBudgetTracker(ctx); ok {
+			state.Usage = tracker.Usage()
+			state.BudgetExceeded = tracker.Exceeded()
+		}
+
 		// Check if max iterations reached or exceeded. When reached, schedule a final forced reasoning step.
-		if state.Iteration >= state.MaxIterations && !state.ForceFinalAnswer {
+		if (state.Iteration >= state.MaxIterations || state.BudgetExceeded != "") && !state.ForceFinalAnswer {
 			// Mark that the next reasoning must produce a final answer and disallow tool calls.
 			state.ForceFinalAnswer = true
 			endNode = nodeKeyToReasoning
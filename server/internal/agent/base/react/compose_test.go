@@ -4,12 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 	"testing"
 	"time"
 
 	"github.com/PGshen/thinking-map/server/internal/agent/base"
-	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/PGshen/thinking-map/server/internal/agent/testutil"
 	"github.com/cloudwego/eino/callbacks"
 	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/components/tool"
@@ -185,22 +184,6 @@ var testCallbackHandler = ub.NewHandlerHelper().ChatModel(&ub.ModelCallbackHandl
 	},
 }).Handler()
 
-// createTestChatModel creates a chat model for testing
-func createTestChatModel(t *testing.T) model.ToolCallingChatModel {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		t.Skip("OPENAI_API_KEY not set, skipping integration test")
-	}
-
-	chatModel, err := openai.NewChatModel(context.Background(), &openai.ChatModelConfig{
-		APIKey:  apiKey,
-		Model:   "gpt-4o-mini",
-		BaseURL: "https://api.openai.com/v1",
-	})
-	require.NoError(t, err)
-	return chatModel
-}
-
 // createTestConfig creates a test configuration for the ReAct agent
 func createTestConfig(chatModel model.ToolCallingChatModel) *ReactAgentConfig {
 	return &ReactAgentConfig{
@@ -221,7 +204,7 @@ func createTestConfig(chatModel model.ToolCallingChatModel) *ReactAgentConfig {
 // TestNewAgent_Success tests successful agent creation
 func TestNewAgent_Success(t *testing.T) {
 	ctx := context.Background()
-	chatModel := createTestChatModel(t)
+	chatModel := testutil.NewChatModel(t)
 	config := createTestConfig(chatModel)
 
 	// Test successful agent creation
@@ -258,7 +241,7 @@ func TestNewAgent_InvalidConfig(t *testing.T) {
 // TestAgent_Generate_SimpleCalculation tests agent generation with simple calculation
 func TestAgent_Generate_SimpleCalculation(t *testing.T) {
 	ctx := context.Background()
-	chatModel := createTestChatModel(t)
+	chatModel := testutil.NewChatModel(t)
 	config := createTestConfig(chatModel)
 
 	agent, err := NewAgent(ctx, *config)
@@ -281,7 +264,7 @@ func TestAgent_Generate_SimpleCalculation(t *testing.T) {
 // TestAgent_Stream_SimpleCalculation tests agent streaming with simple calculation
 func TestAgent_Stream_SimpleCalculation(t *testing.T) {
 	ctx := context.Background()
-	chatModel := createTestChatModel(t)
+	chatModel := testutil.NewChatModel(t)
 	config := createTestConfig(chatModel)
 
 	agent, err := NewAgent(ctx, *config)
@@ -309,7 +292,7 @@ func TestAgent_Stream_SimpleCalculation(t *testing.T) {
 // TestAgent_Generate_WeatherQuery tests agent generation with weather query
 func TestAgent_Generate_WeatherQuery(t *testing.T) {
 	ctx := context.Background()
-	chatModel := createTestChatModel(t)
+	chatModel := testutil.NewChatModel(t)
 	config := createTestConfig(chatModel)
 
 	agent, err := NewAgent(ctx, *config)
@@ -332,7 +315,7 @@ func TestAgent_Generate_SearchQuery(t *testing.T) {
 	// t.Skip("Skipping search query test - requires optimization")
 
 	ctx := context.Background()
-	chatModel := createTestChatModel(t)
+	chatModel := testutil.NewChatModel(t)
 	config := createTestConfig(chatModel)
 	// Increase max iterations for search queries
 	config.MaxStep = 25
@@ -354,7 +337,7 @@ func TestAgent_Generate_SearchQuery(t *testing.T) {
 // TestAgent_Generate_ComplexQuery tests agent generation with complex multi-step query
 func TestAgent_Generate_ComplexQuery(t *testing.T) {
 	ctx := context.Background()
-	chatModel := createTestChatModel(t)
+	chatModel := testutil.NewChatModel(t)
 	config := createTestConfig(chatModel)
 	// Increase max iterations for complex queries
 	config.MaxStep = 25
@@ -376,7 +359,7 @@ func TestAgent_Generate_ComplexQuery(t *testing.T) {
 // TestAgent_Generate_WithOptions tests agent generation with options
 func TestAgent_Generate_WithOptions(t *testing.T) {
 	ctx := context.Background()
-	chatModel := createTestChatModel(t)
+	chatModel := testutil.NewChatModel(t)
 	config := createTestConfig(chatModel)
 
 	agent, err := NewAgent(ctx, *config)
@@ -403,7 +386,7 @@ func TestAgent_Generate_WithOptions(t *testing.T) {
 // TestAgent_Stream_WithTimeout tests agent streaming with timeout
 func TestAgent_Stream_WithTimeout(t *testing.T) {
 	ctx := context.Background()
-	chatModel := createTestChatModel(t)
+	chatModel := testutil.NewChatModel(t)
 	config := createTestConfig(chatModel)
 
 	agent, err := NewAgent(ctx, *config)
@@ -435,7 +418,7 @@ func TestAgent_Stream_WithTimeout(t *testing.T) {
 // TestAgent_Generate_EmptyInput tests agent generation with empty input
 func TestAgent_Generate_EmptyInput(t *testing.T) {
 	ctx := context.Background()
-	chatModel := createTestChatModel(t)
+	chatModel := testutil.NewChatModel(t)
 	config := createTestConfig(chatModel)
 
 	agent, err := NewAgent(ctx, *config)
@@ -455,7 +438,7 @@ func TestAgent_Generate_EmptyInput(t *testing.T) {
 // TestAgent_Stream_EmptyInput tests agent streaming with empty input
 func TestAgent_Stream_EmptyInput(t *testing.T) {
 	ctx := context.Background()
-	chatModel := createTestChatModel(t)
+	chatModel := testutil.NewChatModel(t)
 	config := createTestConfig(chatModel)
 
 	agent, err := NewAgent(ctx, *config)
@@ -531,7 +514,7 @@ func TestParseReasoningResponse(t *testing.T) {
 // TestValidateConfig tests the configuration validation function
 func TestValidateConfig(t *testing.T) {
 	// Test valid config
-	chatModel := createTestChatModel(t)
+	chatModel := testutil.NewChatModel(t)
 	validConfig := createTestConfig(chatModel)
 	err := validateConfig(validConfig)
 	assert.NoError(t, err)
@@ -562,22 +545,8 @@ func TestValidateConfig(t *testing.T) {
 
 // BenchmarkAgent_Generate benchmarks the agent generation performance
 func BenchmarkAgent_Generate(b *testing.B) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		b.Skip("OPENAI_API_KEY not set, skipping benchmark")
-	}
-
 	ctx := context.Background()
-	chatModel, err := openai.NewChatModel(ctx, &openai.ChatModelConfig{
-		APIKey:  apiKey,
-		Model:   "gpt-4o-mini",
-		BaseURL: "https://api.openai.com/v1",
-	})
-	if err != nil {
-		b.Fatalf("Failed to create chat model: %v", err)
-	}
-
-	config := createTestConfig(chatModel)
+	config := createTestConfig(testutil.NewChatModel(b))
 	agent, err := NewAgent(ctx, *config)
 	if err != nil {
 		b.Fatalf("Failed to create agent: %v", err)
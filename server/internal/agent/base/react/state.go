@@ -1,6 +1,7 @@
 package react
 
 import (
+	"github.com/PGshen/thinking-map/server/internal/agent/base"
 	"github.com/cloudwego/eino/schema"
 )
 
@@ -16,6 +17,10 @@ type AgentState struct {
     // ForceFinalAnswer indicates the next reasoning step must produce a final answer
     // and disallow tool calls or continued thinking.
     ForceFinalAnswer         bool              `json:"force_final_answer"`
+    // Usage is the token and cost usage of the current run, synced from the budget tracker in context.
+    Usage                    base.Usage        `json:"usage"`
+    // BudgetExceeded is the reason the run budget was exhausted, which forces a final answer.
+    BudgetExceeded           string            `json:"budget_exceeded,omitempty"`
 }
 
 // State management is now handled by Eino's global state support
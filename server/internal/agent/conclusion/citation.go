@@ -0,0 +1,125 @@
+package conclusionv3
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+)
+
+var (
+	// 来源行：[n] 标题 - URL，允许列表符号和Markdown链接
+	sourceLinePattern = regexp.MustCompile(`^\s*(?:[-*]\s*)?\[(\d+)\]\s*(.*)$`)
+	sourceURLPattern  = regexp.MustCompile(`(?:https?|kb)://[^\s<>()\[\]]+`)
+	// 正文中的编号，排除Markdown链接文本 [1](url)
+	citationMarkPattern = regexp.MustCompile(`\[(\d+)\]`)
+)
+
+// ParseCitations 解析结论中的编号引用和末尾的参考来源
+// 有编号但没有来源的引用标记为 CitationIssueNoSource
+func ParseCitations(content string) []model.Citation {
+	sources := make(map[int]model.Citation)
+	var body []string
+	for _, line := range strings.Split(content, "\n") {
+		if citation, ok := parseSourceLine(line); ok {
+			if _, exists := sources[citation.Index]; !exists {
+				sources[citation.Index] = citation
+			}
+			continue
+		}
+		body = append(body, line)
+	}
+
+	marked := make(map[int]bool)
+	for _, line := range body {
+		for _, loc := range citationMarkPattern.FindAllStringSubmatchIndex(line, -1) {
+			if loc[1] < len(line) && line[loc[1]] == '(' {
+				continue
+			}
+			if index, err := strconv.Atoi(line[loc[2]:loc[3]]); err == nil {
+				marked[index] = true
+			}
+		}
+	}
+	for index := range marked {
+		if _, ok := sources[index]; !ok {
+			sources[index] = model.Citation{Index: index, Issue: model.CitationIssueNoSource}
+		}
+	}
+
+	citations := make([]model.Citation, 0, len(sources))
+	for _, citation := range sources {
+		citations = append(citations, citation)
+	}
+	sort.Slice(citations, func(i, j int) bool {
+		return citations[i].Index < citations[j].Index
+	})
+	return citations
+}
+
+// parseSourceLine 解析来源行，没有URL的行视为正文
+func parseSourceLine(line string) (model.Citation, bool) {
+	match := sourceLinePattern.FindStringSubmatch(line)
+	if match == nil {
+		return model.Citation{}, false
+	}
+	url := sourceURLPattern.FindString(match[2])
+	if url == "" {
+		return model.Citation{}, false
+	}
+	index, err := strconv.Atoi(match[1])
+	if err != nil {
+		return model.Citation{}, false
+	}
+	url = strings.TrimRight(url, ".,;，。；")
+	title := strings.Replace(match[2], url, "", 1)
+	title = strings.NewReplacer("[", "", "]", "", "()", "", "<>", "").Replace(title)
+	title = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(title), "-—:：|"))
+	return model.Citation{Index: index, Title: title, URL: url}, true
+}
+
+// VerifyCitations 校验引用的来源是否为检索记录中的结果，并补全RAG记录ID和标题
+func VerifyCitations(citations []model.Citation, records []*model.RAGRecord) []model.Citation {
+	type retrieved struct {
+		ragID string
+		title string
+	}
+	results := make(map[string]retrieved)
+	for _, record := range records {
+		if record == nil {
+			continue
+		}
+		for _, result := range record.Results {
+			key := normalizeURL(result.URL)
+			if _, ok := results[key]; !ok && key != "" {
+				results[key] = retrieved{ragID: record.ID, title: result.Title}
+			}
+		}
+	}
+
+	verified := make([]model.Citation, 0, len(citations))
+	for _, citation := range citations {
+		citation.RagID = ""
+		citation.Verified = false
+		if citation.Issue != model.CitationIssueNoSource {
+			citation.Issue = ""
+			if result, ok := results[normalizeURL(citation.URL)]; ok {
+				citation.RagID = result.ragID
+				citation.Verified = true
+				if citation.Title == "" {
+					citation.Title = result.title
+				}
+			} else {
+				citation.Issue = model.CitationIssueNotRetrieved
+			}
+		}
+		verified = append(verified, citation)
+	}
+	return verified
+}
+
+func normalizeURL(url string) string {
+	return strings.TrimRight(strings.TrimSpace(url), "/")
+}
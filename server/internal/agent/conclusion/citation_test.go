@@ -0,0 +1,61 @@
+package conclusionv3
+
+import (
+	"testing"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const citedConclusion = `市场规模在2024年增长了30% [1]，主要驱动因素是政策支持 [2][3]。
+详见 [官方报告](https://example.com/report)。
+
+## 参考来源
+[1] 行业报告 - https://example.com/report/
+- [2] [政策解读](https://gov.example.com/policy)
+[3] 知识库笔记 #2 - kb://doc-1#1`
+
+func TestParseCitations(t *testing.T) {
+	citations := ParseCitations(citedConclusion)
+	require.Len(t, citations, 3)
+
+	assert.Equal(t, model.Citation{Index: 1, Title: "行业报告", URL: "https://example.com/report/"}, citations[0])
+	assert.Equal(t, model.Citation{Index: 2, Title: "政策解读", URL: "https://gov.example.com/policy"}, citations[1])
+	assert.Equal(t, model.Citation{Index: 3, Title: "知识库笔记 #2", URL: "kb://doc-1#1"}, citations[2])
+}
+
+func TestParseCitationsMissingSource(t *testing.T) {
+	citations := ParseCitations("结论一 [1]，结论二 [2]\n\n参考来源\n[1] 来源 - https://a.com")
+	require.Len(t, citations, 2)
+	assert.Empty(t, citations[0].Issue)
+	assert.Equal(t, 2, citations[1].Index)
+	assert.Equal(t, model.CitationIssueNoSource, citations[1].Issue)
+
+	assert.Empty(t, ParseCitations("没有引用的结论\n[注] 仅供参考"))
+}
+
+func TestVerifyCitations(t *testing.T) {
+	records := []*model.RAGRecord{
+		{ID: "rag-1", Results: model.Results{{Title: "Report", URL: "https://example.com/report"}}},
+		{ID: "rag-2", Results: model.Results{{Title: "笔记", URL: "kb://doc-1#1"}}},
+	}
+	citations := append(ParseCitations(citedConclusion), model.Citation{Index: 4, Issue: model.CitationIssueNoSource})
+
+	verified := VerifyCitations(citations, records)
+	require.Len(t, verified, 4)
+
+	assert.True(t, verified[0].Verified)
+	assert.Equal(t, "rag-1", verified[0].RagID)
+
+	assert.False(t, verified[1].Verified)
+	assert.Empty(t, verified[1].RagID)
+	assert.Equal(t, model.CitationIssueNotRetrieved, verified[1].Issue)
+
+	assert.True(t, verified[2].Verified)
+	assert.Equal(t, "rag-2", verified[2].RagID)
+	assert.Empty(t, verified[2].Issue)
+
+	assert.False(t, verified[3].Verified)
+	assert.Equal(t, model.CitationIssueNoSource, verified[3].Issue)
+}
@@ -6,26 +6,34 @@ import (
 	"github.com/PGshen/thinking-map/server/internal/agent/base"
 	"github.com/PGshen/thinking-map/server/internal/agent/base/react"
 	"github.com/PGshen/thinking-map/server/internal/agent/llmmodel"
-	"github.com/PGshen/thinking-map/server/internal/agent/tool/search"
+	"github.com/PGshen/thinking-map/server/internal/agent/tool/registry"
+	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
 )
 
-func BuildGenerationAgent(ctx context.Context, option ...base.AgentOption) (r compose.Runnable[[]*schema.Message, *schema.Message], err error) {
-	cm, err := llmmodel.NewOpenAIModel(ctx, nil)
+// BuildGenerationAgent 构建结论生成Agent，模型、工具和提示词按节点类型配置，见 ProfileForNodeType
+func BuildGenerationAgent(ctx context.Context, nodeType string, option ...base.AgentOption) (r compose.Runnable[[]*schema.Message, *schema.Message], err error) {
+	profile := ProfileForNodeType(nodeType)
+	var cm model.ToolCallingChatModel
+	if profile.Specialist != "" {
+		cm, err = llmmodel.NewSpecialistModel(ctx, llmmodel.AgentConclusionGeneration, profile.Specialist)
+	} else {
+		cm, err = llmmodel.NewAgentModel(ctx, llmmodel.AgentConclusionGeneration)
+	}
 	if err != nil {
 		return nil, err
 	}
-	// 添加搜索工具
-	searchTools, err := search.GetAllSearchTools()
+	tools, err := registry.GetTools(profile.ToolGroups)
 	if err != nil {
 		return nil, err
 	}
 	agent, err := react.NewAgent(ctx, react.ReactAgentConfig{
 		ToolCallingModel: cm,
 		ToolsConfig: compose.ToolsNodeConfig{
-			Tools: searchTools,
+			Tools: tools,
 		},
+		MaxStep: profile.MaxStep,
 	}, option...)
 	if err != nil {
 		return nil, err
@@ -34,10 +42,14 @@ func BuildGenerationAgent(ctx context.Context, option ...base.AgentOption) (r co
 	if err != nil {
 		return nil, err
 	}
+	prompt := buildConclusionGenerationPrompt()
+	if profile.Guide != "" {
+		prompt += "\n\n" + profile.Guide
+	}
 	// 构建链
 	chain := compose.NewChain[[]*schema.Message, *schema.Message]()
 	chain.AppendLambda(compose.InvokableLambdaWithOption(func(ctx context.Context, input []*schema.Message, opts ...any) (output []*schema.Message, err error) {
-		systemMsg := schema.SystemMessage(buildConclusionGenerationPrompt())
+		systemMsg := schema.SystemMessage(prompt)
 		return append([]*schema.Message{systemMsg}, input...), nil
 	})).AppendLambda(lbaAgent)
 	return chain.Compile(ctx, compose.WithGraphName("conclusion_generation"))
@@ -0,0 +1,151 @@
+package conclusionv3
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/tool/registry"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+)
+
+// NodeTypeProfile 节点类型对应的结论生成配置
+type NodeTypeProfile struct {
+	NodeType string
+	// Specialist 专家名称，可通过 llm.agents.specialists.<name> 为该类型指定模型配置档，
+	// 为空时使用 llm.agents.conclusion_generation
+	Specialist string
+	ToolGroups []string // 可用的工具组或工具名
+	MaxStep    int      // ReAct最大迭代次数，0 表示使用默认值
+	Guide      string   // 追加到结论生成提示词的节点类型指引
+}
+
+// HasPayload 该节点类型是否有专属的结构化结论
+func (p NodeTypeProfile) HasPayload() bool {
+	return newPayload(p.NodeType) != nil
+}
+
+// 各节点类型的工具和迭代次数与指引一致：信息收集需要多轮检索和全部外部工具；
+// 分析以推理为主，只保留检索；生成只检索相似案例；评估基于前置结论，只查阅知识库
+var nodeTypeProfiles = map[string]NodeTypeProfile{
+	comm.NodeTypeInfoCollection: {
+		NodeType:   comm.NodeTypeInfoCollection,
+		Specialist: "InformationConclusionAgent",
+		ToolGroups: []string{registry.GroupSearch, registry.GroupMCP},
+		MaxStep:    20,
+		Guide:      buildInformationGuide(),
+	},
+	comm.NodeTypeAnalysis: {
+		NodeType:   comm.NodeTypeAnalysis,
+		Specialist: "AnalysisConclusionAgent",
+		ToolGroups: []string{"kb_search", "search"},
+		MaxStep:    6,
+		Guide:      buildAnalysisGuide(),
+	},
+	comm.NodeTypeGeneration: {
+		NodeType:   comm.NodeTypeGeneration,
+		Specialist: "GenerationConclusionAgent",
+		ToolGroups: []string{"search"},
+		MaxStep:    4,
+		Guide:      buildGenerationGuide(),
+	},
+	comm.NodeTypeEvaluation: {
+		NodeType:   comm.NodeTypeEvaluation,
+		Specialist: "EvaluationConclusionAgent",
+		ToolGroups: []string{"kb_search"},
+		MaxStep:    4,
+		Guide:      buildEvaluationGuide(),
+	},
+}
+
+// ProfileForNodeType 获取节点类型的结论生成配置，未单独配置的类型（如问题节点）使用通用配置
+func ProfileForNodeType(nodeType string) NodeTypeProfile {
+	if profile, ok := nodeTypeProfiles[nodeType]; ok {
+		return profile
+	}
+	return NodeTypeProfile{
+		NodeType:   nodeType,
+		ToolGroups: []string{registry.GroupSearch, registry.GroupMCP},
+	}
+}
+
+// newPayload 创建节点类型对应的结构化结论，没有专属结构的类型返回nil
+func newPayload(nodeType string) any {
+	switch nodeType {
+	case comm.NodeTypeInfoCollection:
+		return &model.InformationPayload{}
+	case comm.NodeTypeAnalysis:
+		return &model.AnalysisPayload{}
+	case comm.NodeTypeGeneration:
+		return &model.GenerationPayload{}
+	case comm.NodeTypeEvaluation:
+		return &model.EvaluationPayload{}
+	}
+	return nil
+}
+
+// ParsePayload 解析结构化结论Agent的输出，兼容模型在json前后输出的多余内容
+func ParsePayload(nodeType, content string) (*model.ConclusionPayload, error) {
+	value := newPayload(nodeType)
+	if value == nil {
+		return nil, fmt.Errorf("node type %s has no conclusion payload", nodeType)
+	}
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("invalid conclusion payload: %s", content)
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), value); err != nil {
+		return nil, fmt.Errorf("invalid conclusion payload: %w", err)
+	}
+	payload := &model.ConclusionPayload{}
+	switch v := value.(type) {
+	case *model.InformationPayload:
+		payload.Information = v
+	case *model.AnalysisPayload:
+		payload.Analysis = v
+	case *model.GenerationPayload:
+		payload.Generation = v
+	case *model.EvaluationPayload:
+		scoreEvaluation(v)
+		payload.Evaluation = v
+	}
+	return payload, nil
+}
+
+// scoreEvaluation 按标准权重重新计算各备选方案的加权平均分，不采用模型给出的总分
+// 权重均未设置时按等权计算，没有对应标准的评分不计入
+func scoreEvaluation(p *model.EvaluationPayload) {
+	weights := make(map[string]float64, len(p.Criteria))
+	equal := true
+	for _, criterion := range p.Criteria {
+		if criterion.Weight > 0 {
+			equal = false
+		}
+	}
+	for _, criterion := range p.Criteria {
+		weight := criterion.Weight
+		if equal {
+			weight = 1
+		}
+		weights[criterion.Name] = weight
+	}
+	for i := range p.Alternatives {
+		alternative := &p.Alternatives[i]
+		var sum, total float64
+		for _, score := range alternative.Scores {
+			weight, ok := weights[score.Criterion]
+			if !ok || weight <= 0 {
+				continue
+			}
+			sum += weight
+			total += weight * score.Score
+		}
+		alternative.Total = 0
+		if sum > 0 {
+			alternative.Total = math.Round(total/sum*100) / 100
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package conclusionv3
+
+import (
+	"testing"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/tool/registry"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/getkin/kin-openapi/openapi3gen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileForNodeType(t *testing.T) {
+	for _, nodeType := range []string{comm.NodeTypeInfoCollection, comm.NodeTypeAnalysis, comm.NodeTypeGeneration, comm.NodeTypeEvaluation} {
+		profile := ProfileForNodeType(nodeType)
+		assert.Equal(t, nodeType, profile.NodeType)
+		assert.NotEmpty(t, profile.Specialist, nodeType)
+		assert.NotEmpty(t, profile.Guide, nodeType)
+		assert.True(t, profile.HasPayload(), nodeType)
+	}
+	// 信息收集需要多轮检索，其他类型只保留部分检索工具
+	info := ProfileForNodeType(comm.NodeTypeInfoCollection)
+	assert.Greater(t, info.MaxStep, 10)
+	assert.Contains(t, info.ToolGroups, registry.GroupMCP)
+	for _, nodeType := range []string{comm.NodeTypeAnalysis, comm.NodeTypeGeneration, comm.NodeTypeEvaluation} {
+		profile := ProfileForNodeType(nodeType)
+		assert.Less(t, profile.MaxStep, info.MaxStep, nodeType)
+		assert.NotContains(t, profile.ToolGroups, registry.GroupMCP, nodeType)
+		_, err := registry.GetTools(profile.ToolGroups)
+		assert.NoError(t, err, nodeType)
+	}
+	assert.Equal(t, []string{"kb_search"}, ProfileForNodeType(comm.NodeTypeEvaluation).ToolGroups)
+
+	// 问题节点使用通用配置
+	profile := ProfileForNodeType(comm.NodeTypeProblem)
+	assert.Empty(t, profile.Specialist)
+	assert.Empty(t, profile.Guide)
+	assert.False(t, profile.HasPayload())
+	assert.Equal(t, []string{registry.GroupSearch, registry.GroupMCP}, profile.ToolGroups)
+}
+
+func TestPayloadSchema(t *testing.T) {
+	generator := openapi3gen.NewGenerator(openapi3gen.UseAllExportedFields())
+	for _, nodeType := range []string{comm.NodeTypeInfoCollection, comm.NodeTypeAnalysis, comm.NodeTypeGeneration, comm.NodeTypeEvaluation} {
+		schemaRef, err := generator.NewSchemaRefForValue(newPayload(nodeType), nil)
+		require.NoError(t, err, nodeType)
+		assert.NotEmpty(t, schemaRef.Value.Properties, nodeType)
+	}
+}
+
+func TestParsePayload(t *testing.T) {
+	payload, err := ParsePayload(comm.NodeTypeInfoCollection, "```json\n"+`{"keyFacts":["市场规模增长30%"],"sources":[{"title":"行业报告","url":"https://example.com/report","summary":"2024年市场数据"}],"gaps":[]}`+"\n```")
+	require.NoError(t, err)
+	require.NotNil(t, payload.Information)
+	assert.Nil(t, payload.Analysis)
+	assert.Equal(t, []string{"市场规模增长30%"}, payload.Information.KeyFacts)
+	assert.Equal(t, "https://example.com/report", payload.Information.Sources[0].URL)
+
+	_, err = ParsePayload(comm.NodeTypeAnalysis, "无法提取")
+	assert.Error(t, err)
+	_, err = ParsePayload(comm.NodeTypeProblem, "{}")
+	assert.Error(t, err)
+}
+
+func TestParsePayloadEvaluation(t *testing.T) {
+	content := `{
+		"criteria": [{"name":"成本","description":"","weight":3},{"name":"效果","description":"","weight":1}],
+		"alternatives": [
+			{"name":"方案A","scores":[{"criterion":"成本","score":8,"reason":""},{"criterion":"效果","score":4,"reason":""}],"total":10},
+			{"name":"方案B","scores":[{"criterion":"成本","score":4,"reason":""},{"criterion":"未知","score":10,"reason":""}],"total":10}
+		],
+		"recommendation": "方案A"
+	}`
+	payload, err := ParsePayload(comm.NodeTypeEvaluation, content)
+	require.NoError(t, err)
+	require.NotNil(t, payload.Evaluation)
+	// 按权重重新计算总分，忽略模型给出的总分和未知标准的评分
+	assert.Equal(t, 7.0, payload.Evaluation.Alternatives[0].Total)
+	assert.Equal(t, 4.0, payload.Evaluation.Alternatives[1].Total)
+}
+
+func TestScoreEvaluationEqualWeights(t *testing.T) {
+	payload := &model.EvaluationPayload{
+		Criteria: []model.EvaluationCriterion{{Name: "成本"}, {Name: "效果"}, {Name: "风险"}},
+		Alternatives: []model.EvaluatedAlternative{
+			{Name: "方案A", Scores: []model.CriterionScore{{Criterion: "成本", Score: 8}, {Criterion: "效果", Score: 6}, {Criterion: "风险", Score: 5}}},
+			{Name: "方案B"},
+		},
+	}
+	scoreEvaluation(payload)
+	assert.Equal(t, 6.33, payload.Alternatives[0].Total)
+	assert.Equal(t, 0.0, payload.Alternatives[1].Total)
+}
@@ -11,7 +11,7 @@ import (
 )
 
 func BuildOptimizationAgent(ctx context.Context, option ...base.AgentOption) (r compose.Runnable[[]*schema.Message, *schema.Message], err error) {
-	cm, err := llmmodel.NewOpenAIModel(ctx, nil)
+	cm, err := llmmodel.NewAgentModel(ctx, llmmodel.AgentConclusionOptimization)
 	if err != nil {
 		return nil, err
 	}
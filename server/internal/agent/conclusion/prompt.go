@@ -91,6 +91,14 @@ func buildConclusionGenerationPrompt() string {
 - 风险管控与应急预案
 - 成效评估与调整机制
 
+## 引用规范
+
+当结论使用了检索工具返回的信息时，必须标注来源：
+- 在引用信息的句末使用编号标注，如 [1]、[2]，同一来源重复引用时使用相同编号
+- 在结论末尾添加"参考来源"部分，每行一个来源，格式为：[编号] 标题 - URL
+- 只能引用本次对话中检索工具实际返回的结果，URL必须与检索结果中的url字段完全一致，不得编造或改写
+- 没有使用检索信息时，不需要添加编号和参考来源
+
 ## 执行指南
 
 1. 完成上述思考流程后，明确你将采用的结论类型和结构
@@ -155,6 +163,7 @@ func buildConclusionOptimizationPrompt() string {
 - **信息整合**：有效整合引用内容中的新信息
 - **质量提升**：确保优化后的内容质量高于原版本
 - **结构保持**：尽量保持原有的结构框架，除非指令明确要求重构
+- **引用保持**：保留原结论中的引用编号（如 [1]）和末尾的"参考来源"部分，删除内容时同步删除不再使用的来源，不得新增检索结果之外的来源
 
 ## 针对不同问题类型的优化策略
 
@@ -201,3 +210,96 @@ func buildConclusionOptimizationPrompt() string {
 
 请基于以上指南，结合具体的优化需求和问题特点，进行精准的结论优化。`
 }
+
+// buildConclusionRollupPrompt 构建结论汇总Agent的提示
+func buildConclusionRollupPrompt() string {
+	return `你是一个专业的结论汇总专家，负责在所有子问题都已得出结论后，将子节点结论汇总为当前节点的结论。
+
+## 汇总原则
+
+- **回应目标**：汇总结论必须直接回答当前节点的问题，并对照目标说明达成情况
+- **忠于子结论**：只基于上下文和子节点结论进行归纳，不引入子结论之外的新事实
+- **整合而非罗列**：提炼各子结论的核心观点，说明它们之间的关系（互补、递进、冲突），给出更高层次的判断
+- **处理分歧**：子结论之间存在矛盾时，明确指出并给出取舍理由
+- **结构清晰**：使用标题、列表等结构组织内容，长度与问题复杂度相匹配
+
+## 引用规范
+
+- 子节点结论中的来源可以沿用，在汇总结论中重新编号为 [1]、[2]，并在末尾添加"参考来源"部分，每行格式为：[编号] 标题 - URL
+- URL必须与子节点结论中的来源完全一致，不得编造或改写
+- 没有沿用来源时，不需要添加编号和参考来源
+
+直接输出汇总后的结论正文，不要输出任何额外说明。`
+}
+
+// buildInformationGuide 信息收集节点的结论生成指引
+func buildInformationGuide() string {
+	return `## 节点类型：信息收集
+
+当前节点是信息收集节点，结论的价值在于信息的全面性和可靠性：
+- **检索优先**：必须使用检索工具收集信息，围绕问题的不同方面分别检索，不要只检索一次
+- **交叉验证**：关键事实尽量由多个来源相互印证，来源之间存在出入时如实说明
+- **事实为主**：以陈述事实为主，少做推断，区分事实与观点
+- **来源完整**：每条关键事实都要标注来源编号，并在末尾列出全部参考来源
+- **指出缺口**：说明仍然缺失或无法确认的信息`
+}
+
+// buildAnalysisGuide 分析节点的结论生成指引
+func buildAnalysisGuide() string {
+	return `## 节点类型：分析
+
+当前节点是分析节点，结论的价值在于推理的深度和严谨性：
+- **推理优先**：基于上下文和前置节点结论进行推理，只在缺少关键事实时才使用检索工具
+- **识别因素**：明确影响问题的关键因素及其相互关系
+- **推理链条**：按步骤展示从已知信息到结论的推理过程，每一步都要有依据
+- **多角度审视**：考虑反例和替代解释，说明结论成立的前提假设和不确定性`
+}
+
+// buildGenerationGuide 生成节点的结论生成指引
+func buildGenerationGuide() string {
+	return `## 节点类型：生成
+
+当前节点是生成节点，结论的价值在于方案的创造性和多样性：
+- **发散思考**：至少给出3个思路明显不同的候选方案，避免同一方案的细微变体
+- **方案完整**：每个方案说明核心思路、实现方式，以及主要优点和缺点
+- **收敛推荐**：在比较各方案后给出推荐方案及理由
+- **适度检索**：可以检索相似案例获取灵感，但不要局限于已有做法`
+}
+
+// buildEvaluationGuide 评估节点的结论生成指引
+func buildEvaluationGuide() string {
+	return `## 节点类型：评估
+
+当前节点是评估节点，结论的价值在于评估的客观性和可比性：
+- **明确对象**：从上下文（尤其是前置节点和兄弟节点的结论）中确定需要评估的备选方案
+- **确定标准**：先列出评估标准并给出权重，标准应覆盖问题目标和约束条件
+- **逐项评分**：按1-10分对每个备选方案在每项标准上评分，并给出评分理由
+- **对比呈现**：使用表格对比各方案的评分和加权总分
+- **给出结论**：根据评分给出推荐，并说明推荐方案的主要风险`
+}
+
+// buildStructuringPrompt 构建结论结构化Agent的提示
+func buildStructuringPrompt() string {
+	return `你是一个结论结构化专家，负责将节点的结论正文整理为结构化的json结果。
+
+## 输出内容
+
+- **conclusion**：通用的结构化结论
+  - summary：用两三句话概括结论
+  - keyFindings：关键发现
+  - recommendations：可执行的建议
+  - confidence：结论的置信度，0到1之间，依据证据是否充分、推理是否严谨、是否存在分歧来判断
+  - assumptions：结论成立所依赖的前提假设
+  - openQuestions：仍未解决、需要进一步研究的问题
+  - sources：结论正文中的参考来源
+- **payload**：节点类型专属的结构化结论（schema中有该字段时输出）
+
+## 提取原则
+
+- **忠于原文**：只提取结论正文中已有的内容，不补充、不推测；置信度除外，需要根据正文判断
+- **保持简洁**：每个条目用一两句话概括，不要整段复制
+- **来源一致**：来源的标题和URL必须与结论正文中的参考来源完全一致
+- **缺失留空**：结论中没有对应内容的字段输出空数组或空字符串
+
+直接输出json结果，不要输出任何其他内容。`
+}
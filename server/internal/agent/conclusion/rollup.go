@@ -0,0 +1,23 @@
+package conclusionv3
+
+import (
+	"context"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/llmmodel"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// BuildRollupAgent 构建结论汇总Agent，根据子节点结论一次性生成父节点结论，不调用工具
+func BuildRollupAgent(ctx context.Context) (r compose.Runnable[[]*schema.Message, *schema.Message], err error) {
+	cm, err := llmmodel.NewAgentModel(ctx, llmmodel.AgentConclusionRollup)
+	if err != nil {
+		return nil, err
+	}
+	chain := compose.NewChain[[]*schema.Message, *schema.Message]()
+	chain.AppendLambda(compose.InvokableLambdaWithOption(func(ctx context.Context, input []*schema.Message, opts ...any) (output []*schema.Message, err error) {
+		systemMsg := schema.SystemMessage(buildConclusionRollupPrompt())
+		return append([]*schema.Message{systemMsg}, input...), nil
+	})).AppendChatModel(cm)
+	return chain.Compile(ctx, compose.WithGraphName("conclusion_rollup"))
+}
@@ -0,0 +1,113 @@
+package conclusionv3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/llmmodel"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/pkg/utils"
+	"github.com/cloudwego/eino-ext/libs/acl/openai"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3gen"
+)
+
+// structuringOutput 结论结构化Agent的输出，payload只在节点类型有专属结构时输出
+type structuringOutput struct {
+	Conclusion model.StructuredConclusion `json:"conclusion"`
+	Payload    json.RawMessage            `json:"payload,omitempty"`
+}
+
+// BuildStructuringAgent 构建结论结构化Agent，将结论正文整理为结构化结论及节点类型专属的结构化结论，
+// 输出使用 ParseStructuringOutput 解析
+func BuildStructuringAgent(ctx context.Context, nodeType string) (r compose.Runnable[[]*schema.Message, *schema.Message], err error) {
+	outputSchema, err := structuringSchema(nodeType)
+	if err != nil {
+		return nil, err
+	}
+	responseFormat := &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+			Name:        "conclusion_structuring",
+			Description: "结构化结论的json结果",
+			Strict:      true,
+			Schema:      outputSchema,
+		},
+	}
+	profileName := llmmodel.ProfileNameForAgent(llmmodel.AgentConclusionGeneration)
+	if specialist := ProfileForNodeType(nodeType).Specialist; specialist != "" {
+		profileName = llmmodel.ProfileNameForSpecialist(llmmodel.AgentConclusionGeneration, specialist)
+	}
+	profile, err := llmmodel.GetProfile(profileName)
+	if err != nil {
+		return nil, err
+	}
+	cm, err := llmmodel.NewChatModelWithProfile(ctx, profile, llmmodel.WithResponseFormat(responseFormat))
+	if err != nil {
+		return nil, err
+	}
+	prompt := buildStructuringPrompt()
+	if !profile.StructuredOutput {
+		// 模型不支持结构化输出时，通过提示词约束输出格式
+		schemaJSON, err := json.Marshal(outputSchema)
+		if err != nil {
+			return nil, err
+		}
+		prompt += "\n\n请严格按照以下JSON Schema输出json结果，不要输出任何其他内容：\n" + string(schemaJSON)
+	}
+	chain := compose.NewChain[[]*schema.Message, *schema.Message]()
+	chain.AppendLambda(compose.InvokableLambdaWithOption(func(ctx context.Context, input []*schema.Message, opts ...any) (output []*schema.Message, err error) {
+		systemMsg := schema.SystemMessage(prompt)
+		return append([]*schema.Message{systemMsg}, input...), nil
+	})).AppendChatModel(cm)
+	return chain.Compile(ctx, compose.WithGraphName("conclusion_structuring"))
+}
+
+// structuringSchema 生成结论结构化Agent输出的json schema，节点类型有专属结构时加入payload
+func structuringSchema(nodeType string) (*openapi3.Schema, error) {
+	generator := openapi3gen.NewGenerator(
+		openapi3gen.UseAllExportedFields(),
+	)
+	conclusionSchema, err := generator.NewSchemaRefForValue(&model.StructuredConclusion{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	outputSchema := openapi3.NewObjectSchema().WithProperty("conclusion", conclusionSchema.Value)
+	if value := newPayload(nodeType); value != nil {
+		payloadSchema, err := generator.NewSchemaRefForValue(value, nil)
+		if err != nil {
+			return nil, err
+		}
+		outputSchema.WithProperty("payload", payloadSchema.Value)
+	}
+	utils.MakeAllFieldsRequired(outputSchema)
+	return outputSchema, nil
+}
+
+// ParseStructuringOutput 解析结论结构化Agent的输出，兼容模型在json前后输出的多余内容
+// 节点类型没有专属结构时payload为nil
+func ParseStructuringOutput(nodeType, content string) (*model.StructuredConclusion, *model.ConclusionPayload, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, nil, fmt.Errorf("invalid structured conclusion: %s", content)
+	}
+	var output structuringOutput
+	if err := json.Unmarshal([]byte(content[start:end+1]), &output); err != nil {
+		return nil, nil, fmt.Errorf("invalid structured conclusion: %w", err)
+	}
+	structured := &output.Conclusion
+	structured.Confidence = min(max(structured.Confidence, 0), 1)
+	if !ProfileForNodeType(nodeType).HasPayload() || len(output.Payload) == 0 {
+		return structured, nil, nil
+	}
+	payload, err := ParsePayload(nodeType, string(output.Payload))
+	if err != nil {
+		return nil, nil, err
+	}
+	return structured, payload, nil
+}
@@ -0,0 +1,53 @@
+package conclusionv3
+
+import (
+	"testing"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStructuringSchema(t *testing.T) {
+	outputSchema, err := structuringSchema(comm.NodeTypeProblem)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"conclusion"}, outputSchema.Required)
+	assert.Contains(t, outputSchema.Properties["conclusion"].Value.Required, "openQuestions")
+
+	outputSchema, err = structuringSchema(comm.NodeTypeEvaluation)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"conclusion", "payload"}, outputSchema.Required)
+	assert.Contains(t, outputSchema.Properties["payload"].Value.Properties, "criteria")
+}
+
+func TestParseStructuringOutput(t *testing.T) {
+	content := `{
+		"conclusion": {
+			"summary": "市场持续增长",
+			"keyFindings": ["规模增长30%"],
+			"recommendations": ["加大投入"],
+			"confidence": 1.5,
+			"assumptions": [],
+			"openQuestions": ["海外市场情况"],
+			"sources": [{"title": "行业报告", "url": "https://example.com/report"}]
+		},
+		"payload": {"keyFactors": ["政策"], "reasoningSteps": [], "insights": [], "uncertainties": []}
+	}`
+	structured, payload, err := ParseStructuringOutput(comm.NodeTypeAnalysis, content)
+	require.NoError(t, err)
+	assert.Equal(t, "市场持续增长", structured.Summary)
+	assert.Equal(t, 1.0, structured.Confidence)
+	assert.Equal(t, []model.ConclusionSource{{Title: "行业报告", URL: "https://example.com/report"}}, structured.Sources)
+	require.NotNil(t, payload)
+	assert.Equal(t, []string{"政策"}, payload.Analysis.KeyFactors)
+
+	// 没有专属结构的节点类型忽略payload
+	structured, payload, err = ParseStructuringOutput(comm.NodeTypeProblem, content)
+	require.NoError(t, err)
+	assert.NotNil(t, structured)
+	assert.Nil(t, payload)
+
+	_, _, err = ParseStructuringOutput(comm.NodeTypeProblem, "无法整理")
+	assert.Error(t, err)
+}
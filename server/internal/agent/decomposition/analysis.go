@@ -6,30 +6,22 @@ import (
 	"github.com/PGshen/thinking-map/server/internal/agent/base"
 	"github.com/PGshen/thinking-map/server/internal/agent/base/react"
 	"github.com/PGshen/thinking-map/server/internal/agent/llmmodel"
-	"github.com/PGshen/thinking-map/server/internal/agent/tool/messaging"
-	"github.com/PGshen/thinking-map/server/internal/agent/tool/search"
-	"github.com/cloudwego/eino/components/tool"
+	"github.com/PGshen/thinking-map/server/internal/agent/tool/registry"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
 )
 
 // 分析Agent
 func BuildAnalysisAgent(ctx context.Context, option base.AgentOption) (r compose.Runnable[[]*schema.Message, *schema.Message], err error) {
-	cm, err := llmmodel.NewOpenAIModel(ctx, nil)
+	cm, err := llmmodel.NewAgentModel(ctx, llmmodel.AgentAnalysis)
 	if err != nil {
 		return nil, err
 	}
-	sendActionMsg, err := messaging.ActionTool()
+	// 发送操作消息、搜索工具和MCP工具
+	allTools, err := registry.GetTools([]string{"sendActionMsg", registry.GroupSearch, registry.GroupMCP})
 	if err != nil {
 		return nil, err
 	}
-	allTools := []tool.BaseTool{sendActionMsg}
-	// 添加搜索工具
-	searchTools, err := search.GetAllSearchTools()
-	if err != nil {
-		return nil, err
-	}
-	allTools = append(allTools, searchTools...)
 
 	agent, err := react.NewAgent(ctx, react.ReactAgentConfig{
 		ToolCallingModel: cm,
@@ -0,0 +1,82 @@
+package decomposition
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/llmmodel"
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/utils"
+	"github.com/cloudwego/eino-ext/libs/acl/openai"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/getkin/kin-openapi/openapi3gen"
+)
+
+// DecisionSpecialist 拆解决策专家的名称
+const DecisionSpecialist = "DecompositionDecisionAgent"
+
+// BuildDecisionAgent 构建拆解决策Agent，沿用拆解多智能体中拆解决策专家的提示词和模型，
+// 一次性输出是否拆解的json结果，供自动驾驶使用
+func BuildDecisionAgent(ctx context.Context) (r compose.Runnable[[]*schema.Message, *schema.Message], err error) {
+	spec, err := loadSpec()
+	if err != nil {
+		return nil, err
+	}
+	var prompt, profileName string
+	for _, specialist := range spec.Specialists {
+		if specialist.Name == DecisionSpecialist {
+			prompt = specialist.SystemPrompt
+			profileName = specialist.Model
+			break
+		}
+	}
+	if prompt == "" {
+		return nil, fmt.Errorf("specialist %s not found in decomposition spec", DecisionSpecialist)
+	}
+	if profileName == "" {
+		profileName = llmmodel.ProfileNameForSpecialist(llmmodel.AgentDecomposition, DecisionSpecialist)
+	}
+	prompt += "\n\n" + buildDecisionPrompt()
+
+	generator := openapi3gen.NewGenerator(
+		openapi3gen.UseAllExportedFields(),
+	)
+	decisionSchema, err := generator.NewSchemaRefForValue(&dto.DecompositionDecision{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	utils.MakeAllFieldsRequired(decisionSchema.Value)
+	responseFormat := &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+			Name:        "decomposition_decision",
+			Description: "是否拆解节点的json结果",
+			Strict:      true,
+			Schema:      decisionSchema.Value,
+		},
+	}
+	profile, err := llmmodel.GetProfile(profileName)
+	if err != nil {
+		return nil, err
+	}
+	cm, err := llmmodel.NewChatModelWithProfile(ctx, profile, llmmodel.WithResponseFormat(responseFormat))
+	if err != nil {
+		return nil, err
+	}
+	if !profile.StructuredOutput {
+		// 模型不支持结构化输出时，通过提示词约束输出格式
+		schemaJSON, err := json.Marshal(decisionSchema.Value)
+		if err != nil {
+			return nil, err
+		}
+		prompt += "\n\n请严格按照以下JSON Schema输出json结果，不要输出任何其他内容：\n" + string(schemaJSON)
+	}
+	chain := compose.NewChain[[]*schema.Message, *schema.Message]()
+	chain.AppendLambda(compose.InvokableLambdaWithOption(func(ctx context.Context, input []*schema.Message, opts ...any) (output []*schema.Message, err error) {
+		systemMsg := schema.SystemMessage(prompt)
+		return append([]*schema.Message{systemMsg}, input...), nil
+	})).AppendChatModel(cm)
+	return chain.Compile(ctx, compose.WithGraphName("decomposition_decision"))
+}
@@ -2,89 +2,41 @@ package decomposition
 
 import (
 	"context"
+	_ "embed"
 
 	"github.com/PGshen/thinking-map/server/internal/agent/base"
 	"github.com/PGshen/thinking-map/server/internal/agent/base/multiagent"
-	"github.com/PGshen/thinking-map/server/internal/agent/base/react"
 	"github.com/PGshen/thinking-map/server/internal/agent/llmmodel"
-	"github.com/PGshen/thinking-map/server/internal/agent/tool/node"
-	"github.com/PGshen/thinking-map/server/internal/agent/tool/search"
+	"github.com/PGshen/thinking-map/server/internal/agent/tool/registry"
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
+	"github.com/spf13/viper"
 )
 
-func BuildDecompositionAgent(ctx context.Context, option ...base.AgentOption) (compose.Runnable[[]*schema.Message, *schema.Message], error) {
-	// 创建主模型
-	cm, err := llmmodel.NewOpenAIModel(ctx, nil)
-	if err != nil {
-		return nil, err
-	}
-	// 添加节点操作工具
-	toolInfos, err := node.GetAllToolInfos(ctx)
-	if err != nil {
-		return nil, err
-	}
-	allTools := []tool.BaseTool{}
-	nodeTools, err := node.GetAllNodeTools()
-	if err != nil {
-		return nil, err
-	}
-	allTools = append(allTools, nodeTools...)
-	// 添加搜索工具
-	searchTools, err := search.GetAllSearchTools()
-	if err != nil {
-		return nil, err
-	}
-	allTools = append(allTools, searchTools...)
-
-	searchToolInfos, err := search.GetAllToolInfos(ctx)
-	if err != nil {
-		return nil, err
-	}
-	toolInfos = append(toolInfos, searchToolInfos...)
-	cm, _ = cm.WithTools(toolInfos)
-
-	// 创建拆解决策Agent specialist
-	decompositionDecisionAgent, err := buildDecompositionDecisionAgent(ctx, option...)
-	if err != nil {
-		return nil, err
-	}
+// defaultSpec 内置的拆解多智能体配置
+//
+//go:embed decomposition.yaml
+var defaultSpec []byte
 
-	// 创建问题拆解Agent specialist
-	problemDecompositionAgent, err := buildProblemDecompositionAgent(ctx, option...)
+func BuildDecompositionAgent(ctx context.Context, option ...base.AgentOption) (compose.Runnable[[]*schema.Message, *schema.Message], error) {
+	spec, err := loadSpec()
 	if err != nil {
 		return nil, err
 	}
-
-	// react
-	reactAgent, err := react.NewAgent(ctx, react.ReactAgentConfig{
-		ToolCallingModel: cm,
-		ToolsConfig: compose.ToolsNodeConfig{
-			Tools: allTools,
+	// 按配置创建主持人和各专家的模型、工具
+	config, err := spec.Build(ctx, multiagent.SpecResolver{
+		ChatModel: newSpecChatModel,
+		Tools: func(ctx context.Context, names []string) ([]tool.BaseTool, error) {
+			return registry.GetTools(names)
 		},
 	}, option...)
 	if err != nil {
 		return nil, err
 	}
-
-	// 配置MultiAgent
-	config := &multiagent.MultiAgentConfig{
-		Name:        "DecompositionAgent",
-		Description: "负责问题拆解的多智能体系统",
-		Host: multiagent.Host{
-			Model:      cm,
-			ReactAgent: reactAgent,
-			Planning: multiagent.PlanningConfig{
-				PlanningPrompt: buildPlanningPrompt(),
-			},
-		},
-		Specialists: []*multiagent.Specialist{
-			decompositionDecisionAgent,
-			problemDecompositionAgent,
-		},
-		MaxRounds: 20,
-	}
+	config.CheckpointStore = global.GetCheckpointStore()
 
 	// 创建MultiAgent实例
 	agent, err := multiagent.NewMultiAgent(ctx, config)
@@ -95,77 +47,21 @@ func BuildDecompositionAgent(ctx context.Context, option ...base.AgentOption) (c
 	return agent.Runnable, nil
 }
 
-// buildDecompositionDecisionAgent 创建拆解决策Agent specialist
-func buildDecompositionDecisionAgent(ctx context.Context, option ...base.AgentOption) (*multiagent.Specialist, error) {
-	// 创建模型
-	cm, err := llmmodel.NewOpenAIModel(ctx, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	// 添加节点操作工具
-	allTools := []tool.BaseTool{}
-	nodeTools, err := node.GetAllNodeTools()
-	if err != nil {
-		return nil, err
-	}
-	allTools = append(allTools, nodeTools...)
-
-	agent, err := react.NewAgent(ctx, react.ReactAgentConfig{
-		ToolCallingModel: cm,
-		ToolsConfig:      compose.ToolsNodeConfig{
-			// Tools: allTools,
-		},
-	}, option...)
-	if err != nil {
-		return nil, err
+// loadSpec 加载拆解多智能体配置，配置了 agent_specs.decomposition 时从该文件加载，否则使用内置配置
+func loadSpec() (*multiagent.MultiAgentSpec, error) {
+	if path := viper.GetString("agent_specs.decomposition"); path != "" {
+		return multiagent.LoadSpec(path)
 	}
-
-	return &multiagent.Specialist{
-		Name:        "DecompositionDecisionAgent",
-		IntendedUse: "分析问题复杂度并决定拆解策略类型（顺序型、并行型、层次型、探索型）",
-		// ChatModel:    cm,
-		SystemPrompt: buildDecompositionDecisionPrompt(),
-		ReactAgent:   agent,
-	}, nil
+	return multiagent.ParseSpec(defaultSpec)
 }
 
-// buildProblemDecompositionAgent 创建问题拆解Agent specialist
-func buildProblemDecompositionAgent(ctx context.Context, option ...base.AgentOption) (*multiagent.Specialist, error) {
-	// 创建模型
-	cm, err := llmmodel.NewOpenAIModel(ctx, nil)
-	if err != nil {
-		return nil, err
+// newSpecChatModel 未指定模型配置档时，按 llm.agents 中拆解Agent及专家的配置选择
+func newSpecChatModel(ctx context.Context, profile, owner string) (model.ToolCallingChatModel, error) {
+	if profile != "" {
+		return llmmodel.NewChatModel(ctx, profile)
 	}
-
-	// 添加节点创建工具、知识检索工具
-	allTools := []tool.BaseTool{}
-	nodeTools, err := node.GetAllNodeTools()
-	if err != nil {
-		return nil, err
+	if owner == multiagent.SpecOwnerHost {
+		return llmmodel.NewAgentModel(ctx, llmmodel.AgentDecomposition)
 	}
-	allTools = append(allTools, nodeTools...)
-	searchTools, err := search.GetAllSearchTools()
-	if err != nil {
-		return nil, err
-	}
-	allTools = append(allTools, searchTools...)
-
-	agent, err := react.NewAgent(ctx, react.ReactAgentConfig{
-		ToolCallingModel: cm,
-		ToolsConfig: compose.ToolsNodeConfig{
-			Tools: allTools,
-		},
-	}, option...)
-	if err != nil {
-		return nil, err
-	}
-
-	return &multiagent.Specialist{
-		Name:        "ProblemDecompositionAgent",
-		IntendedUse: "基于拆解策略将复杂问题分解为可管理的子问题，使用思维导图工具来创建子问题节点，根据需要设置依赖关系以标识子问题节点之间执行先后关系",
-		// ChatModel:    cm,
-		SystemPrompt: buildProblemDecompositionPrompt(),
-		ReactAgent:   agent,
-	}, nil
+	return llmmodel.NewSpecialistModel(ctx, llmmodel.AgentDecomposition, owner)
 }
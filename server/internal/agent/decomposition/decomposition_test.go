@@ -0,0 +1,56 @@
+package decomposition
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/base/multiagent"
+	"github.com/PGshen/thinking-map/server/internal/agent/llmmodel"
+	"github.com/PGshen/thinking-map/server/internal/agent/testutil"
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/cloudwego/eino/schema"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setViper 在测试期间覆盖配置项，测试结束后恢复原值
+func setViper(t *testing.T, key string, value any) {
+	t.Helper()
+	old := viper.Get(key)
+	t.Cleanup(func() { viper.Set(key, old) })
+	viper.Set(key, value)
+}
+
+// TestBuildDecompositionAgent 按内置配置创建拆解多智能体并完整执行一次拆解，默认回放 testdata/cassettes 中合成的录制文件
+func TestBuildDecompositionAgent(t *testing.T) {
+	mode := testutil.CassetteMode()
+	if mode != llmmodel.CassetteModeReplay && os.Getenv("OPENAI_API_KEY") == "" {
+		t.Skip("OPENAI_API_KEY not set, skipping live model test")
+	}
+	setViper(t, "llm.cassette.mode", string(mode))
+	setViper(t, "llm.cassette.dir", testutil.CassetteDir)
+	setViper(t, "agent_specs.decomposition", "")
+
+	store := multiagent.NewMemoryCheckpointStore()
+	oldStore := global.GlobalCheckpointStore
+	global.GlobalCheckpointStore = store
+	t.Cleanup(func() { global.GlobalCheckpointStore = oldStore })
+
+	ctx := multiagent.WithCheckpointID(context.Background(), "run-1")
+	agent, err := BuildDecompositionAgent(ctx)
+	require.NoError(t, err)
+
+	sr, err := agent.Stream(ctx, []*schema.Message{
+		schema.UserMessage("请帮我拆解问题：如何系统地学习机器学习"),
+	})
+	require.NoError(t, err)
+	msg, err := schema.ConcatMessageStream(sr)
+	require.NoError(t, err)
+	assert.NotEmpty(t, msg.Content)
+
+	// 运行结束后清理检查点
+	_, err = store.Load(ctx, "run-1")
+	assert.ErrorIs(t, err, multiagent.ErrCheckpointNotFound)
+}
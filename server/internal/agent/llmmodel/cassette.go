@@ -0,0 +1,350 @@
+package llmmodel
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/spf13/viper"
+)
+
+// CassetteMode 录制/回放模式
+type CassetteMode string
+
+const (
+	CassetteModeOff    CassetteMode = "off"
+	CassetteModeRecord CassetteMode = "record" // 调用真实模型并录制请求/响应
+	CassetteModeReplay CassetteMode = "replay" // 仅从录制文件回放，不访问网络
+	CassetteModeAuto   CassetteMode = "auto"   // 有录制文件则回放，否则调用真实模型并录制
+)
+
+var ErrCassetteNotFound = errors.New("cassette not found")
+
+// CassetteConfig 录制/回放配置，对应配置 llm.cassette
+type CassetteConfig struct {
+	Mode CassetteMode
+	Dir  string
+}
+
+// DefaultCassetteConfig 读取配置 llm.cassette.mode / llm.cassette.dir
+func DefaultCassetteConfig() CassetteConfig {
+	cfg := CassetteConfig{
+		Mode: CassetteMode(strings.ToLower(viper.GetString("llm.cassette.mode"))),
+		Dir:  viper.GetString("llm.cassette.dir"),
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = CassetteModeOff
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = filepath.Join("testdata", "cassettes")
+	}
+	return cfg
+}
+
+// Cassette 一次模型调用的录制内容
+type Cassette struct {
+	Key      string            `json:"key"`
+	Request  []*schema.Message `json:"request"`
+	Tools    []string          `json:"tools,omitempty"`
+	Response *schema.Message   `json:"response,omitempty"`
+	Chunks   []*schema.Message `json:"chunks,omitempty"` // 流式输出的分片
+}
+
+// CassetteModel 可录制/回放的ChatModel
+// 以归一化后的prompt hash作为key，录制时调用真实模型并写入录制文件，回放时直接返回录制内容
+type CassetteModel struct {
+	inner   model.ToolCallingChatModel
+	tools   []*schema.ToolInfo
+	options *ModelOptions // 创建模型时的参数，参与key计算
+	cfg     CassetteConfig
+	mu      *sync.Mutex
+}
+
+var _ model.ToolCallingChatModel = (*CassetteModel)(nil)
+
+// NewCassetteModel 创建录制/回放模型，回放模式下inner可以为nil；
+// opts 应与创建inner时的参数一致，结构化输出格式不同的调用不会共用录制文件
+func NewCassetteModel(inner model.ToolCallingChatModel, cfg CassetteConfig, opts ...ModelOption) (*CassetteModel, error) {
+	if cfg.Mode != CassetteModeReplay && inner == nil {
+		return nil, fmt.Errorf("inner model is required in cassette mode %s", cfg.Mode)
+	}
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("cassette dir is required")
+	}
+	options := &ModelOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return &CassetteModel{inner: inner, options: options, cfg: cfg, mu: &sync.Mutex{}}, nil
+}
+
+func (m *CassetteModel) GetType() string {
+	return "Cassette"
+}
+
+func (m *CassetteModel) IsCallbacksEnabled() bool {
+	return true
+}
+
+func (m *CassetteModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	nm := &CassetteModel{tools: tools, options: m.options, cfg: m.cfg, mu: m.mu}
+	if m.inner != nil {
+		inner, err := m.inner.WithTools(tools)
+		if err != nil {
+			return nil, err
+		}
+		nm.inner = inner
+	}
+	return nm, nil
+}
+
+func (m *CassetteModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	key := CassetteKey(input, m.tools, m.options, opts...)
+	if m.shouldReplay(key) {
+		cassette, err := m.load(key)
+		if err != nil {
+			return nil, err
+		}
+		return m.replayGenerate(ctx, input, cassette)
+	}
+
+	output, err := m.inner.Generate(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.save(&Cassette{Key: key, Request: input, Tools: toolNames(m.tools), Response: output}); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (m *CassetteModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	key := CassetteKey(input, m.tools, m.options, opts...)
+	if m.shouldReplay(key) {
+		cassette, err := m.load(key)
+		if err != nil {
+			return nil, err
+		}
+		return m.replayStream(ctx, input, cassette)
+	}
+
+	sr, err := m.inner.Stream(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// 边转发边收集分片，流正常结束后写入录制文件
+	nsr, sw := schema.Pipe[*schema.Message](10)
+	go func() {
+		defer sr.Close()
+		defer sw.Close()
+		var chunks []*schema.Message
+		for {
+			chunk, err := sr.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				sw.Send(nil, err)
+				return
+			}
+			chunks = append(chunks, chunk)
+			if closed := sw.Send(chunk, nil); closed {
+				return
+			}
+		}
+		response, err := schema.ConcatMessages(chunks)
+		if err != nil {
+			sw.Send(nil, err)
+			return
+		}
+		if err := m.save(&Cassette{Key: key, Request: input, Tools: toolNames(m.tools), Response: response, Chunks: chunks}); err != nil {
+			sw.Send(nil, err)
+		}
+	}()
+	return nsr, nil
+}
+
+func (m *CassetteModel) replayGenerate(ctx context.Context, input []*schema.Message, cassette *Cassette) (*schema.Message, error) {
+	ctx = callbacks.EnsureRunInfo(ctx, m.GetType(), components.ComponentOfChatModel)
+	ctx = callbacks.OnStart(ctx, &model.CallbackInput{Messages: input, Tools: m.tools})
+
+	output := cassette.Response
+	if output == nil {
+		var err error
+		if output, err = schema.ConcatMessages(cassette.Chunks); err != nil {
+			callbacks.OnError(ctx, err)
+			return nil, err
+		}
+	}
+	callbacks.OnEnd(ctx, &model.CallbackOutput{Message: output, TokenUsage: tokenUsage(output)})
+	return output, nil
+}
+
+func (m *CassetteModel) replayStream(ctx context.Context, input []*schema.Message, cassette *Cassette) (*schema.StreamReader[*schema.Message], error) {
+	ctx = callbacks.EnsureRunInfo(ctx, m.GetType(), components.ComponentOfChatModel)
+	ctx = callbacks.OnStart(ctx, &model.CallbackInput{Messages: input, Tools: m.tools})
+
+	chunks := cassette.Chunks
+	if len(chunks) == 0 && cassette.Response != nil {
+		chunks = []*schema.Message{cassette.Response}
+	}
+	sr := schema.StreamReaderFromArray(chunks)
+	_, nsr := callbacks.OnEndWithStreamOutput(ctx, schema.StreamReaderWithConvert(sr,
+		func(src *schema.Message) (callbacks.CallbackOutput, error) {
+			return &model.CallbackOutput{Message: src, TokenUsage: tokenUsage(src)}, nil
+		}))
+	return schema.StreamReaderWithConvert(nsr,
+		func(src callbacks.CallbackOutput) (*schema.Message, error) {
+			return src.(*model.CallbackOutput).Message, nil
+		}), nil
+}
+
+func (m *CassetteModel) shouldReplay(key string) bool {
+	switch m.cfg.Mode {
+	case CassetteModeReplay:
+		return true
+	case CassetteModeAuto:
+		_, err := os.Stat(m.path(key))
+		return err == nil
+	default:
+		return false
+	}
+}
+
+func (m *CassetteModel) path(key string) string {
+	return filepath.Join(m.cfg.Dir, key+".json")
+}
+
+func (m *CassetteModel) load(key string) (*Cassette, error) {
+	data, err := os.ReadFile(m.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrCassetteNotFound, m.path(key))
+		}
+		return nil, err
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", m.path(key), err)
+	}
+	return &cassette, nil
+}
+
+func (m *CassetteModel) save(cassette *Cassette) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := os.MkdirAll(m.cfg.Dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.path(cassette.Key), data, 0o644)
+}
+
+var (
+	uuidPattern      = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`)
+	durationPattern  = regexp.MustCompile(`\b(\d+h)?(\d+m)?\d+(\.\d+)?(ns|µs|us|ms)|\b(\d+h)?(\d+m)?\d+\.\d+s\b`)
+	spacePattern     = regexp.MustCompile(`\s+`)
+)
+
+// normalizeText 去除每次运行都会变化的内容（uuid、时间戳、执行耗时）以及多余空白
+func normalizeText(s string) string {
+	s = uuidPattern.ReplaceAllString(s, "<uuid>")
+	s = timestampPattern.ReplaceAllString(s, "<time>")
+	s = durationPattern.ReplaceAllString(s, "<duration>")
+	return strings.TrimSpace(spacePattern.ReplaceAllString(s, " "))
+}
+
+// CassetteKey 计算归一化后的prompt hash
+// 考虑角色、内容、工具调用（名称和参数）、可用工具名称，以及结构化输出格式和调用参数（温度、最大token数等），
+// 忽略工具调用ID等随机字段
+func CassetteKey(input []*schema.Message, tools []*schema.ToolInfo, options *ModelOptions, opts ...model.Option) string {
+	h := sha256.New()
+	for _, msg := range input {
+		if msg == nil {
+			continue
+		}
+		fmt.Fprintf(h, "role:%s\n", msg.Role)
+		fmt.Fprintf(h, "content:%s\n", normalizeText(msg.Content))
+		for _, tc := range msg.ToolCalls {
+			fmt.Fprintf(h, "tool_call:%s(%s)\n", tc.Function.Name, normalizeText(tc.Function.Arguments))
+		}
+		if msg.ToolName != "" {
+			fmt.Fprintf(h, "tool_name:%s\n", msg.ToolName)
+		}
+	}
+	for _, name := range toolNames(tools) {
+		fmt.Fprintf(h, "tool:%s\n", name)
+	}
+	if options != nil && options.ResponseFormat != nil {
+		format, _ := json.Marshal(options.ResponseFormat)
+		fmt.Fprintf(h, "response_format:%s\n", format)
+	}
+	writeCallOptions(h, model.GetCommonOptions(nil, opts...))
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// writeCallOptions 将影响输出的调用参数写入key，未设置的参数不写入，保证不带参数的调用key不变
+func writeCallOptions(w io.Writer, o *model.Options) {
+	if o.Model != nil {
+		fmt.Fprintf(w, "model:%s\n", *o.Model)
+	}
+	if o.Temperature != nil {
+		fmt.Fprintf(w, "temperature:%g\n", *o.Temperature)
+	}
+	if o.TopP != nil {
+		fmt.Fprintf(w, "top_p:%g\n", *o.TopP)
+	}
+	if o.MaxTokens != nil {
+		fmt.Fprintf(w, "max_tokens:%d\n", *o.MaxTokens)
+	}
+	if len(o.Stop) > 0 {
+		fmt.Fprintf(w, "stop:%s\n", strings.Join(o.Stop, ","))
+	}
+	if o.ToolChoice != nil {
+		fmt.Fprintf(w, "tool_choice:%s\n", *o.ToolChoice)
+	}
+	for _, name := range toolNames(o.Tools) {
+		fmt.Fprintf(w, "call_tool:%s\n", name)
+	}
+}
+
+func toolNames(tools []*schema.ToolInfo) []string {
+	names := make([]string, 0, len(tools))
+	for _, t := range tools {
+		if t != nil {
+			names = append(names, t.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func tokenUsage(msg *schema.Message) *model.TokenUsage {
+	if msg == nil || msg.ResponseMeta == nil || msg.ResponseMeta.Usage == nil {
+		return nil
+	}
+	usage := msg.ResponseMeta.Usage
+	return &model.TokenUsage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
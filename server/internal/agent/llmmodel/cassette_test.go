@@ -0,0 +1,170 @@
+package llmmodel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	openai2 "github.com/cloudwego/eino-ext/libs/acl/openai"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChatModel 模拟真实模型，记录调用次数
+type fakeChatModel struct {
+	calls int
+	tools []*schema.ToolInfo
+}
+
+func (f *fakeChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	f.calls++
+	return &schema.Message{
+		Role:    schema.Assistant,
+		Content: "",
+		ToolCalls: []schema.ToolCall{{
+			ID:       "call_1",
+			Function: schema.FunctionCall{Name: "search", Arguments: `{"query":"golang"}`},
+		}},
+		ResponseMeta: &schema.ResponseMeta{Usage: &schema.TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}},
+	}, nil
+}
+
+func (f *fakeChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	f.calls++
+	return schema.StreamReaderFromArray([]*schema.Message{
+		schema.AssistantMessage("hello ", nil),
+		schema.AssistantMessage("world", nil),
+	}), nil
+}
+
+func (f *fakeChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return &fakeChatModel{tools: tools}, nil
+}
+
+func TestCassetteModel_RecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	inner := &fakeChatModel{}
+	tools := []*schema.ToolInfo{{Name: "search"}}
+
+	recorder, err := NewCassetteModel(inner, CassetteConfig{Mode: CassetteModeRecord, Dir: dir})
+	require.NoError(t, err)
+	recorderWithTools, err := recorder.WithTools(tools)
+	require.NoError(t, err)
+
+	input := []*schema.Message{
+		schema.SystemMessage("node 6f1c2d3e-4b5a-6789-abcd-ef0123456789 created at 2025-01-27T10:00:00Z, Duration: 517.885µs"),
+		schema.UserMessage("search golang"),
+	}
+	recorded, err := recorderWithTools.Generate(ctx, input)
+	require.NoError(t, err)
+	require.Len(t, recorded.ToolCalls, 1)
+
+	sr, err := recorder.Stream(ctx, []*schema.Message{schema.UserMessage("hi")})
+	require.NoError(t, err)
+	content := ""
+	for {
+		chunk, err := sr.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		content += chunk.Content
+	}
+	sr.Close()
+	assert.Equal(t, "hello world", content)
+
+	// 回放模式不需要真实模型，uuid、时间戳、耗时和空白差异不影响匹配
+	replayer, err := NewCassetteModel(nil, CassetteConfig{Mode: CassetteModeReplay, Dir: dir})
+	require.NoError(t, err)
+	replayerWithTools, err := replayer.WithTools(tools)
+	require.NoError(t, err)
+	replayed, err := replayerWithTools.Generate(ctx, []*schema.Message{
+		schema.SystemMessage("node 0a1b2c3d-4e5f-6789-abcd-ef0123456789 created at 2025-02-01T08:30:00Z, Duration: 1.284045ms"),
+		schema.UserMessage("  search   golang "),
+	})
+	require.NoError(t, err)
+	require.Len(t, replayed.ToolCalls, 1)
+	assert.Equal(t, "search", replayed.ToolCalls[0].Function.Name)
+	assert.Equal(t, 15, replayed.ResponseMeta.Usage.TotalTokens)
+
+	// 流式录制可以按流式回放，也可以按非流式回放
+	chunks, err := replayer.Stream(ctx, []*schema.Message{schema.UserMessage("hi")})
+	require.NoError(t, err)
+	var got []string
+	for {
+		chunk, err := chunks.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, chunk.Content)
+	}
+	assert.Equal(t, []string{"hello ", "world"}, got)
+	msg, err := replayer.Generate(ctx, []*schema.Message{schema.UserMessage("hi")})
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", msg.Content)
+
+	// 未录制的请求
+	_, err = replayer.Generate(ctx, []*schema.Message{schema.UserMessage("unknown")})
+	assert.ErrorIs(t, err, ErrCassetteNotFound)
+
+	// 工具列表不同视为不同请求
+	_, err = replayer.Generate(ctx, input)
+	assert.ErrorIs(t, err, ErrCassetteNotFound)
+}
+
+func TestCassetteModel_Auto(t *testing.T) {
+	ctx := context.Background()
+	inner := &fakeChatModel{}
+	cm, err := NewCassetteModel(inner, CassetteConfig{Mode: CassetteModeAuto, Dir: t.TempDir()})
+	require.NoError(t, err)
+
+	input := []*schema.Message{schema.UserMessage("hello")}
+	_, err = cm.Generate(ctx, input)
+	require.NoError(t, err)
+	_, err = cm.Generate(ctx, input)
+	require.NoError(t, err)
+	assert.Equal(t, 1, inner.calls)
+}
+
+func TestCassetteKey_Options(t *testing.T) {
+	input := []*schema.Message{schema.UserMessage("analyze the problem")}
+	base := CassetteKey(input, nil, nil)
+	assert.Equal(t, base, CassetteKey(input, nil, &ModelOptions{}))
+
+	// 结构化输出格式不同视为不同请求
+	jsonObject := &ModelOptions{ResponseFormat: &openai2.ChatCompletionResponseFormat{Type: openai2.ChatCompletionResponseFormatTypeJSONObject}}
+	assert.NotEqual(t, base, CassetteKey(input, nil, jsonObject))
+
+	// 调用参数不同视为不同请求
+	assert.NotEqual(t, base, CassetteKey(input, nil, nil, model.WithTemperature(0.2)))
+	assert.NotEqual(t, CassetteKey(input, nil, nil, model.WithTemperature(0.2)), CassetteKey(input, nil, nil, model.WithTemperature(0.7)))
+	assert.NotEqual(t, base, CassetteKey(input, nil, nil, model.WithMaxTokens(512)))
+	assert.Equal(t, CassetteKey(input, nil, nil, model.WithMaxTokens(512)), CassetteKey(input, nil, nil, model.WithMaxTokens(512)))
+}
+
+func TestCassetteModel_ReplayByResponseFormat(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	jsonFormat := WithResponseFormat(&openai2.ChatCompletionResponseFormat{Type: openai2.ChatCompletionResponseFormatTypeJSONObject})
+	input := []*schema.Message{schema.UserMessage("hi")}
+
+	recorder, err := NewCassetteModel(&fakeChatModel{}, CassetteConfig{Mode: CassetteModeRecord, Dir: dir}, jsonFormat)
+	require.NoError(t, err)
+	_, err = recorder.Generate(ctx, input)
+	require.NoError(t, err)
+
+	replayer, err := NewCassetteModel(nil, CassetteConfig{Mode: CassetteModeReplay, Dir: dir}, jsonFormat)
+	require.NoError(t, err)
+	_, err = replayer.Generate(ctx, input)
+	require.NoError(t, err)
+
+	plain, err := NewCassetteModel(nil, CassetteConfig{Mode: CassetteModeReplay, Dir: dir})
+	require.NoError(t, err)
+	_, err = plain.Generate(ctx, input)
+	assert.ErrorIs(t, err, ErrCassetteNotFound)
+}
@@ -0,0 +1,114 @@
+package llmmodel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/spf13/viper"
+)
+
+const defaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// NewDefaultEmbedder 按配置 llm.embedding 指定的配置档创建嵌入模型，未配置时返回nil，表示不启用向量检索
+func NewDefaultEmbedder(ctx context.Context) (embedding.Embedder, error) {
+	profileName := viper.GetString("llm.embedding")
+	if profileName == "" {
+		return nil, nil
+	}
+	return NewEmbedder(ctx, profileName)
+}
+
+// NewEmbedder 根据模型配置档创建嵌入模型，目前仅支持OpenAI兼容的 /embeddings 接口
+func NewEmbedder(ctx context.Context, profileName string) (embedding.Embedder, error) {
+	profile, err := GetProfile(profileName)
+	if err != nil {
+		return nil, err
+	}
+	if profile.Provider != ProviderOpenAI {
+		return nil, fmt.Errorf("llm provider %s does not support embedding", profile.Provider)
+	}
+	timeout := profile.Timeout
+	if timeout == 0 {
+		timeout = 60 * time.Second
+	}
+	baseURL := profile.BaseURL
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	return &openAIEmbedder{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  profile.APIKey,
+		model:   profile.Model,
+		client:  &http.Client{Timeout: timeout},
+	}, nil
+}
+
+type openAIEmbedder struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (e *openAIEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	options := embedding.GetCommonOptions(&embedding.Options{Model: &e.model}, opts...)
+	reqBytes, err := json.Marshal(map[string]any{
+		"model": *options.Model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embedding request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", e.baseURL+"/embeddings", bytes.NewReader(reqBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send embedding request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("embedding request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var embResp embeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
+		return nil, fmt.Errorf("failed to decode embedding response: %w", err)
+	}
+	vectors := make([][]float64, len(texts))
+	for _, data := range embResp.Data {
+		if data.Index < 0 || data.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index out of range: %d", data.Index)
+		}
+		vectors[data.Index] = data.Embedding
+	}
+	for i, vector := range vectors {
+		if vector == nil {
+			return nil, fmt.Errorf("missing embedding for input %d", i)
+		}
+	}
+	return vectors, nil
+}
@@ -0,0 +1,48 @@
+package llmmodel
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewEmbedder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer sk-embed", r.Header.Get("Authorization"))
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "embed-model", req.Model)
+		// 乱序返回，按 index 还原
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data": []map[string]any{
+				{"index": 1, "embedding": []float64{0, 1}},
+				{"index": 0, "embedding": []float64{1, 0}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	setViper(t, "llm.profiles.embedtest.model", "embed-model")
+	setViper(t, "llm.profiles.embedtest.api_key", "sk-embed")
+	setViper(t, "llm.profiles.embedtest.base_url", server.URL+"/v1/")
+
+	embedder, err := NewEmbedder(context.Background(), "embedtest")
+	require.NoError(t, err)
+	vectors, err := embedder.EmbedStrings(context.Background(), []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float64{{1, 0}, {0, 1}}, vectors)
+
+	setViper(t, "llm.profiles.embedclaude.provider", "claude")
+	setViper(t, "llm.profiles.embedclaude.model", "claude-test")
+	_, err = NewEmbedder(context.Background(), "embedclaude")
+	assert.Error(t, err)
+}
@@ -0,0 +1,305 @@
+package llmmodel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino-ext/components/model/claude"
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	openai2 "github.com/cloudwego/eino-ext/libs/acl/openai"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/spf13/viper"
+)
+
+// Provider 模型供应商
+type Provider string
+
+const (
+	ProviderOpenAI Provider = "openai" // OpenAI及兼容接口（deepseek、qwen等）
+	ProviderClaude Provider = "claude"
+)
+
+// DefaultProfile 默认模型配置档名称
+const DefaultProfile = "default"
+
+// Agent角色，用于在配置 llm.agents 中为不同Agent指定模型配置档
+const (
+	AgentDefault                = "default"
+	AgentUnderstanding          = "understanding"
+	AgentDecomposition          = "decomposition"
+	AgentAnalysis               = "analysis"
+	AgentConclusionGeneration   = "conclusion_generation"
+	AgentConclusionOptimization = "conclusion_optimization"
+	AgentConclusionRollup       = "conclusion_rollup"
+	AgentRepeater               = "repeater"
+	AgentSummary                = "summary" // 上下文摘要
+)
+
+// Profile 模型配置档，对应配置 llm.profiles.<name>
+type Profile struct {
+	Name             string        `json:"name"`
+	Provider         Provider      `json:"provider"`
+	APIKey           string        `json:"-"`
+	BaseURL          string        `json:"baseURL"`
+	Model            string        `json:"model"`
+	Temperature      *float32      `json:"temperature,omitempty"`
+	MaxTokens        int           `json:"maxTokens,omitempty"`
+	StructuredOutput bool          `json:"structuredOutput"` // 是否支持json schema结构化输出
+	Timeout          time.Duration `json:"timeout,omitempty"`
+	InputPrice       float64       `json:"inputPrice,omitempty"`  // 每百万输入token的价格
+	OutputPrice      float64       `json:"outputPrice,omitempty"` // 每百万输出token的价格
+	// ContextBudget 节点上下文的token预算，超出时压缩或裁剪上下文，0 表示不限制
+	ContextBudget int `json:"contextBudget,omitempty"`
+}
+
+// ModelOptions 创建模型时的可选参数
+type ModelOptions struct {
+	ResponseFormat *openai2.ChatCompletionResponseFormat
+}
+
+type ModelOption func(*ModelOptions)
+
+// WithResponseFormat 指定结构化输出格式，仅对支持结构化输出的配置档生效
+func WithResponseFormat(responseFormat *openai2.ChatCompletionResponseFormat) ModelOption {
+	return func(o *ModelOptions) {
+		o.ResponseFormat = responseFormat
+	}
+}
+
+// ProviderFactory 根据配置档创建模型
+type ProviderFactory func(ctx context.Context, profile *Profile, opts *ModelOptions) (model.ToolCallingChatModel, error)
+
+var (
+	providerMu sync.RWMutex
+	providers  = map[Provider]ProviderFactory{
+		ProviderOpenAI: newOpenAIProviderModel,
+		ProviderClaude: newClaudeProviderModel,
+	}
+)
+
+// RegisterProvider 注册（或覆盖）模型供应商
+func RegisterProvider(provider Provider, factory ProviderFactory) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	providers[provider] = factory
+}
+
+func getProviderFactory(provider Provider) (ProviderFactory, bool) {
+	providerMu.RLock()
+	defer providerMu.RUnlock()
+	factory, ok := providers[provider]
+	return factory, ok
+}
+
+// ProfileNames 返回配置中的所有模型配置档名称
+func ProfileNames() []string {
+	nameSet := map[string]struct{}{}
+	for _, key := range viper.AllKeys() {
+		if !strings.HasPrefix(key, "llm.profiles.") {
+			continue
+		}
+		parts := strings.Split(key, ".")
+		if len(parts) >= 4 {
+			nameSet[parts[2]] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(nameSet))
+	for name := range nameSet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetProfile 获取模型配置档
+// 未配置 llm.profiles.default 时，default 配置档回退到 llm.openai
+func GetProfile(name string) (*Profile, error) {
+	if name == "" {
+		name = DefaultProfile
+	}
+	name = strings.ToLower(name)
+	prefix := "llm.profiles." + name
+	if !viper.IsSet(prefix + ".model") {
+		if name == DefaultProfile {
+			return legacyOpenAIProfile(), nil
+		}
+		return nil, fmt.Errorf("llm profile not found: %s", name)
+	}
+	profile := &Profile{
+		Name:             name,
+		Provider:         Provider(strings.ToLower(viper.GetString(prefix + ".provider"))),
+		APIKey:           viper.GetString(prefix + ".api_key"),
+		BaseURL:          viper.GetString(prefix + ".base_url"),
+		Model:            viper.GetString(prefix + ".model"),
+		MaxTokens:        viper.GetInt(prefix + ".max_tokens"),
+		StructuredOutput: viper.GetBool(prefix + ".structured_output"),
+		Timeout:          viper.GetDuration(prefix + ".timeout"),
+		InputPrice:       viper.GetFloat64(prefix + ".input_price"),
+		OutputPrice:      viper.GetFloat64(prefix + ".output_price"),
+		ContextBudget:    viper.GetInt(prefix + ".context_budget"),
+	}
+	if profile.Provider == "" {
+		profile.Provider = ProviderOpenAI
+	}
+	if viper.GetString(prefix+".temperature") != "" {
+		temperature := float32(viper.GetFloat64(prefix + ".temperature"))
+		profile.Temperature = &temperature
+	}
+	return profile, nil
+}
+
+// EstimateCost 按配置档中的价格估算一次模型调用的费用
+// 根据模型名称查找配置档，找不到时使用 default 配置档的价格
+func EstimateCost(modelName string, promptTokens, completionTokens int) float64 {
+	profile := findProfileByModel(modelName)
+	if profile == nil {
+		return 0
+	}
+	return (float64(promptTokens)*profile.InputPrice + float64(completionTokens)*profile.OutputPrice) / 1e6
+}
+
+func findProfileByModel(modelName string) *Profile {
+	if modelName != "" {
+		for _, name := range ProfileNames() {
+			if viper.GetString("llm.profiles."+name+".model") != modelName {
+				continue
+			}
+			if profile, err := GetProfile(name); err == nil {
+				return profile
+			}
+		}
+	}
+	profile, err := GetProfile(DefaultProfile)
+	if err != nil {
+		return nil
+	}
+	return profile
+}
+
+// legacyOpenAIProfile 兼容旧配置 llm.openai
+func legacyOpenAIProfile() *Profile {
+	return &Profile{
+		Name:             DefaultProfile,
+		Provider:         ProviderOpenAI,
+		APIKey:           viper.GetString("llm.openai.api_key"),
+		BaseURL:          viper.GetString("llm.openai.base_url"),
+		Model:            viper.GetString("llm.openai.model"),
+		StructuredOutput: true,
+		Timeout:          viper.GetDuration("llm.openai.timeout"),
+	}
+}
+
+// ProfileNameForAgent 获取Agent角色使用的配置档名称
+// 查找顺序：llm.agents.<role> -> llm.agents.default -> default
+func ProfileNameForAgent(role string) string {
+	if name := viper.GetString("llm.agents." + strings.ToLower(role)); name != "" {
+		return name
+	}
+	if name := viper.GetString("llm.agents." + AgentDefault); name != "" {
+		return name
+	}
+	return DefaultProfile
+}
+
+// ProfileNameForSpecialist 获取专家Agent使用的配置档名称
+// 查找顺序：llm.agents.specialists.<name> -> 所属Agent角色的配置档
+func ProfileNameForSpecialist(role, specialist string) string {
+	if name := viper.GetString("llm.agents.specialists." + strings.ToLower(specialist)); name != "" {
+		return name
+	}
+	return ProfileNameForAgent(role)
+}
+
+// GetAgentProfile 获取Agent角色对应的配置档
+func GetAgentProfile(role string) (*Profile, error) {
+	return GetProfile(ProfileNameForAgent(role))
+}
+
+// NewChatModel 根据配置档名称创建模型
+func NewChatModel(ctx context.Context, profileName string, opts ...ModelOption) (model.ToolCallingChatModel, error) {
+	profile, err := GetProfile(profileName)
+	if err != nil {
+		return nil, err
+	}
+	return NewChatModelWithProfile(ctx, profile, opts...)
+}
+
+// NewChatModelWithProfile 根据配置档创建模型
+func NewChatModelWithProfile(ctx context.Context, profile *Profile, opts ...ModelOption) (model.ToolCallingChatModel, error) {
+	options := &ModelOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if !profile.StructuredOutput {
+		options.ResponseFormat = nil
+	}
+	// 录制/回放模式，用于离线测试
+	cassetteCfg := DefaultCassetteConfig()
+	if cassetteCfg.Mode == CassetteModeReplay {
+		return NewCassetteModel(nil, cassetteCfg, WithResponseFormat(options.ResponseFormat))
+	}
+	factory, ok := getProviderFactory(profile.Provider)
+	if !ok {
+		return nil, fmt.Errorf("unsupported llm provider: %s (profile: %s)", profile.Provider, profile.Name)
+	}
+	cm, err := factory(ctx, profile, options)
+	if err != nil {
+		return nil, err
+	}
+	if cassetteCfg.Mode == CassetteModeRecord || cassetteCfg.Mode == CassetteModeAuto {
+		return NewCassetteModel(cm, cassetteCfg, WithResponseFormat(options.ResponseFormat))
+	}
+	return cm, nil
+}
+
+// NewAgentModel 根据Agent角色创建模型
+func NewAgentModel(ctx context.Context, role string, opts ...ModelOption) (model.ToolCallingChatModel, error) {
+	return NewChatModel(ctx, ProfileNameForAgent(role), opts...)
+}
+
+// NewSpecialistModel 根据专家名称创建模型
+func NewSpecialistModel(ctx context.Context, role, specialist string, opts ...ModelOption) (model.ToolCallingChatModel, error) {
+	return NewChatModel(ctx, ProfileNameForSpecialist(role, specialist), opts...)
+}
+
+func newOpenAIProviderModel(ctx context.Context, profile *Profile, opts *ModelOptions) (model.ToolCallingChatModel, error) {
+	cfg := &openai.ChatModelConfig{
+		APIKey:         profile.APIKey,
+		BaseURL:        profile.BaseURL,
+		Model:          profile.Model,
+		Temperature:    profile.Temperature,
+		Timeout:        profile.Timeout,
+		ResponseFormat: opts.ResponseFormat,
+	}
+	if profile.MaxTokens > 0 {
+		maxTokens := profile.MaxTokens
+		cfg.MaxTokens = &maxTokens
+	}
+	return openai.NewChatModel(ctx, cfg)
+}
+
+func newClaudeProviderModel(ctx context.Context, profile *Profile, opts *ModelOptions) (model.ToolCallingChatModel, error) {
+	cfg := &claude.Config{
+		APIKey:      profile.APIKey,
+		Model:       profile.Model,
+		MaxTokens:   profile.MaxTokens,
+		Temperature: profile.Temperature,
+	}
+	if cfg.MaxTokens <= 0 {
+		// claude接口要求必须指定max_tokens
+		cfg.MaxTokens = 4096
+	}
+	if profile.BaseURL != "" {
+		baseURL := profile.BaseURL
+		cfg.BaseURL = &baseURL
+	}
+	if profile.Timeout > 0 {
+		cfg.HTTPClient = &http.Client{Timeout: profile.Timeout}
+	}
+	return claude.NewChatModel(ctx, cfg)
+}
@@ -0,0 +1,98 @@
+package llmmodel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setViper 在测试期间覆盖配置项，测试结束后恢复原值，避免影响包内其他测试
+func setViper(t *testing.T, key string, value any) {
+	t.Helper()
+	old := viper.Get(key)
+	t.Cleanup(func() { viper.Set(key, old) })
+	viper.Set(key, value)
+}
+
+func TestGetProfile(t *testing.T) {
+	setViper(t, "llm.profiles.unittest.provider", "claude")
+	setViper(t, "llm.profiles.unittest.api_key", "sk-test")
+	setViper(t, "llm.profiles.unittest.model", "claude-test")
+	setViper(t, "llm.profiles.unittest.temperature", "0.2")
+	setViper(t, "llm.profiles.unittest.max_tokens", "1024")
+	setViper(t, "llm.profiles.unittest.structured_output", "false")
+	setViper(t, "llm.profiles.unittest.timeout", "30s")
+	setViper(t, "llm.profiles.unittest.context_budget", "16000")
+
+	profile, err := GetProfile("unittest")
+	require.NoError(t, err)
+	assert.Equal(t, ProviderClaude, profile.Provider)
+	assert.Equal(t, "claude-test", profile.Model)
+	assert.Equal(t, 1024, profile.MaxTokens)
+	assert.Equal(t, 16000, profile.ContextBudget)
+	assert.False(t, profile.StructuredOutput)
+	require.NotNil(t, profile.Temperature)
+	assert.InDelta(t, 0.2, *profile.Temperature, 1e-6)
+	assert.Contains(t, ProfileNames(), "unittest")
+
+	_, err = GetProfile("not-exist")
+	assert.Error(t, err)
+}
+
+func TestLegacyOpenAIProfile(t *testing.T) {
+	setViper(t, "llm.openai.model", "legacy-model")
+	setViper(t, "llm.openai.timeout", "300s")
+
+	profile := legacyOpenAIProfile()
+	assert.Equal(t, "legacy-model", profile.Model)
+	assert.Equal(t, 300*time.Second, profile.Timeout)
+}
+
+func TestEstimateCost(t *testing.T) {
+	setViper(t, "llm.profiles.pricing.model", "pricing-model")
+	setViper(t, "llm.profiles.pricing.input_price", "2")
+	setViper(t, "llm.profiles.pricing.output_price", "8")
+
+	assert.InDelta(t, 0.002+0.004, EstimateCost("pricing-model", 1000, 500), 1e-9)
+}
+
+func TestProfileNameForAgent(t *testing.T) {
+	setViper(t, "llm.agents.default", "")
+	setViper(t, "llm.agents.understanding", "")
+	assert.Equal(t, DefaultProfile, ProfileNameForAgent(AgentUnderstanding))
+
+	setViper(t, "llm.agents.default", "fallback")
+	assert.Equal(t, "fallback", ProfileNameForAgent(AgentUnderstanding))
+
+	setViper(t, "llm.agents.understanding", "unittest")
+	assert.Equal(t, "unittest", ProfileNameForAgent(AgentUnderstanding))
+
+	// 专家未单独配置时使用所属Agent的配置档
+	setViper(t, "llm.agents.decomposition", "unittest")
+	assert.Equal(t, "unittest", ProfileNameForSpecialist(AgentDecomposition, "UnitTestSpecialist"))
+	setViper(t, "llm.agents.specialists.UnitTestSpecialist", "specialist")
+	assert.Equal(t, "specialist", ProfileNameForSpecialist(AgentDecomposition, "UnitTestSpecialist"))
+
+}
+
+func TestRegisterProvider(t *testing.T) {
+	var gotOpts *ModelOptions
+	RegisterProvider("unittest", func(ctx context.Context, profile *Profile, opts *ModelOptions) (model.ToolCallingChatModel, error) {
+		gotOpts = opts
+		return nil, nil
+	})
+
+	profile := &Profile{Name: "unittest", Provider: "unittest", StructuredOutput: false}
+	_, err := NewChatModelWithProfile(context.Background(), profile, WithResponseFormat(nil))
+	require.NoError(t, err)
+	require.NotNil(t, gotOpts)
+	assert.Nil(t, gotOpts.ResponseFormat)
+
+	_, err = NewChatModelWithProfile(context.Background(), &Profile{Name: "bad", Provider: "bad"})
+	assert.Error(t, err)
+}
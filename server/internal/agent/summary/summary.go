@@ -0,0 +1,47 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/llmmodel"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// 摘要类型
+const (
+	KindConversation = "conversation" // 较早的对话历史
+	KindAncestors    = "ancestors"    // 较远的祖先节点
+	KindAbstract     = "abstract"     // 节点结论摘要
+)
+
+// BuildSummaryAgent 构建摘要Agent，将超出上下文预算的内容或节点结论压缩为摘要，不调用工具
+func BuildSummaryAgent(ctx context.Context, kind string) (r compose.Runnable[[]*schema.Message, *schema.Message], err error) {
+	prompt, err := buildSummaryPrompt(kind)
+	if err != nil {
+		return nil, err
+	}
+	cm, err := llmmodel.NewAgentModel(ctx, llmmodel.AgentSummary)
+	if err != nil {
+		return nil, err
+	}
+	chain := compose.NewChain[[]*schema.Message, *schema.Message]()
+	chain.AppendLambda(compose.InvokableLambdaWithOption(func(ctx context.Context, input []*schema.Message, opts ...any) (output []*schema.Message, err error) {
+		systemMsg := schema.SystemMessage(prompt)
+		return append([]*schema.Message{systemMsg}, input...), nil
+	})).AppendChatModel(cm)
+	return chain.Compile(ctx, compose.WithGraphName("summary_"+kind))
+}
+
+func buildSummaryPrompt(kind string) (string, error) {
+	switch kind {
+	case KindConversation:
+		return buildConversationSummaryPrompt(), nil
+	case KindAncestors:
+		return buildAncestorsSummaryPrompt(), nil
+	case KindAbstract:
+		return buildAbstractPrompt(), nil
+	}
+	return "", fmt.Errorf("unsupported summary kind: %s", kind)
+}
@@ -0,0 +1,59 @@
+// Package testutil Agent测试的公共工具
+package testutil
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/llmmodel"
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/components/model"
+)
+
+// CassetteDir 各测试包的录制文件目录
+const CassetteDir = "testdata/cassettes"
+
+// CassetteMode 测试使用的录制/回放模式，由环境变量 LLM_CASSETTE_MODE 指定，默认回放
+func CassetteMode() llmmodel.CassetteMode {
+	if mode := os.Getenv("LLM_CASSETTE_MODE"); mode != "" {
+		return llmmodel.CassetteMode(mode)
+	}
+	return llmmodel.CassetteModeReplay
+}
+
+// NewChatModel 创建Agent测试使用的模型
+// 默认从 testdata/cassettes 回放录制文件，不访问网络；已提交的录制文件由脚本化模型生成，是合成数据，
+// 回放只验证Agent的编排逻辑，不代表真实模型的行为；
+// LLM_CASSETTE_MODE=record|auto 时调用真实模型（OPENAI_API_KEY）并录制，=off 时直接调用真实模型，未设置 OPENAI_API_KEY 时跳过测试
+func NewChatModel(tb testing.TB) model.ToolCallingChatModel {
+	tb.Helper()
+	cfg := llmmodel.CassetteConfig{Mode: CassetteMode(), Dir: CassetteDir}
+	if cfg.Mode == llmmodel.CassetteModeReplay {
+		cm, err := llmmodel.NewCassetteModel(nil, cfg)
+		if err != nil {
+			tb.Fatalf("failed to create cassette model: %v", err)
+		}
+		return cm
+	}
+
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		tb.Skip("OPENAI_API_KEY not set, skipping live model test")
+	}
+	cm, err := openai.NewChatModel(context.Background(), &openai.ChatModelConfig{
+		APIKey: apiKey,
+		Model:  "gpt-4o-mini",
+	})
+	if err != nil {
+		tb.Fatalf("failed to create chat model: %v", err)
+	}
+	if cfg.Mode == llmmodel.CassetteModeRecord || cfg.Mode == llmmodel.CassetteModeAuto {
+		cassette, err := llmmodel.NewCassetteModel(cm, cfg)
+		if err != nil {
+			tb.Fatalf("failed to create cassette model: %v", err)
+		}
+		return cassette
+	}
+	return cm
+}
@@ -0,0 +1,310 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/tool/registry"
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/PGshen/thinking-map/server/internal/pkg/sse"
+	mcpp "github.com/cloudwego/eino-ext/components/tool/mcp"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// 传输方式
+const (
+	TransportStdio = "stdio"
+	TransportHTTP  = "http" // streamable HTTP
+)
+
+// ServerConfig MCP服务器配置，env、url、headers 中可以用 ${VAR} 引用环境变量
+type ServerConfig struct {
+	Name      string            `yaml:"-" json:"name"` // 配置中的键名
+	Transport string            `yaml:"transport" json:"transport"`
+	Command   string            `yaml:"command" json:"command"` // stdio: 启动命令
+	Args      []string          `yaml:"args" json:"args"`
+	Env       []string          `yaml:"env" json:"env"` // stdio: KEY=VALUE
+	URL       string            `yaml:"url" json:"url"` // http: 服务地址
+	Headers   map[string]string `yaml:"headers" json:"headers"`
+	Tools     []string          `yaml:"tools" json:"tools"` // 允许使用的工具，为空时使用全部工具
+	Timeout   time.Duration     `yaml:"timeout" json:"timeout"`
+}
+
+// GroupPrefix 每个MCP服务器的工具注册为工具组 mcp_<服务器名>
+const GroupPrefix = "mcp_"
+
+var (
+	mu          sync.Mutex
+	clients     = map[string]client.MCPClient{}
+	serverTools = map[string][]string{} // 服务器名 -> 已注册的工具名
+)
+
+// Load 连接配置文件 mcp.servers 中的所有MCP服务器，并将工具注册到工具注册表
+func Load(ctx context.Context) error {
+	var servers map[string]*ServerConfig
+	if err := viper.UnmarshalKey("mcp.servers", &servers); err != nil {
+		return fmt.Errorf("failed to parse mcp config: %w", err)
+	}
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	configs := make([]ServerConfig, 0, len(servers))
+	for _, name := range names {
+		if servers[name] == nil {
+			continue
+		}
+		server := *servers[name]
+		server.Name = name
+		configs = append(configs, server)
+	}
+	return LoadServers(ctx, configs)
+}
+
+// LoadServers 连接MCP服务器并注册工具
+// 每个服务器的工具注册为工具组 GroupPrefix+服务器名，所有MCP工具同时加入工具组 registry.GroupMCP；
+// 某个服务器连接失败不影响其他服务器
+func LoadServers(ctx context.Context, servers []ServerConfig) error {
+	var errs []error
+	for _, server := range servers {
+		cli, err := Connect(ctx, server)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mcp server %s: %w", server.Name, err))
+			continue
+		}
+		tools, err := GetTools(ctx, cli, server)
+		if err != nil {
+			_ = cli.Close()
+			errs = append(errs, fmt.Errorf("mcp server %s: %w", server.Name, err))
+			continue
+		}
+		mu.Lock()
+		if old, ok := clients[server.Name]; ok {
+			_ = old.Close()
+		}
+		clients[server.Name] = cli
+		mu.Unlock()
+		if err := register(ctx, server, tools); err != nil {
+			errs = append(errs, fmt.Errorf("mcp server %s: %w", server.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close 关闭所有MCP服务器连接
+func Close() {
+	mu.Lock()
+	defer mu.Unlock()
+	for name, cli := range clients {
+		if err := cli.Close(); err != nil {
+			logger.Warn("failed to close mcp client", zap.String("server", name), zap.Error(err))
+		}
+		delete(clients, name)
+	}
+}
+
+// Connect 连接MCP服务器并完成初始化
+func Connect(ctx context.Context, server ServerConfig) (*client.Client, error) {
+	var (
+		cli *client.Client
+		err error
+	)
+	switch server.Transport {
+	case TransportStdio:
+		if server.Command == "" {
+			return nil, errors.New("stdio transport requires command")
+		}
+		env := make([]string, 0, len(server.Env))
+		for _, e := range server.Env {
+			env = append(env, os.ExpandEnv(e))
+		}
+		// stdio客户端创建时已经启动子进程
+		cli, err = client.NewStdioMCPClient(server.Command, env, server.Args...)
+		if err != nil {
+			return nil, err
+		}
+	case TransportHTTP, "":
+		if server.URL == "" {
+			return nil, errors.New("http transport requires url")
+		}
+		headers := make(map[string]string, len(server.Headers))
+		for k, v := range server.Headers {
+			headers[k] = os.ExpandEnv(v)
+		}
+		opts := []transport.StreamableHTTPCOption{transport.WithHTTPHeaders(headers)}
+		if server.Timeout > 0 {
+			opts = append(opts, transport.WithHTTPTimeout(server.Timeout))
+		}
+		cli, err = client.NewStreamableHttpClient(os.ExpandEnv(server.URL), opts...)
+		if err != nil {
+			return nil, err
+		}
+		if err = cli.Start(ctx); err != nil {
+			return nil, fmt.Errorf("failed to start mcp client: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported mcp transport: %s", server.Transport)
+	}
+	if err := initialize(ctx, cli); err != nil {
+		_ = cli.Close()
+		return nil, err
+	}
+	return cli, nil
+}
+
+func initialize(ctx context.Context, cli *client.Client) error {
+	req := mcpgo.InitializeRequest{}
+	req.Params.ProtocolVersion = mcpgo.LATEST_PROTOCOL_VERSION
+	req.Params.ClientInfo = mcpgo.Implementation{
+		Name:    "thinking-map",
+		Version: "1.0.0",
+	}
+	if _, err := cli.Initialize(ctx, req); err != nil {
+		return fmt.Errorf("failed to initialize mcp client: %w", err)
+	}
+	return nil
+}
+
+// GetTools 获取MCP服务器上允许使用的工具，工具调用时向前端发送通知
+func GetTools(ctx context.Context, cli client.MCPClient, server ServerConfig) ([]tool.BaseTool, error) {
+	tools, err := mcpp.GetTools(ctx, &mcpp.Config{
+		Cli:          cli,
+		ToolNameList: server.Tools,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]tool.BaseTool, 0, len(tools))
+	for _, t := range tools {
+		invokable, ok := t.(tool.InvokableTool)
+		if !ok {
+			continue
+		}
+		result = append(result, &noticeTool{InvokableTool: invokable, server: server.Name})
+	}
+	return result, nil
+}
+
+// register 将工具注册到工具注册表，与其他已注册工具重名的工具跳过
+func register(ctx context.Context, server ServerConfig, tools []tool.BaseTool) error {
+	mu.Lock()
+	defer mu.Unlock()
+	own := map[string]bool{}
+	for _, name := range serverTools[server.Name] {
+		own[name] = true
+	}
+	registered := map[string]bool{}
+	for _, name := range registry.Names() {
+		registered[name] = !own[name]
+	}
+	var names []string
+	for _, t := range tools {
+		info, err := t.Info(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get tool info: %w", err)
+		}
+		if registered[info.Name] {
+			logger.Warn("mcp tool conflicts with registered tool, skipped", zap.String("server", server.Name), zap.String("tool", info.Name))
+			continue
+		}
+		registry.Register(info.Name, func() (tool.BaseTool, error) {
+			return t, nil
+		})
+		registered[info.Name] = true
+		names = append(names, info.Name)
+	}
+	serverTools[server.Name] = names
+	registry.RegisterGroup(GroupPrefix+server.Name, names...)
+
+	// 汇总所有MCP服务器的工具
+	servers := make([]string, 0, len(serverTools))
+	for name := range serverTools {
+		servers = append(servers, name)
+	}
+	sort.Strings(servers)
+	var all []string
+	for _, name := range servers {
+		all = append(all, serverTools[name]...)
+	}
+	registry.RegisterGroup(registry.GroupMCP, all...)
+	logger.Info("mcp tools loaded", zap.String("server", server.Name), zap.Strings("tools", names))
+	return nil
+}
+
+// noticeTool 调用MCP工具时向前端发送通知
+type noticeTool struct {
+	tool.InvokableTool
+	server string
+}
+
+func (t *noticeTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	info, err := t.Info(ctx)
+	if err != nil {
+		return "", err
+	}
+	notify(ctx, model.Notice{
+		Type:    model.NoticeTypeInfo,
+		Name:    "MCP工具",
+		Content: fmt.Sprintf("%s/%s: %s", t.server, info.Name, truncate(argumentsInJSON, 200)),
+	})
+	result, err := t.InvokableTool.InvokableRun(ctx, argumentsInJSON, opts...)
+	if err != nil {
+		notify(ctx, model.Notice{
+			Type:    model.NoticeTypeError,
+			Name:    "MCP工具",
+			Content: fmt.Sprintf("%s/%s: %s", t.server, info.Name, err.Error()),
+		})
+		return "", err
+	}
+	return result, nil
+}
+
+// notify 发送工具调用通知，运行上下文之外（没有mapID）调用时不发送
+var notify = func(ctx context.Context, notice model.Notice) {
+	mapID, _ := ctx.Value("mapID").(string)
+	nodeID, _ := ctx.Value("nodeID").(string)
+	operation, _ := ctx.Value("operation").(string)
+	if mapID == "" {
+		return
+	}
+	global.GetBroker().PublishToSession(mapID, sse.Event{
+		ID:   uuid.NewString(),
+		Type: dto.MessageNoticeEventType,
+		Data: dto.MessageNoticeEvent{
+			NodeID:    nodeID,
+			MessageID: uuid.NewString(),
+			Notice:    notice,
+		},
+	})
+	msg := dto.CreateMessageRequest{
+		ID:          uuid.NewString(),
+		MessageType: model.MsgTypeNotice,
+		Role:        schema.Assistant,
+		Content:     model.MessageContent{Notice: &notice},
+	}
+	global.GetMessageManager().SaveRunMessage(ctx, nodeID, operation, msg)
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "..."
+}
@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/tool/registry"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/mark3labs/mcp-go/client"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestServer() *server.MCPServer {
+	svr := server.NewMCPServer("test", "1.0.0", server.WithToolCapabilities(true))
+	svr.AddTool(mcpgo.NewTool("echo",
+		mcpgo.WithDescription("Echo the input text"),
+		mcpgo.WithString("text", mcpgo.Required()),
+	), func(ctx context.Context, req mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+		return mcpgo.NewToolResultText("echo: " + req.GetString("text", "")), nil
+	})
+	svr.AddTool(mcpgo.NewTool("fail",
+		mcpgo.WithDescription("Always fails"),
+	), func(ctx context.Context, req mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+		return mcpgo.NewToolResultError("boom"), nil
+	})
+	return svr
+}
+
+// captureNotices 记录工具调用通知
+func captureNotices(t *testing.T) func() []model.Notice {
+	t.Helper()
+	if logger.Log == nil {
+		logger.Log = zap.NewNop()
+	}
+	var (
+		noticeMu sync.Mutex
+		notices  []model.Notice
+	)
+	old := notify
+	notify = func(ctx context.Context, notice model.Notice) {
+		noticeMu.Lock()
+		defer noticeMu.Unlock()
+		notices = append(notices, notice)
+	}
+	t.Cleanup(func() { notify = old })
+	return func() []model.Notice {
+		noticeMu.Lock()
+		defer noticeMu.Unlock()
+		return append([]model.Notice(nil), notices...)
+	}
+}
+
+func TestGetTools(t *testing.T) {
+	ctx := context.Background()
+	notices := captureNotices(t)
+	cli, err := client.NewInProcessClient(newTestServer())
+	require.NoError(t, err)
+	defer cli.Close()
+	require.NoError(t, cli.Start(ctx))
+	require.NoError(t, initialize(ctx, cli))
+
+	// 只使用允许的工具
+	tools, err := GetTools(ctx, cli, ServerConfig{Name: "local", Tools: []string{"echo"}})
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	info, err := tools[0].Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "echo", info.Name)
+
+	result, err := tools[0].(tool.InvokableTool).InvokableRun(ctx, `{"text":"hello"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "echo: hello")
+	require.Len(t, notices(), 1)
+	assert.Equal(t, model.NoticeTypeInfo, notices()[0].Type)
+	assert.Contains(t, notices()[0].Content, "local/echo")
+
+	tools, err = GetTools(ctx, cli, ServerConfig{Name: "local"})
+	require.NoError(t, err)
+	require.Len(t, tools, 2)
+	_, err = tools[1].(tool.InvokableTool).InvokableRun(ctx, `{}`)
+	assert.Error(t, err)
+	require.Len(t, notices(), 3)
+	assert.Equal(t, model.NoticeTypeError, notices()[2].Type)
+}
+
+func TestLoadServers(t *testing.T) {
+	ctx := context.Background()
+	captureNotices(t)
+	httpServer := server.NewTestStreamableHTTPServer(newTestServer())
+	defer httpServer.Close()
+	defer Close()
+
+	err := LoadServers(ctx, []ServerConfig{
+		{Name: "remote", Transport: TransportHTTP, URL: httpServer.URL, Tools: []string{"echo"}},
+		{Name: "broken", Transport: "ws", URL: httpServer.URL},
+	})
+	// 连接失败的服务器不影响其他服务器
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "broken")
+
+	tools, err := registry.GetTools([]string{GroupPrefix + "remote"})
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	tools, err = registry.GetTools([]string{registry.GroupMCP})
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	result, err := tools[0].(tool.InvokableTool).InvokableRun(ctx, `{"text":"remote"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "echo: remote")
+}
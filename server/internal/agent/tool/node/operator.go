@@ -7,6 +7,7 @@ import (
 	"github.com/PGshen/thinking-map/server/internal/global"
 	"github.com/PGshen/thinking-map/server/internal/model"
 	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
 	"github.com/PGshen/thinking-map/server/internal/pkg/sse"
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/components/tool/utils"
@@ -59,9 +60,52 @@ type SetNodeDependenciesResponse struct {
 
 // CreateNodeFunc 创建节点函数
 func CreateNodeFunc(ctx context.Context, req *CreateNodeRequest) (*dto.NodeResponse, error) {
-	// 构建DTO请求
 	mapID := ctx.Value("mapID").(string)
 	parentID := ctx.Value("nodeID").(string)
+	runID, _ := ctx.Value("runID").(string)
+
+	policy := global.GetNodePolicy()
+	if err := policy.CheckCreate(ctx, mapID, runID, parentID); err != nil {
+		return nil, err
+	}
+	resp, err := createNode(ctx, mapID, parentID, req)
+	if err != nil {
+		policy.ReleaseCreate(ctx, runID, parentID)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// createNode 按试运行设置创建节点或提出创建变更
+func createNode(ctx context.Context, mapID, parentID string, req *CreateNodeRequest) (*dto.NodeResponse, error) {
+	dryRun, err := global.GetNodePolicy().DryRun(ctx, mapID)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		// 预先分配节点ID，后续的更新和依赖设置可以引用尚未创建的节点
+		req.NodeID = uuid.NewString()
+		if err := propose(ctx, model.NodeChangeOpCreate, req.NodeID, req); err != nil {
+			return nil, err
+		}
+		return &dto.NodeResponse{
+			ID:       req.NodeID,
+			NodeID:   req.NodeID,
+			MapID:    mapID,
+			ParentID: parentID,
+			NodeType: req.NodeType,
+			Question: req.Question,
+			Target:   req.Target,
+			Status:   comm.NodeStatusInitial,
+			Position: model.Position{X: req.X, Y: req.Y},
+		}, nil
+	}
+	return applyCreate(ctx, mapID, parentID, uuid.NewString(), req)
+}
+
+// applyCreate 创建节点并通知前端
+func applyCreate(ctx context.Context, mapID, parentID, nodeID string, req *CreateNodeRequest) (*dto.NodeResponse, error) {
+	// 构建DTO请求
 	createReq := dto.CreateNodeRequest{
 		MapID:    mapID,
 		ParentID: parentID,
@@ -75,7 +119,7 @@ func CreateNodeFunc(ctx context.Context, req *CreateNodeRequest) (*dto.NodeRespo
 	}
 
 	// 调用全局节点操作器
-	resp, err := global.GetNodeOperator().CreateNode(ctx, createReq)
+	resp, err := global.GetNodeOperator().CreateNodeWithID(ctx, nodeID, createReq)
 	if err != nil {
 		return nil, err
 	}
@@ -120,7 +164,6 @@ func CreateNodeFunc(ctx context.Context, req *CreateNodeRequest) (*dto.NodeRespo
 		},
 	})
 
-	resp.NodeID = resp.ID
 	return resp, nil
 }
 
@@ -128,7 +171,38 @@ func CreateNodeFunc(ctx context.Context, req *CreateNodeRequest) (*dto.NodeRespo
 func UpdateNodeFunc(ctx context.Context, req *UpdateNodeRequest) (*dto.NodeResponse, error) {
 	mapID := ctx.Value("mapID").(string)
 	parentID := ctx.Value("nodeID").(string)
+	runID, _ := ctx.Value("runID").(string)
+
+	policy := global.GetNodePolicy()
+	node, err := policy.Target(ctx, mapID, runID, req.NodeID)
+	if err != nil {
+		return nil, err
+	}
+	dryRun, err := policy.DryRun(ctx, mapID)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		if err := propose(ctx, model.NodeChangeOpUpdate, req.NodeID, req); err != nil {
+			return nil, err
+		}
+		resp := dto.ToNodeResponse(node)
+		if req.Question != "" {
+			resp.Question = req.Question
+		}
+		if req.Target != "" {
+			resp.Target = req.Target
+		}
+		if req.X != 0 || req.Y != 0 {
+			resp.Position = model.Position{X: req.X, Y: req.Y}
+		}
+		return &resp, nil
+	}
+	return applyUpdate(ctx, mapID, parentID, req)
+}
 
+// applyUpdate 更新节点并通知前端
+func applyUpdate(ctx context.Context, mapID, parentID string, req *UpdateNodeRequest) (*dto.NodeResponse, error) {
 	// 构建DTO请求
 	updateReq := dto.UpdateNodeRequest{
 		Question: req.Question,
@@ -206,23 +280,36 @@ func UpdateNodeFunc(ctx context.Context, req *UpdateNodeRequest) (*dto.NodeRespo
 func DeleteNodeFunc(ctx context.Context, req *DeleteNodeRequest) (*DeleteNodeResponse, error) {
 	mapID := ctx.Value("mapID").(string)
 	parentID := ctx.Value("nodeID").(string)
+	runID, _ := ctx.Value("runID").(string)
 
-	// 先获取节点信息用于事件发送
-	nodeOperator := global.GetNodeOperator()
-	if nodeOperator == nil {
-		return nil, fmt.Errorf("node operator not initialized")
+	policy := global.GetNodePolicy()
+	node, err := policy.Target(ctx, mapID, runID, req.NodeID)
+	if err != nil {
+		return nil, err
 	}
-
-	// 通过nodeRepo获取节点信息
-	nodes, err := nodeOperator.GetNodesByIDs(ctx, []string{req.NodeID})
-	if err != nil || len(nodes) == 0 || nodes[0] == nil {
+	if err := policy.CheckDelete(node); err != nil {
 		return nil, err
 	}
-	node := nodes[0]
+	dryRun, err := policy.DryRun(ctx, mapID)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		if err := propose(ctx, model.NodeChangeOpDelete, req.NodeID, req); err != nil {
+			return nil, err
+		}
+		return &DeleteNodeResponse{
+			Success: true,
+			Message: proposedMessage,
+		}, nil
+	}
+	return applyDelete(ctx, mapID, parentID, node)
+}
 
+// applyDelete 删除节点并通知前端
+func applyDelete(ctx context.Context, mapID, parentID string, node *model.ThinkingNode) (*DeleteNodeResponse, error) {
 	// 调用全局节点操作器删除节点
-	err = global.GetNodeOperator().DeleteNode(ctx, req.NodeID)
-	if err != nil {
+	if err := global.GetNodeOperator().DeleteNode(ctx, node.ID); err != nil {
 		return nil, err
 	}
 
@@ -231,7 +318,7 @@ func DeleteNodeFunc(ctx context.Context, req *DeleteNodeRequest) (*DeleteNodeRes
 		ID:   uuid.NewString(),
 		Type: dto.NodeDeletedEventType,
 		Data: dto.NodeDeletedEvent{
-			NodeID:   req.NodeID,
+			NodeID:   node.ID,
 			Question: node.Question,
 		},
 	})
@@ -277,18 +364,43 @@ func DeleteNodeFunc(ctx context.Context, req *DeleteNodeRequest) (*DeleteNodeRes
 func SetNodeDependenciesFunc(ctx context.Context, req *SetNodeDependenciesRequest) (*SetNodeDependenciesResponse, error) {
 	mapID := ctx.Value("mapID").(string)
 	parentID := ctx.Value("nodeID").(string)
-	// 获取ContextManager实例
-	nodeOperator := global.GetNodeOperator()
-	if nodeOperator == nil {
-		return nil, fmt.Errorf("node operator not initialized")
-	}
-	fmt.Println("SetNodeDependencies:", req.Op, req.NodeID, req.Dependencies)
+	runID, _ := ctx.Value("runID").(string)
 
 	// 验证操作类型
 	if req.Op != "add" && req.Op != "remove" {
 		return nil, fmt.Errorf("invalid operation: %s, must be 'add' or 'remove'", req.Op)
 	}
 
+	// 当前节点和依赖节点都必须属于当前思维导图
+	policy := global.GetNodePolicy()
+	if _, err := policy.Target(ctx, mapID, runID, req.NodeID); err != nil {
+		return nil, err
+	}
+	for _, dep := range req.Dependencies {
+		if _, err := policy.Target(ctx, mapID, runID, dep); err != nil {
+			return nil, err
+		}
+	}
+	dryRun, err := policy.DryRun(ctx, mapID)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		if err := propose(ctx, model.NodeChangeOpSetDependencies, req.NodeID, req); err != nil {
+			return nil, err
+		}
+		return &SetNodeDependenciesResponse{
+			Success: true,
+			Message: proposedMessage,
+		}, nil
+	}
+	return applySetDependencies(ctx, mapID, parentID, req)
+}
+
+// applySetDependencies 更新节点依赖关系并通知前端
+func applySetDependencies(ctx context.Context, mapID, parentID string, req *SetNodeDependenciesRequest) (*SetNodeDependenciesResponse, error) {
+	nodeOperator := global.GetNodeOperator()
+
 	// 获取当前节点
 	currentNodes, err := nodeOperator.GetNodesByIDs(ctx, []string{req.NodeID})
 	if err != nil {
@@ -424,7 +536,7 @@ func CreateNodeTool() (tool.InvokableTool, error) {
 			},
 		),
 	}, CreateNodeFunc)
-	return tool, nil
+	return policyGuard{tool}, nil
 }
 
 // UpdateNodeTool 更新节点工具
@@ -458,7 +570,7 @@ func UpdateNodeTool() (tool.InvokableTool, error) {
 			},
 		),
 	}, UpdateNodeFunc)
-	return tool, nil
+	return policyGuard{tool}, nil
 }
 
 // DeleteNodeTool 删除节点工具
@@ -476,7 +588,7 @@ func DeleteNodeTool() (tool.InvokableTool, error) {
 			},
 		),
 	}, DeleteNodeFunc)
-	return tool, nil
+	return policyGuard{tool}, nil
 }
 
 // SetNodeDependenciesTool 设置节点依赖关系工具
@@ -509,7 +621,7 @@ func SetNodeDependenciesTool() (tool.InvokableTool, error) {
 			},
 		),
 	}, SetNodeDependenciesFunc)
-	return tool, nil
+	return policyGuard{tool}, nil
 }
 
 // GetAllNodeTools 获取所有节点操作工具
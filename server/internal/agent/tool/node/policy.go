@@ -0,0 +1,96 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// proposedMessage 试运行时返回给模型的结果
+const proposedMessage = "变更已记录，等待用户确认后生效"
+
+// policyGuard 节点操作被策略拒绝时，将原因作为工具结果返回给模型以便调整，其他错误照常返回
+type policyGuard struct {
+	tool.InvokableTool
+}
+
+func (g policyGuard) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	result, err := g.InvokableTool.InvokableRun(ctx, argumentsInJSON, opts...)
+	if err != nil && global.IsNodePolicyError(err) {
+		return fmt.Sprintf("操作被拒绝：%s", err.Error()), nil
+	}
+	return result, err
+}
+
+// propose 试运行时将工具调用记录为当前运行变更集中的一项变更
+func propose(ctx context.Context, op, targetID string, req interface{}) error {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal node change: %w", err)
+	}
+	runID, _ := ctx.Value("runID").(string)
+	return global.GetNodePolicy().Propose(ctx, &model.NodeChange{
+		RunID:    runID,
+		MapID:    ctx.Value("mapID").(string),
+		NodeID:   ctx.Value("nodeID").(string),
+		Op:       op,
+		TargetID: targetID,
+		Payload:  payload,
+	})
+}
+
+// ApplyChange 执行用户接受的变更：重新检查节点范围和删除保护（提出变更后节点可能已变化），
+// 数量和深度限制已在提出变更时检查
+func ApplyChange(ctx context.Context, change *model.NodeChange) error {
+	policy := global.GetNodePolicy()
+	switch change.Op {
+	case model.NodeChangeOpCreate:
+		var req CreateNodeRequest
+		if err := json.Unmarshal(change.Payload, &req); err != nil {
+			return fmt.Errorf("failed to unmarshal node change: %w", err)
+		}
+		if _, err := policy.Target(ctx, change.MapID, "", change.NodeID); err != nil {
+			return err
+		}
+		_, err := applyCreate(ctx, change.MapID, change.NodeID, change.TargetID, &req)
+		return err
+	case model.NodeChangeOpUpdate:
+		var req UpdateNodeRequest
+		if err := json.Unmarshal(change.Payload, &req); err != nil {
+			return fmt.Errorf("failed to unmarshal node change: %w", err)
+		}
+		if _, err := policy.Target(ctx, change.MapID, "", req.NodeID); err != nil {
+			return err
+		}
+		_, err := applyUpdate(ctx, change.MapID, change.NodeID, &req)
+		return err
+	case model.NodeChangeOpDelete:
+		node, err := policy.Target(ctx, change.MapID, "", change.TargetID)
+		if err != nil {
+			return err
+		}
+		if err := policy.CheckDelete(node); err != nil {
+			return err
+		}
+		_, err = applyDelete(ctx, change.MapID, change.NodeID, node)
+		return err
+	case model.NodeChangeOpSetDependencies:
+		var req SetNodeDependenciesRequest
+		if err := json.Unmarshal(change.Payload, &req); err != nil {
+			return fmt.Errorf("failed to unmarshal node change: %w", err)
+		}
+		for _, id := range append([]string{req.NodeID}, req.Dependencies...) {
+			if _, err := policy.Target(ctx, change.MapID, "", id); err != nil {
+				return err
+			}
+		}
+		_, err := applySetDependencies(ctx, change.MapID, change.NodeID, &req)
+		return err
+	default:
+		return fmt.Errorf("unknown node change op: %s", change.Op)
+	}
+}
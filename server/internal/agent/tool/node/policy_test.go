@@ -0,0 +1,42 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+type errTool struct {
+	err error
+}
+
+func (t errTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{Name: "errTool"}, nil
+}
+
+func (t errTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return "", t.err
+}
+
+// TestPolicyGuard 测试策略拒绝作为工具结果返回，其他错误照常返回
+func TestPolicyGuard(t *testing.T) {
+	guarded := policyGuard{errTool{err: fmt.Errorf("%w: node", comm.ErrNodeProtected)}}
+	result, err := guarded.InvokableRun(context.Background(), "{}")
+	if err != nil {
+		t.Fatalf("InvokableRun() error = %v", err)
+	}
+	if result == "" {
+		t.Fatal("InvokableRun() returned empty result for policy error")
+	}
+
+	failure := errors.New("database error")
+	guarded = policyGuard{errTool{err: failure}}
+	if _, err := guarded.InvokableRun(context.Background(), "{}"); !errors.Is(err, failure) {
+		t.Fatalf("InvokableRun() error = %v, want %v", err, failure)
+	}
+}
@@ -0,0 +1,122 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/tool/messaging"
+	"github.com/PGshen/thinking-map/server/internal/agent/tool/node"
+	"github.com/PGshen/thinking-map/server/internal/agent/tool/search"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// Factory 创建工具
+type Factory func() (tool.BaseTool, error)
+
+// 工具组，声明式配置中可以用组名引用一组工具
+const (
+	GroupNode   = "node_tools"
+	GroupSearch = "search_tools"
+	GroupMCP    = "mcp_tools" // 所有MCP服务器的工具，未配置MCP服务器时为空
+)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{
+		"createNode":          invokable(node.CreateNodeTool),
+		"updateNode":          invokable(node.UpdateNodeTool),
+		"deleteNode":          invokable(node.DeleteNodeTool),
+		"setNodeDependencies": invokable(node.SetNodeDependenciesTool),
+		"search":              invokable(search.SearchTool),
+		"extract":             invokable(search.ExtractTool),
+		"kb_search":           invokable(search.KBSearchTool),
+		"sendActionMsg":       invokable(messaging.ActionTool),
+	}
+	groups = map[string][]string{
+		GroupNode:   {"createNode", "updateNode", "deleteNode", "setNodeDependencies"},
+		GroupSearch: {"search", "extract", "kb_search"},
+		GroupMCP:    {},
+	}
+)
+
+func invokable(f func() (tool.InvokableTool, error)) Factory {
+	return func() (tool.BaseTool, error) {
+		return f()
+	}
+}
+
+// Register 注册（或覆盖）工具
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[name] = factory
+}
+
+// RegisterGroup 注册（或覆盖）工具组
+func RegisterGroup(name string, toolNames ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	groups[name] = toolNames
+}
+
+// Names 返回所有已注册的工具名称
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetTools 按名称获取工具，名称可以是工具名或工具组名，重复的工具只返回一次
+func GetTools(names []string) ([]tool.BaseTool, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	var tools []tool.BaseTool
+	seen := map[string]bool{}
+	for _, name := range names {
+		toolNames, ok := groups[name]
+		if !ok {
+			toolNames = []string{name}
+		}
+		for _, toolName := range toolNames {
+			if seen[toolName] {
+				continue
+			}
+			seen[toolName] = true
+			factory, ok := factories[toolName]
+			if !ok {
+				return nil, fmt.Errorf("tool not found: %s", toolName)
+			}
+			t, err := factory()
+			if err != nil {
+				return nil, fmt.Errorf("failed to create tool %s: %w", toolName, err)
+			}
+			tools = append(tools, t)
+		}
+	}
+	return tools, nil
+}
+
+// GetToolInfos 按名称获取工具信息
+func GetToolInfos(ctx context.Context, names []string) ([]*schema.ToolInfo, error) {
+	tools, err := GetTools(names)
+	if err != nil {
+		return nil, err
+	}
+	toolInfos := make([]*schema.ToolInfo, 0, len(tools))
+	for _, t := range tools {
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tool info: %w", err)
+		}
+		toolInfos = append(toolInfos, info)
+	}
+	return toolInfos, nil
+}
@@ -0,0 +1,47 @@
+package registry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTools(t *testing.T) {
+	ctx := context.Background()
+
+	// 工具组展开，重复的工具只返回一次
+	infos, err := GetToolInfos(ctx, []string{GroupNode, "createNode", "search"})
+	require.NoError(t, err)
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	assert.Equal(t, []string{"createNode", "updateNode", "deleteNode", "setNodeDependencies", "search"}, names)
+
+	_, err = GetTools([]string{"missing"})
+	assert.Error(t, err)
+}
+
+func TestRegister(t *testing.T) {
+	Register("echo", func() (tool.BaseTool, error) {
+		return utils.InferTool("echo", "echo input", func(ctx context.Context, in struct{ Text string }) (string, error) {
+			return in.Text, nil
+		})
+	})
+	RegisterGroup("test_tools", "echo", "extract")
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(factories, "echo")
+		delete(groups, "test_tools")
+	})
+
+	assert.Contains(t, Names(), "echo")
+	tools, err := GetTools([]string{"test_tools"})
+	require.NoError(t, err)
+	assert.Len(t, tools, 2)
+}
@@ -0,0 +1,116 @@
+package search
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 检索缓存的作用范围
+const (
+	CacheScopeMap    = "map"    // 同一思维导图内共享
+	CacheScopeGlobal = "global" // 所有思维导图共享
+)
+
+// CacheConfig 检索缓存配置，对应配置 search.cache，TTL为0时不缓存
+type CacheConfig struct {
+	TTL   time.Duration
+	Scope string
+}
+
+// GetCacheConfig 读取检索缓存配置，作用范围默认为思维导图
+func GetCacheConfig() CacheConfig {
+	cfg := CacheConfig{
+		TTL:   viper.GetDuration("search.cache.ttl"),
+		Scope: viper.GetString("search.cache.scope"),
+	}
+	if cfg.Scope != CacheScopeGlobal {
+		cfg.Scope = CacheScopeMap
+	}
+	return cfg
+}
+
+// cacheKey 缓存键：作用范围、检索服务提供方、规范化后的关键词和影响结果的检索参数
+func cacheKey(cfg CacheConfig, mapID string, provider model.RagSource, req *SearchRequest) string {
+	scope := CacheScopeGlobal
+	if cfg.Scope == CacheScopeMap {
+		scope = "map:" + mapID
+	}
+	parts := []string{
+		scope,
+		string(provider),
+		normalizeQuery(req.Query),
+		req.SearchDepth,
+		fmt.Sprintf("%d|%t|%t", req.MaxResults, req.IncludeAnswer, req.IncludeRawContent),
+		normalizeDomains(req.IncludeDomains),
+		normalizeDomains(req.ExcludeDomains),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeQuery 忽略大小写、多余空白和结尾的标点
+func normalizeQuery(query string) string {
+	query = strings.ToLower(strings.Join(strings.Fields(query), " "))
+	return strings.TrimRight(query, "?？!！。.,，;；")
+}
+
+func normalizeDomains(domains []string) string {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			normalized = append(normalized, domain)
+		}
+	}
+	sort.Strings(normalized)
+	return strings.Join(normalized, ",")
+}
+
+// lookupCache 查找有效期内的检索记录，没有结果的记录不复用
+func lookupCache(ctx context.Context, cfg CacheConfig, key string) *model.RAGRecord {
+	if cfg.TTL <= 0 {
+		return nil
+	}
+	record, err := global.GetRAGRecordRepository().FindLatestByCacheKey(ctx, key, time.Now().Add(-cfg.TTL))
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Warn("lookup search cache failed", zap.Error(err))
+		}
+		return nil
+	}
+	if len(record.Results) == 0 {
+		return nil
+	}
+	return record
+}
+
+// responseFromRecord 将缓存的检索记录还原为检索结果
+func responseFromRecord(record *model.RAGRecord) *SearchResponse {
+	resp := &SearchResponse{
+		Query:  record.Query,
+		Answer: record.Answer,
+	}
+	for _, result := range record.Results {
+		resp.Results = append(resp.Results, SearchResult{
+			Title:      result.Title,
+			URL:        result.URL,
+			Content:    result.Content,
+			Score:      result.Score,
+			RawContent: result.RawContent,
+			Favicon:    result.Favicon,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,74 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type fakeRAGRecordRepo struct {
+	repository.RAGRecord
+	records []*model.RAGRecord
+}
+
+func (r *fakeRAGRecordRepo) FindLatestByCacheKey(ctx context.Context, cacheKey string, since time.Time) (*model.RAGRecord, error) {
+	for i := len(r.records) - 1; i >= 0; i-- {
+		if record := r.records[i]; record.CacheKey == cacheKey && !record.CreatedAt.Before(since) {
+			return record, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestCacheKey(t *testing.T) {
+	mapScope := CacheConfig{TTL: time.Hour, Scope: CacheScopeMap}
+	req := &SearchRequest{Query: "What is  Raft?", SearchDepth: "basic", MaxResults: 5, IncludeDomains: []string{"b.com", "A.com"}}
+	same := &SearchRequest{Query: " what is raft ", SearchDepth: "basic", MaxResults: 5, IncludeDomains: []string{"a.com", "b.com"}}
+	key := cacheKey(mapScope, "map-1", model.RagTavily, req)
+	assert.Len(t, key, 64)
+	assert.Equal(t, key, cacheKey(mapScope, "map-1", model.RagTavily, same))
+
+	// 检索参数、提供方、思维导图不同时缓存键不同
+	assert.NotEqual(t, key, cacheKey(mapScope, "map-1", model.RagTavily, &SearchRequest{Query: "what is raft", SearchDepth: "advanced", MaxResults: 5}))
+	assert.NotEqual(t, key, cacheKey(mapScope, "map-1", model.RagSearXNG, req))
+	assert.NotEqual(t, key, cacheKey(mapScope, "map-2", model.RagTavily, req))
+
+	// 全局作用范围下不同思维导图共享缓存
+	globalScope := CacheConfig{TTL: time.Hour, Scope: CacheScopeGlobal}
+	assert.Equal(t, cacheKey(globalScope, "map-1", model.RagTavily, req), cacheKey(globalScope, "map-2", model.RagTavily, req))
+}
+
+func TestLookupCache(t *testing.T) {
+	previous := global.GetRAGRecordRepository()
+	t.Cleanup(func() { global.InitRAGRecordRepository(previous) })
+	repo := &fakeRAGRecordRepo{records: []*model.RAGRecord{
+		{ID: "stale", CacheKey: "k", CreatedAt: time.Now().Add(-2 * time.Hour), Results: model.Results{{Title: "old"}}},
+		{ID: "empty", CacheKey: "empty", CreatedAt: time.Now()},
+		{ID: "fresh", CacheKey: "k", Query: "raft", Answer: "consensus", CreatedAt: time.Now(), Results: model.Results{{Title: "Raft", URL: "https://raft.github.io", Score: 0.9}}},
+	}}
+	global.InitRAGRecordRepository(repo)
+	ctx := context.Background()
+	cfg := CacheConfig{TTL: time.Hour, Scope: CacheScopeMap}
+
+	record := lookupCache(ctx, cfg, "k")
+	require.NotNil(t, record)
+	assert.Equal(t, "fresh", record.ID)
+	resp := responseFromRecord(record)
+	assert.Equal(t, "consensus", resp.Answer)
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, "https://raft.github.io", resp.Results[0].URL)
+
+	assert.Nil(t, lookupCache(ctx, cfg, "empty"))
+	assert.Nil(t, lookupCache(ctx, cfg, "missing"))
+	assert.Nil(t, lookupCache(ctx, CacheConfig{}, "k"))
+
+	repo.records = repo.records[:1]
+	assert.Nil(t, lookupCache(ctx, cfg, "k"))
+}
@@ -0,0 +1,86 @@
+package search
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+)
+
+type KBSearchFuncRequest struct {
+	Query      string `json:"query"`
+	MaxResults int    `json:"max_results,omitempty"`
+}
+
+// KBSearchFunc 检索当前思维导图的知识库，结果与网络检索一样保存为RAG记录
+func KBSearchFunc(ctx context.Context, req *KBSearchFuncRequest) (*SearchResponse, error) {
+	mapID := ctx.Value("mapID").(string)
+	maxResults := req.MaxResults
+	if maxResults == 0 {
+		maxResults = DefaultMaxResults
+	}
+
+	publishNotice(ctx, model.Notice{
+		Type:    model.NoticeTypeInfo,
+		Name:    "知识库检索",
+		Content: fmt.Sprintf("关键词: %s", req.Query),
+	})
+
+	hits, err := global.GetKnowledgeBase().Search(ctx, mapID, req.Query, maxResults)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &SearchResponse{Query: req.Query}
+	ragRecord := model.RAGRecord{
+		ID:      uuid.NewString(),
+		Query:   req.Query,
+		Sources: model.RagKB,
+	}
+	for _, hit := range hits {
+		result := SearchResult{
+			Title:   fmt.Sprintf("%s #%d", hit.DocumentName, hit.Seq+1),
+			URL:     fmt.Sprintf("kb://%s#%d", hit.DocumentID, hit.Seq),
+			Content: hit.Content,
+			Score:   hit.Rank,
+		}
+		resp.Results = append(resp.Results, result)
+		ragRecord.Results = append(ragRecord.Results, model.Result{
+			Title:   result.Title,
+			URL:     result.URL,
+			Content: result.Content,
+			Score:   result.Score,
+		})
+	}
+
+	if err := saveRAGRecord(ctx, &ragRecord); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func KBSearchTool() (tool.InvokableTool, error) {
+	t := utils.NewTool(&schema.ToolInfo{
+		Name: "kb_search",
+		Desc: "检索当前思维导图知识库中用户上传的文档（笔记、资料、表格等），优先于网络检索使用",
+		ParamsOneOf: schema.NewParamsOneOfByParams(
+			map[string]*schema.ParameterInfo{
+				"query": {
+					Type:     schema.String,
+					Desc:     "检索关键词或问题",
+					Required: true,
+				},
+				"max_results": {
+					Type: schema.Integer,
+					Desc: "最大返回结果数量",
+				},
+			},
+		),
+	}, KBSearchFunc)
+	return t, nil
+}
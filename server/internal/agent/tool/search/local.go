@@ -0,0 +1,205 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/pkg/utils"
+)
+
+const (
+	localChunkSize = 800 // 每个片段的最大字符数
+	bm25K1         = 1.2
+	bm25B          = 0.75
+)
+
+// localExtensions 本地索引收录的文件类型
+var localExtensions = map[string]bool{
+	".md":       true,
+	".markdown": true,
+	".txt":      true,
+}
+
+// LocalIndex 本地文档索引，收录目录下的文本文件，按段落切分后用BM25排序
+type LocalIndex struct {
+	Dir string
+
+	mu     sync.RWMutex
+	loaded bool
+	chunks []*localChunk
+	df     map[string]int
+	avgLen float64
+}
+
+type localChunk struct {
+	title   string
+	path    string
+	content string
+	terms   map[string]int
+	length  int
+}
+
+var (
+	localIndexMu sync.Mutex
+	localIndexes = map[string]*LocalIndex{}
+)
+
+func NewLocalIndex(dir string) *LocalIndex {
+	return &LocalIndex{Dir: dir}
+}
+
+// GetLocalIndex 获取目录对应的本地索引，同一目录共享索引，首次检索时建立
+func GetLocalIndex(dir string) (*LocalIndex, error) {
+	if dir == "" {
+		return nil, errors.New("local search dir is not configured")
+	}
+	localIndexMu.Lock()
+	defer localIndexMu.Unlock()
+	index, ok := localIndexes[dir]
+	if !ok {
+		index = NewLocalIndex(dir)
+		localIndexes[dir] = index
+	}
+	return index, nil
+}
+
+func (i *LocalIndex) Name() model.RagSource {
+	return model.RagLocal
+}
+
+// Load 重新建立索引，文档更新后调用
+func (i *LocalIndex) Load() error {
+	var chunks []*localChunk
+	err := filepath.WalkDir(i.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !localExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		chunks = append(chunks, splitDocument(path, string(data))...)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load local search index: %w", err)
+	}
+
+	df := make(map[string]int)
+	total := 0
+	for _, chunk := range chunks {
+		for term := range chunk.terms {
+			df[term]++
+		}
+		total += chunk.length
+	}
+	var avgLen float64
+	if len(chunks) > 0 {
+		avgLen = float64(total) / float64(len(chunks))
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.chunks, i.df, i.avgLen, i.loaded = chunks, df, avgLen, true
+	return nil
+}
+
+func (i *LocalIndex) Search(ctx context.Context, req *SearchRequest) (*SearchResponse, error) {
+	start := time.Now()
+	i.mu.RLock()
+	loaded := i.loaded
+	i.mu.RUnlock()
+	if !loaded {
+		if err := i.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	type scored struct {
+		chunk *localChunk
+		score float64
+	}
+	var hits []scored
+	queryTerms := utils.Tokenize(req.Query)
+	n := float64(len(i.chunks))
+	for _, chunk := range i.chunks {
+		var score float64
+		for _, term := range queryTerms {
+			tf := float64(chunk.terms[term])
+			if tf == 0 {
+				continue
+			}
+			df := float64(i.df[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*float64(chunk.length)/i.avgLen))
+		}
+		if score > 0 {
+			hits = append(hits, scored{chunk: chunk, score: score})
+		}
+	}
+	sort.SliceStable(hits, func(a, b int) bool {
+		return hits[a].score > hits[b].score
+	})
+	if req.MaxResults > 0 && len(hits) > req.MaxResults {
+		hits = hits[:req.MaxResults]
+	}
+
+	resp := &SearchResponse{Query: req.Query}
+	for _, hit := range hits {
+		// 分数归一化到 (0, 1]，与网络检索的分数范围一致
+		resp.Results = append(resp.Results, SearchResult{
+			Title:   hit.chunk.title,
+			URL:     "file://" + hit.chunk.path,
+			Content: hit.chunk.content,
+			Score:   hit.score / hits[0].score,
+		})
+	}
+	resp.ResponseTime = time.Since(start).Seconds()
+	return resp, nil
+}
+
+// splitDocument 切分文档，标题取第一个一级标题，没有时取文件名
+func splitDocument(path, content string) []*localChunk {
+	title := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "# ") {
+			title = strings.TrimSpace(strings.TrimPrefix(line, "# "))
+			break
+		}
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	var chunks []*localChunk
+	for _, text := range utils.ChunkText(content, localChunkSize) {
+		terms := make(map[string]int)
+		tokens := utils.Tokenize(text)
+		for _, token := range tokens {
+			terms[token]++
+		}
+		chunks = append(chunks, &localChunk{
+			title:   title,
+			path:    path,
+			content: text,
+			terms:   terms,
+			length:  len(tokens),
+		})
+	}
+	return chunks
+}
@@ -0,0 +1,84 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// SearchProvider 检索服务提供方，检索结果统一转换为 SearchResponse
+type SearchProvider interface {
+	// Name 提供方名称，记录在 RAGRecord.Sources 中
+	Name() model.RagSource
+	Search(ctx context.Context, req *SearchRequest) (*SearchResponse, error)
+}
+
+// ProviderFactory 创建检索服务提供方
+type ProviderFactory func() (SearchProvider, error)
+
+var (
+	providerMu sync.RWMutex
+	providers  = map[model.RagSource]ProviderFactory{
+		model.RagTavily: func() (SearchProvider, error) {
+			return NewTavilyClient(), nil
+		},
+		model.RagSearXNG: func() (SearchProvider, error) {
+			return NewSearXNGClient()
+		},
+		model.RagLocal: func() (SearchProvider, error) {
+			return GetLocalIndex(viper.GetString("service.local.dir"))
+		},
+	}
+)
+
+// RegisterProvider 注册（或覆盖）检索服务提供方
+func RegisterProvider(name model.RagSource, factory ProviderFactory) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	providers[name] = factory
+}
+
+// NewProvider 按名称创建检索服务提供方，名称为空时使用配置 search.provider，未配置时使用Tavily
+func NewProvider(name string) (SearchProvider, error) {
+	if name == "" {
+		name = viper.GetString("search.provider")
+	}
+	if name == "" {
+		name = string(model.RagTavily)
+	}
+	providerMu.RLock()
+	factory, ok := providers[model.RagSource(name)]
+	providerMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown search provider: %s", name)
+	}
+	return factory()
+}
+
+// ProviderForMap 思维导图设置了检索服务提供方时使用该提供方，否则使用全局配置
+func ProviderForMap(ctx context.Context, mapID string) (SearchProvider, error) {
+	var name string
+	if repo := global.GetThinkingMapRepository(); repo != nil && mapID != "" {
+		thinkingMap, err := repo.FindByID(ctx, mapID)
+		if err != nil {
+			logger.Warn("load map search provider failed", zap.String("mapID", mapID), zap.Error(err))
+		} else {
+			name = thinkingMap.MetaString(model.MapMetaSearchProvider)
+		}
+	}
+	return NewProvider(name)
+}
+
+// noticeName 检索通知的标题
+func noticeName(provider SearchProvider) string {
+	if provider.Name() == model.RagLocal {
+		return "本地检索"
+	}
+	return "网络检索"
+}
@@ -0,0 +1,126 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearXNGClient_Search(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/search", r.URL.Path)
+		assert.Equal(t, "json", r.URL.Query().Get("format"))
+		assert.Equal(t, "golang generics", r.URL.Query().Get("q"))
+		assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"query":   "golang generics",
+			"answers": []any{map[string]any{"answer": "Go 1.18 added generics"}},
+			"results": []map[string]any{
+				{"title": "Spam", "url": "https://spam.example.com/a", "content": "spam", "score": 3.0},
+				{"title": "Go blog", "url": "https://go.dev/blog/intro-generics", "content": "intro", "score": 2.0},
+				{"title": "Go wiki", "url": "https://www.go.dev/wiki", "content": "wiki", "score": 1.0},
+				{"title": "Other", "url": "https://other.org", "content": "other", "score": 0.5},
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := &SearXNGClient{BaseURL: server.URL, APIKey: "key", HttpClient: server.Client()}
+	assert.Equal(t, model.RagSearXNG, client.Name())
+
+	resp, err := client.Search(context.Background(), &SearchRequest{
+		Query:          "golang generics",
+		IncludeAnswer:  true,
+		MaxResults:     2,
+		ExcludeDomains: []string{"spam.example.com"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Go 1.18 added generics", resp.Answer)
+	require.Len(t, resp.Results, 2)
+	assert.Equal(t, "https://go.dev/blog/intro-generics", resp.Results[0].URL)
+	assert.Equal(t, "Go wiki", resp.Results[1].Title)
+
+	resp, err = client.Search(context.Background(), &SearchRequest{
+		Query:          "golang generics",
+		IncludeDomains: []string{"other.org"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Answer)
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, "Other", resp.Results[0].Title)
+}
+
+func TestSearXNGClient_SearchError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "json format disabled", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client := &SearXNGClient{BaseURL: server.URL, HttpClient: server.Client()}
+	_, err := client.Search(context.Background(), &SearchRequest{Query: "q"})
+	assert.ErrorContains(t, err, "403")
+}
+
+func TestLocalIndex_Search(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "raft.md"), []byte("# Raft 共识算法\n\nRaft 通过领导者选举和日志复制实现一致性。\n\n成员变更使用联合共识。"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "paxos.txt"), []byte("Paxos is a consensus protocol.\n\nMulti-Paxos elects a stable leader."), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "image.png"), []byte("leader"), 0o644))
+
+	index := NewLocalIndex(dir)
+	assert.Equal(t, model.RagLocal, index.Name())
+
+	resp, err := index.Search(context.Background(), &SearchRequest{Query: "leader election", MaxResults: 5})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, "paxos", resp.Results[0].Title)
+	assert.Equal(t, 1.0, resp.Results[0].Score)
+	assert.Contains(t, resp.Results[0].URL, "file://")
+
+	resp, err = index.Search(context.Background(), &SearchRequest{Query: "领导者选举"})
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.Results)
+	assert.Equal(t, "Raft 共识算法", resp.Results[0].Title)
+
+	resp, err = index.Search(context.Background(), &SearchRequest{Query: "kubernetes"})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Results)
+}
+
+func TestNewProvider(t *testing.T) {
+	t.Cleanup(func() {
+		viper.Set("search.provider", nil)
+		viper.Set("service.local.dir", nil)
+	})
+
+	viper.Set("search.provider", "local")
+	viper.Set("service.local.dir", t.TempDir())
+	provider, err := NewProvider("")
+	require.NoError(t, err)
+	assert.Equal(t, model.RagLocal, provider.Name())
+
+	// 思维导图的设置优先于全局配置
+	provider, err = NewProvider("tavily")
+	require.NoError(t, err)
+	assert.Equal(t, model.RagTavily, provider.Name())
+
+	_, err = NewProvider("bing")
+	assert.Error(t, err)
+}
+
+func TestThinkingMapSearchProvider(t *testing.T) {
+	thinkingMap := &model.ThinkingMap{Metadata: []byte(`{"other": 1}`)}
+	assert.Empty(t, thinkingMap.MetaString(model.MapMetaSearchProvider))
+	require.NoError(t, thinkingMap.SetMeta(model.MapMetaSearchProvider, "searxng"))
+	assert.Equal(t, "searxng", thinkingMap.MetaString(model.MapMetaSearchProvider))
+	assert.JSONEq(t, `{"other": 1, "searchProvider": "searxng"}`, string(thinkingMap.Metadata))
+}
@@ -0,0 +1,75 @@
+package search
+
+import (
+	"context"
+
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/PGshen/thinking-map/server/internal/pkg/sse"
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// publishNotice 发送检索通知给前端，并按当前操作保存到拆解或结论对话中
+func publishNotice(ctx context.Context, notice model.Notice) {
+	mapID := ctx.Value("mapID").(string)
+	nodeID := ctx.Value("nodeID").(string)
+	operation := ctx.Value("operation").(string)
+
+	global.GetBroker().PublishToSession(mapID, sse.Event{
+		ID:   uuid.NewString(),
+		Type: dto.MessageNoticeEventType,
+		Data: dto.MessageNoticeEvent{
+			NodeID:    nodeID,
+			MessageID: uuid.NewString(),
+			Notice:    notice,
+		},
+	})
+
+	global.GetMessageManager().SaveRunMessage(ctx, nodeID, operation, dto.CreateMessageRequest{
+		ID:          uuid.NewString(),
+		MessageType: model.MsgTypeNotice,
+		Role:        schema.Assistant,
+		Content:     model.MessageContent{Notice: &notice},
+	})
+}
+
+// saveRAGRecord 保存检索记录，发送给前端，并按当前操作保存到拆解或结论对话中
+func saveRAGRecord(ctx context.Context, ragRecord *model.RAGRecord) error {
+	if err := global.GetRAGRecordRepository().Create(ctx, ragRecord); err != nil {
+		logger.Error("save rag record failed", zap.Error(err))
+		return err
+	}
+	publishRAGRecord(ctx, ragRecord)
+	return nil
+}
+
+// publishRAGRecord 将已保存的检索记录发送给前端，并在对话中保存引用该记录的消息
+func publishRAGRecord(ctx context.Context, ragRecord *model.RAGRecord) {
+	mapID := ctx.Value("mapID").(string)
+	nodeID := ctx.Value("nodeID").(string)
+	operation := ctx.Value("operation").(string)
+
+	messageID := uuid.NewString()
+	global.GetBroker().PublishToSession(mapID, sse.Event{
+		ID:   uuid.NewString(),
+		Type: dto.MessageRagEventType,
+		Data: dto.MessageRagEvent{
+			NodeID:    nodeID,
+			MessageID: messageID,
+			RagRecord: *ragRecord,
+		},
+	})
+
+	global.GetMessageManager().SaveRunMessage(ctx, nodeID, operation, dto.CreateMessageRequest{
+		ID:          messageID,
+		MessageType: model.MsgTypeRAG,
+		Role:        schema.Tool,
+		Content: model.MessageContent{
+			RagID: ragRecord.ID,
+		},
+	})
+}
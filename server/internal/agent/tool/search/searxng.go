@@ -0,0 +1,157 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/spf13/viper"
+)
+
+// SearXNGClient SearXNG兼容的JSON检索接口（GET /search?format=json）
+type SearXNGClient struct {
+	BaseURL    string
+	APIKey     string // 可选，经过鉴权代理访问时使用
+	HttpClient *http.Client
+}
+
+func NewSearXNGClient() (*SearXNGClient, error) {
+	baseURL := viper.GetString("service.searxng.url")
+	if baseURL == "" {
+		return nil, errors.New("searxng url is not configured")
+	}
+	timeout := viper.GetDuration("service.searxng.timeout")
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	return &SearXNGClient{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIKey:  viper.GetString("service.searxng.api_key"),
+		HttpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}, nil
+}
+
+func (c *SearXNGClient) Name() model.RagSource {
+	return model.RagSearXNG
+}
+
+type searxngResponse struct {
+	Query   string            `json:"query"`
+	Answers []json.RawMessage `json:"answers"`
+	Results []struct {
+		Title   string  `json:"title"`
+		URL     string  `json:"url"`
+		Content string  `json:"content"`
+		Score   float64 `json:"score"`
+	} `json:"results"`
+}
+
+func (c *SearXNGClient) Search(ctx context.Context, req *SearchRequest) (*SearchResponse, error) {
+	start := time.Now()
+	params := url.Values{}
+	params.Set("q", req.Query)
+	params.Set("format", "json")
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request: %w", err)
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if c.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	resp, err := c.HttpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send search request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("search request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var sr searxngResponse
+	if err := json.NewDecoder(resp.Body).Decode(&sr); err != nil {
+		return nil, fmt.Errorf("failed to decode search response: %w", err)
+	}
+
+	searchResp := &SearchResponse{
+		Query: req.Query,
+	}
+	if req.IncludeAnswer {
+		searchResp.Answer = searxngAnswer(sr.Answers)
+	}
+	// SearXNG不支持按域名过滤，在结果中过滤
+	for _, result := range sr.Results {
+		if !domainAllowed(result.URL, req.IncludeDomains, req.ExcludeDomains) {
+			continue
+		}
+		searchResp.Results = append(searchResp.Results, SearchResult{
+			Title:   result.Title,
+			URL:     result.URL,
+			Content: result.Content,
+			Score:   result.Score,
+		})
+		if req.MaxResults > 0 && len(searchResp.Results) >= req.MaxResults {
+			break
+		}
+	}
+	searchResp.ResponseTime = time.Since(start).Seconds()
+	return searchResp, nil
+}
+
+// searxngAnswer 取第一个直接答案，不同版本的SearXNG返回字符串或 {"answer": ...} 对象
+func searxngAnswer(answers []json.RawMessage) string {
+	for _, raw := range answers {
+		var text string
+		if err := json.Unmarshal(raw, &text); err == nil && text != "" {
+			return text
+		}
+		var obj struct {
+			Answer string `json:"answer"`
+		}
+		if err := json.Unmarshal(raw, &obj); err == nil && obj.Answer != "" {
+			return obj.Answer
+		}
+	}
+	return ""
+}
+
+// domainAllowed 检查链接的域名是否满足包含、排除条件，子域名视为匹配
+func domainAllowed(rawURL string, include, exclude []string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return len(include) == 0
+	}
+	host := strings.ToLower(u.Hostname())
+	match := func(domain string) bool {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "www."))
+		host := strings.TrimPrefix(host, "www.")
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	}
+	for _, domain := range exclude {
+		if match(domain) {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, domain := range include {
+		if match(domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -12,14 +12,12 @@ import (
 	"github.com/PGshen/thinking-map/server/internal/global"
 	"github.com/PGshen/thinking-map/server/internal/model"
 	"github.com/PGshen/thinking-map/server/internal/model/dto"
-	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
 	"github.com/PGshen/thinking-map/server/internal/pkg/sse"
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/components/tool/utils"
 	"github.com/cloudwego/eino/schema"
 	"github.com/google/uuid"
 	"github.com/spf13/viper"
-	"go.uber.org/zap"
 )
 
 const (
@@ -47,6 +45,10 @@ func NewTavilyClient() *TavilyClient {
 	}
 }
 
+func (c *TavilyClient) Name() model.RagSource {
+	return model.RagTavily
+}
+
 type SearchRequest struct {
 	Query             string   `json:"query"`
 	SearchDepth       string   `json:"search_depth,omitempty"`
@@ -161,9 +163,11 @@ type SearchFuncRequest struct {
 
 func SearchFunc(ctx context.Context, req *SearchFuncRequest) (*SearchResponse, error) {
 	mapID := ctx.Value("mapID").(string)
-	nodeID := ctx.Value("nodeID").(string)
-	operation := ctx.Value("operation").(string)
-	client := NewTavilyClient()
+	// 按思维导图设置或全局配置选择检索服务提供方
+	provider, err := ProviderForMap(ctx, mapID)
+	if err != nil {
+		return nil, err
+	}
 
 	searchReq := &SearchRequest{
 		Query:             req.Query,
@@ -182,50 +186,39 @@ func SearchFunc(ctx context.Context, req *SearchFuncRequest) (*SearchResponse, e
 		searchReq.MaxResults = DefaultMaxResults
 	}
 
+	// 有效期内相同的检索直接复用已有的RAG记录
+	cacheCfg := GetCacheConfig()
+	key := cacheKey(cacheCfg, mapID, provider.Name(), searchReq)
+	if cached := lookupCache(ctx, cacheCfg, key); cached != nil {
+		publishNotice(ctx, model.Notice{
+			Type:    model.NoticeTypeInfo,
+			Name:    noticeName(provider),
+			Content: fmt.Sprintf("关键词: %s（命中缓存）", req.Query),
+		})
+		publishRAGRecord(ctx, cached)
+		return responseFromRecord(cached), nil
+	}
+
 	notice := model.Notice{
 		Type:    model.NoticeTypeInfo,
-		Name:    "网络检索",
+		Name:    noticeName(provider),
 		Content: fmt.Sprintf("关键词: %s", req.Query),
 	}
 
 	// 发送开始检索的消息给前端
-	global.GetBroker().PublishToSession(mapID, sse.Event{
-		ID:   uuid.NewString(),
-		Type: dto.MessageNoticeEventType,
-		Data: dto.MessageNoticeEvent{
-			NodeID:    nodeID,
-			MessageID: uuid.NewString(),
-			Notice:    notice,
-		},
-	})
+	publishNotice(ctx, notice)
 
-	switch operation {
-	case "decomposition":
-		global.GetMessageManager().SaveDecompositionMessage(ctx, nodeID, dto.CreateMessageRequest{
-			ID:          uuid.NewString(),
-			MessageType: model.MsgTypeNotice,
-			Role:        schema.Assistant,
-			Content:     model.MessageContent{Notice: &notice},
-		})
-	case "conclusion":
-		global.GetMessageManager().SaveConclusionMessage(ctx, nodeID, dto.CreateMessageRequest{
-			ID:          uuid.NewString(),
-			MessageType: model.MsgTypeNotice,
-			Role:        schema.Assistant,
-			Content:     model.MessageContent{Notice: &notice},
-		})
-	}
-
-	resp, err := client.Search(ctx, searchReq)
+	resp, err := provider.Search(ctx, searchReq)
 	if err != nil {
 		return nil, err
 	}
 
 	// 将搜索结果转换为ragRecord
 	ragRecord := model.RAGRecord{
-		ID:     uuid.NewString(),
-		Query:  resp.Query,
-		Answer: resp.Answer,
+		ID:       uuid.NewString(),
+		Query:    resp.Query,
+		Answer:   resp.Answer,
+		CacheKey: key,
 	}
 
 	var results model.Results
@@ -239,48 +232,14 @@ func SearchFunc(ctx context.Context, req *SearchFuncRequest) (*SearchResponse, e
 			Favicon:    result.Favicon,
 		})
 	}
-	ragRecord.Sources = model.RagTavily
+	ragRecord.Sources = provider.Name()
 	ragRecord.Results = results
 
-	// 4. 保存 RAG 记录到数据库
-	if err := global.GetRAGRecordRepository().Create(ctx, &ragRecord); err != nil {
-		logger.Error("save rag record failed", zap.Error(err))
+	// 保存 RAG 记录并发送给前端
+	if err := saveRAGRecord(ctx, &ragRecord); err != nil {
 		return nil, err
 	}
 
-	// 5. 发送 SSE 到前端
-	messageID := uuid.NewString()
-	global.GetBroker().PublishToSession(mapID, sse.Event{
-		ID:   uuid.NewString(),
-		Type: dto.MessageRagEventType,
-		Data: dto.MessageRagEvent{
-			NodeID:    nodeID,
-			MessageID: messageID,
-			RagRecord: ragRecord,
-		},
-	})
-
-	switch operation {
-	case "decomposition":
-		global.GetMessageManager().SaveDecompositionMessage(ctx, nodeID, dto.CreateMessageRequest{
-			ID:          messageID,
-			MessageType: model.MsgTypeRAG,
-			Role:        schema.Tool,
-			Content: model.MessageContent{
-				RagID: ragRecord.ID,
-			},
-		})
-	case "conclusion":
-		global.GetMessageManager().SaveConclusionMessage(ctx, nodeID, dto.CreateMessageRequest{
-			ID:          messageID,
-			MessageType: model.MsgTypeRAG,
-			Role:        schema.Tool,
-			Content: model.MessageContent{
-				RagID: ragRecord.ID,
-			},
-		})
-	}
-
 	return resp, nil
 }
 
@@ -335,7 +294,7 @@ func ExtractFunc(ctx context.Context, req *ExtractFuncRequest) (*ExtractResponse
 func SearchTool() (tool.InvokableTool, error) {
 	t := utils.NewTool(&schema.ToolInfo{
 		Name: "search",
-		Desc: "使用Tavily搜索引擎进行网络检索",
+		Desc: "使用配置的检索服务（网络搜索引擎或本地文档索引）进行检索",
 		ParamsOneOf: schema.NewParamsOneOfByParams(
 			map[string]*schema.ParameterInfo{
 				"query": {
@@ -415,7 +374,11 @@ func GetAllSearchTools() ([]tool.BaseTool, error) {
 	if err != nil {
 		return nil, err
 	}
-	return []tool.BaseTool{searchTool, extractTool}, nil
+	kbSearchTool, err := KBSearchTool()
+	if err != nil {
+		return nil, err
+	}
+	return []tool.BaseTool{searchTool, extractTool, kbSearchTool}, nil
 }
 
 // genToolInfos generates tool information from tools config
@@ -1,141 +0,0 @@
-/*
- * Copyright 2025 CloudWeGo Authors
- *
- * Licensed under the Apache License, Version 2.0 (the "License");
- * you may not use this file except in compliance with the License.
- * You may obtain a copy of the License at
- *
- *     http://www.apache.org/licenses/LICENSE-2.0
- *
- * Unless required by applicable law or agreed to in writing, software
- * distributed under the License is distributed on an "AS IS" BASIS,
- * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
- * See the License for the specific language governing permissions and
- * limitations under the License.
- */
-
-package main
-
-import (
-	"context"
-	"fmt"
-	"log"
-	"time"
-
-	"github.com/cloudwego/eino/components/tool"
-	"github.com/mark3labs/mcp-go/client"
-	"github.com/mark3labs/mcp-go/mcp"
-	"github.com/mark3labs/mcp-go/server"
-
-	mcpp "github.com/cloudwego/eino-ext/components/tool/mcp"
-)
-
-func main() {
-	// startMCPServer()
-	time.Sleep(1 * time.Second)
-	ctx := context.Background()
-
-	mcpTools := getMCPTool(ctx)
-
-	for i, mcpTool := range mcpTools {
-		fmt.Println(i, ":")
-		info, err := mcpTool.Info(ctx)
-		if err != nil {
-			log.Fatal(err)
-		}
-		fmt.Println("Name:", info.Name)
-		fmt.Println("Desc:", info.Desc)
-		// result, err := mcpTool.(tool.InvokableTool).InvokableRun(ctx, `{"operation":"add", "x":1, "y":1}`)
-		// if err != nil {
-		// log.Fatal(err)
-		// }
-		// fmt.Println("Result:", result)
-		fmt.Println()
-	}
-}
-
-func getMCPTool(ctx context.Context) []tool.BaseTool {
-	cli, err := client.NewStreamableHttpClient("https://mcp.tavily.com/mcp/?tavilyApiKey=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")
-	if err != nil {
-		log.Fatal(err)
-	}
-	err = cli.Start(ctx)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	initRequest := mcp.InitializeRequest{}
-	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
-	initRequest.Params.ClientInfo = mcp.Implementation{
-		Name:    "example-client",
-		Version: "1.0.0",
-	}
-
-	_, err = cli.Initialize(ctx, initRequest)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	tools, err := mcpp.GetTools(ctx, &mcpp.Config{Cli: cli})
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	return tools
-}
-
-func startMCPServer() {
-	svr := server.NewMCPServer("demo", mcp.LATEST_PROTOCOL_VERSION)
-	svr.AddTool(mcp.NewTool("calculate",
-		mcp.WithDescription("Perform basic arithmetic operations"),
-		mcp.WithString("operation",
-			mcp.Required(),
-			mcp.Description("The operation to perform (add, subtract, multiply, divide)"),
-			mcp.Enum("add", "subtract", "multiply", "divide"),
-		),
-		mcp.WithNumber("x",
-			mcp.Required(),
-			mcp.Description("First number"),
-		),
-		mcp.WithNumber("y",
-			mcp.Required(),
-			mcp.Description("Second number"),
-		),
-	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		arg := request.Params.Arguments.(map[string]any)
-		op := arg["operation"].(string)
-		x := arg["x"].(float64)
-		y := arg["y"].(float64)
-
-		var result float64
-		switch op {
-		case "add":
-			result = x + y
-		case "subtract":
-			result = x - y
-		case "multiply":
-			result = x * y
-		case "divide":
-			if y == 0 {
-				return mcp.NewToolResultText("Cannot divide by zero"), nil
-			}
-			result = x / y
-		}
-		log.Printf("Calculated result: %.2f", result)
-		return mcp.NewToolResultText(fmt.Sprintf("%.2f", result)), nil
-	})
-	go func() {
-		defer func() {
-			e := recover()
-			if e != nil {
-				fmt.Println(e)
-			}
-		}()
-
-		err := server.NewSSEServer(svr, server.WithBaseURL("http://localhost:12345")).Start("localhost:12345")
-
-		if err != nil {
-			log.Fatal(err)
-		}
-	}()
-}
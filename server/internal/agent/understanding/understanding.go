@@ -2,6 +2,7 @@ package understanding
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/PGshen/thinking-map/server/internal/agent/llmmodel"
 	"github.com/PGshen/thinking-map/server/internal/model/dto"
@@ -36,13 +37,26 @@ func BuildUnderstandingAgent(ctx context.Context) (r compose.Runnable[[]*schema.
 			Schema:      understandingSchema.Value,
 		},
 	}
-	cm, err := llmmodel.NewOpenAIModel(ctx, responseFormat)
+	profile, err := llmmodel.GetAgentProfile(llmmodel.AgentUnderstanding)
 	if err != nil {
 		return nil, err
 	}
+	cm, err := llmmodel.NewChatModelWithProfile(ctx, profile, llmmodel.WithResponseFormat(responseFormat))
+	if err != nil {
+		return nil, err
+	}
+	prompt := systemPrompt
+	if !profile.StructuredOutput {
+		// 模型不支持结构化输出时，通过提示词约束输出格式
+		schemaJSON, err := json.Marshal(understandingSchema.Value)
+		if err != nil {
+			return nil, err
+		}
+		prompt += "\n\n请严格按照以下JSON Schema输出json结果，不要输出任何其他内容：\n" + string(schemaJSON)
+	}
 	chain := compose.NewChain[[]*schema.Message, *schema.Message]()
 	chain.AppendLambda(compose.InvokableLambdaWithOption(func(ctx context.Context, input []*schema.Message, opts ...any) (output []*schema.Message, err error) {
-		systemMsg := schema.SystemMessage(systemPrompt)
+		systemMsg := schema.SystemMessage(prompt)
 		return append([]*schema.Message{systemMsg}, input...), nil
 	})).AppendChatModel(cm)
 	return chain.Compile(ctx, compose.WithGraphName("understanding"))
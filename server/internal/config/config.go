@@ -7,16 +7,23 @@
 package config
 
 import (
+	"github.com/PGshen/thinking-map/server/internal/agent/base"
+	"github.com/PGshen/thinking-map/server/internal/pkg/autopilot"
 	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/PGshen/thinking-map/server/internal/pkg/queue"
 )
 
 // Config 配置结构体
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	Redis    RedisConfig    `yaml:"redis"`
-	JWT      JWTConfig      `yaml:"jwt"`
-	Log      logger.Config  `yaml:"log"`
+	Server     ServerConfig      `yaml:"server"`
+	Database   DatabaseConfig    `yaml:"database"`
+	Redis      RedisConfig       `yaml:"redis"`
+	JWT        JWTConfig         `yaml:"jwt"`
+	Log        logger.Config     `yaml:"log"`
+	Queue      queue.Config      `yaml:"queue"`
+	Budget     base.BudgetConfig `yaml:"budget"`
+	Autopilot  autopilot.Config  `yaml:"autopilot"`
+	NodePolicy NodePolicyConfig  `yaml:"node_policy" mapstructure:"node_policy"`
 }
 
 // ServerConfig 服务器配置
@@ -42,6 +49,13 @@ type JWTConfig struct {
 	Expire string `yaml:"expire"`
 }
 
+// NodePolicyConfig Agent节点操作工具的策略，限制为0表示不限制
+type NodePolicyConfig struct {
+	MaxNodesPerStep int  `yaml:"max_nodes_per_step" mapstructure:"max_nodes_per_step"` // 每个步骤（一次拆解，或自动驾驶的一次展开）最多创建的节点数
+	MaxDepth        int  `yaml:"max_depth" mapstructure:"max_depth"`                   // 创建节点的最大深度，根节点深度为0
+	DryRun          bool `yaml:"dry_run" mapstructure:"dry_run"`                       // 试运行默认值，思维导图可以单独设置
+}
+
 // RedisConfig Redis配置
 // 可根据需要扩展更多配置项
 // 例如：PoolSize、MinIdleConns等
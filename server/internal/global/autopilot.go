@@ -0,0 +1,29 @@
+package global
+
+import (
+	"sync"
+
+	"github.com/PGshen/thinking-map/server/internal/pkg/autopilot"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// GlobalAutopilotStore 全局自动驾驶状态存储实例
+	GlobalAutopilotStore *autopilot.Store
+	autopilotStoreOnce   sync.Once
+)
+
+// InitAutopilotStore 初始化全局自动驾驶状态存储
+func InitAutopilotStore(client *redis.Client, cfg autopilot.Config) {
+	autopilotStoreOnce.Do(func() {
+		GlobalAutopilotStore = autopilot.NewStore(client, cfg)
+	})
+}
+
+// GetAutopilotStore 获取全局自动驾驶状态存储实例
+func GetAutopilotStore() *autopilot.Store {
+	if GlobalAutopilotStore == nil {
+		panic("autopilot store not initialized, call InitAutopilotStore first")
+	}
+	return GlobalAutopilotStore
+}
@@ -0,0 +1,122 @@
+package global
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/base"
+	"github.com/PGshen/thinking-map/server/internal/agent/llmmodel"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+)
+
+var (
+	// GlobalBudgetManager 全局预算管理器实例
+	GlobalBudgetManager *BudgetManager
+	budgetManagerOnce   sync.Once
+)
+
+// InitBudgetManager 初始化全局预算管理器，并注册统计模型token用量的全局回调
+func InitBudgetManager(repo repository.AgentUsage, cfg base.BudgetConfig) {
+	budgetManagerOnce.Do(func() {
+		GlobalBudgetManager = NewBudgetManager(repo, cfg)
+		base.RegisterUsageCallback()
+	})
+}
+
+// GetBudgetManager 获取全局预算管理器实例
+func GetBudgetManager() *BudgetManager {
+	if GlobalBudgetManager == nil {
+		panic("budget manager not initialized, call InitBudgetManager first")
+	}
+	return GlobalBudgetManager
+}
+
+// BudgetManager 管理Agent运行的预算
+// 单次运行的用量由 base.BudgetTracker 统计，运行结束后记录到用量表，用于统计思维导图、用户维度的累计用量
+type BudgetManager struct {
+	repo   repository.AgentUsage
+	config base.BudgetConfig
+}
+
+// NewBudgetManager 创建预算管理器
+func NewBudgetManager(repo repository.AgentUsage, cfg base.BudgetConfig) *BudgetManager {
+	return &BudgetManager{repo: repo, config: cfg}
+}
+
+// NewTracker 为一次运行创建预算跟踪器，思维导图、用户维度的预算计入时间窗口内已经消耗的用量
+func (m *BudgetManager) NewTracker(ctx context.Context, mapID, userID string) (*base.BudgetTracker, error) {
+	limits := []base.BudgetLimit{{Scope: "run", Budget: m.config.Run}}
+	if mapID != "" && limited(m.config.Map) {
+		used, err := m.repo.SumByMapID(ctx, mapID, m.since())
+		if err != nil {
+			return nil, fmt.Errorf("failed to sum map usage: %w", err)
+		}
+		limits = append(limits, base.BudgetLimit{Scope: "map", Budget: m.config.Map, Used: toUsage(used)})
+	}
+	if userID != "" && limited(m.config.User) {
+		used, err := m.repo.SumByUserID(ctx, userID, m.since())
+		if err != nil {
+			return nil, fmt.Errorf("failed to sum user usage: %w", err)
+		}
+		limits = append(limits, base.BudgetLimit{Scope: "user", Budget: m.config.User, Used: toUsage(used)})
+	}
+	return base.NewBudgetTracker(llmmodel.EstimateCost, limits...), nil
+}
+
+// Check 检查思维导图、用户维度的预算，已经耗尽时返回 comm.ErrBudgetExceeded
+func (m *BudgetManager) Check(ctx context.Context, mapID, userID string) error {
+	tracker, err := m.NewTracker(ctx, mapID, userID)
+	if err != nil {
+		return err
+	}
+	if reason := tracker.Exceeded(); reason != "" {
+		return fmt.Errorf("%w: %s", comm.ErrBudgetExceeded, reason)
+	}
+	return nil
+}
+
+// Record 记录一次运行的用量
+func (m *BudgetManager) Record(ctx context.Context, run *Run, tracker *base.BudgetTracker) error {
+	return m.RecordUsage(ctx, run.ID, run.MapID, run.UserID, run.Operation, tracker)
+}
+
+// RecordUsage 记录不属于运行的模型调用（如保存结论时的结构化整理）的用量
+func (m *BudgetManager) RecordUsage(ctx context.Context, runID, mapID, userID, operation string, tracker *base.BudgetTracker) error {
+	usage := tracker.Usage()
+	return m.repo.Create(ctx, &model.AgentUsage{
+		RunID:            runID,
+		MapID:            mapID,
+		UserID:           userID,
+		Operation:        operation,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+		Cost:             usage.Cost,
+		DurationMs:       tracker.Elapsed().Milliseconds(),
+	})
+}
+
+func (m *BudgetManager) since() time.Time {
+	if m.config.Window <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(-m.config.Window)
+}
+
+// limited 累计预算只统计token和费用
+func limited(budget base.Budget) bool {
+	return budget.MaxTokens > 0 || budget.MaxCost > 0
+}
+
+func toUsage(sum *model.UsageSum) base.Usage {
+	return base.Usage{
+		PromptTokens:     sum.PromptTokens,
+		CompletionTokens: sum.CompletionTokens,
+		TotalTokens:      sum.TotalTokens,
+		Cost:             sum.Cost,
+	}
+}
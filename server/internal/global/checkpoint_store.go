@@ -0,0 +1,67 @@
+package global
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/base/multiagent"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	// GlobalCheckpointStore 全局检查点存储实例
+	GlobalCheckpointStore multiagent.CheckpointStore
+	checkpointStoreOnce   sync.Once
+)
+
+// InitCheckpointStore 初始化全局检查点存储
+func InitCheckpointStore(repo repository.AgentCheckpoint) {
+	checkpointStoreOnce.Do(func() {
+		GlobalCheckpointStore = &dbCheckpointStore{repo: repo}
+	})
+}
+
+// GetCheckpointStore 获取全局检查点存储实例
+func GetCheckpointStore() multiagent.CheckpointStore {
+	if GlobalCheckpointStore == nil {
+		panic("checkpoint store not initialized, call InitCheckpointStore first")
+	}
+	return GlobalCheckpointStore
+}
+
+// dbCheckpointStore 基于数据库的检查点存储
+type dbCheckpointStore struct {
+	repo repository.AgentCheckpoint
+}
+
+func (s *dbCheckpointStore) Save(ctx context.Context, checkpoint *multiagent.Checkpoint) error {
+	return s.repo.Create(ctx, &model.AgentCheckpoint{
+		RunID:     checkpoint.RunID,
+		NodeKey:   checkpoint.NodeKey,
+		State:     checkpoint.State,
+		CreatedAt: checkpoint.CreatedAt,
+	})
+}
+
+func (s *dbCheckpointStore) Load(ctx context.Context, runID string) (*multiagent.Checkpoint, error) {
+	checkpoint, err := s.repo.FindLatestByRunID(ctx, runID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, multiagent.ErrCheckpointNotFound
+		}
+		return nil, err
+	}
+	return &multiagent.Checkpoint{
+		RunID:     checkpoint.RunID,
+		NodeKey:   checkpoint.NodeKey,
+		State:     checkpoint.State,
+		CreatedAt: checkpoint.CreatedAt,
+	}, nil
+}
+
+func (s *dbCheckpointStore) Delete(ctx context.Context, runID string) error {
+	return s.repo.DeleteByRunID(ctx, runID)
+}
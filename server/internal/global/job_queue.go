@@ -0,0 +1,29 @@
+package global
+
+import (
+	"sync"
+
+	"github.com/PGshen/thinking-map/server/internal/pkg/queue"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// GlobalJobQueue 全局任务队列实例
+	GlobalJobQueue *queue.Queue
+	jobQueueOnce   sync.Once
+)
+
+// InitJobQueue 初始化全局任务队列
+func InitJobQueue(client *redis.Client, cfg queue.Config) {
+	jobQueueOnce.Do(func() {
+		GlobalJobQueue = queue.New(client, cfg)
+	})
+}
+
+// GetJobQueue 获取全局任务队列实例
+func GetJobQueue() *queue.Queue {
+	if GlobalJobQueue == nil {
+		panic("job queue not initialized, call InitJobQueue first")
+	}
+	return GlobalJobQueue
+}
@@ -0,0 +1,266 @@
+package global
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/PGshen/thinking-map/server/internal/pkg/utils"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	kbChunkSize      = 800 // 每个片段的最大字符数
+	kbEmbedBatchSize = 64  // 每次请求嵌入模型的片段数
+	kbCandidates     = 4   // 每一路检索的候选数为返回数量的倍数
+	kbRRFK           = 60  // RRF融合排序的平滑常数
+	kbVectorPageSize = 500 // 向量检索每次从数据库读取的片段数
+)
+
+var (
+	// GlobalKnowledgeBase 全局知识库实例
+	GlobalKnowledgeBase *KnowledgeBase
+	knowledgeBaseOnce   sync.Once
+)
+
+// InitKnowledgeBase 初始化全局知识库，embedder为空时只使用全文检索
+func InitKnowledgeBase(repo repository.KnowledgeBase, embedder embedding.Embedder) {
+	knowledgeBaseOnce.Do(func() {
+		GlobalKnowledgeBase = NewKnowledgeBase(repo, embedder)
+	})
+}
+
+// GetKnowledgeBase 获取全局知识库实例
+func GetKnowledgeBase() *KnowledgeBase {
+	if GlobalKnowledgeBase == nil {
+		panic("knowledge base not initialized, call InitKnowledgeBase first")
+	}
+	return GlobalKnowledgeBase
+}
+
+// KnowledgeBase 思维导图知识库
+// 上传的文档切分为片段后建立全文索引，配置了嵌入模型时同时生成向量，检索时用RRF融合两路结果
+type KnowledgeBase struct {
+	repo     repository.KnowledgeBase
+	embedder embedding.Embedder
+}
+
+// NewKnowledgeBase 创建知识库
+func NewKnowledgeBase(repo repository.KnowledgeBase, embedder embedding.Embedder) *KnowledgeBase {
+	return &KnowledgeBase{repo: repo, embedder: embedder}
+}
+
+// AddDocument 切分文档并建立索引，向量生成失败时只建立全文索引
+func (kb *KnowledgeBase) AddDocument(ctx context.Context, doc *model.KBDocument, content string) error {
+	var texts []string
+	switch doc.ContentType {
+	case model.KBContentCSV:
+		var err error
+		if texts, err = utils.ChunkCSV(content, kbChunkSize); err != nil {
+			return fmt.Errorf("%w: %v", comm.ErrUnsupportedDocument, err)
+		}
+	case model.KBContentText, model.KBContentMarkdown:
+		texts = utils.ChunkText(content, kbChunkSize)
+	default:
+		return fmt.Errorf("%w: %s", comm.ErrUnsupportedDocument, doc.ContentType)
+	}
+	if len(texts) == 0 {
+		return comm.ErrEmptyDocument
+	}
+
+	if doc.ID == "" {
+		doc.ID = uuid.NewString()
+	}
+	chunks := make([]*model.KBChunk, len(texts))
+	for i, text := range texts {
+		chunks[i] = &model.KBChunk{
+			ID:         uuid.NewString(),
+			DocumentID: doc.ID,
+			MapID:      doc.MapID,
+			Seq:        i,
+			Content:    text,
+			Terms:      strings.Join(utils.Tokenize(text), " "),
+		}
+	}
+	if kb.embedder != nil {
+		if err := kb.embedChunks(ctx, chunks); err != nil {
+			logger.Warn("embed knowledge base document failed", zap.String("document", doc.Name), zap.Error(err))
+			for _, chunk := range chunks {
+				chunk.Embedding = nil
+			}
+		} else {
+			doc.Embedded = true
+		}
+	}
+	doc.ChunkCount = len(chunks)
+	doc.Size = int64(len(content))
+	return kb.repo.CreateDocument(ctx, doc, chunks)
+}
+
+func (kb *KnowledgeBase) embedChunks(ctx context.Context, chunks []*model.KBChunk) error {
+	for start := 0; start < len(chunks); start += kbEmbedBatchSize {
+		end := min(start+kbEmbedBatchSize, len(chunks))
+		texts := make([]string, 0, end-start)
+		for _, chunk := range chunks[start:end] {
+			texts = append(texts, chunk.Content)
+		}
+		vectors, err := kb.embedder.EmbedStrings(ctx, texts)
+		if err != nil {
+			return err
+		}
+		if len(vectors) != len(texts) {
+			return fmt.Errorf("embedder returned %d vectors for %d texts", len(vectors), len(texts))
+		}
+		for i, vector := range vectors {
+			chunks[start+i].Embedding = vector
+		}
+	}
+	return nil
+}
+
+// ListDocuments 思维导图知识库中的文档
+func (kb *KnowledgeBase) ListDocuments(ctx context.Context, mapID string) ([]*model.KBDocument, error) {
+	return kb.repo.ListDocuments(ctx, mapID)
+}
+
+// DeleteDocument 删除思维导图知识库中的文档及其片段
+func (kb *KnowledgeBase) DeleteDocument(ctx context.Context, mapID, documentID string) error {
+	doc, err := kb.repo.FindDocumentByID(ctx, documentID)
+	if err != nil || doc.MapID != mapID {
+		return comm.ErrKBDocumentNotFound
+	}
+	return kb.repo.DeleteDocument(ctx, documentID)
+}
+
+// Search 检索思维导图知识库，返回按相关度排序的片段，Rank 归一化到 (0, 1]
+func (kb *KnowledgeBase) Search(ctx context.Context, mapID, query string, limit int) ([]*model.KBChunkHit, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+	terms := dedupTerms(utils.Tokenize(query))
+	textHits, err := kb.repo.SearchChunks(ctx, mapID, terms, limit*kbCandidates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search knowledge base: %w", err)
+	}
+	rankings := [][]*model.KBChunkHit{textHits}
+	if kb.embedder != nil {
+		vectorHits, err := kb.vectorSearch(ctx, mapID, query, limit*kbCandidates)
+		if err != nil {
+			// 向量检索是可选的，失败时只使用全文检索结果
+			logger.Warn("knowledge base vector search failed", zap.String("mapID", mapID), zap.Error(err))
+		} else {
+			rankings = append(rankings, vectorHits)
+		}
+	}
+	return fuseRankings(rankings, limit), nil
+}
+
+// vectorSearch 按余弦相似度检索已生成向量的片段
+// 分页读取片段并只保留相似度最高的limit个，内存占用不随知识库大小增长
+func (kb *KnowledgeBase) vectorSearch(ctx context.Context, mapID, query string, limit int) ([]*model.KBChunkHit, error) {
+	var queryVector []float64
+	var top []*model.KBChunkHit
+	var after int64
+	for {
+		page, err := kb.repo.FindEmbeddedChunks(ctx, mapID, after, kbVectorPageSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			break
+		}
+		// 有已生成向量的片段时才计算查询向量
+		if queryVector == nil {
+			vectors, err := kb.embedder.EmbedStrings(ctx, []string{query})
+			if err != nil {
+				return nil, err
+			}
+			if len(vectors) != 1 {
+				return nil, fmt.Errorf("embedder returned %d vectors for query", len(vectors))
+			}
+			queryVector = vectors[0]
+		}
+		for _, chunk := range page {
+			chunk.Rank = cosine(queryVector, chunk.Embedding)
+		}
+		top = append(top, page...)
+		sort.SliceStable(top, func(i, j int) bool {
+			return top[i].Rank > top[j].Rank
+		})
+		if len(top) > limit {
+			clear(top[limit:])
+			top = top[:limit]
+		}
+		if len(page) < kbVectorPageSize {
+			break
+		}
+		after = page[len(page)-1].SerialID
+	}
+	return top, nil
+}
+
+// fuseRankings 用RRF（Reciprocal Rank Fusion）融合多路检索结果
+func fuseRankings(rankings [][]*model.KBChunkHit, limit int) []*model.KBChunkHit {
+	scores := make(map[string]float64)
+	hits := make(map[string]*model.KBChunkHit)
+	var order []string
+	for _, ranking := range rankings {
+		for i, hit := range ranking {
+			if _, ok := hits[hit.ID]; !ok {
+				hits[hit.ID] = hit
+				order = append(order, hit.ID)
+			}
+			scores[hit.ID] += 1 / float64(kbRRFK+i+1)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+	if len(order) > limit {
+		order = order[:limit]
+	}
+	result := make([]*model.KBChunkHit, 0, len(order))
+	for _, id := range order {
+		hit := hits[id]
+		hit.Rank = scores[id] / scores[order[0]]
+		result = append(result, hit)
+	}
+	return result
+}
+
+func dedupTerms(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	result := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			result = append(result, term)
+		}
+	}
+	return result
+}
+
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,189 @@
+package global
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeKBRepo 内存知识库仓储，全文检索按分词命中数排序
+type fakeKBRepo struct {
+	repository.KnowledgeBase
+	docs   map[string]*model.KBDocument
+	chunks []*model.KBChunk
+	pages  int // FindEmbeddedChunks的调用次数
+}
+
+func (r *fakeKBRepo) CreateDocument(ctx context.Context, doc *model.KBDocument, chunks []*model.KBChunk) error {
+	r.docs[doc.ID] = doc
+	for _, chunk := range chunks {
+		chunk.SerialID = int64(len(r.chunks) + 1)
+		r.chunks = append(r.chunks, chunk)
+	}
+	return nil
+}
+
+func (r *fakeKBRepo) FindDocumentByID(ctx context.Context, id string) (*model.KBDocument, error) {
+	if doc, ok := r.docs[id]; ok {
+		return doc, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeKBRepo) SearchChunks(ctx context.Context, mapID string, terms []string, limit int) ([]*model.KBChunkHit, error) {
+	var hits []*model.KBChunkHit
+	for _, chunk := range r.chunks {
+		matched := 0
+		for _, term := range terms {
+			if strings.Contains(" "+chunk.Terms+" ", " "+term+" ") {
+				matched++
+			}
+		}
+		if chunk.MapID == mapID && matched > 0 {
+			hits = append(hits, &model.KBChunkHit{KBChunk: *chunk, DocumentName: r.docs[chunk.DocumentID].Name, Rank: float64(matched)})
+		}
+	}
+	return hits, nil
+}
+
+func (r *fakeKBRepo) FindEmbeddedChunks(ctx context.Context, mapID string, afterSerialID int64, limit int) ([]*model.KBChunkHit, error) {
+	r.pages++
+	var hits []*model.KBChunkHit
+	for _, chunk := range r.chunks {
+		if chunk.MapID == mapID && chunk.Embedding != nil && chunk.SerialID > afterSerialID {
+			hits = append(hits, &model.KBChunkHit{KBChunk: *chunk, DocumentName: r.docs[chunk.DocumentID].Name})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].SerialID < hits[j].SerialID })
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// keywordEmbedder 以是否包含关键词作为向量维度
+type keywordEmbedder struct {
+	keywords []string
+	err      error
+}
+
+func (e *keywordEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float64, len(e.keywords))
+		for j, keyword := range e.keywords {
+			if strings.Contains(text, keyword) {
+				vectors[i][j] = 1
+			}
+		}
+	}
+	return vectors, nil
+}
+
+func TestKnowledgeBase_AddDocumentAndSearch(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeKBRepo{docs: map[string]*model.KBDocument{}}
+	kb := NewKnowledgeBase(repo, &keywordEmbedder{keywords: []string{"latency", "cost"}})
+
+	doc := &model.KBDocument{MapID: "map-1", Name: "notes.md", ContentType: model.KBContentMarkdown}
+	require.NoError(t, kb.AddDocument(ctx, doc, "Cache reduces latency.\n\nServers cost money."))
+	assert.NotEmpty(t, doc.ID)
+	assert.True(t, doc.Embedded)
+	assert.Equal(t, 1, doc.ChunkCount)
+
+	csvDoc := &model.KBDocument{MapID: "map-1", Name: "bench.csv", ContentType: model.KBContentCSV}
+	require.NoError(t, kb.AddDocument(ctx, csvDoc, "region,latency\nus,20ms\neu,35ms\n"))
+
+	hits, err := kb.Search(ctx, "map-1", "latency", 5)
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+	assert.Equal(t, 1.0, hits[0].Rank)
+	for _, hit := range hits {
+		assert.Contains(t, hit.Content, "latency")
+	}
+
+	// 其他思维导图检索不到
+	hits, err = kb.Search(ctx, "map-2", "latency", 5)
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+
+	assert.ErrorIs(t, kb.AddDocument(ctx, &model.KBDocument{MapID: "map-1", ContentType: model.KBContentText}, "\n\n"), comm.ErrEmptyDocument)
+	assert.ErrorIs(t, kb.AddDocument(ctx, &model.KBDocument{MapID: "map-1", ContentType: "pdf"}, "x"), comm.ErrUnsupportedDocument)
+	assert.ErrorIs(t, kb.DeleteDocument(ctx, "map-2", doc.ID), comm.ErrKBDocumentNotFound)
+}
+
+func TestKnowledgeBase_EmbedderFailure(t *testing.T) {
+	if logger.Log == nil {
+		logger.Log = zap.NewNop()
+	}
+	ctx := context.Background()
+	repo := &fakeKBRepo{docs: map[string]*model.KBDocument{}}
+	kb := NewKnowledgeBase(repo, &keywordEmbedder{err: errors.New("embedding service unavailable")})
+
+	doc := &model.KBDocument{MapID: "map-1", Name: "a.txt", ContentType: model.KBContentText}
+	require.NoError(t, kb.AddDocument(ctx, doc, "领导者选举"))
+	assert.False(t, doc.Embedded)
+
+	hits, err := kb.Search(ctx, "map-1", "选举", 5)
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, "a.txt", hits[0].DocumentName)
+}
+
+func TestFuseRankings(t *testing.T) {
+	hit := func(id string) *model.KBChunkHit {
+		return &model.KBChunkHit{KBChunk: model.KBChunk{ID: id}}
+	}
+	// b 在两路结果中都靠前，融合后排在第一
+	fused := fuseRankings([][]*model.KBChunkHit{
+		{hit("a"), hit("b"), hit("c")},
+		{hit("b"), hit("d")},
+	}, 3)
+	require.Len(t, fused, 3)
+	assert.Equal(t, "b", fused[0].ID)
+	assert.Equal(t, 1.0, fused[0].Rank)
+	assert.Equal(t, "a", fused[1].ID)
+}
+
+func TestKnowledgeBase_VectorSearchPaging(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeKBRepo{docs: map[string]*model.KBDocument{"doc-1": {ID: "doc-1", Name: "notes.md"}}}
+	kb := NewKnowledgeBase(repo, &keywordEmbedder{keywords: []string{"latency", "cost"}})
+	total := kbVectorPageSize*2 + 10
+	for i := 0; i < total; i++ {
+		embedding := model.Embedding{0, 1}
+		if i == total-1 {
+			// 最相关的片段在最后一页
+			embedding = model.Embedding{1, 0}
+		}
+		repo.chunks = append(repo.chunks, &model.KBChunk{
+			SerialID:   int64(i + 1),
+			ID:         fmt.Sprintf("chunk-%05d", i),
+			MapID:      "map-1",
+			DocumentID: "doc-1",
+			Embedding:  embedding,
+		})
+	}
+
+	hits, err := kb.vectorSearch(ctx, "map-1", "latency", 3)
+	require.NoError(t, err)
+	require.Len(t, hits, 3)
+	assert.Equal(t, fmt.Sprintf("chunk-%05d", total-1), hits[0].ID)
+	assert.Equal(t, 1.0, hits[0].Rank)
+	assert.Equal(t, 3, repo.pages)
+}
@@ -81,6 +81,9 @@ func (s *MessageManager) CreateMessage(ctx context.Context, userID string, req d
 			conversationID = uuid.NewString()
 		}
 	}
+	if req.ParentID == "" && req.ConversationID != "" {
+		conversationID = req.ConversationID
+	}
 	// 处理空ParentID
 	parentID := req.ParentID
 	if parentID == "" {
@@ -134,6 +137,9 @@ func (s *MessageManager) CreateMessageInTx(ctx context.Context, tx *gorm.DB, use
 			conversationID = uuid.NewString()
 		}
 	}
+	if req.ParentID == "" && req.ConversationID != "" {
+		conversationID = req.ConversationID
+	}
 	// 处理空ParentID
 	parentID := req.ParentID
 	if parentID == "" {
@@ -191,6 +197,10 @@ func (s *MessageManager) SaveDecompositionMessage(ctx context.Context, nodeID st
 	// 2. 获取当前节点的最后消息ID作为新消息的父ID
 	lastMessageID := node.Decomposition.LastMessageID
 	req.ParentID = lastMessageID
+	if lastMessageID == "" {
+		// 分叉到根消息之前时，新消息作为根消息留在原会话中
+		req.ConversationID = node.Decomposition.ConversationID
+	}
 	// fmt.Println("lastMessageID", lastMessageID)
 
 	// 3. 在事务中创建新消息
@@ -238,6 +248,10 @@ func (s *MessageManager) SaveConclusionMessage(ctx context.Context, nodeID strin
 	// 2. 获取当前节点的最后消息ID作为新消息的父ID
 	lastMessageID := node.Conclusion.LastMessageID
 	req.ParentID = lastMessageID
+	if lastMessageID == "" {
+		// 分叉到根消息之前时，新消息作为根消息留在原会话中
+		req.ConversationID = node.Conclusion.ConversationID
+	}
 
 	// 3. 在事务中创建新消息
 	msg, err := s.CreateMessageInTx(ctx, tx, req.UserID, req)
@@ -349,6 +363,41 @@ func (s *MessageManager) DeleteMessage(ctx context.Context, id string) error {
 	return s.messageRepo.Delete(ctx, id)
 }
 
+// RunConversationType 运行操作对应的节点对话：拆解及自动驾驶的展开步骤记入拆解对话，
+// 结论、汇总及自动驾驶的总结、汇总步骤记入结论对话，其他操作返回空
+func RunConversationType(operation string) string {
+	switch operation {
+	case "decomposition", "expand":
+		return dto.ConversationTypeDecomposition
+	case "conclusion", "conclude", "rollup":
+		return dto.ConversationTypeConclusion
+	}
+	return ""
+}
+
+// SaveRunMessage 按运行操作将消息保存到节点的拆解或结论对话中，操作没有对应的对话时不保存
+func (s *MessageManager) SaveRunMessage(ctx context.Context, nodeID, operation string, req dto.CreateMessageRequest) (*dto.MessageResponse, error) {
+	switch RunConversationType(operation) {
+	case dto.ConversationTypeDecomposition:
+		return s.SaveDecompositionMessage(ctx, nodeID, req)
+	case dto.ConversationTypeConclusion:
+		return s.SaveConclusionMessage(ctx, nodeID, req)
+	}
+	return nil, nil
+}
+
+// DiscardNodeMessage 删除刚保存到节点对话的消息，并将节点的最后消息恢复为其父消息，用于后续步骤失败时回滚
+func (s *MessageManager) DiscardNodeMessage(ctx context.Context, nodeID string, msg *dto.MessageResponse, conversationType string) error {
+	if err := s.messageRepo.Delete(ctx, msg.ID); err != nil {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+	parentID := msg.ParentID
+	if parentID == uuid.Nil.String() {
+		parentID = ""
+	}
+	return s.LinkMessageToNode(ctx, nodeID, parentID, msg.ConversationID, conversationType)
+}
+
 // GetMessageByID 根据ID获取消息
 func (s *MessageManager) GetMessageByID(ctx context.Context, id string) (*dto.MessageResponse, error) {
 	msg, err := s.messageRepo.FindByID(ctx, id)
@@ -641,17 +690,9 @@ func (s *MessageManager) GetNodeMessages(ctx context.Context, nodeID string, con
 		return nil, fmt.Errorf("failed to get node: %w", err)
 	}
 
-	var lastMessageID string
-	var conversationID string
-	switch conversationType {
-	case dto.ConversationTypeDecomposition:
-		lastMessageID = node.Decomposition.LastMessageID
-		conversationID = node.Decomposition.ConversationID
-	case dto.ConversationTypeConclusion:
-		lastMessageID = node.Conclusion.LastMessageID
-		conversationID = node.Conclusion.ConversationID
-	default:
-		return nil, fmt.Errorf("unsupported conversation type: %s", conversationType)
+	conversationID, lastMessageID, err := NodeConversation(node, conversationType)
+	if err != nil {
+		return nil, err
 	}
 
 	if lastMessageID == "" {
@@ -662,6 +703,17 @@ func (s *MessageManager) GetNodeMessages(ctx context.Context, nodeID string, con
 	return s.GetMessageChain(ctx, lastMessageID, conversationID)
 }
 
+// NodeConversation 获取节点拆解或结论对话的会话ID和当前分支的最后消息ID
+func NodeConversation(node *model.ThinkingNode, conversationType string) (conversationID, lastMessageID string, err error) {
+	switch conversationType {
+	case dto.ConversationTypeDecomposition:
+		return node.Decomposition.ConversationID, node.Decomposition.LastMessageID, nil
+	case dto.ConversationTypeConclusion:
+		return node.Conclusion.ConversationID, node.Conclusion.LastMessageID, nil
+	}
+	return "", "", fmt.Errorf("unsupported conversation type: %s", conversationType)
+}
+
 // UpdateNodeLastMessage 更新节点的最后消息ID
 func (s *MessageManager) UpdateNodeLastMessage(ctx context.Context, nodeID string, messageID, conversationID string, conversationType string) error {
 	return s.LinkMessageToNode(ctx, nodeID, messageID, conversationID, conversationType)
@@ -729,7 +781,7 @@ func (s *MessageManager) GetNodeChildren(ctx context.Context, nodeID string) ([]
 			idx+1, child.ID, child.Question, child.Target, child.Status, child.NodeType)
 
 		if child.Conclusion.Content != "" {
-			childContent += fmt.Sprintf("- 结论：%s\n", child.Conclusion.Content)
+			childContent += fmt.Sprintf("- 结论：%s\n", child.Conclusion.ContextText())
 		}
 		childContentList = append(childContentList, childContent)
 	}
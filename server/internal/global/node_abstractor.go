@@ -0,0 +1,159 @@
+package global
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/callback"
+	"github.com/PGshen/thinking-map/server/internal/agent/summary"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/PGshen/thinking-map/server/internal/pkg/sse"
+	"github.com/PGshen/thinking-map/server/internal/pkg/utils"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
+)
+
+var (
+	// GlobalNodeAbstractor 全局节点结论摘要生成器实例
+	GlobalNodeAbstractor *NodeAbstractor
+	nodeAbstractorOnce   sync.Once
+)
+
+const (
+	// AbstractMinTokens 结论不超过该长度时不生成摘要，上下文中直接使用全文
+	AbstractMinTokens = 200
+	abstractQueueSize = 256
+	abstractTimeout   = 2 * time.Minute
+)
+
+// AbstractFunc 根据节点问题、目标和结论生成摘要
+type AbstractFunc func(ctx context.Context, node *model.ThinkingNode) (string, error)
+
+// NodeAbstractor 节点结论摘要：结论保存、汇总或重置后在后台重新生成简短摘要，
+// 结论在其他节点的上下文中放不下时使用摘要代替全文
+type NodeAbstractor struct {
+	nodeRepo repository.ThinkingNode
+	generate AbstractFunc
+	tasks    chan string
+	mu       sync.Mutex
+	pending  map[string]bool // 已排队未处理的节点，多次刷新只处理一次
+}
+
+// InitNodeAbstractor 初始化全局节点结论摘要生成器并启动后台处理
+func InitNodeAbstractor(nodeRepo repository.ThinkingNode) {
+	nodeAbstractorOnce.Do(func() {
+		GlobalNodeAbstractor = newNodeAbstractor(nodeRepo, generateAbstract)
+		go GlobalNodeAbstractor.run()
+	})
+}
+
+// GetNodeAbstractor 获取全局节点结论摘要生成器实例
+func GetNodeAbstractor() *NodeAbstractor {
+	if GlobalNodeAbstractor == nil {
+		panic("node abstractor not initialized, call InitNodeAbstractor first")
+	}
+	return GlobalNodeAbstractor
+}
+
+func newNodeAbstractor(nodeRepo repository.ThinkingNode, generate AbstractFunc) *NodeAbstractor {
+	return &NodeAbstractor{
+		nodeRepo: nodeRepo,
+		generate: generate,
+		tasks:    make(chan string, abstractQueueSize),
+		pending:  make(map[string]bool),
+	}
+}
+
+// Refresh 结论变化后排队重新生成节点摘要，不阻塞调用方；队列已满时丢弃，摘要在下次结论变化时刷新
+func (a *NodeAbstractor) Refresh(nodeID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.pending[nodeID] {
+		return
+	}
+	select {
+	case a.tasks <- nodeID:
+		a.pending[nodeID] = true
+	default:
+		logger.Warn("node abstract queue is full", zap.String("nodeID", nodeID))
+	}
+}
+
+func (a *NodeAbstractor) run() {
+	for nodeID := range a.tasks {
+		a.mu.Lock()
+		delete(a.pending, nodeID)
+		a.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), abstractTimeout)
+		if err := a.process(ctx, nodeID); err != nil {
+			logger.Error("refresh node abstract failed", zap.String("nodeID", nodeID), zap.Error(err))
+		}
+		cancel()
+	}
+}
+
+// process 为节点当前的结论生成摘要，生成期间结论再次变化时放弃本次结果
+func (a *NodeAbstractor) process(ctx context.Context, nodeID string) error {
+	node, err := a.nodeRepo.FindByID(ctx, nodeID)
+	if err != nil {
+		return err
+	}
+	text := node.Conclusion.ContextText()
+	abstract := ""
+	if utils.EstimateTokens(text) > AbstractMinTokens {
+		if abstract, err = a.generate(ctx, node); err != nil {
+			return err
+		}
+	}
+
+	latest, err := a.nodeRepo.FindByID(ctx, nodeID)
+	if err != nil {
+		return err
+	}
+	if latest.Conclusion.ContextText() != text || latest.Conclusion.Abstract == abstract {
+		return nil
+	}
+	if err := a.nodeRepo.UpdateConclusionAbstract(ctx, nodeID, abstract); err != nil {
+		return fmt.Errorf("failed to update node abstract: %w", err)
+	}
+	latest.Conclusion.Abstract = abstract
+	if GlobalBroker != nil {
+		GlobalBroker.PublishToSession(latest.MapID, sse.Event{
+			ID:   nodeID,
+			Type: dto.NodeUpdatedEventType,
+			Data: dto.NodeUpdatedEvent{
+				NodeID:  nodeID,
+				Mode:    "replace",
+				Updates: map[string]interface{}{"conclusion": latest.Conclusion},
+			},
+		})
+	}
+	return nil
+}
+
+// generateAbstract 调用摘要Agent生成节点结论摘要
+func generateAbstract(ctx context.Context, node *model.ThinkingNode) (string, error) {
+	agent, err := summary.BuildSummaryAgent(ctx, summary.KindAbstract)
+	if err != nil {
+		return "", err
+	}
+	input := fmt.Sprintf("节点问题：%s\n节点目标：%s\n\n结论：\n%s", node.Question, node.Target, node.Conclusion.ContextText())
+	output, err := agent.Invoke(ctx, []*schema.Message{schema.UserMessage(input)}, compose.WithCallbacks(callback.LogCbHandler))
+	if err != nil {
+		return "", err
+	}
+	abstract := strings.TrimSpace(output.Content)
+	if abstract == "" {
+		return "", errors.New("summary agent returned empty abstract")
+	}
+	return abstract, nil
+}
@@ -0,0 +1,58 @@
+package global
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// abstractNodeRepo 内存节点仓库，只实现摘要生成用到的方法
+type abstractNodeRepo struct {
+	repository.ThinkingNode
+	node *model.ThinkingNode
+}
+
+func (r *abstractNodeRepo) FindByID(ctx context.Context, id string) (*model.ThinkingNode, error) {
+	copied := *r.node
+	return &copied, nil
+}
+
+func (r *abstractNodeRepo) UpdateConclusionAbstract(ctx context.Context, id, abstract string) error {
+	r.node.Conclusion.Abstract = abstract
+	return nil
+}
+
+func TestNodeAbstractor_Process(t *testing.T) {
+	repo := &abstractNodeRepo{node: &model.ThinkingNode{
+		ID:         "node",
+		Conclusion: model.Conclusion{Content: strings.Repeat("结论", 200)},
+	}}
+	calls := 0
+	abstractor := newNodeAbstractor(repo, func(ctx context.Context, node *model.ThinkingNode) (string, error) {
+		calls++
+		return "结论摘要", nil
+	})
+
+	require.NoError(t, abstractor.process(context.Background(), "node"))
+	assert.Equal(t, "结论摘要", repo.node.Conclusion.Abstract)
+	assert.Equal(t, 1, calls)
+
+	// 结论较短时清空摘要，不调用模型
+	repo.node.Conclusion.Content = "简短结论"
+	require.NoError(t, abstractor.process(context.Background(), "node"))
+	assert.Empty(t, repo.node.Conclusion.Abstract)
+	assert.Equal(t, 1, calls)
+}
+
+func TestNodeAbstractor_RefreshDeduplicates(t *testing.T) {
+	abstractor := newNodeAbstractor(&abstractNodeRepo{}, nil)
+	abstractor.Refresh("node")
+	abstractor.Refresh("node")
+	abstractor.Refresh("other")
+	assert.Len(t, abstractor.tasks, 2)
+}
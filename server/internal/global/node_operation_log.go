@@ -0,0 +1,129 @@
+package global
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	// GlobalNodeOperationLog 全局节点操作日志实例
+	GlobalNodeOperationLog *NodeOperationLog
+	nodeOperationLogOnce   sync.Once
+)
+
+// NodeOperationLog 节点操作日志：记录用户和Agent对节点的创建、修改、移动、删除、依赖和结论变更，
+// 包含操作前后的节点快照和操作者，用于按思维导图撤销和重做
+type NodeOperationLog struct {
+	opRepo repository.NodeOperation
+}
+
+// InitNodeOperationLog 初始化全局节点操作日志
+func InitNodeOperationLog(opRepo repository.NodeOperation) {
+	nodeOperationLogOnce.Do(func() {
+		GlobalNodeOperationLog = NewNodeOperationLog(opRepo)
+	})
+}
+
+// NewNodeOperationLog 创建节点操作日志
+func NewNodeOperationLog(opRepo repository.NodeOperation) *NodeOperationLog {
+	return &NodeOperationLog{opRepo: opRepo}
+}
+
+// GetNodeOperationLog 获取全局节点操作日志实例，未初始化时返回nil，此时 Record 不记录操作
+func GetNodeOperationLog() *NodeOperationLog {
+	return GlobalNodeOperationLog
+}
+
+// NodeOpChange 批量记录中的一项节点操作
+type NodeOpChange struct {
+	Op     string
+	Before *model.ThinkingNode
+	After  *model.ThinkingNode
+}
+
+// Record 记录已完成的节点操作，创建时before为空，删除时after为空；
+// 修改只涉及位置或父节点时记为移动，没有变化时不记录。记录失败不影响已完成的操作，只记录日志
+func (l *NodeOperationLog) Record(ctx context.Context, op string, before, after *model.ThinkingNode) {
+	if l == nil {
+		return
+	}
+	l.record(ctx, "", op, before, after)
+}
+
+// RecordBatch 记录同一次操作涉及的多个节点，各记录使用相同的批次ID，撤销和重做时按批次整体还原
+func (l *NodeOperationLog) RecordBatch(ctx context.Context, changes ...NodeOpChange) {
+	if l == nil {
+		return
+	}
+	batchID := uuid.NewString()
+	for _, change := range changes {
+		l.record(ctx, batchID, change.Op, change.Before, change.After)
+	}
+}
+
+func (l *NodeOperationLog) record(ctx context.Context, batchID, op string, before, after *model.ThinkingNode) {
+	if before != nil && after != nil {
+		switch op {
+		case model.NodeOpUpdate:
+			if before.Question == after.Question && before.Target == after.Target {
+				if before.Position == after.Position && before.ParentID == after.ParentID {
+					return
+				}
+				op = model.NodeOpMove
+			}
+		case model.NodeOpDependencies:
+			if slices.Equal(before.Dependencies, after.Dependencies) {
+				return
+			}
+		}
+	}
+	node := after
+	if node == nil {
+		node = before
+	}
+	if err := l.Append(ctx, &model.NodeOperation{
+		MapID:   node.MapID,
+		NodeID:  node.ID,
+		Op:      op,
+		Action:  model.NodeOpActionApply,
+		BatchID: batchID,
+	}, before, after); err != nil {
+		logger.Error("record node operation failed", zap.String("nodeID", node.ID), zap.String("op", op), zap.Error(err))
+	}
+}
+
+// Append 追加一条操作记录，填充节点快照和操作者
+func (l *NodeOperationLog) Append(ctx context.Context, entry *model.NodeOperation, before, after *model.ThinkingNode) error {
+	if l == nil {
+		return errors.New("node operation log not initialized")
+	}
+	var err error
+	if entry.Before, err = model.NodeSnapshot(before); err != nil {
+		return fmt.Errorf("failed to marshal node snapshot: %w", err)
+	}
+	if entry.After, err = model.NodeSnapshot(after); err != nil {
+		return fmt.Errorf("failed to marshal node snapshot: %w", err)
+	}
+	entry.ActorType, entry.ActorID = nodeOperationActor(ctx)
+	return l.opRepo.Create(ctx, entry)
+}
+
+// nodeOperationActor 操作者：Agent运行中的操作记为运行，否则记为请求的用户
+func nodeOperationActor(ctx context.Context) (string, string) {
+	if runID, ok := ctx.Value("runID").(string); ok && runID != "" {
+		return model.NodeOpActorAgent, runID
+	}
+	if userID, ok := ctx.Value("user_id").(string); ok && userID != "" {
+		return model.NodeOpActorUser, userID
+	}
+	return model.NodeOpActorSystem, ""
+}
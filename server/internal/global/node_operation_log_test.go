@@ -0,0 +1,68 @@
+package global
+
+import (
+	"context"
+	"testing"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryOperationRepo 内存操作日志仓库
+type memoryOperationRepo struct {
+	ops []*model.NodeOperation
+}
+
+func (r *memoryOperationRepo) Create(ctx context.Context, op *model.NodeOperation) error {
+	r.ops = append(r.ops, op)
+	return nil
+}
+
+func (r *memoryOperationRepo) FindByMapID(ctx context.Context, mapID string) ([]*model.NodeOperation, error) {
+	return r.ops, nil
+}
+
+func TestNodeOperationLog_Record(t *testing.T) {
+	repo := &memoryOperationRepo{}
+	log := NewNodeOperationLog(repo)
+	node := &model.ThinkingNode{ID: "node", MapID: "map", Question: "问题"}
+
+	runCtx := WithValues(context.Background(), map[string]any{"runID": "run", "user_id": "user"})
+	log.Record(runCtx, model.NodeOpCreate, nil, node)
+	require.Len(t, repo.ops, 1)
+	assert.Equal(t, model.NodeOpCreate, repo.ops[0].Op)
+	assert.Equal(t, model.NodeOpActionApply, repo.ops[0].Action)
+	assert.Equal(t, model.NodeOpActorAgent, repo.ops[0].ActorType)
+	assert.Equal(t, "run", repo.ops[0].ActorID)
+	assert.Empty(t, repo.ops[0].Before)
+	after, err := model.ParseNodeSnapshot(repo.ops[0].After)
+	require.NoError(t, err)
+	assert.Equal(t, "问题", after.Question)
+
+	userCtx := WithValues(context.Background(), map[string]any{"user_id": "user"})
+	// 只修改位置记为移动
+	moved := *node
+	moved.Position = model.Position{X: 10, Y: 20}
+	log.Record(userCtx, model.NodeOpUpdate, node, &moved)
+	require.Len(t, repo.ops, 2)
+	assert.Equal(t, model.NodeOpMove, repo.ops[1].Op)
+	assert.Equal(t, model.NodeOpActorUser, repo.ops[1].ActorType)
+	assert.Equal(t, "user", repo.ops[1].ActorID)
+
+	// 没有变化时不记录
+	log.Record(userCtx, model.NodeOpUpdate, node, node)
+	log.Record(userCtx, model.NodeOpDependencies, node, node)
+	assert.Len(t, repo.ops, 2)
+
+	log.Record(context.Background(), model.NodeOpDelete, node, nil)
+	require.Len(t, repo.ops, 3)
+	assert.Equal(t, "node", repo.ops[2].NodeID)
+	assert.Equal(t, model.NodeOpActorSystem, repo.ops[2].ActorType)
+	assert.Empty(t, repo.ops[2].After)
+
+	// 未初始化的日志不记录操作
+	var uninitialized *NodeOperationLog
+	assert.NotPanics(t, func() { uninitialized.Record(userCtx, model.NodeOpCreate, nil, node) })
+	assert.Error(t, uninitialized.Append(userCtx, &model.NodeOperation{}, nil, node))
+}
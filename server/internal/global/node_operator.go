@@ -34,13 +34,18 @@ type NodeOperator struct {
 // InitNodeOperator 初始化全局节点操作器
 func InitNodeOperator(nodeRepo repository.ThinkingNode, mapRepo repository.ThinkingMap) {
 	nodeOperatorOnce.Do(func() {
-		GlobalNodeOperator = &NodeOperator{
-			nodeRepo: nodeRepo,
-			mapRepo:  mapRepo,
-		}
+		GlobalNodeOperator = NewNodeOperator(nodeRepo, mapRepo)
 	})
 }
 
+// NewNodeOperator 创建节点操作器
+func NewNodeOperator(nodeRepo repository.ThinkingNode, mapRepo repository.ThinkingMap) *NodeOperator {
+	return &NodeOperator{
+		nodeRepo: nodeRepo,
+		mapRepo:  mapRepo,
+	}
+}
+
 // GetNodeOperator 获取全局节点操作器实例
 func GetNodeOperator() *NodeOperator {
 	if GlobalNodeOperator == nil {
@@ -51,6 +56,12 @@ func GetNodeOperator() *NodeOperator {
 
 // CreateNode 创建节点
 func (s *NodeOperator) CreateNode(ctx context.Context, req dto.CreateNodeRequest) (*dto.NodeResponse, error) {
+	return s.CreateNodeWithID(ctx, uuid.NewString(), req)
+}
+
+// CreateNodeWithID 使用预先分配的节点ID创建节点，用于执行试运行时提出的创建变更
+// 在Agent运行中创建时，在节点 Metadata 中记录运行ID
+func (s *NodeOperator) CreateNodeWithID(ctx context.Context, nodeID string, req dto.CreateNodeRequest) (*dto.NodeResponse, error) {
 	// 验证父节点是否存在（如果不是根节点）
 	if req.ParentID != "" && req.ParentID != uuid.Nil.String() {
 		if _, err := s.nodeRepo.FindByID(ctx, req.ParentID); err != nil {
@@ -64,7 +75,7 @@ func (s *NodeOperator) CreateNode(ctx context.Context, req dto.CreateNodeRequest
 	}
 
 	node := &model.ThinkingNode{
-		ID:       uuid.NewString(),
+		ID:       nodeID,
 		MapID:    req.MapID,
 		ParentID: req.ParentID,
 		NodeType: req.NodeType,
@@ -85,9 +96,16 @@ func (s *NodeOperator) CreateNode(ctx context.Context, req dto.CreateNodeRequest
 		UpdatedAt: time.Now(),
 	}
 
+	if runID, ok := ctx.Value("runID").(string); ok && runID != "" {
+		if err := node.SetMeta(model.NodeMetaCreatedByRun, runID); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := s.nodeRepo.Create(ctx, node); err != nil {
 		return nil, fmt.Errorf("failed to create node: %w", err)
 	}
+	GetNodeOperationLog().Record(ctx, model.NodeOpCreate, nil, node)
 
 	resp := dto.ToNodeResponse(node)
 	return &resp, nil
@@ -99,6 +117,7 @@ func (s *NodeOperator) UpdateNode(ctx context.Context, nodeID string, req dto.Up
 	if err != nil {
 		return nil, fmt.Errorf("node not found: %w", err)
 	}
+	before := *node
 
 	// 更新字段
 	if req.Question != "" {
@@ -121,6 +140,7 @@ func (s *NodeOperator) UpdateNode(ctx context.Context, nodeID string, req dto.Up
 	if err != nil {
 		return nil, fmt.Errorf("failed to get updated node: %w", err)
 	}
+	GetNodeOperationLog().Record(ctx, model.NodeOpUpdate, &before, updatedNode)
 
 	resp := dto.ToNodeResponse(updatedNode)
 	return &resp, nil
@@ -148,7 +168,11 @@ func (s *NodeOperator) DeleteNode(ctx context.Context, nodeID string) error {
 		return errors.New("cannot delete root node")
 	}
 
-	return s.nodeRepo.Delete(ctx, nodeID)
+	if err := s.nodeRepo.Delete(ctx, nodeID); err != nil {
+		return err
+	}
+	GetNodeOperationLog().Record(ctx, model.NodeOpDelete, node, nil)
+	return nil
 }
 
 // UpdateNodeDependencies 更新节点依赖关系
@@ -158,6 +182,7 @@ func (s *NodeOperator) UpdateNodeDependencies(ctx context.Context, nodeID string
 	if err != nil {
 		return nil, fmt.Errorf("node not found: %w", err)
 	}
+	before := *node
 
 	// 更新依赖关系
 	node.Dependencies = dependencies
@@ -167,6 +192,7 @@ func (s *NodeOperator) UpdateNodeDependencies(ctx context.Context, nodeID string
 	if err := s.nodeRepo.Update(ctx, node); err != nil {
 		return nil, fmt.Errorf("failed to update node dependencies: %w", err)
 	}
+	GetNodeOperationLog().Record(ctx, model.NodeOpDependencies, &before, node)
 
 	return node, nil
 }
@@ -0,0 +1,236 @@
+package global
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/config"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/pkg/sse"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// nodeCreateCountTTL 运行中创建节点计数的过期时间，覆盖任务重试和自动驾驶的整个会话
+const nodeCreateCountTTL = 24 * time.Hour
+
+var (
+	// GlobalNodePolicy 全局节点操作策略实例
+	GlobalNodePolicy *NodePolicy
+	nodePolicyOnce   sync.Once
+)
+
+// NodePolicy Agent节点操作工具的策略：工具只能操作当前思维导图的节点，每个步骤创建的节点数和节点深度受限，
+// 已完成或用户创建、编辑过的节点不能被删除；试运行模式下变更不直接生效，记录为变更集等待用户确认
+type NodePolicy struct {
+	cfg        config.NodePolicyConfig
+	nodeRepo   repository.ThinkingNode
+	mapRepo    repository.ThinkingMap
+	changeRepo repository.NodeChange
+	redis      *redis.Client
+}
+
+// InitNodePolicy 初始化全局节点操作策略
+func InitNodePolicy(nodeRepo repository.ThinkingNode, mapRepo repository.ThinkingMap, changeRepo repository.NodeChange, redisClient *redis.Client, cfg config.NodePolicyConfig) {
+	nodePolicyOnce.Do(func() {
+		GlobalNodePolicy = NewNodePolicy(nodeRepo, mapRepo, changeRepo, redisClient, cfg)
+	})
+}
+
+// NewNodePolicy 创建节点操作策略，redisClient用于统计每个步骤创建的节点数
+func NewNodePolicy(nodeRepo repository.ThinkingNode, mapRepo repository.ThinkingMap, changeRepo repository.NodeChange, redisClient *redis.Client, cfg config.NodePolicyConfig) *NodePolicy {
+	return &NodePolicy{
+		cfg:        cfg,
+		nodeRepo:   nodeRepo,
+		mapRepo:    mapRepo,
+		changeRepo: changeRepo,
+		redis:      redisClient,
+	}
+}
+
+// GetNodePolicy 获取全局节点操作策略实例
+func GetNodePolicy() *NodePolicy {
+	if GlobalNodePolicy == nil {
+		panic("node policy not initialized, call InitNodePolicy first")
+	}
+	return GlobalNodePolicy
+}
+
+// IsNodePolicyError 是否为节点操作被策略拒绝的错误
+func IsNodePolicyError(err error) bool {
+	return errors.Is(err, comm.ErrNodeOutOfScope) ||
+		errors.Is(err, comm.ErrNodeCreateLimit) ||
+		errors.Is(err, comm.ErrNodeDepthLimit) ||
+		errors.Is(err, comm.ErrNodeProtected)
+}
+
+// DryRun 思维导图是否开启试运行，未单独设置时使用全局配置
+func (p *NodePolicy) DryRun(ctx context.Context, mapID string) (bool, error) {
+	thinkingMap, err := p.mapRepo.FindByID(ctx, mapID)
+	if err != nil {
+		return false, fmt.Errorf("map not found: %w", err)
+	}
+	return thinkingMap.MetaBoolOr(model.MapMetaNodeDryRun, p.cfg.DryRun), nil
+}
+
+// Target 获取工具要操作的节点并检查其属于当前思维导图；
+// 试运行时也可以是本次运行提出但尚未接受的创建变更中的节点，runID为空时只查找已存在的节点
+func (p *NodePolicy) Target(ctx context.Context, mapID, runID, nodeID string) (*model.ThinkingNode, error) {
+	proposed, err := p.proposedNodes(ctx, mapID, runID)
+	if err != nil {
+		return nil, err
+	}
+	return p.lookup(ctx, mapID, nodeID, proposed)
+}
+
+// CheckCreate 检查能否在父节点下创建节点：父节点属于当前思维导图，新节点不超过最大深度，本步骤创建的节点数未达上限；
+// 通过检查时占用一个创建名额，创建失败时调用 ReleaseCreate 归还
+func (p *NodePolicy) CheckCreate(ctx context.Context, mapID, runID, parentID string) error {
+	proposed, err := p.proposedNodes(ctx, mapID, runID)
+	if err != nil {
+		return err
+	}
+	parent, err := p.lookup(ctx, mapID, parentID, proposed)
+	if err != nil {
+		return err
+	}
+
+	if p.cfg.MaxDepth > 0 {
+		depth := 0
+		// 数据异常形成环时最多向上查找 MaxDepth 层
+		for node := parent; !isRootParent(node.ParentID) && depth < p.cfg.MaxDepth; depth++ {
+			if node, err = p.lookup(ctx, mapID, node.ParentID, proposed); err != nil {
+				return err
+			}
+		}
+		if depth+1 > p.cfg.MaxDepth {
+			return fmt.Errorf("%w: max depth is %d", comm.ErrNodeDepthLimit, p.cfg.MaxDepth)
+		}
+	}
+
+	if p.cfg.MaxNodesPerStep > 0 && runID != "" {
+		key := nodeCreateCountKey(runID, parentID)
+		created, err := p.redis.Incr(ctx, key).Result()
+		if err != nil {
+			return fmt.Errorf("failed to count created nodes: %w", err)
+		}
+		if created == 1 {
+			p.redis.Expire(ctx, key, nodeCreateCountTTL)
+		}
+		if created > int64(p.cfg.MaxNodesPerStep) {
+			p.redis.Decr(ctx, key)
+			return fmt.Errorf("%w: at most %d nodes per step", comm.ErrNodeCreateLimit, p.cfg.MaxNodesPerStep)
+		}
+	}
+	return nil
+}
+
+// ReleaseCreate 归还 CheckCreate 占用的创建名额，在节点未能创建或提出变更时调用
+func (p *NodePolicy) ReleaseCreate(ctx context.Context, runID, parentID string) {
+	if p.cfg.MaxNodesPerStep > 0 && runID != "" {
+		p.redis.Decr(ctx, nodeCreateCountKey(runID, parentID))
+	}
+}
+
+// nodeCreateCountKey 运行中在父节点下创建节点的计数；Agent只在当前步骤的节点下创建子节点，
+// 按运行和父节点计数即按步骤计数，自动驾驶的多个步骤共用一个运行时各自计数
+func nodeCreateCountKey(runID, parentID string) string {
+	return fmt.Sprintf("node_policy:created:%s:%s", runID, parentID)
+}
+
+// CheckDelete 检查节点能否被删除，已完成或用户创建、编辑过的节点受保护
+func (p *NodePolicy) CheckDelete(node *model.ThinkingNode) error {
+	if node.Status == comm.NodeStatusCompleted || node.MetaBool(model.NodeMetaUserEdited) {
+		return fmt.Errorf("%w: %s", comm.ErrNodeProtected, node.Question)
+	}
+	return nil
+}
+
+// Propose 记录试运行时提出的变更并通知前端
+func (p *NodePolicy) Propose(ctx context.Context, change *model.NodeChange) error {
+	change.Status = model.NodeChangeStatusPending
+	if err := p.changeRepo.Create(ctx, change); err != nil {
+		return fmt.Errorf("failed to save node change: %w", err)
+	}
+	GetBroker().PublishToSession(change.MapID, sse.Event{
+		ID:   change.ID,
+		Type: dto.NodeChangeProposedEventType,
+		Data: dto.NodeChangeProposedEvent{
+			RunID:    change.RunID,
+			NodeID:   change.NodeID,
+			ChangeID: change.ID,
+			Op:       change.Op,
+			TargetID: change.TargetID,
+			Payload:  json.RawMessage(change.Payload),
+		},
+	})
+	return nil
+}
+
+// lookup 查找当前思维导图中的节点，已存在的节点优先，其次是本次运行提出的创建变更
+func (p *NodePolicy) lookup(ctx context.Context, mapID, nodeID string, proposed map[string]*model.ThinkingNode) (*model.ThinkingNode, error) {
+	if _, err := uuid.Parse(nodeID); err != nil {
+		return nil, fmt.Errorf("%w: %s", comm.ErrNodeOutOfScope, nodeID)
+	}
+	node, err := p.nodeRepo.FindByID(ctx, nodeID)
+	if err == nil {
+		if node.MapID != mapID {
+			return nil, fmt.Errorf("%w: %s", comm.ErrNodeOutOfScope, nodeID)
+		}
+		return node, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if node, ok := proposed[nodeID]; ok {
+		return node, nil
+	}
+	return nil, fmt.Errorf("%w: %s", comm.ErrNodeOutOfScope, nodeID)
+}
+
+// proposedNodes 本次运行提出但尚未接受的创建变更中的节点
+func (p *NodePolicy) proposedNodes(ctx context.Context, mapID, runID string) (map[string]*model.ThinkingNode, error) {
+	proposed := map[string]*model.ThinkingNode{}
+	if runID == "" {
+		return proposed, nil
+	}
+	changes, err := p.changeRepo.FindByRunID(ctx, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node changes: %w", err)
+	}
+	for _, change := range changes {
+		if change.MapID != mapID || change.Op != model.NodeChangeOpCreate || change.Status != model.NodeChangeStatusPending {
+			continue
+		}
+		var payload struct {
+			NodeType string `json:"nodeType"`
+			Question string `json:"question"`
+			Target   string `json:"target"`
+		}
+		if err := json.Unmarshal(change.Payload, &payload); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal node change: %w", err)
+		}
+		proposed[change.TargetID] = &model.ThinkingNode{
+			ID:       change.TargetID,
+			MapID:    change.MapID,
+			ParentID: change.NodeID,
+			NodeType: payload.NodeType,
+			Question: payload.Question,
+			Target:   payload.Target,
+			Status:   comm.NodeStatusInitial,
+		}
+	}
+	return proposed, nil
+}
+
+func isRootParent(parentID string) bool {
+	return parentID == "" || parentID == uuid.Nil.String()
+}
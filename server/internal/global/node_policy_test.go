@@ -0,0 +1,206 @@
+package global
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/PGshen/thinking-map/server/internal/config"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// policyNodeRepo 内存节点仓库，只实现策略检查用到的方法
+type policyNodeRepo struct {
+	repository.ThinkingNode
+	nodes map[string]*model.ThinkingNode
+}
+
+func (r *policyNodeRepo) FindByID(ctx context.Context, id string) (*model.ThinkingNode, error) {
+	if node, ok := r.nodes[id]; ok {
+		return node, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *policyNodeRepo) FindByMapID(ctx context.Context, mapID string) ([]*model.ThinkingNode, error) {
+	var nodes []*model.ThinkingNode
+	for _, node := range r.nodes {
+		if node.MapID == mapID {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
+
+type policyMapRepo struct {
+	repository.ThinkingMap
+	thinkingMap *model.ThinkingMap
+}
+
+func (r *policyMapRepo) FindByID(ctx context.Context, id string) (*model.ThinkingMap, error) {
+	return r.thinkingMap, nil
+}
+
+type policyChangeRepo struct {
+	repository.NodeChange
+	changes []*model.NodeChange
+}
+
+func (r *policyChangeRepo) Create(ctx context.Context, change *model.NodeChange) error {
+	r.changes = append(r.changes, change)
+	return nil
+}
+
+func (r *policyChangeRepo) FindByRunID(ctx context.Context, runID string) ([]*model.NodeChange, error) {
+	var changes []*model.NodeChange
+	for _, change := range r.changes {
+		if change.RunID == runID {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+type policyFixture struct {
+	policy  *NodePolicy
+	nodes   *policyNodeRepo
+	changes *policyChangeRepo
+	mapID   string
+	root    *model.ThinkingNode
+	child   *model.ThinkingNode
+}
+
+func newPolicyFixture(cfg config.NodePolicyConfig) *policyFixture {
+	mapID := uuid.NewString()
+	root := &model.ThinkingNode{ID: uuid.NewString(), MapID: mapID, ParentID: uuid.Nil.String(), NodeType: "root"}
+	child := &model.ThinkingNode{ID: uuid.NewString(), MapID: mapID, ParentID: root.ID, Question: "子问题"}
+	other := &model.ThinkingNode{ID: uuid.NewString(), MapID: uuid.NewString(), ParentID: uuid.Nil.String()}
+	nodes := &policyNodeRepo{nodes: map[string]*model.ThinkingNode{root.ID: root, child.ID: child, other.ID: other}}
+	changes := &policyChangeRepo{}
+	return &policyFixture{
+		policy:  NewNodePolicy(nodes, &policyMapRepo{thinkingMap: &model.ThinkingMap{ID: mapID}}, changes, testRedis, cfg),
+		nodes:   nodes,
+		changes: changes,
+		mapID:   mapID,
+		root:    root,
+		child:   child,
+	}
+}
+
+// propose 记录一项创建变更，不经过SSE通知
+func (f *policyFixture) propose(runID, parentID string) string {
+	id := uuid.NewString()
+	payload, _ := json.Marshal(map[string]string{"nodeType": "analysis", "question": "新问题"})
+	f.changes.changes = append(f.changes.changes, &model.NodeChange{
+		ID: uuid.NewString(), RunID: runID, MapID: f.mapID, NodeID: parentID,
+		Op: model.NodeChangeOpCreate, TargetID: id, Payload: payload, Status: model.NodeChangeStatusPending,
+	})
+	return id
+}
+
+func TestNodePolicy_Target(t *testing.T) {
+	f := newPolicyFixture(config.NodePolicyConfig{})
+	ctx := context.Background()
+
+	node, err := f.policy.Target(ctx, f.mapID, "", f.child.ID)
+	require.NoError(t, err)
+	assert.Equal(t, f.child.ID, node.ID)
+
+	// 其他思维导图的节点、不存在的节点和非法ID都超出范围
+	for id := range f.nodes.nodes {
+		if f.nodes.nodes[id].MapID != f.mapID {
+			_, err = f.policy.Target(ctx, f.mapID, "", id)
+			assert.ErrorIs(t, err, comm.ErrNodeOutOfScope)
+		}
+	}
+	_, err = f.policy.Target(ctx, f.mapID, "", uuid.NewString())
+	assert.ErrorIs(t, err, comm.ErrNodeOutOfScope)
+	_, err = f.policy.Target(ctx, f.mapID, "", "node-1")
+	assert.ErrorIs(t, err, comm.ErrNodeOutOfScope)
+
+	// 本次运行提出的节点只在同一运行中可见
+	proposed := f.propose("run-1", f.child.ID)
+	node, err = f.policy.Target(ctx, f.mapID, "run-1", proposed)
+	require.NoError(t, err)
+	assert.Equal(t, f.child.ID, node.ParentID)
+	_, err = f.policy.Target(ctx, f.mapID, "run-2", proposed)
+	assert.ErrorIs(t, err, comm.ErrNodeOutOfScope)
+}
+
+func TestNodePolicy_CheckCreate(t *testing.T) {
+	ctx := context.Background()
+
+	// 根节点深度为0，子节点深度为1，最大深度为2时可以在子节点下创建，不能在其下的节点下创建
+	f := newPolicyFixture(config.NodePolicyConfig{MaxDepth: 2})
+	assert.NoError(t, f.policy.CheckCreate(ctx, f.mapID, "run-1", f.child.ID))
+	proposed := f.propose("run-1", f.child.ID)
+	assert.ErrorIs(t, f.policy.CheckCreate(ctx, f.mapID, "run-1", proposed), comm.ErrNodeDepthLimit)
+
+	// 按运行和父节点（即步骤）计数，自动驾驶的不同步骤各自计数
+	f = newPolicyFixture(config.NodePolicyConfig{MaxNodesPerStep: 2})
+	run1, run2 := uuid.NewString(), uuid.NewString()
+	assert.NoError(t, f.policy.CheckCreate(ctx, f.mapID, run1, f.root.ID))
+	assert.NoError(t, f.policy.CheckCreate(ctx, f.mapID, run1, f.root.ID))
+	assert.ErrorIs(t, f.policy.CheckCreate(ctx, f.mapID, run1, f.root.ID), comm.ErrNodeCreateLimit)
+	assert.NoError(t, f.policy.CheckCreate(ctx, f.mapID, run1, f.child.ID))
+	assert.NoError(t, f.policy.CheckCreate(ctx, f.mapID, run2, f.root.ID))
+
+	// 超出上限的检查不占用名额，归还的名额可以再次使用
+	f.policy.ReleaseCreate(ctx, run1, f.root.ID)
+	assert.NoError(t, f.policy.CheckCreate(ctx, f.mapID, run1, f.root.ID))
+	assert.ErrorIs(t, f.policy.CheckCreate(ctx, f.mapID, run1, f.root.ID), comm.ErrNodeCreateLimit)
+
+	// 并发检查时不超过上限
+	run3 := uuid.NewString()
+	var wg sync.WaitGroup
+	var passed atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if f.policy.CheckCreate(ctx, f.mapID, run3, f.root.ID) == nil {
+				passed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), passed.Load())
+}
+
+func TestNodePolicy_CheckDelete(t *testing.T) {
+	f := newPolicyFixture(config.NodePolicyConfig{})
+	assert.NoError(t, f.policy.CheckDelete(f.child))
+
+	f.child.Status = comm.NodeStatusCompleted
+	assert.ErrorIs(t, f.policy.CheckDelete(f.child), comm.ErrNodeProtected)
+
+	edited := &model.ThinkingNode{Status: comm.NodeStatusPending}
+	require.NoError(t, edited.SetMeta(model.NodeMetaUserEdited, true))
+	err := f.policy.CheckDelete(edited)
+	assert.ErrorIs(t, err, comm.ErrNodeProtected)
+	assert.True(t, IsNodePolicyError(err))
+}
+
+func TestNodePolicy_DryRun(t *testing.T) {
+	ctx := context.Background()
+	thinkingMap := &model.ThinkingMap{}
+	policy := NewNodePolicy(nil, &policyMapRepo{thinkingMap: thinkingMap}, nil, nil, config.NodePolicyConfig{DryRun: true})
+
+	dryRun, err := policy.DryRun(ctx, "map")
+	require.NoError(t, err)
+	assert.True(t, dryRun)
+
+	// 思维导图单独设置时覆盖全局配置
+	require.NoError(t, thinkingMap.SetMeta(model.MapMetaNodeDryRun, false))
+	dryRun, err = policy.DryRun(ctx, "map")
+	require.NoError(t, err)
+	assert.False(t, dryRun)
+}
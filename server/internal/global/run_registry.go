@@ -0,0 +1,280 @@
+package global
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	// GlobalRunRegistry 全局Agent运行注册表实例
+	GlobalRunRegistry *RunRegistry
+	runRegistryOnce   sync.Once
+)
+
+// RunTask 运行中的一个任务，ctx在运行被取消时结束
+type RunTask func(ctx context.Context) error
+
+// RunSpec 启动运行的参数
+type RunSpec struct {
+	ID        string // 运行ID，为空时自动生成；由任务队列执行时使用任务ID
+	MapID     string
+	NodeID    string
+	Operation string // decomposition | conclusion | rollup | autopilot
+	Tasks     []RunTask
+	// Values 额外的上下文键值（如 user_id），无请求上下文时使用
+	Values map[string]any
+	// OnCancelled 运行被取消且所有任务退出后调用，用于恢复节点状态并通知前端
+	OnCancelled func(ctx context.Context, run *Run)
+}
+
+// Run 一次Agent运行
+type Run struct {
+	ID        string    `json:"runID"`
+	MapID     string    `json:"mapID"`
+	NodeID    string    `json:"nodeID"`
+	Operation string    `json:"operation"`
+	UserID    string    `json:"userID"`
+	StartedAt time.Time `json:"startedAt"`
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	cancelled bool
+	err       error
+	done      chan struct{}
+}
+
+// Context 运行的上下文，取消运行时结束
+func (r *Run) Context() context.Context {
+	return r.ctx
+}
+
+// Done 所有任务退出后关闭
+func (r *Run) Done() <-chan struct{} {
+	return r.done
+}
+
+// Err 第一个失败任务的错误（不含取消），Done关闭后有效
+func (r *Run) Err() error {
+	return r.err
+}
+
+// Cancelled 运行是否被取消，Done关闭后有效
+func (r *Run) Cancelled() bool {
+	return r.cancelled
+}
+
+// RunRegistry Agent运行注册表
+// 记录每个正在运行的Agent，同一节点同一时间只允许一个运行
+type RunRegistry struct {
+	mu       sync.Mutex
+	runs     map[string]*Run
+	nodeRuns map[string]string // nodeID -> runID
+}
+
+// InitRunRegistry 初始化全局运行注册表
+func InitRunRegistry() {
+	runRegistryOnce.Do(func() {
+		GlobalRunRegistry = NewRunRegistry()
+	})
+}
+
+// GetRunRegistry 获取全局运行注册表实例
+func GetRunRegistry() *RunRegistry {
+	if GlobalRunRegistry == nil {
+		panic("run registry not initialized, call InitRunRegistry first")
+	}
+	return GlobalRunRegistry
+}
+
+// NewRunRegistry 创建运行注册表
+func NewRunRegistry() *RunRegistry {
+	return &RunRegistry{
+		runs:     make(map[string]*Run),
+		nodeRuns: make(map[string]string),
+	}
+}
+
+// Start 注册并启动一次运行
+// 运行上下文与请求上下文分离（请求结束后gin.Context会被复用），但保留请求中设置的键值（user_id等），
+// 并额外设置 mapID、nodeID、operation、runID 供工具调用使用
+func (rr *RunRegistry) Start(c *gin.Context, spec RunSpec) (*Run, error) {
+	rr.mu.Lock()
+	if runID, ok := rr.nodeRuns[spec.NodeID]; ok {
+		rr.mu.Unlock()
+		logger.Warn("node already has a running agent", zap.String("nodeID", spec.NodeID), zap.String("runID", runID))
+		return nil, comm.ErrRunConflict
+	}
+
+	keys := map[string]any{}
+	if c != nil {
+		for k, v := range c.Keys {
+			if key, ok := k.(string); ok {
+				keys[key] = v
+			}
+		}
+	}
+	for k, v := range spec.Values {
+		keys[k] = v
+	}
+	run := &Run{
+		ID:        spec.ID,
+		MapID:     spec.MapID,
+		NodeID:    spec.NodeID,
+		Operation: spec.Operation,
+		StartedAt: time.Now(),
+		done:      make(chan struct{}),
+	}
+	if run.ID == "" {
+		run.ID = uuid.NewString()
+	}
+	run.UserID, _ = keys["user_id"].(string)
+	keys["mapID"] = spec.MapID
+	keys["nodeID"] = spec.NodeID
+	keys["operation"] = spec.Operation
+	keys["runID"] = run.ID
+	ctx, cancel := context.WithCancel(context.Background())
+	run.ctx = &runContext{Context: ctx, keys: keys}
+	run.cancel = cancel
+
+	rr.runs[run.ID] = run
+	rr.nodeRuns[spec.NodeID] = run.ID
+	rr.mu.Unlock()
+
+	var (
+		wg    sync.WaitGroup
+		errMu sync.Mutex
+	)
+	setErr := func(err error) {
+		errMu.Lock()
+		defer errMu.Unlock()
+		if run.err == nil {
+			run.err = err
+		}
+	}
+	for _, task := range spec.Tasks {
+		wg.Add(1)
+		go func(task RunTask) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Error("run task panic", zap.String("runID", run.ID), zap.Any("panic", r))
+					setErr(fmt.Errorf("run task panic: %v", r))
+				}
+			}()
+			if err := task(run.ctx); err != nil {
+				if errors.Is(err, context.Canceled) {
+					logger.Info("run task cancelled", zap.String("runID", run.ID), zap.String("operation", run.Operation))
+					return
+				}
+				logger.Error("run task failed", zap.String("runID", run.ID), zap.String("operation", run.Operation), zap.Error(err))
+				setErr(err)
+			}
+		}(task)
+	}
+	go func() {
+		wg.Wait()
+		rr.finish(run, spec.OnCancelled)
+	}()
+	return run, nil
+}
+
+// finish 所有任务退出后注销运行；如果运行被取消，恢复节点状态并通知
+func (rr *RunRegistry) finish(run *Run, onCancelled func(ctx context.Context, run *Run)) {
+	rr.mu.Lock()
+	delete(rr.runs, run.ID)
+	if rr.nodeRuns[run.NodeID] == run.ID {
+		delete(rr.nodeRuns, run.NodeID)
+	}
+	cancelled := run.cancelled
+	rr.mu.Unlock()
+	run.cancel()
+
+	if cancelled && onCancelled != nil {
+		// 运行上下文已取消，使用新的上下文恢复状态
+		onCancelled(context.Background(), run)
+	}
+	close(run.done)
+}
+
+// Cancel 取消运行
+func (rr *RunRegistry) Cancel(runID string) (*Run, error) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	run, ok := rr.runs[runID]
+	if !ok {
+		return nil, comm.ErrRunNotFound
+	}
+	run.cancelled = true
+	run.cancel()
+	return run, nil
+}
+
+// Get 获取运行
+func (rr *RunRegistry) Get(runID string) (*Run, bool) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	run, ok := rr.runs[runID]
+	return run, ok
+}
+
+// GetByNode 获取节点上正在进行的运行
+func (rr *RunRegistry) GetByNode(nodeID string) (*Run, bool) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	runID, ok := rr.nodeRuns[nodeID]
+	if !ok {
+		return nil, false
+	}
+	return rr.runs[runID], true
+}
+
+// ListByMap 获取导图下所有正在进行的运行
+func (rr *RunRegistry) ListByMap(mapID string) []*Run {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	var runs []*Run
+	for _, run := range rr.runs {
+		if run.MapID == mapID {
+			runs = append(runs, run)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].StartedAt.Before(runs[j].StartedAt)
+	})
+	return runs
+}
+
+// runContext 可取消的上下文，同时通过字符串key提供请求中的键值
+type runContext struct {
+	context.Context
+	keys map[string]any
+}
+
+func (c *runContext) Value(key any) any {
+	if k, ok := key.(string); ok {
+		if v, exists := c.keys[k]; exists {
+			return v
+		}
+	}
+	return c.Context.Value(key)
+}
+
+// WithValues 在运行上下文上覆盖字符串key的键值，
+// 用于在同一运行中依次处理多个节点时切换工具调用使用的 nodeID、operation
+func WithValues(ctx context.Context, values map[string]any) context.Context {
+	keys := make(map[string]any, len(values))
+	for k, v := range values {
+		keys[k] = v
+	}
+	return &runContext{Context: ctx, keys: keys}
+}
@@ -0,0 +1,126 @@
+package global
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestRunRegistry() *RunRegistry {
+	if logger.Log == nil {
+		logger.Log = zap.NewNop()
+	}
+	return NewRunRegistry()
+}
+
+func TestRunRegistry_StartAndCancel(t *testing.T) {
+	registry := newTestRunRegistry()
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_id", "user-1")
+
+	mapID := uuid.NewString()
+	nodeID := uuid.NewString()
+	started := make(chan context.Context, 1)
+	cancelled := make(chan string, 1)
+
+	run, err := registry.Start(c, RunSpec{
+		MapID:     mapID,
+		NodeID:    nodeID,
+		Operation: "decomposition",
+		Tasks: []RunTask{
+			func(ctx context.Context) error {
+				started <- ctx
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+		OnCancelled: func(ctx context.Context, run *Run) {
+			assert.NoError(t, ctx.Err())
+			cancelled <- run.ID
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", run.UserID)
+
+	// 运行上下文携带请求键值以及mapID、nodeID、operation
+	ctx := <-started
+	assert.Equal(t, "user-1", ctx.Value("user_id"))
+	assert.Equal(t, mapID, ctx.Value("mapID"))
+	assert.Equal(t, nodeID, ctx.Value("nodeID"))
+	assert.Equal(t, "decomposition", ctx.Value("operation"))
+
+	// 同一节点不允许重复运行
+	_, err = registry.Start(c, RunSpec{MapID: mapID, NodeID: nodeID, Operation: "conclusion"})
+	assert.ErrorIs(t, err, comm.ErrRunConflict)
+
+	got, ok := registry.GetByNode(nodeID)
+	require.True(t, ok)
+	assert.Equal(t, run.ID, got.ID)
+	assert.Len(t, registry.ListByMap(mapID), 1)
+
+	_, err = registry.Cancel(run.ID)
+	require.NoError(t, err)
+	select {
+	case id := <-cancelled:
+		assert.Equal(t, run.ID, id)
+	case <-time.After(time.Second):
+		t.Fatal("OnCancelled not called")
+	}
+	<-run.Done()
+
+	_, ok = registry.GetByNode(nodeID)
+	assert.False(t, ok)
+	_, err = registry.Cancel(run.ID)
+	assert.ErrorIs(t, err, comm.ErrRunNotFound)
+}
+
+func TestRunRegistry_FinishWithoutCancel(t *testing.T) {
+	registry := newTestRunRegistry()
+	nodeID := uuid.NewString()
+	run, err := registry.Start(nil, RunSpec{
+		MapID:  uuid.NewString(),
+		NodeID: nodeID,
+		Tasks: []RunTask{
+			func(ctx context.Context) error { return nil },
+			func(ctx context.Context) error { panic("boom") },
+		},
+		OnCancelled: func(ctx context.Context, run *Run) {
+			t.Error("OnCancelled should not be called")
+		},
+	})
+	require.NoError(t, err)
+	<-run.Done()
+
+	// 运行结束后可以再次在该节点上启动
+	_, ok := registry.GetByNode(nodeID)
+	assert.False(t, ok)
+	assert.Error(t, run.Context().Err())
+	// 任务panic视为失败
+	assert.Error(t, run.Err())
+	assert.False(t, run.Cancelled())
+}
+
+func TestWithValues(t *testing.T) {
+	ctx, cancel := context.WithCancel(&runContext{Context: context.Background(), keys: map[string]any{
+		"user_id": "user-1",
+		"nodeID":  "root",
+	}})
+	stepCtx := WithValues(ctx, map[string]any{"nodeID": "child", "operation": "conclusion"})
+	assert.Equal(t, "child", stepCtx.Value("nodeID"))
+	assert.Equal(t, "conclusion", stepCtx.Value("operation"))
+	assert.Equal(t, "user-1", stepCtx.Value("user_id"))
+	assert.Equal(t, "root", ctx.Value("nodeID"))
+
+	// 取消原上下文时同时取消
+	cancel()
+	assert.ErrorIs(t, stepCtx.Err(), context.Canceled)
+}
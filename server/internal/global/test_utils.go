@@ -154,9 +154,39 @@ func SetupTestEnvironment() (*TestConfig, error) {
 	// 初始化全局消息管理器
 	InitMessageManager(repository.NewMessageRepository(db), repository.NewThinkingNodeRepository(db), repository.NewRAGRecordRepository(db), db)
 
+	// 初始化检索记录仓库
+	InitRAGRecordRepository(repository.NewRAGRecordRepository(db))
+
+	// 初始化思维导图仓库
+	InitThinkingMapRepository(repository.NewThinkingMapRepository(db))
+
 	// 初始化全局节点操作器
 	InitNodeOperator(repository.NewThinkingNodeRepository(db), repository.NewThinkingMapRepository(db))
 
+	// 初始化节点操作策略
+	InitNodePolicy(repository.NewThinkingNodeRepository(db), repository.NewThinkingMapRepository(db), repository.NewNodeChangeRepository(db), redisClient, cfg.NodePolicy)
+
+	// 初始化节点结论摘要生成器
+	InitNodeAbstractor(repository.NewThinkingNodeRepository(db))
+
+	// 初始化节点操作日志
+	InitNodeOperationLog(repository.NewNodeOperationRepository(db))
+
+	// 初始化Agent运行注册表
+	InitRunRegistry()
+
+	// 初始化任务队列
+	InitJobQueue(redisClient, cfg.Queue)
+
+	// 初始化运行检查点存储
+	InitCheckpointStore(repository.NewAgentCheckpointRepository(db))
+
+	// 初始化预算管理器
+	InitBudgetManager(repository.NewAgentUsageRepository(db), cfg.Budget)
+
+	// 初始化知识库（测试环境只使用全文检索）
+	InitKnowledgeBase(repository.NewKnowledgeBaseRepository(db), nil)
+
 	return &TestConfig{
 		DB:    db,
 		Redis: redisClient,
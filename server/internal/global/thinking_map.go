@@ -0,0 +1,17 @@
+package global
+
+import "github.com/PGshen/thinking-map/server/internal/repository"
+
+var (
+	thinkingMapRepo repository.ThinkingMap
+)
+
+// InitThinkingMapRepository 初始化全局思维导图仓库，供工具读取思维导图设置
+func InitThinkingMapRepository(repo repository.ThinkingMap) {
+	thinkingMapRepo = repo
+}
+
+// GetThinkingMapRepository 获取全局思维导图仓库，未初始化时返回nil
+func GetThinkingMapRepository() repository.ThinkingMap {
+	return thinkingMapRepo
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type BranchHandler struct {
+	branchService *service.BranchService
+}
+
+func NewBranchHandler(branchService *service.BranchService) *BranchHandler {
+	return &BranchHandler{
+		branchService: branchService,
+	}
+}
+
+// ListBranches 列出节点拆解或结论对话的所有分支
+func (h *BranchHandler) ListBranches(c *gin.Context) {
+	var query dto.BranchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.error(c, http.StatusBadRequest, "invalid query parameters", err)
+		return
+	}
+	branches, err := h.branchService.ListBranches(c.Request.Context(), c.Param("nodeID"), query.ConversationType)
+	if err != nil {
+		h.serviceError(c, "failed to list branches", err)
+		return
+	}
+	h.success(c, branches)
+}
+
+// GetBranchMessages 获取从根消息到指定消息的分支消息，用于查看非当前分支
+func (h *BranchHandler) GetBranchMessages(c *gin.Context) {
+	var query dto.BranchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.error(c, http.StatusBadRequest, "invalid query parameters", err)
+		return
+	}
+	messages, err := h.branchService.GetBranchMessages(c.Request.Context(), c.Param("nodeID"), query.ConversationType, c.Param("messageID"))
+	if err != nil {
+		h.serviceError(c, "failed to get branch messages", err)
+		return
+	}
+	h.success(c, messages)
+}
+
+// Fork 从指定消息分叉
+func (h *BranchHandler) Fork(c *gin.Context) {
+	var req dto.ForkBranchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.error(c, http.StatusBadRequest, "invalid request parameters", err)
+		return
+	}
+	branches, err := h.branchService.Fork(c.Request.Context(), c.Param("nodeID"), req)
+	if err != nil {
+		h.serviceError(c, "failed to fork branch", err)
+		return
+	}
+	h.success(c, branches)
+}
+
+// Switch 切换当前分支
+func (h *BranchHandler) Switch(c *gin.Context) {
+	var req dto.SwitchBranchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.error(c, http.StatusBadRequest, "invalid request parameters", err)
+		return
+	}
+	branches, err := h.branchService.Switch(c.Request.Context(), c.Param("nodeID"), req)
+	if err != nil {
+		h.serviceError(c, "failed to switch branch", err)
+		return
+	}
+	h.success(c, branches)
+}
+
+// EditMessage 编辑历史用户消息并重新运行
+func (h *BranchHandler) EditMessage(c *gin.Context) {
+	var req dto.EditMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.error(c, http.StatusBadRequest, "invalid request parameters", err)
+		return
+	}
+	resp, err := h.branchService.EditMessage(c.Request.Context(), c.Param("nodeID"), c.GetString("user_id"), c.Param("messageID"), req)
+	if err != nil {
+		h.serviceError(c, "failed to edit message", err)
+		return
+	}
+	h.success(c, resp)
+}
+
+// Regenerate 重新生成助手消息
+func (h *BranchHandler) Regenerate(c *gin.Context) {
+	var req dto.RegenerateMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.error(c, http.StatusBadRequest, "invalid request parameters", err)
+		return
+	}
+	resp, err := h.branchService.Regenerate(c.Request.Context(), c.Param("nodeID"), c.GetString("user_id"), c.Param("messageID"), req)
+	if err != nil {
+		h.serviceError(c, "failed to regenerate message", err)
+		return
+	}
+	h.success(c, resp)
+}
+
+func (h *BranchHandler) serviceError(c *gin.Context, message string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, comm.ErrMessageNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, comm.ErrNotBranchLeaf), errors.Is(err, comm.ErrMessageNotRerunnable):
+		status = http.StatusBadRequest
+	case errors.Is(err, comm.ErrRunConflict):
+		status = http.StatusConflict
+	case errors.Is(err, comm.ErrBudgetExceeded):
+		status = http.StatusTooManyRequests
+	}
+	h.error(c, status, message, err)
+}
+
+func (h *BranchHandler) error(c *gin.Context, status int, message string, err error) {
+	c.JSON(status, dto.Response{
+		Code:      status,
+		Message:   message,
+		Data:      dto.ErrorData{Error: err.Error()},
+		Timestamp: time.Now(),
+		RequestID: uuid.New().String(),
+	})
+}
+
+func (h *BranchHandler) success(c *gin.Context, data interface{}) {
+	c.JSON(http.StatusOK, dto.Response{
+		Code:      http.StatusOK,
+		Message:   "success",
+		Data:      data,
+		Timestamp: time.Now(),
+		RequestID: uuid.New().String(),
+	})
+}
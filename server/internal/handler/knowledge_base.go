@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type KnowledgeBaseHandler struct {
+	kbService *service.KnowledgeBaseService
+}
+
+func NewKnowledgeBaseHandler(kbService *service.KnowledgeBaseService) *KnowledgeBaseHandler {
+	return &KnowledgeBaseHandler{
+		kbService: kbService,
+	}
+}
+
+// UploadDocument 上传文档到思维导图知识库（multipart表单字段 file）
+func (h *KnowledgeBaseHandler) UploadDocument(c *gin.Context) {
+	mapID := c.Param("mapID")
+	file, err := c.FormFile("file")
+	if err != nil {
+		h.error(c, http.StatusBadRequest, "file is required", err)
+		return
+	}
+	if file.Size > service.MaxKBDocumentSize {
+		h.error(c, http.StatusRequestEntityTooLarge, "file too large", fmt.Errorf("file exceeds %d bytes", service.MaxKBDocumentSize))
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		h.error(c, http.StatusBadRequest, "failed to read file", err)
+		return
+	}
+	defer f.Close()
+	content, err := io.ReadAll(io.LimitReader(f, service.MaxKBDocumentSize))
+	if err != nil {
+		h.error(c, http.StatusBadRequest, "failed to read file", err)
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	doc, err := h.kbService.UploadDocument(c.Request.Context(), mapID, userID.(string), file.Filename, content)
+	if err != nil {
+		h.serviceError(c, "failed to upload document", err)
+		return
+	}
+	h.success(c, doc)
+}
+
+// ListDocuments 思维导图知识库中的文档
+func (h *KnowledgeBaseHandler) ListDocuments(c *gin.Context) {
+	docs, err := h.kbService.ListDocuments(c.Request.Context(), c.Param("mapID"))
+	if err != nil {
+		h.serviceError(c, "failed to list documents", err)
+		return
+	}
+	h.success(c, docs)
+}
+
+// DeleteDocument 删除知识库文档
+func (h *KnowledgeBaseHandler) DeleteDocument(c *gin.Context) {
+	if err := h.kbService.DeleteDocument(c.Request.Context(), c.Param("mapID"), c.Param("documentID")); err != nil {
+		h.serviceError(c, "failed to delete document", err)
+		return
+	}
+	h.success(c, nil)
+}
+
+// Search 检索思维导图知识库
+func (h *KnowledgeBaseHandler) Search(c *gin.Context) {
+	var query dto.KBSearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.error(c, http.StatusBadRequest, "invalid query parameters", err)
+		return
+	}
+	hits, err := h.kbService.Search(c.Request.Context(), c.Param("mapID"), query)
+	if err != nil {
+		h.serviceError(c, "failed to search knowledge base", err)
+		return
+	}
+	h.success(c, hits)
+}
+
+func (h *KnowledgeBaseHandler) serviceError(c *gin.Context, message string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, comm.ErrUnsupportedDocument), errors.Is(err, comm.ErrEmptyDocument):
+		status = http.StatusBadRequest
+	case errors.Is(err, comm.ErrKBDocumentNotFound):
+		status = http.StatusNotFound
+	}
+	h.error(c, status, message, err)
+}
+
+func (h *KnowledgeBaseHandler) error(c *gin.Context, status int, message string, err error) {
+	c.JSON(status, dto.Response{
+		Code:      status,
+		Message:   message,
+		Data:      dto.ErrorData{Error: err.Error()},
+		Timestamp: time.Now(),
+		RequestID: uuid.New().String(),
+	})
+}
+
+func (h *KnowledgeBaseHandler) success(c *gin.Context, data interface{}) {
+	c.JSON(http.StatusOK, dto.Response{
+		Code:      http.StatusOK,
+		Message:   "success",
+		Data:      data,
+		Timestamp: time.Now(),
+		RequestID: uuid.New().String(),
+	})
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/mcpserver"
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// MCPHandler 以 streamable HTTP 方式提供思维导图MCP服务，需要用户token认证
+type MCPHandler struct {
+	server *server.StreamableHTTPServer
+}
+
+func NewMCPHandler(mapServer *mcpserver.MapServer) *MCPHandler {
+	// 无状态模式，每个请求单独认证，多实例部署时不需要会话粘滞
+	return &MCPHandler{
+		server: server.NewStreamableHTTPServer(mapServer.MCPServer(), server.WithStateLess(true)),
+	}
+}
+
+// Handle 处理MCP请求，将认证中间件解析出的用户放入请求上下文
+func (h *MCPHandler) Handle(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, dto.Response{
+			Code:      http.StatusUnauthorized,
+			Message:   "unauthorized",
+			Data:      nil,
+			Timestamp: time.Now(),
+			RequestID: uuid.New().String(),
+		})
+		return
+	}
+	ctx := mcpserver.WithUserID(c.Request.Context(), userID)
+	h.server.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+}
@@ -85,7 +85,7 @@ func (h *NodeHandler) CreateNode(c *gin.Context) {
 		})
 		return
 	}
-	resp, err := h.NodeService.CreateNode(c.Request.Context(), mapID, req)
+	resp, err := h.NodeService.CreateNode(c, mapID, req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dto.Response{
 			Code:      http.StatusInternalServerError,
@@ -130,7 +130,7 @@ func (h *NodeHandler) UpdateNode(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.NodeService.UpdateNode(c.Request.Context(), nodeID, req)
+	resp, err := h.NodeService.UpdateNode(c, nodeID, req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dto.Response{
 			Code:      http.StatusInternalServerError,
@@ -243,7 +243,7 @@ func (h *NodeHandler) DeleteNode(c *gin.Context) {
 		})
 		return
 	}
-	if err := h.NodeService.DeleteNode(c.Request.Context(), nodeID); err != nil {
+	if err := h.NodeService.DeleteNode(c, nodeID); err != nil {
 		c.JSON(http.StatusInternalServerError, dto.Response{
 			Code:      http.StatusInternalServerError,
 			Message:   err.Error(),
@@ -389,6 +389,41 @@ func (h *NodeHandler) ResetDecomposition(c *gin.Context) {
 	})
 }
 
+// GetConclusion handles getting a conclusion with its cited sources
+func (h *NodeHandler) GetConclusion(c *gin.Context) {
+	nodeID := c.Param("nodeID")
+	if nodeID == "" {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:      http.StatusBadRequest,
+			Message:   "node ID is required",
+			Data:      nil,
+			Timestamp: time.Now(),
+			RequestID: uuid.New().String(),
+		})
+		return
+	}
+
+	conclusion, err := h.ConclusionService.GetConclusion(c, nodeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:      http.StatusInternalServerError,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now(),
+			RequestID: uuid.New().String(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:      http.StatusOK,
+		Message:   "success",
+		Data:      conclusion,
+		Timestamp: time.Now(),
+		RequestID: uuid.New().String(),
+	})
+}
+
 // SaveConclusion handles saving a conclusion
 func (h *NodeHandler) SaveConclusion(c *gin.Context) {
 	nodeID := c.Param("nodeID")
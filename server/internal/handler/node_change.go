@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type NodeChangeHandler struct {
+	nodeChangeService *service.NodeChangeService
+}
+
+func NewNodeChangeHandler(nodeChangeService *service.NodeChangeService) *NodeChangeHandler {
+	return &NodeChangeHandler{
+		nodeChangeService: nodeChangeService,
+	}
+}
+
+// ListChangesets 列出等待确认的节点变更集
+func (h *NodeChangeHandler) ListChangesets(c *gin.Context) {
+	changesets, err := h.nodeChangeService.ListChangesets(c.Request.Context(), c.Param("mapID"))
+	if err != nil {
+		h.serviceError(c, "failed to list changesets", err)
+		return
+	}
+	h.success(c, changesets)
+}
+
+// AcceptChangeset 接受变更集
+func (h *NodeChangeHandler) AcceptChangeset(c *gin.Context) {
+	changeset, err := h.nodeChangeService.AcceptChangeset(c, c.Param("mapID"), c.Param("runID"))
+	if err != nil {
+		h.serviceError(c, "failed to accept changeset", err)
+		return
+	}
+	h.success(c, changeset)
+}
+
+// RejectChangeset 拒绝变更集
+func (h *NodeChangeHandler) RejectChangeset(c *gin.Context) {
+	changeset, err := h.nodeChangeService.RejectChangeset(c.Request.Context(), c.Param("mapID"), c.Param("runID"))
+	if err != nil {
+		h.serviceError(c, "failed to reject changeset", err)
+		return
+	}
+	h.success(c, changeset)
+}
+
+func (h *NodeChangeHandler) serviceError(c *gin.Context, message string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, comm.ErrChangesetNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, comm.ErrChangesetRunning):
+		status = http.StatusConflict
+	}
+	h.error(c, status, message, err)
+}
+
+func (h *NodeChangeHandler) error(c *gin.Context, status int, message string, err error) {
+	c.JSON(status, dto.Response{
+		Code:      status,
+		Message:   message,
+		Data:      dto.ErrorData{Error: err.Error()},
+		Timestamp: time.Now(),
+		RequestID: uuid.New().String(),
+	})
+}
+
+func (h *NodeChangeHandler) success(c *gin.Context, data interface{}) {
+	c.JSON(http.StatusOK, dto.Response{
+		Code:      http.StatusOK,
+		Message:   "success",
+		Data:      data,
+		Timestamp: time.Now(),
+		RequestID: uuid.New().String(),
+	})
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type NodeOperationHandler struct {
+	nodeOperationService *service.NodeOperationService
+}
+
+func NewNodeOperationHandler(nodeOperationService *service.NodeOperationService) *NodeOperationHandler {
+	return &NodeOperationHandler{
+		nodeOperationService: nodeOperationService,
+	}
+}
+
+// ListOperations 获取思维导图的节点操作日志
+func (h *NodeOperationHandler) ListOperations(c *gin.Context) {
+	history, err := h.nodeOperationService.History(c, c.Param("mapID"))
+	if err != nil {
+		h.serviceError(c, "failed to list operations", err)
+		return
+	}
+	h.success(c, history)
+}
+
+// Undo 撤销最近一次操作
+func (h *NodeOperationHandler) Undo(c *gin.Context) {
+	op, err := h.nodeOperationService.Undo(c, c.Param("mapID"))
+	if err != nil {
+		h.serviceError(c, "failed to undo operation", err)
+		return
+	}
+	h.success(c, op)
+}
+
+// Redo 重做最近一次撤销的操作
+func (h *NodeOperationHandler) Redo(c *gin.Context) {
+	op, err := h.nodeOperationService.Redo(c, c.Param("mapID"))
+	if err != nil {
+		h.serviceError(c, "failed to redo operation", err)
+		return
+	}
+	h.success(c, op)
+}
+
+func (h *NodeOperationHandler) serviceError(c *gin.Context, message string, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, comm.ErrNothingToUndo) || errors.Is(err, comm.ErrNothingToRedo) || errors.Is(err, comm.ErrOperationConflict) || errors.Is(err, comm.ErrOperationBusy) {
+		status = http.StatusConflict
+	}
+	c.JSON(status, dto.Response{
+		Code:      status,
+		Message:   message,
+		Data:      dto.ErrorData{Error: err.Error()},
+		Timestamp: time.Now(),
+		RequestID: uuid.New().String(),
+	})
+}
+
+func (h *NodeOperationHandler) success(c *gin.Context, data interface{}) {
+	c.JSON(http.StatusOK, dto.Response{
+		Code:      http.StatusOK,
+		Message:   "success",
+		Data:      data,
+		Timestamp: time.Now(),
+		RequestID: uuid.New().String(),
+	})
+}
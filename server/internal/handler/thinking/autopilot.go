@@ -0,0 +1,96 @@
+package thinking
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AutopilotHandler handles map autopilot HTTP requests
+type AutopilotHandler struct {
+	autopilotService *service.AutopilotService
+}
+
+// NewAutopilotHandler creates a new autopilot handler
+func NewAutopilotHandler(autopilotService *service.AutopilotService) *AutopilotHandler {
+	return &AutopilotHandler{
+		autopilotService: autopilotService,
+	}
+}
+
+// Start 启动自动驾驶，请求体可为空，此时使用默认限制
+func (h *AutopilotHandler) Start(c *gin.Context) {
+	var req dto.StartAutopilotRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:      http.StatusBadRequest,
+			Message:   "invalid request parameters",
+			Data:      dto.ErrorData{Error: err.Error()},
+			Timestamp: time.Now(),
+			RequestID: uuid.New().String(),
+		})
+		return
+	}
+	resp, err := h.autopilotService.Start(c.Request.Context(), c.Param("mapID"), c.GetString("user_id"), req)
+	h.reply(c, resp, err)
+}
+
+// Get 查询自动驾驶状态
+func (h *AutopilotHandler) Get(c *gin.Context) {
+	resp, err := h.autopilotService.Get(c.Request.Context(), c.Param("mapID"))
+	h.reply(c, resp, err)
+}
+
+// Pause 暂停自动驾驶，当前步骤结束后生效
+func (h *AutopilotHandler) Pause(c *gin.Context) {
+	resp, err := h.autopilotService.Pause(c.Request.Context(), c.Param("mapID"))
+	h.reply(c, resp, err)
+}
+
+// Resume 恢复已暂停的自动驾驶
+func (h *AutopilotHandler) Resume(c *gin.Context) {
+	resp, err := h.autopilotService.Resume(c.Request.Context(), c.Param("mapID"), c.GetString("user_id"))
+	h.reply(c, resp, err)
+}
+
+// Stop 停止自动驾驶
+func (h *AutopilotHandler) Stop(c *gin.Context) {
+	resp, err := h.autopilotService.Stop(c.Request.Context(), c.Param("mapID"))
+	h.reply(c, resp, err)
+}
+
+func (h *AutopilotHandler) reply(c *gin.Context, resp *dto.AutopilotResponse, err error) {
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, comm.ErrAutopilotNotFound), errors.Is(err, comm.ErrThinkingNodeNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, comm.ErrAutopilotConflict), errors.Is(err, comm.ErrAutopilotInvalidState), errors.Is(err, comm.ErrRunConflict):
+			status = http.StatusConflict
+		case errors.Is(err, comm.ErrBudgetExceeded):
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, dto.Response{
+			Code:      status,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now(),
+			RequestID: uuid.NewString(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, dto.Response{
+		Code:      http.StatusOK,
+		Message:   "success",
+		Data:      resp,
+		Timestamp: time.Now(),
+		RequestID: uuid.NewString(),
+	})
+}
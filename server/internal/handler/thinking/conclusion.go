@@ -1,10 +1,12 @@
 package thinking
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
 	"github.com/PGshen/thinking-map/server/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -34,12 +36,29 @@ func (h *ConclusionHandler) Handle(c *gin.Context) {
 		})
 		return
 	}
-	h.conclusionService.Conclusion(c, req)
+	run, err := h.conclusionService.Conclusion(c, req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, comm.ErrRunConflict):
+			status = http.StatusConflict
+		case errors.Is(err, comm.ErrBudgetExceeded):
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, dto.Response{
+			Code:      status,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now(),
+			RequestID: uuid.NewString(),
+		})
+		return
+	}
 	// 响应
 	c.JSON(http.StatusOK, dto.Response{
 		Code:      http.StatusOK,
 		Message:   "success",
-		Data:      nil,
+		Data:      run,
 		Timestamp: time.Now(),
 		RequestID: uuid.NewString(),
 	})
@@ -1,10 +1,12 @@
 package thinking
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
 	"github.com/PGshen/thinking-map/server/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -34,12 +36,75 @@ func (h *DecompositionHandler) Handle(c *gin.Context) {
 		})
 		return
 	}
-	h.decompositionService.Decomposition(c, req)
+	run, err := h.decompositionService.Decomposition(c, req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, comm.ErrRunConflict):
+			status = http.StatusConflict
+		case errors.Is(err, comm.ErrBudgetExceeded):
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, dto.Response{
+			Code:      status,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now(),
+			RequestID: uuid.NewString(),
+		})
+		return
+	}
 	// 响应
 	c.JSON(http.StatusOK, dto.Response{
 		Code:      http.StatusOK,
 		Message:   "success",
-		Data:      nil,
+		Data:      run,
+		Timestamp: time.Now(),
+		RequestID: uuid.NewString(),
+	})
+}
+
+// ReviewPlan handles approving, editing or rejecting a plan awaiting approval
+func (h *DecompositionHandler) ReviewPlan(c *gin.Context) {
+	var req dto.PlanReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:      http.StatusBadRequest,
+			Message:   "invalid request parameters",
+			Data:      dto.ErrorData{Error: err.Error()},
+			Timestamp: time.Now(),
+			RequestID: uuid.New().String(),
+		})
+		return
+	}
+	resp, err := h.decompositionService.ReviewPlan(c.Request.Context(), c.Param("runID"), c.GetString("user_id"), req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, comm.ErrInvalidPlanReview):
+			status = http.StatusBadRequest
+		case errors.Is(err, comm.ErrRunNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, comm.ErrNoPermission):
+			status = http.StatusForbidden
+		case errors.Is(err, comm.ErrPlanNotAwaitingApproval), errors.Is(err, comm.ErrRunConflict):
+			status = http.StatusConflict
+		case errors.Is(err, comm.ErrBudgetExceeded):
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, dto.Response{
+			Code:      status,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now(),
+			RequestID: uuid.NewString(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, dto.Response{
+		Code:      http.StatusOK,
+		Message:   "success",
+		Data:      resp,
 		Timestamp: time.Now(),
 		RequestID: uuid.NewString(),
 	})
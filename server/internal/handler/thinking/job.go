@@ -0,0 +1,65 @@
+package thinking
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// JobHandler handles agent job HTTP requests
+type JobHandler struct {
+	jobService *service.JobService
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(jobService *service.JobService) *JobHandler {
+	return &JobHandler{
+		jobService: jobService,
+	}
+}
+
+// GetJob handles querying the status of a queued agent run
+func (h *JobHandler) GetJob(c *gin.Context) {
+	jobID := c.Param("jobID")
+	if jobID == "" {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:      http.StatusBadRequest,
+			Message:   "job ID is required",
+			Data:      nil,
+			Timestamp: time.Now(),
+			RequestID: uuid.NewString(),
+		})
+		return
+	}
+	job, err := h.jobService.GetJob(c.Request.Context(), jobID, c.GetString("user_id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, comm.ErrJobNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, comm.ErrNoPermission):
+			status = http.StatusForbidden
+		}
+		c.JSON(status, dto.Response{
+			Code:      status,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now(),
+			RequestID: uuid.NewString(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, dto.Response{
+		Code:      http.StatusOK,
+		Message:   "success",
+		Data:      job,
+		Timestamp: time.Now(),
+		RequestID: uuid.NewString(),
+	})
+}
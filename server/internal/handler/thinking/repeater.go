@@ -31,7 +31,7 @@ func (r *RepeaterHandler) Handle(c *gin.Context) (msgID, event string, sr *schem
 		Content: message,
 	})
 	var cm model.ToolCallingChatModel
-	cm, err = llmmodel.NewOpenAIModel(c, nil)
+	cm, err = llmmodel.NewAgentModel(c, llmmodel.AgentRepeater)
 	if err != nil {
 		return
 	}
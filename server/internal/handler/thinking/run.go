@@ -0,0 +1,65 @@
+package thinking
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RunHandler handles agent run HTTP requests
+type RunHandler struct {
+	runService *service.RunService
+}
+
+// NewRunHandler creates a new run handler
+func NewRunHandler(runService *service.RunService) *RunHandler {
+	return &RunHandler{
+		runService: runService,
+	}
+}
+
+// CancelRun handles cancelling a running agent
+func (h *RunHandler) CancelRun(c *gin.Context) {
+	runID := c.Param("runID")
+	if runID == "" {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:      http.StatusBadRequest,
+			Message:   "run ID is required",
+			Data:      nil,
+			Timestamp: time.Now(),
+			RequestID: uuid.NewString(),
+		})
+		return
+	}
+	run, err := h.runService.CancelRun(c.Request.Context(), runID, c.GetString("user_id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, comm.ErrRunNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, comm.ErrNoPermission):
+			status = http.StatusForbidden
+		}
+		c.JSON(status, dto.Response{
+			Code:      status,
+			Message:   err.Error(),
+			Data:      nil,
+			Timestamp: time.Now(),
+			RequestID: uuid.NewString(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, dto.Response{
+		Code:      http.StatusOK,
+		Message:   "success",
+		Data:      run,
+		Timestamp: time.Now(),
+		RequestID: uuid.NewString(),
+	})
+}
@@ -0,0 +1,367 @@
+// Package mcpserver 将思维导图以MCP工具的形式提供给IDE助手等外部Agent
+package mcpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/pkg/sse"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+	"github.com/PGshen/thinking-map/server/internal/service"
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ServerName MCP服务名称
+const ServerName = "thinking-map"
+
+type userIDKey struct{}
+
+// WithUserID 将当前用户放入上下文，工具只能访问该用户的思维导图；
+// 同时以 user_id 提供给节点操作日志，记录操作者
+func WithUserID(ctx context.Context, userID string) context.Context {
+	ctx = global.WithValues(ctx, map[string]any{"user_id": userID})
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+func userIDFromContext(ctx context.Context) (string, error) {
+	userID, _ := ctx.Value(userIDKey{}).(string)
+	if userID == "" {
+		return "", comm.ErrInvalidToken
+	}
+	return userID, nil
+}
+
+// MapServer 思维导图MCP服务
+// 节点的修改通过全局节点操作器完成，并向思维导图的SSE会话发送节点事件，打开的页面可以实时看到变化
+type MapServer struct {
+	mapRepo     repository.ThinkingMap
+	nodeRepo    repository.ThinkingNode
+	nodeService *service.NodeService
+	operator    *global.NodeOperator
+	publish     func(mapID string, event sse.Event)
+}
+
+// NewMapServer 创建思维导图MCP服务，需要先初始化全局节点操作器和SSE broker
+func NewMapServer(mapRepo repository.ThinkingMap, nodeRepo repository.ThinkingNode) *MapServer {
+	return &MapServer{
+		mapRepo:     mapRepo,
+		nodeRepo:    nodeRepo,
+		nodeService: service.NewNodeService(nodeRepo, mapRepo),
+		operator:    global.GetNodeOperator(),
+		publish: func(mapID string, event sse.Event) {
+			global.GetBroker().PublishToSession(mapID, event)
+		},
+	}
+}
+
+// MCPServer 创建注册了思维导图工具的MCP服务器
+func (s *MapServer) MCPServer() *server.MCPServer {
+	svr := server.NewMCPServer(ServerName, "1.0.0", server.WithToolCapabilities(false))
+	svr.AddTool(mcp.NewTool("list_maps",
+		mcp.WithDescription("List the current user's thinking maps"),
+		mcp.WithString("search", mcp.Description("Filter maps whose problem or target contains this text")),
+		mcp.WithNumber("page", mcp.Description("Page number, starting from 1")),
+		mcp.WithNumber("limit", mcp.Description("Page size, 20 by default")),
+	), mcp.NewTypedToolHandler(s.listMaps))
+	svr.AddTool(mcp.NewTool("list_nodes",
+		mcp.WithDescription("List the nodes of a thinking map"),
+		mcp.WithString("mapID", mcp.Required()),
+	), mcp.NewTypedToolHandler(s.listNodes))
+	svr.AddTool(mcp.NewTool("get_node",
+		mcp.WithDescription("Read a node, including its dependent context (ancestors, previous siblings and children)"),
+		mcp.WithString("nodeID", mcp.Required()),
+	), mcp.NewTypedToolHandler(s.getNode))
+	svr.AddTool(mcp.NewTool("create_child_node",
+		mcp.WithDescription("Create a child node under an existing node"),
+		mcp.WithString("parentID", mcp.Required()),
+		mcp.WithString("nodeType", mcp.Required(), mcp.Enum("problem", "information", "analysis", "generation", "evaluation")),
+		mcp.WithString("question", mcp.Required()),
+		mcp.WithString("target"),
+		mcp.WithNumber("x"),
+		mcp.WithNumber("y"),
+	), mcp.NewTypedToolHandler(s.createChildNode))
+	svr.AddTool(mcp.NewTool("set_dependencies",
+		mcp.WithDescription("Replace the dependencies of a node, dependencies must be nodes of the same map"),
+		mcp.WithString("nodeID", mcp.Required()),
+		mcp.WithArray("dependencies", mcp.Required(), mcp.WithStringItems()),
+	), mcp.NewTypedToolHandler(s.setDependencies))
+	svr.AddTool(mcp.NewTool("get_conclusions",
+		mcp.WithDescription("Read the conclusions of a map, or of a single node when nodeID is given"),
+		mcp.WithString("mapID", mcp.Required()),
+		mcp.WithString("nodeID"),
+	), mcp.NewTypedToolHandler(s.getConclusions))
+	return svr
+}
+
+type listMapsArgs struct {
+	Search string `json:"search"`
+	Page   int    `json:"page"`
+	Limit  int    `json:"limit"`
+}
+
+// MapSummary 思维导图摘要
+type MapSummary struct {
+	ID         string `json:"id"`
+	Title      string `json:"title"`
+	Problem    string `json:"problem"`
+	Target     string `json:"target"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion,omitempty"`
+}
+
+func (s *MapServer) listMaps(ctx context.Context, _ mcp.CallToolRequest, args listMapsArgs) (*mcp.CallToolResult, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if args.Page <= 0 {
+		args.Page = 1
+	}
+	if args.Limit <= 0 {
+		args.Limit = 20
+	}
+	maps, total, err := s.mapRepo.List(ctx, userID, "", "", args.Search, time.Time{}, time.Time{}, args.Page, args.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list maps: %w", err)
+	}
+	items := make([]MapSummary, 0, len(maps))
+	for _, m := range maps {
+		items = append(items, MapSummary{
+			ID:         m.ID,
+			Title:      m.Title,
+			Problem:    m.Problem,
+			Target:     m.Target,
+			Status:     m.Status,
+			Conclusion: m.Conclusion,
+		})
+	}
+	return mcp.NewToolResultJSON(map[string]any{"total": total, "items": items})
+}
+
+type mapArgs struct {
+	MapID string `json:"mapID"`
+}
+
+// NodeSummary 节点摘要
+type NodeSummary struct {
+	ID           string   `json:"id"`
+	ParentID     string   `json:"parentID"`
+	NodeType     string   `json:"nodeType"`
+	Question     string   `json:"question"`
+	Status       string   `json:"status"`
+	Dependencies []string `json:"dependencies,omitempty"`
+}
+
+func (s *MapServer) listNodes(ctx context.Context, _ mcp.CallToolRequest, args mapArgs) (*mcp.CallToolResult, error) {
+	if _, err := s.checkMap(ctx, args.MapID); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	nodes, err := s.nodeRepo.FindByMapID(ctx, args.MapID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	items := make([]NodeSummary, 0, len(nodes))
+	for _, n := range nodes {
+		items = append(items, NodeSummary{
+			ID:           n.ID,
+			ParentID:     n.ParentID,
+			NodeType:     n.NodeType,
+			Question:     n.Question,
+			Status:       n.Status,
+			Dependencies: n.Dependencies,
+		})
+	}
+	return mcp.NewToolResultJSON(items)
+}
+
+type nodeArgs struct {
+	NodeID string `json:"nodeID"`
+}
+
+func (s *MapServer) getNode(ctx context.Context, _ mcp.CallToolRequest, args nodeArgs) (*mcp.CallToolResult, error) {
+	node, err := s.checkNode(ctx, args.NodeID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	node.Context = s.nodeService.GetNodeContext(ctx, node)
+	return mcp.NewToolResultJSON(dto.ToNodeResponse(node))
+}
+
+type createChildNodeArgs struct {
+	ParentID string  `json:"parentID"`
+	NodeType string  `json:"nodeType"`
+	Question string  `json:"question"`
+	Target   string  `json:"target"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+}
+
+func (s *MapServer) createChildNode(ctx context.Context, _ mcp.CallToolRequest, args createChildNodeArgs) (*mcp.CallToolResult, error) {
+	if args.Question == "" || args.NodeType == "" {
+		return mcp.NewToolResultError("nodeType and question are required"), nil
+	}
+	parent, err := s.checkNode(ctx, args.ParentID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	resp, err := s.operator.CreateNode(ctx, dto.CreateNodeRequest{
+		MapID:    parent.MapID,
+		ParentID: parent.ID,
+		NodeType: args.NodeType,
+		Question: args.Question,
+		Target:   args.Target,
+		Position: model.Position{X: args.X, Y: args.Y},
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.publish(parent.MapID, sse.Event{
+		ID:   uuid.NewString(),
+		Type: dto.NodeCreatedEventType,
+		Data: dto.NodeCreatedEvent{
+			NodeID:   resp.ID,
+			ParentID: parent.ID,
+			NodeType: resp.NodeType,
+			Question: resp.Question,
+			Target:   resp.Target,
+			Position: resp.Position,
+		},
+	})
+	return mcp.NewToolResultJSON(resp)
+}
+
+type setDependenciesArgs struct {
+	NodeID       string   `json:"nodeID"`
+	Dependencies []string `json:"dependencies"`
+}
+
+func (s *MapServer) setDependencies(ctx context.Context, _ mcp.CallToolRequest, args setDependenciesArgs) (*mcp.CallToolResult, error) {
+	node, err := s.checkNode(ctx, args.NodeID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	dependencies := make([]string, 0, len(args.Dependencies))
+	if len(args.Dependencies) > 0 {
+		nodes, err := s.nodeRepo.FindByIDs(ctx, args.Dependencies)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get dependencies: %w", err)
+		}
+		found := map[string]bool{}
+		for _, n := range nodes {
+			if n != nil && n.MapID == node.MapID {
+				found[n.ID] = true
+			}
+		}
+		for _, id := range args.Dependencies {
+			if id == node.ID {
+				return mcp.NewToolResultError("node cannot depend on itself"), nil
+			}
+			if !found[id] {
+				return mcp.NewToolResultError(fmt.Sprintf("dependency %s is not a node of the same map", id)), nil
+			}
+			dependencies = append(dependencies, id)
+		}
+	}
+	if _, err := s.operator.UpdateNodeDependencies(ctx, node.ID, dependencies); err != nil {
+		return nil, err
+	}
+	s.publish(node.MapID, sse.Event{
+		ID:   uuid.NewString(),
+		Type: dto.NodeDependenciesUpdatedEventType,
+		Data: dto.NodeDependenciesUpdatedEvent{
+			NodeID:       node.ID,
+			Dependencies: dependencies,
+		},
+	})
+	return mcp.NewToolResultJSON(map[string]any{"nodeID": node.ID, "dependencies": dependencies})
+}
+
+type conclusionsArgs struct {
+	MapID  string `json:"mapID"`
+	NodeID string `json:"nodeID"`
+}
+
+// NodeConclusion 节点结论
+type NodeConclusion struct {
+	NodeID     string `json:"nodeID"`
+	Question   string `json:"question"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+}
+
+func (s *MapServer) getConclusions(ctx context.Context, _ mcp.CallToolRequest, args conclusionsArgs) (*mcp.CallToolResult, error) {
+	thinkingMap, err := s.checkMap(ctx, args.MapID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	var nodes []*model.ThinkingNode
+	if args.NodeID != "" {
+		node, err := s.checkNode(ctx, args.NodeID)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if node.MapID != thinkingMap.ID {
+			return mcp.NewToolResultError("node does not belong to map"), nil
+		}
+		nodes = []*model.ThinkingNode{node}
+	} else {
+		if nodes, err = s.nodeRepo.FindByMapID(ctx, args.MapID); err != nil {
+			return nil, fmt.Errorf("failed to list nodes: %w", err)
+		}
+	}
+	conclusions := make([]NodeConclusion, 0, len(nodes))
+	for _, n := range nodes {
+		if args.NodeID == "" && n.Conclusion.Content == "" {
+			continue
+		}
+		conclusions = append(conclusions, NodeConclusion{
+			NodeID:     n.ID,
+			Question:   n.Question,
+			Status:     n.Status,
+			Conclusion: n.Conclusion.Content,
+		})
+	}
+	return mcp.NewToolResultJSON(map[string]any{"mapConclusion": thinkingMap.Conclusion, "nodes": conclusions})
+}
+
+// checkMap 获取思维导图，并检查是否属于当前用户
+func (s *MapServer) checkMap(ctx context.Context, mapID string) (*model.ThinkingMap, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if mapID == "" {
+		return nil, errors.New("mapID is required")
+	}
+	thinkingMap, err := s.mapRepo.FindByID(ctx, mapID)
+	if err != nil {
+		return nil, comm.ErrThinkingMapNotFound
+	}
+	if thinkingMap.UserID != userID {
+		return nil, comm.ErrNoPermission
+	}
+	return thinkingMap, nil
+}
+
+// checkNode 获取节点，并检查所属思维导图是否属于当前用户
+func (s *MapServer) checkNode(ctx context.Context, nodeID string) (*model.ThinkingNode, error) {
+	if nodeID == "" {
+		return nil, errors.New("nodeID is required")
+	}
+	node, err := s.nodeRepo.FindByID(ctx, nodeID)
+	if err != nil {
+		return nil, comm.ErrThinkingNodeNotFound
+	}
+	if _, err := s.checkMap(ctx, node.MapID); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
@@ -0,0 +1,225 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/pkg/sse"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+	"github.com/PGshen/thinking-map/server/internal/service"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMapRepo struct {
+	repository.ThinkingMap
+	maps map[string]*model.ThinkingMap
+}
+
+func (r *fakeMapRepo) FindByID(ctx context.Context, id string) (*model.ThinkingMap, error) {
+	if m, ok := r.maps[id]; ok {
+		return m, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeMapRepo) List(ctx context.Context, userID string, status string, problemType, search string, startTime, endTime time.Time, page, limit int) ([]*model.ThinkingMap, int64, error) {
+	var maps []*model.ThinkingMap
+	for _, m := range r.maps {
+		if m.UserID == userID {
+			maps = append(maps, m)
+		}
+	}
+	return maps, int64(len(maps)), nil
+}
+
+type fakeNodeRepo struct {
+	repository.ThinkingNode
+	mu    sync.Mutex
+	nodes map[string]*model.ThinkingNode
+}
+
+func (r *fakeNodeRepo) Create(ctx context.Context, node *model.ThinkingNode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nodes[node.ID] = node
+	return nil
+}
+
+func (r *fakeNodeRepo) Update(ctx context.Context, node *model.ThinkingNode) error {
+	return r.Create(ctx, node)
+}
+
+func (r *fakeNodeRepo) FindByID(ctx context.Context, id string) (*model.ThinkingNode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n, ok := r.nodes[id]; ok {
+		copied := *n
+		return &copied, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeNodeRepo) FindByIDs(ctx context.Context, ids []string) ([]*model.ThinkingNode, error) {
+	var nodes []*model.ThinkingNode
+	for _, id := range ids {
+		if n, err := r.FindByID(ctx, id); err == nil {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes, nil
+}
+
+func (r *fakeNodeRepo) FindByMapID(ctx context.Context, mapID string) ([]*model.ThinkingNode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var nodes []*model.ThinkingNode
+	for _, n := range r.nodes {
+		if n.MapID == mapID {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes, nil
+}
+
+func (r *fakeNodeRepo) FindByParentID(ctx context.Context, parentID string) ([]*model.ThinkingNode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var nodes []*model.ThinkingNode
+	for _, n := range r.nodes {
+		if n.ParentID == parentID {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes, nil
+}
+
+const (
+	testUserID = "user-1"
+	testMapID  = "map-1"
+	rootID     = "root-1"
+)
+
+func newTestClient(t *testing.T) (*client.Client, *[]sse.Event) {
+	t.Helper()
+	mapRepo := &fakeMapRepo{maps: map[string]*model.ThinkingMap{
+		testMapID: {ID: testMapID, UserID: testUserID, Title: "map", Problem: "problem", Conclusion: "map conclusion"},
+		"map-2":   {ID: "map-2", UserID: "user-2", Title: "other"},
+	}}
+	nodeRepo := &fakeNodeRepo{nodes: map[string]*model.ThinkingNode{
+		rootID: {ID: rootID, MapID: testMapID, NodeType: "root", Question: "root question",
+			Context:    model.DependentContext{Children: []model.NodeContext{{Question: "child"}}},
+			Conclusion: model.Conclusion{Content: "root conclusion"}},
+		"other-node": {ID: "other-node", MapID: "map-2", NodeType: "root", Question: "other"},
+	}}
+	var events []sse.Event
+	mapServer := &MapServer{
+		mapRepo:     mapRepo,
+		nodeRepo:    nodeRepo,
+		nodeService: service.NewNodeService(nodeRepo, mapRepo),
+		operator:    global.NewNodeOperator(nodeRepo, mapRepo),
+		publish: func(mapID string, event sse.Event) {
+			events = append(events, event)
+		},
+	}
+	cli, err := client.NewInProcessClient(mapServer.MCPServer())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cli.Close() })
+	ctx := context.Background()
+	require.NoError(t, cli.Start(ctx))
+	req := mcp.InitializeRequest{}
+	req.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	_, err = cli.Initialize(ctx, req)
+	require.NoError(t, err)
+	return cli, &events
+}
+
+func callTool(t *testing.T, ctx context.Context, cli *client.Client, name string, args map[string]any) (*mcp.CallToolResult, string) {
+	t.Helper()
+	req := mcp.CallToolRequest{}
+	req.Params.Name = name
+	req.Params.Arguments = args
+	result, err := cli.CallTool(ctx, req)
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Content)
+	text, ok := result.Content[0].(mcp.TextContent)
+	require.True(t, ok)
+	return result, text.Text
+}
+
+func TestMapServer(t *testing.T) {
+	cli, events := newTestClient(t)
+	ctx := WithUserID(context.Background(), testUserID)
+
+	_, text := callTool(t, ctx, cli, "list_maps", nil)
+	assert.Contains(t, text, testMapID)
+	assert.NotContains(t, text, "map-2")
+
+	_, text = callTool(t, ctx, cli, "get_node", map[string]any{"nodeID": rootID})
+	assert.Contains(t, text, `"children":[{"question":"child"`)
+
+	// 创建子节点并发送节点创建事件
+	_, text = callTool(t, ctx, cli, "create_child_node", map[string]any{
+		"parentID": rootID, "nodeType": "analysis", "question": "sub question",
+	})
+	var created struct {
+		ID       string `json:"id"`
+		ParentID string `json:"parentID"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(text), &created))
+	assert.Equal(t, rootID, created.ParentID)
+	require.Len(t, *events, 1)
+
+	// 读取节点时补全祖先、依赖和子节点上下文
+	_, text = callTool(t, ctx, cli, "get_node", map[string]any{"nodeID": created.ID})
+	var node struct {
+		NodeID  string `json:"nodeID"`
+		Context struct {
+			Ancestor []struct {
+				Question string `json:"question"`
+			} `json:"ancestor"`
+		} `json:"context"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(text), &node))
+	assert.Equal(t, created.ID, node.NodeID)
+	require.Len(t, node.Context.Ancestor, 1)
+	assert.Equal(t, "root question", node.Context.Ancestor[0].Question)
+
+	_, text = callTool(t, ctx, cli, "set_dependencies", map[string]any{"nodeID": created.ID, "dependencies": []string{rootID}})
+	assert.Contains(t, text, rootID)
+	require.Len(t, *events, 2)
+	result, _ := callTool(t, ctx, cli, "set_dependencies", map[string]any{"nodeID": created.ID, "dependencies": []string{"other-node"}})
+	assert.True(t, result.IsError)
+
+	_, text = callTool(t, ctx, cli, "get_conclusions", map[string]any{"mapID": testMapID})
+	assert.Contains(t, text, "map conclusion")
+	assert.Contains(t, text, "root conclusion")
+	assert.NotContains(t, text, "sub question")
+}
+
+func TestMapServer_Permission(t *testing.T) {
+	cli, events := newTestClient(t)
+
+	// 没有用户
+	result, _ := callTool(t, context.Background(), cli, "list_maps", nil)
+	assert.True(t, result.IsError)
+
+	// 其他用户的思维导图
+	ctx := WithUserID(context.Background(), testUserID)
+	result, text := callTool(t, ctx, cli, "get_node", map[string]any{"nodeID": "other-node"})
+	assert.True(t, result.IsError)
+	assert.Contains(t, text, "no permission")
+	result, _ = callTool(t, ctx, cli, "create_child_node", map[string]any{
+		"parentID": "other-node", "nodeType": "analysis", "question": "q",
+	})
+	assert.True(t, result.IsError)
+	assert.Empty(t, *events)
+}
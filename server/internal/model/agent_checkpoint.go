@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// AgentCheckpoint 多智能体运行检查点，每个节点执行完成后保存一次状态
+type AgentCheckpoint struct {
+	SerialID  int64          `gorm:"primaryKey;autoIncrement;column:serial_id" json:"-"`
+	ID        string         `gorm:"type:uuid;uniqueIndex" json:"id"`
+	RunID     string         `gorm:"type:varchar(64);not null;index" json:"runID"`
+	NodeKey   string         `gorm:"type:varchar(64);not null" json:"nodeKey"`
+	State     datatypes.JSON `gorm:"type:jsonb;not null" json:"state"`
+	CreatedAt time.Time      `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"createdAt"`
+}
+
+func (c *AgentCheckpoint) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.NewString()
+	}
+	return nil
+}
+
+func (AgentCheckpoint) TableName() string {
+	return "agent_checkpoints"
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AgentUsage Agent运行的资源用量，每次运行结束后记录一条，用于统计思维导图、用户维度的累计预算
+type AgentUsage struct {
+	SerialID         int64     `gorm:"primaryKey;autoIncrement;column:serial_id" json:"-"`
+	ID               string    `gorm:"type:uuid;uniqueIndex" json:"id"`
+	RunID            string    `gorm:"type:varchar(64);not null;index" json:"runID"`
+	MapID            string    `gorm:"type:uuid;index" json:"mapID"`
+	UserID           string    `gorm:"type:uuid;index" json:"userID"`
+	Operation        string    `gorm:"type:varchar(32)" json:"operation"`
+	PromptTokens     int       `gorm:"not null;default:0" json:"promptTokens"`
+	CompletionTokens int       `gorm:"not null;default:0" json:"completionTokens"`
+	TotalTokens      int       `gorm:"not null;default:0" json:"totalTokens"`
+	Cost             float64   `gorm:"not null;default:0" json:"cost"`
+	DurationMs       int64     `gorm:"not null;default:0" json:"durationMs"`
+	CreatedAt        time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP;index" json:"createdAt"`
+}
+
+// UsageSum 累计用量
+type UsageSum struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	Cost             float64
+}
+
+func (u *AgentUsage) BeforeCreate(tx *gorm.DB) error {
+	if u.ID == "" {
+		u.ID = uuid.NewString()
+	}
+	return nil
+}
+
+func (AgentUsage) TableName() string {
+	return "agent_usages"
+}
@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/pkg/autopilot"
+)
+
+// StartAutopilotRequest 启动自动驾驶，未设置（为0）的限制使用配置中的默认值
+type StartAutopilotRequest struct {
+	MaxDepth  int     `json:"maxDepth" binding:"omitempty,min=1"`
+	MaxNodes  int     `json:"maxNodes" binding:"omitempty,min=1"`
+	MaxSteps  int     `json:"maxSteps" binding:"omitempty,min=1"`
+	MaxTokens int     `json:"maxTokens" binding:"omitempty,min=1"`
+	MaxCost   float64 `json:"maxCost" binding:"omitempty,gt=0"`
+}
+
+// AutopilotResponse 自动驾驶状态
+type AutopilotResponse struct {
+	ID            string           `json:"id"`
+	MapID         string           `json:"mapID"`
+	RootNodeID    string           `json:"rootNodeID"`
+	RunID         string           `json:"runID,omitempty"` // 当前执行的运行ID
+	Status        string           `json:"status"`          // running | pausing | paused | stopped | completed | failed
+	Limits        autopilot.Limits `json:"limits"`
+	Steps         int              `json:"steps"`
+	NodesCreated  int              `json:"nodesCreated"`
+	TotalTokens   int              `json:"totalTokens"`
+	Cost          float64          `json:"cost"`
+	CurrentNodeID string           `json:"currentNodeID,omitempty"`
+	StopReason    string           `json:"stopReason,omitempty"`
+	CreatedAt     time.Time        `json:"createdAt"`
+	UpdatedAt     time.Time        `json:"updatedAt"`
+	FinishedAt    *time.Time       `json:"finishedAt,omitempty"`
+}
@@ -0,0 +1,58 @@
+package dto
+
+import "time"
+
+// BranchQuery 查询节点对话分支的参数
+type BranchQuery struct {
+	ConversationType string `form:"conversationType" binding:"required,oneof=decomposition conclusion"`
+}
+
+// ForkBranchRequest 从对话中的某条消息分叉，之后的新消息以该消息为父消息，原有消息保留在原分支
+type ForkBranchRequest struct {
+	ConversationType string `json:"conversationType" binding:"required,oneof=decomposition conclusion"`
+	MessageID        string `json:"messageID" binding:"required,uuid"`
+}
+
+// SwitchBranchRequest 切换当前分支
+type SwitchBranchRequest struct {
+	ConversationType string `json:"conversationType" binding:"required,oneof=decomposition conclusion"`
+	LeafMessageID    string `json:"leafMessageID" binding:"required,uuid"` // 目标分支的最后一条消息
+}
+
+// BranchListResponse 节点对话的所有分支
+type BranchListResponse struct {
+	NodeID           string          `json:"nodeID"`
+	ConversationType string          `json:"conversationType"`
+	ConversationID   string          `json:"conversationID"`
+	ActiveMessageID  string          `json:"activeMessageID"` // 当前分支的最后一条消息，分叉后新消息产生前为分叉点
+	Branches         []MessageBranch `json:"branches"`
+}
+
+// MessageBranch 对话分支，即从根消息到某条叶子消息的路径
+type MessageBranch struct {
+	LeafMessageID string    `json:"leafMessageID"`
+	ForkMessageID string    `json:"forkMessageID,omitempty"` // 分支从该消息分叉出来，主干为空
+	Length        int       `json:"length"`                  // 分支上的消息数量
+	Preview       string    `json:"preview"`                 // 分支上最后一条文本消息的摘要
+	Active        bool      `json:"active"`                  // 是否为当前分支
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// EditMessageRequest 编辑历史用户消息，编辑后的消息作为原消息的兄弟消息保存并重新运行
+type EditMessageRequest struct {
+	ConversationType string `json:"conversationType" binding:"required,oneof=decomposition conclusion"`
+	Text             string `json:"text" binding:"required"`
+}
+
+// RegenerateMessageRequest 重新生成助手消息
+type RegenerateMessageRequest struct {
+	ConversationType string `json:"conversationType" binding:"required,oneof=decomposition conclusion"`
+}
+
+// RerunMessageResponse 编辑或重新生成消息后启动的运行
+type RerunMessageResponse struct {
+	RunResponse
+	SupersededMessageID string `json:"supersededMessageID"` // 被替代的消息
+	ForkMessageID       string `json:"forkMessageID"`       // 分叉点，为空表示根消息
+	MessageID           string `json:"messageID,omitempty"` // 编辑后的用户消息
+}
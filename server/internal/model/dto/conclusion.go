@@ -1,5 +1,7 @@
 package dto
 
+import "github.com/PGshen/thinking-map/server/internal/model"
+
 type ConclusionRequest struct {
 	NodeID      string `json:"nodeID" binding:"required,uuid"`
 	Reference   string `json:"reference"`
@@ -13,3 +15,23 @@ type SaveConclusionRequest struct {
 type ResetConclusionRequest struct {
 	NodeID string `json:"nodeID" binding:"required,uuid"`
 }
+
+// ConclusionResponse 节点结论及其引用来源
+type ConclusionResponse struct {
+	NodeID     string                      `json:"nodeID"`
+	NodeType   string                      `json:"nodeType"`
+	Content    string                      `json:"content"`
+	Abstract   string                      `json:"abstract,omitempty"`   // 结论摘要，后台生成
+	Structured *model.StructuredConclusion `json:"structured,omitempty"` // 结构化结论
+	Payload    *model.ConclusionPayload    `json:"payload,omitempty"`    // 节点类型专属的结构化结论
+	Citations  []CitationSource            `json:"citations"`
+	Unverified int                         `json:"unverified"` // 未通过校验的引用数量
+}
+
+// CitationSource 解析后的引用来源，包含检索结果中的摘要
+type CitationSource struct {
+	model.Citation
+	Source  model.RagSource `json:"source,omitempty"`  // 检索服务提供方
+	Snippet string          `json:"snippet,omitempty"` // 检索结果摘要
+	Favicon string          `json:"favicon,omitempty"`
+}
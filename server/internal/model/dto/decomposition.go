@@ -2,9 +2,10 @@ package dto
 
 // DecompositionRequest represents the request for intent recognition
 type DecompositionRequest struct {
-	NodeID        string `json:"nodeID" binding:"required,uuid"`
-	IsDecomposed  bool   `json:"isDecomposed"`
-	Clarification string `json:"clarification"`
+	NodeID              string `json:"nodeID" binding:"required,uuid"`
+	IsDecomposed        bool   `json:"isDecomposed"`
+	Clarification       string `json:"clarification"`
+	RequirePlanApproval bool   `json:"requirePlanApproval"` // 计划生成后等待用户审批再执行
 }
 
 // DecompositionResponse represents the response from intent recognition
@@ -16,3 +17,10 @@ type DecompositionResponse struct {
 	NextAction   string   `json:"nextAction"`   // 下一步行动
 	RequiredInfo []string `json:"requiredInfo"` // 需要的额外信息
 }
+
+// DecompositionDecision 拆解决策结果，自动驾驶据此决定拆解还是直接总结节点
+type DecompositionDecision struct {
+	ShouldDecompose bool   `json:"shouldDecompose"` // 是否需要继续拆解
+	Strategy        string `json:"strategy"`        // 拆解策略：sequential | parallel | hierarchical | exploratory，不拆解时为空
+	Reason          string `json:"reason"`          // 决策理由
+}
@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+)
+
+// KBDocumentResponse 知识库文档
+type KBDocumentResponse struct {
+	ID          string    `json:"id"`
+	MapID       string    `json:"mapID"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	ChunkCount  int       `json:"chunkCount"`
+	Embedded    bool      `json:"embedded"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// KBSearchQuery 知识库检索参数
+type KBSearchQuery struct {
+	Query string `form:"query" binding:"required,max=1000"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=50"`
+}
+
+// KBSearchHit 知识库检索命中的片段
+type KBSearchHit struct {
+	DocumentID   string  `json:"documentID"`
+	DocumentName string  `json:"documentName"`
+	Seq          int     `json:"seq"`
+	Content      string  `json:"content"`
+	Score        float64 `json:"score"`
+}
+
+func ToKBDocumentResponse(doc *model.KBDocument) KBDocumentResponse {
+	return KBDocumentResponse{
+		ID:          doc.ID,
+		MapID:       doc.MapID,
+		Name:        doc.Name,
+		ContentType: doc.ContentType,
+		Size:        doc.Size,
+		ChunkCount:  doc.ChunkCount,
+		Embedded:    doc.Embedded,
+		CreatedAt:   doc.CreatedAt,
+	}
+}
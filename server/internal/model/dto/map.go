@@ -22,6 +22,10 @@ type CreateMapRequest struct {
 	Target      string            `json:"target" binding:"max=1000"`
 	KeyPoints   model.KeyPoints   `json:"keyPoints"`
 	Constraints model.Constraints `json:"constraints"`
+	// SearchProvider 检索服务提供方，为空时使用全局配置
+	SearchProvider string `json:"searchProvider" binding:"omitempty,oneof=tavily searxng local"`
+	// AutoRollup 子节点全部完成后自动汇总生成父节点结论，根节点完成时写入思维导图结论
+	AutoRollup bool `json:"autoRollup"`
 }
 
 // UpdateMapRequest represents the request body for updating a mind map
@@ -34,6 +38,12 @@ type UpdateMapRequest struct {
 	KeyPoints   model.KeyPoints   `json:"keyPoints"`
 	Constraints model.Constraints `json:"constraints"`
 	Conclusion  string            `json:"conclusion" binding:"max=1000"`
+	// SearchProvider 检索服务提供方，为空时不修改
+	SearchProvider string `json:"searchProvider" binding:"omitempty,oneof=tavily searxng local"`
+	// AutoRollup 自动汇总结论，为空时不修改
+	AutoRollup *bool `json:"autoRollup"`
+	// NodeDryRun Agent节点操作试运行，为空时不修改
+	NodeDryRun *bool `json:"nodeDryRun"`
 }
 
 // MapResponse represents the mind map data in responses
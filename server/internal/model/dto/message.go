@@ -11,13 +11,14 @@ import (
 
 // CreateMessageRequest represents the request body for creating a message
 type CreateMessageRequest struct {
-	ID          string               `json:"ID"`
-	ParentID    string               `json:"parentID" binding:"omitempty,uuid"`
-	UserID      string               `json:"userID" binding:"omitempty,uuid"`
-	MessageType model.MsgType        `json:"messageType" binding:"required,oneof=text rag notice action"`
-	Role        schema.RoleType      `json:"role" binding:"required,oneof=system assistant user"`
-	Content     model.MessageContent `json:"content" binding:"required"`
-	Metadata    interface{}          `json:"metadata"`
+	ID             string               `json:"ID"`
+	ParentID       string               `json:"parentID" binding:"omitempty,uuid"`
+	ConversationID string               `json:"conversationID" binding:"omitempty,uuid"` // 没有父消息时使用的会话ID，为空则创建新会话
+	UserID         string               `json:"userID" binding:"omitempty,uuid"`
+	MessageType    model.MsgType        `json:"messageType" binding:"required,oneof=text rag notice action"`
+	Role           schema.RoleType      `json:"role" binding:"required,oneof=system assistant user"`
+	Content        model.MessageContent `json:"content" binding:"required"`
+	Metadata       interface{}          `json:"metadata"`
 }
 
 // UpdateMessageRequest represents the request body for updating a message
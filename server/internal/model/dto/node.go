@@ -73,6 +73,7 @@ type ExecutableNodesResponse struct {
 func ToNodeResponse(n *model.ThinkingNode) NodeResponse {
 	return NodeResponse{
 		ID:            n.ID,
+		NodeID:        n.ID,
 		MapID:         n.MapID,
 		ParentID:      n.ParentID,
 		NodeType:      n.NodeType,
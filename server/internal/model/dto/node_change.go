@@ -0,0 +1,16 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+)
+
+// NodeChangesetResponse 一次Agent运行在试运行模式下提出的节点变更集
+type NodeChangesetResponse struct {
+	RunID     string              `json:"runID"`
+	MapID     string              `json:"mapID"`
+	NodeID    string              `json:"nodeID"` // 发起运行的节点
+	Changes   []*model.NodeChange `json:"changes"`
+	CreatedAt time.Time           `json:"createdAt"` // 第一项变更提出的时间
+}
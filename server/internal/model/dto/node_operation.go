@@ -0,0 +1,12 @@
+package dto
+
+import (
+	"github.com/PGshen/thinking-map/server/internal/model"
+)
+
+// NodeOperationHistoryResponse 思维导图的节点操作日志及当前可撤销、可重做的操作
+type NodeOperationHistoryResponse struct {
+	Operations []*model.NodeOperation `json:"operations"`     // 按记录顺序
+	Undo       *model.NodeOperation   `json:"undo,omitempty"` // 撤销时将还原的操作
+	Redo       *model.NodeOperation   `json:"redo,omitempty"` // 重做时将重新执行的操作
+}
@@ -0,0 +1,49 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/base/multiagent"
+)
+
+// RunResponse represents a running agent
+type RunResponse struct {
+	RunID     string     `json:"runID"`
+	MapID     string     `json:"mapID"`
+	NodeID    string     `json:"nodeID"`
+	Operation string     `json:"operation"` // decomposition | conclusion | rollup | autopilot
+	Status    string     `json:"status,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+}
+
+// JobResponse represents a queued agent run
+type JobResponse struct {
+	JobID       string     `json:"jobID"`
+	Type        string     `json:"type"`
+	MapID       string     `json:"mapID"`
+	NodeID      string     `json:"nodeID"`
+	Status      string     `json:"status"` // queued | running | retrying | succeeded | failed | cancelled
+	Attempts    int        `json:"attempts"`
+	MaxAttempts int        `json:"maxAttempts"`
+	LastError   string     `json:"lastError,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+	NextRunAt   *time.Time `json:"nextRunAt,omitempty"`
+}
+
+// PlanReviewRequest 审批等待中的计划
+type PlanReviewRequest struct {
+	Action     string                      `json:"action" binding:"required,oneof=approve edit reject"`
+	Operations []*multiagent.OperationData `json:"operations"` // edit时的步骤操作：add | modify | remove | reorder
+	Reason     string                      `json:"reason"`
+}
+
+// PlanReviewResponse 计划审批结果，通过时返回继续执行的运行
+type PlanReviewResponse struct {
+	Status string              `json:"status"` // approved | rejected
+	Plan   multiagent.TaskPlan `json:"plan"`
+	Run    *RunResponse        `json:"run,omitempty"`
+}
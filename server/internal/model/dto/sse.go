@@ -7,6 +7,7 @@
 package dto
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/PGshen/thinking-map/server/internal/agent/base/multiagent"
@@ -32,6 +33,10 @@ const (
   CustomEventType                  = "custom"
   ConclusionCompletedEventType     = "conclusionCompleted"
   DecompositionCompletedEventType  = "decompositionCompleted"
+	RunCancelledEventType            = "runCancelled"
+	MessageSupersededEventType       = "messageSuperseded"
+	NodeChangeProposedEventType      = "nodeChangeProposed"
+	NodeChangesetResolvedEventType   = "nodeChangesetResolved"
 )
 
 type ConnectionEstablishedEvent struct {
@@ -73,7 +78,7 @@ type NodeDependenciesUpdatedEvent struct {
 // ThinkingProgressEvent represents the thinking progress event
 type ThinkingProgressEvent struct {
 	NodeID   string `json:"nodeID"`
-	Stage    string `json:"stage"`
+	Stage    string `json:"stage"` // 自动驾驶：deciding | decomposing | concluding | rolling_up | paused | stopped | completed | failed
 	Progress int    `json:"progress"`
 	Message  string `json:"message"`
 }
@@ -111,6 +116,8 @@ type MessagePlanEvent struct {
 	MessageID string              `json:"messageID"`
 	Plan      multiagent.TaskPlan `json:"plan"`
 	IsEnd     bool                `json:"isEnd"`
+	RunID     string              `json:"runID,omitempty"`
+	Status    string              `json:"status,omitempty"` // awaiting_approval | approved | rejected
 }
 
 type MessageRagEvent struct {
@@ -128,7 +135,7 @@ type ErrorEvent struct {
 
 type ConclusionCompletedEvent struct {
   NodeID string `json:"nodeID"`
-  Mode   string `json:"mode"`   // generate | optimize
+  Mode   string `json:"mode"`   // generate | optimize | rollup | map
   Status string `json:"status"` // completed
 }
 
@@ -138,6 +145,43 @@ type DecompositionCompletedEvent struct {
   Status string `json:"status"` // completed
 }
 
+// RunCancelledEvent represents the agent run cancellation event
+type RunCancelledEvent struct {
+	RunID     string `json:"runID"`
+	NodeID    string `json:"nodeID"`
+	Operation string `json:"operation"` // decomposition | conclusion | rollup | autopilot
+	Status    string `json:"status"`    // 恢复后的节点状态
+}
+
+// MessageSupersededEvent 消息被编辑或重新生成，新版本作为兄弟消息产生，原消息保留在原分支
+type MessageSupersededEvent struct {
+	NodeID           string `json:"nodeID"`
+	ConversationType string `json:"conversationType"`
+	MessageID        string `json:"messageID"`              // 被替代的消息
+	ParentID         string `json:"parentID"`               // 分叉点，新版本以该消息为父消息，为空表示根消息
+	NewMessageID     string `json:"newMessageID,omitempty"` // 编辑后的用户消息，重新生成时新消息由运行产生
+	Mode             string `json:"mode"`                   // edit | regenerate
+	RunID            string `json:"runID"`
+}
+
+// NodeChangeProposedEvent 试运行模式下Agent提出节点变更，等待用户确认
+type NodeChangeProposedEvent struct {
+	RunID    string          `json:"runID"`
+	NodeID   string          `json:"nodeID"` // 发起运行的节点
+	ChangeID string          `json:"changeID"`
+	Op       string          `json:"op"` // create | update | delete | setDependencies
+	TargetID string          `json:"targetID"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// NodeChangesetResolvedEvent 变更集被接受或拒绝
+type NodeChangesetResolvedEvent struct {
+	RunID   string `json:"runID"`
+	Status  string `json:"status"` // applied | rejected
+	Applied int    `json:"applied"`
+	Failed  int    `json:"failed"`
+}
+
 // TestEventRequest represents the request for testing SSE events
 type TestEventRequest struct {
 	EventType string                 `json:"eventType" binding:"required,oneof=nodeCreated nodeUpdated thinkingProgress error custom"`
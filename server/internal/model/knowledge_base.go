@@ -0,0 +1,101 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 知识库文档类型
+const (
+	KBContentText     = "text"
+	KBContentMarkdown = "markdown"
+	KBContentCSV      = "csv"
+)
+
+// KBDocument 思维导图知识库中的文档
+type KBDocument struct {
+	SerialID    int64          `gorm:"primaryKey;autoIncrement;column:serial_id" json:"-"`
+	ID          string         `gorm:"type:uuid;uniqueIndex" json:"id"`
+	MapID       string         `gorm:"type:uuid;not null;index" json:"mapID"`
+	UserID      string         `gorm:"type:uuid;not null" json:"userID"`
+	Name        string         `gorm:"type:varchar(255);not null" json:"name"`
+	ContentType string         `gorm:"type:varchar(32);not null" json:"contentType"` // text | markdown | csv
+	Size        int64          `gorm:"not null;default:0" json:"size"`
+	ChunkCount  int            `gorm:"not null;default:0" json:"chunkCount"`
+	Embedded    bool           `gorm:"not null;default:false" json:"embedded"` // 片段是否已生成向量
+	CreatedAt   time.Time      `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	UpdatedAt   time.Time      `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (d *KBDocument) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == "" {
+		d.ID = uuid.NewString()
+	}
+	return nil
+}
+
+func (KBDocument) TableName() string {
+	return "kb_documents"
+}
+
+// KBChunk 知识库文档切分后的片段
+// Terms 为分词结果，全文检索基于 to_tsvector('simple', terms)，中文按相邻两字切分后也能检索
+type KBChunk struct {
+	SerialID   int64     `gorm:"primaryKey;autoIncrement;column:serial_id" json:"-"`
+	ID         string    `gorm:"type:uuid;uniqueIndex" json:"id"`
+	DocumentID string    `gorm:"type:uuid;not null;index" json:"documentID"`
+	MapID      string    `gorm:"type:uuid;not null;index" json:"mapID"`
+	Seq        int       `gorm:"not null" json:"seq"`
+	Content    string    `gorm:"type:text;not null" json:"content"`
+	Terms      string    `gorm:"type:text;not null" json:"-"`
+	Embedding  Embedding `gorm:"type:jsonb" json:"-"`
+	CreatedAt  time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"createdAt"`
+}
+
+func (c *KBChunk) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.NewString()
+	}
+	return nil
+}
+
+func (KBChunk) TableName() string {
+	return "kb_chunks"
+}
+
+// KBChunkHit 检索命中的片段
+type KBChunkHit struct {
+	KBChunk
+	DocumentName string  `json:"documentName"`
+	Rank         float64 `json:"rank"`
+}
+
+// Embedding 向量
+type Embedding []float64
+
+// Scan implements the Scanner interface for Embedding
+func (e *Embedding) Scan(value interface{}) error {
+	if value == nil {
+		*e = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal JSON value: %v", value)
+	}
+	return json.Unmarshal(bytes, e)
+}
+
+// Value implements the Valuer interface for Embedding
+func (e Embedding) Value() (driver.Value, error) {
+	if len(e) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(e)
+}
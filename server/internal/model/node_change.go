@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// 节点变更操作
+const (
+	NodeChangeOpCreate          = "create"
+	NodeChangeOpUpdate          = "update"
+	NodeChangeOpDelete          = "delete"
+	NodeChangeOpSetDependencies = "setDependencies"
+)
+
+// 节点变更状态
+const (
+	NodeChangeStatusPending  = "pending"  // 等待用户确认
+	NodeChangeStatusApplied  = "applied"  // 已接受并执行
+	NodeChangeStatusRejected = "rejected" // 已拒绝
+	NodeChangeStatusFailed   = "failed"   // 已接受但执行失败
+)
+
+// NodeChange 试运行模式下Agent节点操作工具提出的变更，不直接修改思维导图；
+// 同一次运行提出的变更组成一个变更集（以运行ID标识），由用户整体接受或拒绝，接受时按提出顺序执行
+type NodeChange struct {
+	SerialID  int64          `gorm:"primaryKey;autoIncrement;column:serial_id" json:"-"`
+	ID        string         `gorm:"type:uuid;uniqueIndex" json:"id"`
+	RunID     string         `gorm:"type:varchar(64);not null;index" json:"runID"`
+	MapID     string         `gorm:"type:uuid;not null;index" json:"mapID"`
+	NodeID    string         `gorm:"type:uuid" json:"nodeID"` // 发起运行的节点，创建的节点挂在该节点下
+	Op        string         `gorm:"type:varchar(32);not null" json:"op"`
+	TargetID  string         `gorm:"type:uuid" json:"targetID"`                        // 操作的节点，创建时为预先分配的节点ID
+	Payload   datatypes.JSON `gorm:"type:jsonb;default:'{}'" json:"payload"`           // 工具调用参数
+	Status    string         `gorm:"type:varchar(16);default:'pending'" json:"status"` // pending, applied, rejected, failed
+	Error     string         `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt time.Time      `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	UpdatedAt time.Time      `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"updatedAt"`
+}
+
+func (c *NodeChange) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.NewString()
+	}
+	return nil
+}
+
+func (NodeChange) TableName() string {
+	return "node_changes"
+}
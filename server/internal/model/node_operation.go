@@ -0,0 +1,104 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// 节点操作类型
+const (
+	NodeOpCreate        = "create"
+	NodeOpUpdate        = "update"        // 修改问题、目标
+	NodeOpMove          = "move"          // 修改位置或父节点
+	NodeOpDelete        = "delete"        // 删除节点
+	NodeOpDependencies  = "dependencies"  // 修改依赖关系
+	NodeOpConclusion    = "conclusion"    // 保存、汇总或重置结论
+	NodeOpDecomposition = "decomposition" // 重置拆解
+)
+
+// 操作记录的动作
+const (
+	NodeOpActionApply = "apply" // 用户或Agent执行的操作
+	NodeOpActionUndo  = "undo"  // 撤销 RefID 指向的操作
+	NodeOpActionRedo  = "redo"  // 重做 RefID 指向的操作
+)
+
+// 操作者类型
+const (
+	NodeOpActorUser   = "user"
+	NodeOpActorAgent  = "agent"
+	NodeOpActorSystem = "system"
+)
+
+// NodeOperation 思维导图节点操作日志，只追加不修改；
+// Before、After 为操作前后的节点快照，创建前和删除后为空。撤销和重做也记录为一条操作，以 RefID 指向原操作；
+// 同一次操作涉及多个节点时（如重置拆解）各节点的记录使用相同的 BatchID，撤销和重做时作为整体还原
+type NodeOperation struct {
+	SerialID  int64          `gorm:"primaryKey;autoIncrement;column:serial_id" json:"-"`
+	ID        string         `gorm:"type:uuid;uniqueIndex" json:"id"`
+	MapID     string         `gorm:"type:uuid;not null;index" json:"mapID"`
+	NodeID    string         `gorm:"type:uuid;not null" json:"nodeID"`
+	Op        string         `gorm:"type:varchar(32);not null" json:"op"`
+	Action    string         `gorm:"type:varchar(16);not null;default:'apply'" json:"action"`
+	RefID     string         `gorm:"type:varchar(64)" json:"refID,omitempty"`
+	BatchID   string         `gorm:"type:varchar(64);index" json:"batchID,omitempty"`
+	Before    datatypes.JSON `gorm:"type:jsonb" json:"before"`
+	After     datatypes.JSON `gorm:"type:jsonb" json:"after"`
+	ActorType string         `gorm:"type:varchar(16);not null" json:"actorType"` // user, agent, system
+	ActorID   string         `gorm:"type:varchar(64)" json:"actorID"`            // 用户ID或Agent运行ID
+	CreatedAt time.Time      `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"createdAt"`
+}
+
+func (o *NodeOperation) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == "" {
+		o.ID = uuid.NewString()
+	}
+	return nil
+}
+
+func (NodeOperation) TableName() string {
+	return "node_operations"
+}
+
+// NodeSnapshot 节点快照，节点为空时返回空快照
+func NodeSnapshot(node *ThinkingNode) (datatypes.JSON, error) {
+	if node == nil {
+		return nil, nil
+	}
+	return json.Marshal(node)
+}
+
+// ParseNodeSnapshot 解析节点快照，空快照表示节点不存在，返回nil
+func ParseNodeSnapshot(snapshot datatypes.JSON) (*ThinkingNode, error) {
+	if len(snapshot) == 0 || string(snapshot) == "null" {
+		return nil, nil
+	}
+	var node ThinkingNode
+	if err := json.Unmarshal(snapshot, &node); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// CopyNodeOpFields 将快照中与操作相关的字段写入节点，撤销和重做只恢复这些字段，不影响节点的其他状态
+func CopyNodeOpFields(op string, node, snapshot *ThinkingNode) {
+	switch op {
+	case NodeOpUpdate, NodeOpMove:
+		node.Question = snapshot.Question
+		node.Target = snapshot.Target
+		node.Position = snapshot.Position
+		node.ParentID = snapshot.ParentID
+	case NodeOpDependencies:
+		node.Dependencies = snapshot.Dependencies
+	case NodeOpConclusion:
+		node.Conclusion = snapshot.Conclusion
+		node.Status = snapshot.Status
+	case NodeOpDecomposition:
+		node.Decomposition = snapshot.Decomposition
+		node.Status = snapshot.Status
+	}
+}
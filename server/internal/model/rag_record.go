@@ -20,7 +20,10 @@ import (
 type RagSource string
 
 const (
-	RagTavily RagSource = "tavily"
+	RagTavily  RagSource = "tavily"
+	RagSearXNG RagSource = "searxng"
+	RagLocal   RagSource = "local" // 本地文档索引
+	RagKB      RagSource = "kb"    // 思维导图知识库
 )
 
 // RAGRecord RAG 记录模型
@@ -32,6 +35,7 @@ type RAGRecord struct {
 	Sources   RagSource      `gorm:"type:varchar(128);not null" json:"sources"`
 	Results   Results        `gorm:"type:jsonb;default:'[]'" json:"results"`
 	Metadata  datatypes.JSON `gorm:"type:jsonb;default:'{}'" json:"metadata"`
+	CacheKey  string         `gorm:"type:varchar(64);index" json:"-"` // 检索缓存键，为空时不参与缓存
 	CreatedAt time.Time      `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"createdAt"`
 	UpdatedAt time.Time      `gorm:"type:timestamp;default:CURRENT_TIMESTAMP" json:"updatedAt"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
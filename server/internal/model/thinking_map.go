@@ -48,6 +48,70 @@ func (ThinkingMap) TableName() string {
 	return "thinking_maps"
 }
 
+// Metadata 中的思维导图设置
+const (
+	MapMetaSearchProvider = "searchProvider" // 检索服务提供方，为空时使用全局配置
+	MapMetaAutoRollup     = "autoRollup"     // 自动汇总子节点结论
+	MapMetaNodeDryRun     = "nodeDryRun"     // Agent节点操作试运行，为空时使用全局配置
+)
+
+// MetaString 读取 Metadata 中的字符串字段，不存在时返回空字符串
+func (t *ThinkingMap) MetaString(key string) string {
+	value, _ := metaValue(t.Metadata, key).(string)
+	return value
+}
+
+// MetaBool 读取 Metadata 中的布尔字段，不存在时返回false
+func (t *ThinkingMap) MetaBool(key string) bool {
+	return t.MetaBoolOr(key, false)
+}
+
+// MetaBoolOr 读取 Metadata 中的布尔字段，不存在时返回默认值
+func (t *ThinkingMap) MetaBoolOr(key string, def bool) bool {
+	if value, ok := metaValue(t.Metadata, key).(bool); ok {
+		return value
+	}
+	return def
+}
+
+// SetMeta 设置 Metadata 中的字段，保留其他字段
+func (t *ThinkingMap) SetMeta(key string, value interface{}) error {
+	data, err := setMetaValue(t.Metadata, key, value)
+	if err != nil {
+		return err
+	}
+	t.Metadata = data
+	return nil
+}
+
+// metaValue 读取 Metadata 中的字段，不存在或无法解析时返回nil
+func metaValue(data datatypes.JSON, key string) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	var meta map[string]interface{}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil
+	}
+	return meta[key]
+}
+
+// setMetaValue 返回设置字段后的 Metadata，保留其他字段
+func setMetaValue(data datatypes.JSON, key string, value interface{}) (datatypes.JSON, error) {
+	meta := map[string]interface{}{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+		}
+	}
+	meta[key] = value
+	updated, err := json.Marshal(meta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	return updated, nil
+}
+
 type KeyPoints []string
 
 // 实现Scanner接口
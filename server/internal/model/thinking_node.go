@@ -10,6 +10,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -49,6 +50,34 @@ func (ThinkingNode) TableName() string {
 	return "thinking_nodes"
 }
 
+// Metadata 中的节点信息
+const (
+	NodeMetaUserEdited   = "userEdited"   // 用户手动创建或编辑过的节点，Agent不能删除
+	NodeMetaCreatedByRun = "createdByRun" // 创建节点的Agent运行ID
+)
+
+// MetaString 读取 Metadata 中的字符串字段，不存在时返回空字符串
+func (t *ThinkingNode) MetaString(key string) string {
+	value, _ := metaValue(t.Metadata, key).(string)
+	return value
+}
+
+// MetaBool 读取 Metadata 中的布尔字段，不存在时返回false
+func (t *ThinkingNode) MetaBool(key string) bool {
+	value, _ := metaValue(t.Metadata, key).(bool)
+	return value
+}
+
+// SetMeta 设置 Metadata 中的字段，保留其他字段
+func (t *ThinkingNode) SetMeta(key string, value interface{}) error {
+	data, err := setMetaValue(t.Metadata, key, value)
+	if err != nil {
+		return err
+	}
+	t.Metadata = data
+	return nil
+}
+
 // Position 节点位置信息
 type Position struct {
 	X float64 `json:"x"`
@@ -98,9 +127,163 @@ func (d Decomposition) Value() (driver.Value, error) {
 }
 
 type Conclusion struct {
-	ConversationID string `json:"conversationID"` // 对话ID
-	LastMessageID  string `json:"lastMessageID"`  // 最后一条消息ID
-	Content        string `json:"content"`        // 最终结论
+	ConversationID string     `json:"conversationID"`      // 对话ID
+	LastMessageID  string     `json:"lastMessageID"`       // 最后一条消息ID
+	Content        string     `json:"content"`             // 最终结论
+	Citations      []Citation `json:"citations,omitempty"` // 结论中的编号引用
+	// 从结论正文整理出的结构化结论，整理失败或旧数据为空
+	Structured *StructuredConclusion `json:"structured,omitempty"`
+	// 按节点类型提取的结构化结论，问题节点等没有专属结构的类型为空
+	Payload *ConclusionPayload `json:"payload,omitempty"`
+	// 结论的简短摘要，结论变化后在后台重新生成，结论较短时为空
+	Abstract string `json:"abstract,omitempty"`
+}
+
+// StructuredConclusion 结构化结论，作为节点结论在上下文中传递
+type StructuredConclusion struct {
+	Summary         string             `json:"summary"`         // 结论摘要
+	KeyFindings     []string           `json:"keyFindings"`     // 关键发现
+	Recommendations []string           `json:"recommendations"` // 建议
+	Confidence      float64            `json:"confidence"`      // 置信度，0-1
+	Assumptions     []string           `json:"assumptions"`     // 前提假设
+	OpenQuestions   []string           `json:"openQuestions"`   // 待解决的问题
+	Sources         []ConclusionSource `json:"sources"`         // 参考来源
+}
+
+// ConclusionSource 结构化结论的参考来源
+type ConclusionSource struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// ContextText 结论在Agent上下文中的文本，有结构化结论时使用其渲染结果，否则使用结论正文
+func (c Conclusion) ContextText() string {
+	if c.Structured == nil {
+		return c.Content
+	}
+	return c.Structured.Render()
+}
+
+// AbstractText 结论在上下文中放不下时使用的文本，有摘要时使用摘要，否则使用完整结论
+func (c Conclusion) AbstractText() string {
+	if c.Abstract != "" {
+		return c.Abstract
+	}
+	return c.ContextText()
+}
+
+// Render 将结构化结论渲染为文本
+func (s *StructuredConclusion) Render() string {
+	var b strings.Builder
+	b.WriteString("摘要：" + s.Summary + "\n")
+	writeList := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		b.WriteString(title + "：\n")
+		for _, item := range items {
+			b.WriteString("- " + item + "\n")
+		}
+	}
+	writeList("关键发现", s.KeyFindings)
+	writeList("建议", s.Recommendations)
+	b.WriteString(fmt.Sprintf("置信度：%.2f\n", s.Confidence))
+	writeList("前提假设", s.Assumptions)
+	writeList("待解决问题", s.OpenQuestions)
+	sources := make([]string, 0, len(s.Sources))
+	for _, source := range s.Sources {
+		sources = append(sources, source.Title+" - "+source.URL)
+	}
+	writeList("参考来源", sources)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// ConclusionPayload 节点类型专属的结构化结论，只有与节点类型对应的字段有值
+type ConclusionPayload struct {
+	Information *InformationPayload `json:"information,omitempty"` // 信息收集节点
+	Analysis    *AnalysisPayload    `json:"analysis,omitempty"`    // 分析节点
+	Generation  *GenerationPayload  `json:"generation,omitempty"`  // 生成节点
+	Evaluation  *EvaluationPayload  `json:"evaluation,omitempty"`  // 评估节点
+}
+
+// InformationPayload 信息收集节点的结构化结论
+type InformationPayload struct {
+	KeyFacts []string        `json:"keyFacts"` // 收集到的关键事实
+	Sources  []PayloadSource `json:"sources"`  // 信息来源
+	Gaps     []string        `json:"gaps"`     // 仍然缺失或存疑的信息
+}
+
+// PayloadSource 信息来源
+type PayloadSource struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Summary string `json:"summary"` // 该来源提供的信息摘要
+}
+
+// AnalysisPayload 分析节点的结构化结论
+type AnalysisPayload struct {
+	KeyFactors     []string `json:"keyFactors"`     // 关键因素
+	ReasoningSteps []string `json:"reasoningSteps"` // 推理过程
+	Insights       []string `json:"insights"`       // 分析得出的洞察
+	Uncertainties  []string `json:"uncertainties"`  // 不确定性和前提假设
+}
+
+// GenerationPayload 生成节点的结构化结论
+type GenerationPayload struct {
+	Options     []GeneratedOption `json:"options"`     // 生成的候选方案
+	Recommended string            `json:"recommended"` // 推荐的方案名称
+}
+
+// GeneratedOption 生成的候选方案
+type GeneratedOption struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Pros        []string `json:"pros"`
+	Cons        []string `json:"cons"`
+}
+
+// EvaluationPayload 评估节点的结构化结论
+type EvaluationPayload struct {
+	Criteria       []EvaluationCriterion  `json:"criteria"`       // 评估标准
+	Alternatives   []EvaluatedAlternative `json:"alternatives"`   // 备选方案及评分
+	Recommendation string                 `json:"recommendation"` // 评估结论
+}
+
+// EvaluationCriterion 评估标准
+type EvaluationCriterion struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Weight      float64 `json:"weight"` // 权重，无需归一化
+}
+
+// EvaluatedAlternative 备选方案的评分
+type EvaluatedAlternative struct {
+	Name   string           `json:"name"`
+	Scores []CriterionScore `json:"scores"`
+	Total  float64          `json:"total"` // 按权重计算的加权平均分
+}
+
+// CriterionScore 备选方案在某项标准上的评分
+type CriterionScore struct {
+	Criterion string  `json:"criterion"` // 评估标准名称
+	Score     float64 `json:"score"`     // 1-10分
+	Reason    string  `json:"reason"`
+}
+
+// 引用校验问题
+const (
+	CitationIssueNotRetrieved = "not_retrieved" // 来源不在本节点结论对话的检索结果中
+	CitationIssueNoSource     = "no_source"     // 正文中的编号没有对应的来源
+)
+
+// Citation 结论正文中 [n] 编号对应的来源
+type Citation struct {
+	Index    int    `json:"index"`           // 正文中的编号
+	Title    string `json:"title"`           // 来源标题
+	URL      string `json:"url"`             // 来源地址
+	RagID    string `json:"ragID,omitempty"` // 检索到该来源的RAG记录
+	Verified bool   `json:"verified"`        // 是否为本节点结论对话中检索到的来源
+	Issue    string `json:"issue,omitempty"` // 未通过校验的原因
 }
 
 // Scan implements the Scanner interface for Conclusion
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConclusionContextText(t *testing.T) {
+	conclusion := Conclusion{Content: "结论正文"}
+	assert.Equal(t, "结论正文", conclusion.ContextText())
+
+	conclusion.Structured = &StructuredConclusion{
+		Summary:         "市场持续增长",
+		KeyFindings:     []string{"规模增长30%", "政策支持"},
+		Recommendations: []string{"加大投入"},
+		Confidence:      0.8,
+		OpenQuestions:   []string{"海外市场情况"},
+		Sources:         []ConclusionSource{{Title: "行业报告", URL: "https://example.com/report"}},
+	}
+	assert.Equal(t, `摘要：市场持续增长
+关键发现：
+- 规模增长30%
+- 政策支持
+建议：
+- 加大投入
+置信度：0.80
+待解决问题：
+- 海外市场情况
+参考来源：
+- 行业报告 - https://example.com/report`, conclusion.ContextText())
+}
+
+func TestConclusionAbstractText(t *testing.T) {
+	conclusion := Conclusion{Content: "结论正文"}
+	assert.Equal(t, "结论正文", conclusion.AbstractText())
+	conclusion.Abstract = "结论摘要"
+	assert.Equal(t, "结论摘要", conclusion.AbstractText())
+	assert.Equal(t, "结论正文", conclusion.ContextText())
+}
+
+func TestNodeMeta(t *testing.T) {
+	node := &ThinkingNode{}
+	assert.False(t, node.MetaBool(NodeMetaUserEdited))
+	assert.NoError(t, node.SetMeta(NodeMetaUserEdited, true))
+	assert.NoError(t, node.SetMeta(NodeMetaCreatedByRun, "run-1"))
+	assert.True(t, node.MetaBool(NodeMetaUserEdited))
+	assert.Equal(t, "run-1", node.MetaString(NodeMetaCreatedByRun))
+
+	thinkingMap := &ThinkingMap{}
+	assert.True(t, thinkingMap.MetaBoolOr(MapMetaNodeDryRun, true))
+	assert.NoError(t, thinkingMap.SetMeta(MapMetaNodeDryRun, false))
+	assert.False(t, thinkingMap.MetaBoolOr(MapMetaNodeDryRun, true))
+}
@@ -0,0 +1,231 @@
+package autopilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Status 自动驾驶状态
+type Status string
+
+const (
+	StatusRunning   Status = "running"   // 执行中
+	StatusPausing   Status = "pausing"   // 已请求暂停，当前步骤结束后暂停
+	StatusPaused    Status = "paused"    // 已暂停
+	StatusStopped   Status = "stopped"   // 已停止（用户停止或达到限制）
+	StatusCompleted Status = "completed" // 思维导图已完成
+	StatusFailed    Status = "failed"    // 执行失败
+)
+
+// Active 是否仍占用思维导图（未结束）
+func (s Status) Active() bool {
+	return s == StatusRunning || s == StatusPausing || s == StatusPaused
+}
+
+var (
+	ErrNotFound = errors.New("autopilot not found")
+	// ErrActive 思维导图已有未结束的自动驾驶
+	ErrActive = errors.New("autopilot already active")
+	// ErrConflict 并发更新冲突，重试次数用尽
+	ErrConflict = errors.New("autopilot state update conflict")
+)
+
+// Limits 自动驾驶的限制，零值表示不限制
+type Limits struct {
+	MaxDepth  int     `yaml:"max_depth" mapstructure:"max_depth" json:"maxDepth"`    // 最大拆解深度，根节点深度为0
+	MaxNodes  int     `yaml:"max_nodes" mapstructure:"max_nodes" json:"maxNodes"`    // 思维导图最大节点数，达到后不再拆解
+	MaxSteps  int     `yaml:"max_steps" mapstructure:"max_steps" json:"maxSteps"`    // 最大执行步骤数
+	MaxTokens int     `yaml:"max_tokens" mapstructure:"max_tokens" json:"maxTokens"` // 累计token上限
+	MaxCost   float64 `yaml:"max_cost" mapstructure:"max_cost" json:"maxCost"`       // 累计估算费用上限
+}
+
+// Merge 以override中的非零值覆盖当前限制
+func (l Limits) Merge(override Limits) Limits {
+	if override.MaxDepth > 0 {
+		l.MaxDepth = override.MaxDepth
+	}
+	if override.MaxNodes > 0 {
+		l.MaxNodes = override.MaxNodes
+	}
+	if override.MaxSteps > 0 {
+		l.MaxSteps = override.MaxSteps
+	}
+	if override.MaxTokens > 0 {
+		l.MaxTokens = override.MaxTokens
+	}
+	if override.MaxCost > 0 {
+		l.MaxCost = override.MaxCost
+	}
+	return l
+}
+
+// Config 自动驾驶配置
+type Config struct {
+	Limits   Limits        `yaml:"limits"`                             // 默认限制，启动时可按需覆盖
+	StateTTL time.Duration `yaml:"state_ttl" mapstructure:"state_ttl"` // 状态保留时长
+}
+
+// withDefaults 填充未配置的参数，避免未配置限制时无限拆解
+func (c Config) withDefaults() Config {
+	if c.Limits.MaxDepth <= 0 {
+		c.Limits.MaxDepth = 3
+	}
+	if c.Limits.MaxNodes <= 0 {
+		c.Limits.MaxNodes = 30
+	}
+	if c.Limits.MaxSteps <= 0 {
+		c.Limits.MaxSteps = 60
+	}
+	if c.StateTTL <= 0 {
+		c.StateTTL = 7 * 24 * time.Hour
+	}
+	return c
+}
+
+// State 一次自动驾驶的状态，每个思维导图同一时间只有一个
+type State struct {
+	ID               string     `json:"id"`
+	MapID            string     `json:"mapID"`
+	RootNodeID       string     `json:"rootNodeID"`
+	UserID           string     `json:"userID"`
+	JobID            string     `json:"jobID,omitempty"` // 当前执行的任务ID，暂停后恢复时更新
+	Status           Status     `json:"status"`
+	Limits           Limits     `json:"limits"`
+	Steps            int        `json:"steps"`
+	NodesCreated     int        `json:"nodesCreated"`
+	PromptTokens     int        `json:"promptTokens"`
+	CompletionTokens int        `json:"completionTokens"`
+	TotalTokens      int        `json:"totalTokens"`
+	Cost             float64    `json:"cost"`
+	CurrentNodeID    string     `json:"currentNodeID,omitempty"`
+	StopReason       string     `json:"stopReason,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+	FinishedAt       *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Finish 结束自动驾驶
+func (s *State) Finish(status Status, reason string) {
+	now := time.Now()
+	s.Status = status
+	s.StopReason = reason
+	s.CurrentNodeID = ""
+	s.FinishedAt = &now
+}
+
+// Store 基于redis的自动驾驶状态存储
+//
+// key说明：
+//   - autopilot:<mapID> 思维导图的自动驾驶状态
+type Store struct {
+	client *redis.Client
+	cfg    Config
+}
+
+// NewStore 创建状态存储
+func NewStore(client *redis.Client, cfg Config) *Store {
+	return &Store{client: client, cfg: cfg.withDefaults()}
+}
+
+// DefaultLimits 默认限制（已填充默认值）
+func (s *Store) DefaultLimits() Limits {
+	return s.cfg.Limits
+}
+
+func (s *Store) key(mapID string) string {
+	return "autopilot:" + mapID
+}
+
+// Get 获取思维导图的自动驾驶状态
+func (s *Store) Get(ctx context.Context, mapID string) (*State, error) {
+	return s.get(ctx, s.client, mapID)
+}
+
+func (s *Store) get(ctx context.Context, c redis.Cmdable, mapID string) (*State, error) {
+	data, err := c.Get(ctx, s.key(mapID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// Save 保存状态，覆盖已有状态
+func (s *Store) Save(ctx context.Context, state *State) error {
+	return s.save(ctx, s.client, state)
+}
+
+func (s *Store) save(ctx context.Context, c redis.Cmdable, state *State) error {
+	state.UpdatedAt = time.Now()
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return c.Set(ctx, s.key(state.MapID), data, s.cfg.StateTTL).Err()
+}
+
+// Create 保存新的状态，已有未结束的自动驾驶时返回ErrActive
+// 检查和保存在同一事务中执行，并发创建时只有一个成功
+func (s *Store) Create(ctx context.Context, state *State) error {
+	txf := func(tx *redis.Tx) error {
+		existing, err := s.get(ctx, tx, state.MapID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		if existing != nil && existing.Status.Active() {
+			return ErrActive
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			return s.save(ctx, pipe, state)
+		})
+		return err
+	}
+	for i := 0; i < 10; i++ {
+		err := s.client.Watch(ctx, txf, s.key(state.MapID))
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		return err
+	}
+	return ErrConflict
+}
+
+// Update 读取状态并由fn修改后保存，与其他更新并发时重试（乐观锁）
+// fn返回错误时不保存，并原样返回该错误
+func (s *Store) Update(ctx context.Context, mapID string, fn func(state *State) error) (*State, error) {
+	var result *State
+	txf := func(tx *redis.Tx) error {
+		state, err := s.get(ctx, tx, mapID)
+		if err != nil {
+			return err
+		}
+		if err := fn(state); err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			return s.save(ctx, pipe, state)
+		})
+		if err == nil {
+			result = state
+		}
+		return err
+	}
+	for i := 0; i < 10; i++ {
+		err := s.client.Watch(ctx, txf, s.key(mapID))
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		return result, err
+	}
+	return nil, ErrConflict
+}
@@ -0,0 +1,127 @@
+package autopilot
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStore 连接测试Redis（REDIS_ADDR，默认localhost:6379），不可用时跳过
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: os.Getenv("REDIS_PASSWORD"),
+		DB:       2, // 测试专用数据库
+	})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Skipf("redis not available: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return NewStore(client, Config{StateTTL: time.Minute})
+}
+
+func TestConfig_Defaults(t *testing.T) {
+	cfg := Config{Limits: Limits{MaxDepth: 5}}.withDefaults()
+	assert.Equal(t, 5, cfg.Limits.MaxDepth)
+	assert.Equal(t, 30, cfg.Limits.MaxNodes)
+	assert.Equal(t, 60, cfg.Limits.MaxSteps)
+	assert.Equal(t, 0, cfg.Limits.MaxTokens)
+	assert.Equal(t, 7*24*time.Hour, cfg.StateTTL)
+}
+
+func TestLimits_Merge(t *testing.T) {
+	defaults := Limits{MaxDepth: 3, MaxNodes: 30, MaxSteps: 60, MaxCost: 1}
+	merged := defaults.Merge(Limits{MaxDepth: 2, MaxTokens: 10000})
+	assert.Equal(t, Limits{MaxDepth: 2, MaxNodes: 30, MaxSteps: 60, MaxTokens: 10000, MaxCost: 1}, merged)
+}
+
+func TestStatus_Active(t *testing.T) {
+	assert.True(t, StatusRunning.Active())
+	assert.True(t, StatusPausing.Active())
+	assert.True(t, StatusPaused.Active())
+	assert.False(t, StatusStopped.Active())
+	assert.False(t, StatusCompleted.Active())
+	assert.False(t, StatusFailed.Active())
+}
+
+func TestStore_SaveAndUpdate(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	mapID := uuid.NewString()
+
+	_, err := store.Get(ctx, mapID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = store.Update(ctx, mapID, func(state *State) error { return nil })
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, store.Save(ctx, &State{ID: uuid.NewString(), MapID: mapID, Status: StatusRunning, CreatedAt: time.Now()}))
+	state, err := store.Update(ctx, mapID, func(state *State) error {
+		state.Steps++
+		state.Status = StatusPausing
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, state.Steps)
+
+	// fn返回错误时不保存
+	errInvalid := errors.New("invalid")
+	_, err = store.Update(ctx, mapID, func(state *State) error {
+		state.Steps = 100
+		return errInvalid
+	})
+	assert.ErrorIs(t, err, errInvalid)
+
+	state, err = store.Get(ctx, mapID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPausing, state.Status)
+	assert.Equal(t, 1, state.Steps)
+
+	state.Finish(StatusStopped, "stopped by user")
+	assert.NotNil(t, state.FinishedAt)
+	assert.Equal(t, "stopped by user", state.StopReason)
+}
+
+func TestStore_Create(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	mapID := uuid.NewString()
+
+	// 并发创建时只有一个成功
+	const n = 5
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			errs <- store.Create(ctx, &State{ID: uuid.NewString(), MapID: mapID, Status: StatusRunning, CreatedAt: time.Now()})
+		}()
+	}
+	created := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			created++
+			continue
+		}
+		assert.ErrorIs(t, err, ErrActive)
+	}
+	assert.Equal(t, 1, created)
+
+	// 已结束的自动驾驶可以重新创建
+	_, err := store.Update(ctx, mapID, func(state *State) error {
+		state.Finish(StatusCompleted, "")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.NoError(t, store.Create(ctx, &State{ID: uuid.NewString(), MapID: mapID, Status: StatusRunning, CreatedAt: time.Now()}))
+}
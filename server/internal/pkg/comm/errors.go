@@ -29,8 +29,46 @@ var (
 	ErrNodeDetailNotFound = errors.New("node detail not found")
 
 	// 消息相关错误
-	ErrMessageNotFound = errors.New("message not found")
+	ErrMessageNotFound      = errors.New("message not found")
+	ErrNotBranchLeaf        = errors.New("message is not the last message of a branch")
+	ErrMessageNotRerunnable = errors.New("only user text messages can be edited and only assistant messages can be regenerated")
 
 	// RAG 相关错误
 	ErrRAGRecordNotFound = errors.New("RAG record not found")
+
+	// 知识库相关错误
+	ErrKBDocumentNotFound  = errors.New("knowledge base document not found")
+	ErrUnsupportedDocument = errors.New("unsupported document type")
+	ErrEmptyDocument       = errors.New("document has no content")
+
+	// Agent运行相关错误
+	ErrRunNotFound = errors.New("run not found")
+	ErrRunConflict = errors.New("node already has a running agent")
+	ErrJobNotFound = errors.New("job not found")
+
+	// 预算相关错误
+	ErrBudgetExceeded = errors.New("budget exceeded")
+
+	// 计划审批相关错误
+	ErrPlanNotAwaitingApproval = errors.New("plan is not awaiting approval")
+	ErrInvalidPlanReview       = errors.New("invalid plan review")
+
+	// 节点操作策略相关错误
+	ErrNodeOutOfScope    = errors.New("node does not belong to the current map")
+	ErrNodeCreateLimit   = errors.New("node creation limit of this step reached")
+	ErrNodeDepthLimit    = errors.New("node depth limit reached")
+	ErrNodeProtected     = errors.New("completed or user-edited nodes cannot be deleted")
+	ErrChangesetNotFound = errors.New("no pending changes in changeset")
+	ErrChangesetRunning  = errors.New("run of the changeset has not finished")
+
+	// 操作日志相关错误
+	ErrNothingToUndo     = errors.New("nothing to undo")
+	ErrNothingToRedo     = errors.New("nothing to redo")
+	ErrOperationConflict = errors.New("node has changed since the operation, cannot undo or redo")
+	ErrOperationBusy     = errors.New("another undo or redo of this map is in progress")
+
+	// 自动驾驶相关错误
+	ErrAutopilotNotFound     = errors.New("autopilot not found")
+	ErrAutopilotConflict     = errors.New("map already has an active autopilot")
+	ErrAutopilotInvalidState = errors.New("autopilot is not in a state that allows this operation")
 )
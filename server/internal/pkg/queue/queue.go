@@ -0,0 +1,494 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Status 任务状态
+type Status string
+
+const (
+	StatusQueued    Status = "queued"    // 等待执行
+	StatusRunning   Status = "running"   // 执行中
+	StatusRetrying  Status = "retrying"  // 执行失败，等待重试
+	StatusSucceeded Status = "succeeded" // 执行成功
+	StatusFailed    Status = "failed"    // 重试次数用尽
+	StatusCancelled Status = "cancelled" // 已取消
+)
+
+// Finished 是否为终态
+func (s Status) Finished() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCancelled
+}
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobConflict = errors.New("node already has an active job")
+	ErrJobFinished = errors.New("job already finished")
+	// ErrJobCancelled 任务被取消，处理函数返回该错误时任务标记为已取消，不再重试
+	ErrJobCancelled = errors.New("job cancelled")
+	// ErrWorkerStopped worker停止时作为任务上下文的取消原因，任务会重新入队
+	ErrWorkerStopped = errors.New("worker stopped")
+)
+
+// Config 任务队列配置
+type Config struct {
+	Name              string        `yaml:"name" mapstructure:"name"`                             // 队列名称，用作redis key前缀
+	Workers           int           `yaml:"workers" mapstructure:"workers"`                       // 本进程的worker数量，0表示只入队不消费
+	VisibilityTimeout time.Duration `yaml:"visibility_timeout" mapstructure:"visibility_timeout"` // 可见性超时，超时未续期的任务会被其他worker重新领取
+	MaxAttempts       int           `yaml:"max_attempts" mapstructure:"max_attempts"`             // 最大执行次数
+	BackoffBase       time.Duration `yaml:"backoff_base" mapstructure:"backoff_base"`             // 重试退避基数
+	BackoffMax        time.Duration `yaml:"backoff_max" mapstructure:"backoff_max"`               // 重试退避上限
+	JobTTL            time.Duration `yaml:"job_ttl" mapstructure:"job_ttl"`                       // 任务状态保留时长
+	PollInterval      time.Duration `yaml:"poll_interval" mapstructure:"poll_interval"`           // 空闲时的轮询间隔
+}
+
+// withDefaults 填充未配置的参数
+func (c Config) withDefaults() Config {
+	if c.Name == "" {
+		c.Name = "agent"
+	}
+	if c.VisibilityTimeout <= 0 {
+		c.VisibilityTimeout = time.Minute
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 3
+	}
+	if c.BackoffBase <= 0 {
+		c.BackoffBase = 5 * time.Second
+	}
+	if c.BackoffMax <= 0 {
+		c.BackoffMax = 5 * time.Minute
+	}
+	if c.JobTTL <= 0 {
+		c.JobTTL = 7 * 24 * time.Hour
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = time.Second
+	}
+	return c
+}
+
+// Backoff 第attempt次执行失败后的重试等待时间，指数增长，不超过BackoffMax
+func (c Config) Backoff(attempt int) time.Duration {
+	c = c.withDefaults()
+	d := c.BackoffBase
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= c.BackoffMax {
+			return c.BackoffMax
+		}
+	}
+	return d
+}
+
+// Job 任务
+type Job struct {
+	ID              string          `json:"id"`
+	Type            string          `json:"type"`
+	Payload         json.RawMessage `json:"payload"`
+	MapID           string          `json:"mapID"`
+	NodeID          string          `json:"nodeID"`
+	UserID          string          `json:"userID"`
+	Status          Status          `json:"status"`
+	Attempts        int             `json:"attempts"`
+	MaxAttempts     int             `json:"maxAttempts"`
+	LastError       string          `json:"lastError,omitempty"`
+	CancelRequested bool            `json:"cancelRequested,omitempty"`
+	CreatedAt       time.Time       `json:"createdAt"`
+	UpdatedAt       time.Time       `json:"updatedAt"`
+	StartedAt       *time.Time      `json:"startedAt,omitempty"`
+	FinishedAt      *time.Time      `json:"finishedAt,omitempty"`
+	NextRunAt       *time.Time      `json:"nextRunAt,omitempty"`
+}
+
+// Decode 解析任务参数
+func (j *Job) Decode(v any) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// EnqueueRequest 入队参数
+type EnqueueRequest struct {
+	Type    string
+	MapID   string
+	NodeID  string // 不为空时同一节点同一时间只允许一个未结束的任务
+	UserID  string
+	Payload any
+}
+
+// Queue 基于redis的任务队列，至少一次投递
+//
+// key说明（{name}为hash tag，保证集群模式下落在同一slot）：
+//   - queue:{name}:ready       待执行任务ID列表
+//   - queue:{name}:delayed     等待重试的任务，score为可执行时间
+//   - queue:{name}:processing  执行中的任务，score为可见性截止时间
+//   - queue:{name}:job:<id>    任务状态
+//   - queue:{name}:node:<id>   节点上未结束的任务ID
+//   - queue:{name}:cancel:<id> 取消标记
+//   - queue:{name}:locks:<id>  任务执行中额外占用的节点
+type Queue struct {
+	client *redis.Client
+	cfg    Config
+}
+
+// New 创建任务队列
+func New(client *redis.Client, cfg Config) *Queue {
+	return &Queue{client: client, cfg: cfg.withDefaults()}
+}
+
+// Config 队列配置（已填充默认值）
+func (q *Queue) Config() Config {
+	return q.cfg
+}
+
+func (q *Queue) key(parts ...string) string {
+	key := "queue:{" + q.cfg.Name + "}"
+	for _, p := range parts {
+		key += ":" + p
+	}
+	return key
+}
+
+// Enqueue 任务入队
+func (q *Queue) Enqueue(ctx context.Context, req EnqueueRequest) (*Job, error) {
+	payload, err := json.Marshal(req.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job payload: %w", err)
+	}
+	now := time.Now()
+	job := &Job{
+		ID:          uuid.NewString(),
+		Type:        req.Type,
+		Payload:     payload,
+		MapID:       req.MapID,
+		NodeID:      req.NodeID,
+		UserID:      req.UserID,
+		Status:      StatusQueued,
+		MaxAttempts: q.cfg.MaxAttempts,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if job.NodeID != "" {
+		ok, err := q.client.SetNX(ctx, q.key("node", job.NodeID), job.ID, q.cfg.JobTTL).Result()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrJobConflict
+		}
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+	pipe := q.client.TxPipeline()
+	pipe.Set(ctx, q.key("job", job.ID), data, q.cfg.JobTTL)
+	pipe.LPush(ctx, q.key("ready"), job.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		q.releaseNode(ctx, job)
+		return nil, err
+	}
+	return job, nil
+}
+
+// Get 获取任务，取消标记从 cancel key 读取
+func (q *Queue) Get(ctx context.Context, jobID string) (*Job, error) {
+	pipe := q.client.Pipeline()
+	getJob := pipe.Get(ctx, q.key("job", jobID))
+	cancelFlag := pipe.Exists(ctx, q.key("cancel", jobID))
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	data, err := getJob.Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job %s: %w", jobID, err)
+	}
+	job.CancelRequested = !job.Status.Finished() && cancelFlag.Val() > 0
+	return &job, nil
+}
+
+// ActiveJobID 获取节点上未结束的任务ID
+func (q *Queue) ActiveJobID(ctx context.Context, nodeID string) (string, bool, error) {
+	jobID, err := q.client.Get(ctx, q.key("node", nodeID)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return jobID, true, nil
+}
+
+// LockNode 任务执行中占用其他节点，与入队使用同一个节点占用，节点上有其他未结束的任务时返回false
+// 同一任务重复占用（如重试）视为成功；任务结束时释放所有未释放的占用
+func (q *Queue) LockNode(ctx context.Context, jobID, nodeID string) (bool, error) {
+	ok, err := lockNodeScript.Run(ctx, q.client,
+		[]string{q.key("node", nodeID), q.key("locks", jobID)},
+		jobID, nodeID, q.cfg.JobTTL.Milliseconds(),
+	).Bool()
+	if err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// UnlockNode 释放任务占用的节点（仅当占用者为该任务时）
+func (q *Queue) UnlockNode(ctx context.Context, jobID, nodeID string) error {
+	if err := releaseNodeScript.Run(ctx, q.client, []string{q.key("node", nodeID)}, jobID).Err(); err != nil {
+		return err
+	}
+	return q.client.SRem(ctx, q.key("locks", jobID), nodeID).Err()
+}
+
+var lockNodeScript = redis.NewScript(`
+local owner = redis.call('GET', KEYS[1])
+if owner and owner ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[3])
+redis.call('SADD', KEYS[2], ARGV[2])
+redis.call('PEXPIRE', KEYS[2], ARGV[3])
+return 1
+`)
+
+// Cancel 取消任务
+// 未开始执行的任务直接标记为已取消；执行中的任务只设置取消标记，由worker中断执行后标记，
+// 不回写任务状态，避免覆盖worker同时写入的状态
+func (q *Queue) Cancel(ctx context.Context, jobID string) (*Job, error) {
+	job, err := q.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status.Finished() {
+		return job, ErrJobFinished
+	}
+	pipe := q.client.TxPipeline()
+	removedReady := pipe.LRem(ctx, q.key("ready"), 0, jobID)
+	removedDelayed := pipe.ZRem(ctx, q.key("delayed"), jobID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	if removedReady.Val() > 0 || removedDelayed.Val() > 0 {
+		if err := q.finish(ctx, job, StatusCancelled, ""); err != nil {
+			return nil, err
+		}
+		return job, nil
+	}
+	// 执行中（或刚被领取），由worker处理取消
+	if err := q.client.Set(ctx, q.key("cancel", jobID), 1, q.cfg.JobTTL).Err(); err != nil {
+		return nil, err
+	}
+	job.CancelRequested = true
+	return job, nil
+}
+
+// cancelRequested 是否有取消标记
+func (q *Queue) cancelRequested(ctx context.Context, jobID string) (bool, error) {
+	n, err := q.client.Exists(ctx, q.key("cancel", jobID)).Result()
+	return n > 0, err
+}
+
+// dequeueScript 将到期的重试任务和可见性超时的任务放回待执行列表，再领取一个任务
+var dequeueScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local deadline = tonumber(ARGV[2])
+local due = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', now, 'LIMIT', 0, 100)
+for _, id in ipairs(due) do
+	redis.call('ZREM', KEYS[2], id)
+	redis.call('LPUSH', KEYS[1], id)
+end
+local expired = redis.call('ZRANGEBYSCORE', KEYS[3], '-inf', now, 'LIMIT', 0, 100)
+for _, id in ipairs(expired) do
+	redis.call('ZREM', KEYS[3], id)
+	redis.call('RPUSH', KEYS[1], id)
+end
+local id = redis.call('RPOP', KEYS[1])
+if not id then
+	return false
+end
+redis.call('ZADD', KEYS[3], deadline, id)
+return id
+`)
+
+// dequeue 领取一个任务，没有可执行任务时返回nil
+func (q *Queue) dequeue(ctx context.Context) (*Job, error) {
+	now := time.Now()
+	jobID, err := dequeueScript.Run(ctx, q.client,
+		[]string{q.key("ready"), q.key("delayed"), q.key("processing")},
+		now.UnixMilli(), now.Add(q.cfg.VisibilityTimeout).UnixMilli(),
+	).Text()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	job, err := q.Get(ctx, jobID)
+	if err != nil {
+		if errors.Is(err, ErrJobNotFound) {
+			// 任务状态已过期，丢弃
+			q.client.ZRem(ctx, q.key("processing"), jobID)
+			return nil, nil
+		}
+		return nil, err
+	}
+	if job.Status.Finished() {
+		q.client.ZRem(ctx, q.key("processing"), jobID)
+		return nil, nil
+	}
+	job.Attempts++
+	if job.Attempts > job.MaxAttempts {
+		// 多次可见性超时（worker崩溃）导致次数用尽
+		if err := q.fail(ctx, job, errors.New("visibility timeout exceeded")); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	job.Status = StatusRunning
+	job.StartedAt = &now
+	job.NextRunAt = nil
+	if err := q.save(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// extend 续期可见性超时，返回false表示任务已不属于当前worker
+func (q *Queue) extend(ctx context.Context, jobID string) (bool, error) {
+	deadline := time.Now().Add(q.cfg.VisibilityTimeout).UnixMilli()
+	n, err := q.client.ZAddXX(ctx, q.key("processing"), redis.Z{Score: float64(deadline), Member: jobID}).Result()
+	if err != nil {
+		return false, err
+	}
+	// ZADD XX 更新已有成员时返回0，需要再确认成员是否存在
+	if n == 0 {
+		if _, err := q.client.ZScore(ctx, q.key("processing"), jobID).Result(); err != nil {
+			if errors.Is(err, redis.Nil) {
+				return false, nil
+			}
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// ack 从执行中移除，返回false表示任务已被其他worker重新领取
+func (q *Queue) ack(ctx context.Context, jobID string) (bool, error) {
+	n, err := q.client.ZRem(ctx, q.key("processing"), jobID).Result()
+	return n > 0, err
+}
+
+// complete 任务执行成功
+func (q *Queue) complete(ctx context.Context, job *Job) error {
+	if ok, err := q.ack(ctx, job.ID); err != nil || !ok {
+		return err
+	}
+	return q.finish(ctx, job, StatusSucceeded, "")
+}
+
+// cancelled 任务在执行中被取消
+func (q *Queue) cancelled(ctx context.Context, job *Job) error {
+	if ok, err := q.ack(ctx, job.ID); err != nil || !ok {
+		return err
+	}
+	return q.finish(ctx, job, StatusCancelled, "")
+}
+
+// fail 任务执行失败，未超过最大次数时按退避时间重试
+func (q *Queue) fail(ctx context.Context, job *Job, cause error) error {
+	if ok, err := q.ack(ctx, job.ID); err != nil || !ok {
+		return err
+	}
+	if job.Attempts >= job.MaxAttempts {
+		return q.finish(ctx, job, StatusFailed, cause.Error())
+	}
+	next := time.Now().Add(q.cfg.Backoff(job.Attempts))
+	job.Status = StatusRetrying
+	job.LastError = cause.Error()
+	job.NextRunAt = &next
+	if err := q.save(ctx, job); err != nil {
+		return err
+	}
+	return q.client.ZAdd(ctx, q.key("delayed"), redis.Z{Score: float64(next.UnixMilli()), Member: job.ID}).Err()
+}
+
+// requeue worker停止时将任务放回待执行列表，本次执行不计入次数
+func (q *Queue) requeue(ctx context.Context, job *Job) error {
+	if ok, err := q.ack(ctx, job.ID); err != nil || !ok {
+		return err
+	}
+	job.Attempts--
+	job.Status = StatusQueued
+	if err := q.save(ctx, job); err != nil {
+		return err
+	}
+	return q.client.RPush(ctx, q.key("ready"), job.ID).Err()
+}
+
+// finish 标记终态并释放节点
+func (q *Queue) finish(ctx context.Context, job *Job, status Status, lastError string) error {
+	now := time.Now()
+	job.Status = status
+	job.FinishedAt = &now
+	job.NextRunAt = nil
+	if lastError != "" {
+		job.LastError = lastError
+	}
+	if err := q.save(ctx, job); err != nil {
+		return err
+	}
+	q.client.Del(ctx, q.key("cancel", job.ID))
+	q.releaseNode(ctx, job)
+	q.releaseLocks(ctx, job)
+	return nil
+}
+
+// releaseNode 释放节点上的任务占用（仅当占用者为该任务时）
+func (q *Queue) releaseNode(ctx context.Context, job *Job) {
+	if job.NodeID == "" {
+		return
+	}
+	releaseNodeScript.Run(ctx, q.client, []string{q.key("node", job.NodeID)}, job.ID)
+}
+
+// releaseLocks 释放任务执行中占用且未释放的节点
+func (q *Queue) releaseLocks(ctx context.Context, job *Job) {
+	nodeIDs, err := q.client.SMembers(ctx, q.key("locks", job.ID)).Result()
+	if err != nil {
+		return
+	}
+	for _, nodeID := range nodeIDs {
+		releaseNodeScript.Run(ctx, q.client, []string{q.key("node", nodeID)}, job.ID)
+	}
+	q.client.Del(ctx, q.key("locks", job.ID))
+}
+
+var releaseNodeScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+func (q *Queue) save(ctx context.Context, job *Job) error {
+	job.UpdatedAt = time.Now()
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return q.client.Set(ctx, q.key("job", job.ID), data, q.cfg.JobTTL).Err()
+}
@@ -0,0 +1,229 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newTestQueue 连接测试Redis（REDIS_ADDR，默认localhost:6379），不可用时跳过
+func newTestQueue(t *testing.T, cfg Config) *Queue {
+	t.Helper()
+	if logger.Log == nil {
+		logger.Log = zap.NewNop()
+	}
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: os.Getenv("REDIS_PASSWORD"),
+		DB:       2, // 测试专用数据库
+	})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Skipf("redis not available: %v", err)
+	}
+	// 每个测试使用独立的队列名称
+	cfg.Name = "test-" + uuid.NewString()
+	t.Cleanup(func() { _ = client.Close() })
+	return New(client, cfg)
+}
+
+func TestConfig_Backoff(t *testing.T) {
+	cfg := Config{BackoffBase: time.Second, BackoffMax: 10 * time.Second}
+	assert.Equal(t, time.Second, cfg.Backoff(1))
+	assert.Equal(t, 2*time.Second, cfg.Backoff(2))
+	assert.Equal(t, 4*time.Second, cfg.Backoff(3))
+	assert.Equal(t, 10*time.Second, cfg.Backoff(5))
+	assert.Equal(t, 10*time.Second, cfg.Backoff(100))
+}
+
+func TestQueue_EnqueueAndCancel(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, Config{})
+	nodeID := uuid.NewString()
+
+	job, err := q.Enqueue(ctx, EnqueueRequest{Type: "decomposition", NodeID: nodeID, UserID: "user-1", Payload: map[string]string{"k": "v"}})
+	require.NoError(t, err)
+	assert.Equal(t, StatusQueued, job.Status)
+
+	// 同一节点不允许同时存在多个未结束的任务
+	_, err = q.Enqueue(ctx, EnqueueRequest{Type: "conclusion", NodeID: nodeID})
+	assert.ErrorIs(t, err, ErrJobConflict)
+	activeID, active, err := q.ActiveJobID(ctx, nodeID)
+	require.NoError(t, err)
+	assert.True(t, active)
+	assert.Equal(t, job.ID, activeID)
+
+	// 排队中的任务直接取消并释放节点
+	cancelled, err := q.Cancel(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCancelled, cancelled.Status)
+	_, active, err = q.ActiveJobID(ctx, nodeID)
+	require.NoError(t, err)
+	assert.False(t, active)
+	_, err = q.Cancel(ctx, job.ID)
+	assert.ErrorIs(t, err, ErrJobFinished)
+
+	got, err := q.dequeue(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestQueue_CancelRunning(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, Config{})
+	job, err := q.Enqueue(ctx, EnqueueRequest{Type: "decomposition", NodeID: uuid.NewString()})
+	require.NoError(t, err)
+	running, err := q.dequeue(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, running)
+
+	cancelled, err := q.Cancel(ctx, job.ID)
+	require.NoError(t, err)
+	assert.True(t, cancelled.CancelRequested)
+	got, err := q.Get(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, got.Status)
+	assert.True(t, got.CancelRequested)
+
+	// 取消不回写任务状态，worker持有的状态写入后不丢失取消标记，也不被取消覆盖
+	require.NoError(t, q.complete(ctx, running))
+	got, err = q.Get(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, got.Status)
+	assert.False(t, got.CancelRequested)
+}
+
+func TestQueue_LockNode(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, Config{})
+	owner, err := q.Enqueue(ctx, EnqueueRequest{Type: "autopilot", NodeID: uuid.NewString()})
+	require.NoError(t, err)
+	nodeID := uuid.NewString()
+
+	ok, err := q.LockNode(ctx, owner.ID, nodeID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	// 重复占用视为成功，占用期间不允许入队
+	ok, err = q.LockNode(ctx, owner.ID, nodeID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	_, err = q.Enqueue(ctx, EnqueueRequest{Type: "conclusion", NodeID: nodeID})
+	assert.ErrorIs(t, err, ErrJobConflict)
+	ok, err = q.LockNode(ctx, uuid.NewString(), nodeID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, q.UnlockNode(ctx, owner.ID, nodeID))
+	_, active, err := q.ActiveJobID(ctx, nodeID)
+	require.NoError(t, err)
+	assert.False(t, active)
+
+	// 有任务的节点不能占用；任务结束时释放未释放的占用
+	other, err := q.Enqueue(ctx, EnqueueRequest{Type: "conclusion", NodeID: uuid.NewString()})
+	require.NoError(t, err)
+	ok, err = q.LockNode(ctx, owner.ID, other.NodeID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = q.LockNode(ctx, owner.ID, nodeID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	_, err = q.Cancel(ctx, owner.ID)
+	require.NoError(t, err)
+	_, active, err = q.ActiveJobID(ctx, nodeID)
+	require.NoError(t, err)
+	assert.False(t, active)
+}
+
+func TestQueue_VisibilityTimeout(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueue(t, Config{VisibilityTimeout: 100 * time.Millisecond, MaxAttempts: 2})
+	job, err := q.Enqueue(ctx, EnqueueRequest{Type: "decomposition", NodeID: uuid.NewString()})
+	require.NoError(t, err)
+
+	got, err := q.dequeue(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, 1, got.Attempts)
+
+	// 未续期的任务超时后被重新领取（模拟worker崩溃）
+	got, err = q.dequeue(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	time.Sleep(150 * time.Millisecond)
+	got, err = q.dequeue(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, job.ID, got.ID)
+	assert.Equal(t, 2, got.Attempts)
+
+	// 次数用尽后标记为失败
+	time.Sleep(150 * time.Millisecond)
+	got, err = q.dequeue(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	job, err = q.Get(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, job.Status)
+}
+
+func TestWorker_RetryAndCancel(t *testing.T) {
+	q := newTestQueue(t, Config{BackoffBase: 10 * time.Millisecond, PollInterval: 10 * time.Millisecond, MaxAttempts: 3})
+	ctx := context.Background()
+
+	attempts := 0
+	started := make(chan struct{}, 1)
+	worker := NewWorker(q, 1)
+	worker.Handle("flaky", func(ctx context.Context, job *Job) error {
+		attempts++
+		if attempts < 2 {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	worker.Handle("long", func(ctx context.Context, job *Job) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	worker.Start(ctx)
+	defer worker.Stop()
+
+	// 失败后按退避时间重试
+	flaky, err := q.Enqueue(ctx, EnqueueRequest{Type: "flaky"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		job, err := q.Get(ctx, flaky.ID)
+		return err == nil && job.Status == StatusSucceeded
+	}, 5*time.Second, 20*time.Millisecond)
+	job, err := q.Get(ctx, flaky.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, job.Attempts)
+	assert.Equal(t, "boom", job.LastError)
+
+	// 执行中的任务通过取消标记中断
+	long, err := q.Enqueue(ctx, EnqueueRequest{Type: "long", NodeID: uuid.NewString()})
+	require.NoError(t, err)
+	<-started
+	job, err = q.Cancel(ctx, long.ID)
+	require.NoError(t, err)
+	assert.True(t, job.CancelRequested)
+	require.Eventually(t, func() bool {
+		job, err := q.Get(ctx, long.ID)
+		return err == nil && job.Status == StatusCancelled
+	}, 5*time.Second, 20*time.Millisecond)
+	_, active, err := q.ActiveJobID(ctx, long.NodeID)
+	require.NoError(t, err)
+	assert.False(t, active)
+}
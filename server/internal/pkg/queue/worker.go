@@ -0,0 +1,183 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Handler 任务处理函数
+// ctx在任务被取消（cause为ErrJobCancelled）或worker停止（cause为ErrWorkerStopped）时结束，
+// 返回nil表示成功，返回ErrJobCancelled表示已取消，其他错误按退避时间重试
+type Handler func(ctx context.Context, job *Job) error
+
+// cancelCheckInterval 检查取消标记的间隔
+const cancelCheckInterval = time.Second
+
+// Worker 任务消费者
+type Worker struct {
+	queue    *Queue
+	handlers map[string]Handler
+	count    int
+
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+}
+
+// NewWorker 创建任务消费者，count为并发的worker数量
+func NewWorker(queue *Queue, count int) *Worker {
+	if count <= 0 {
+		count = 1
+	}
+	return &Worker{
+		queue:    queue,
+		handlers: make(map[string]Handler),
+		count:    count,
+	}
+}
+
+// Handle 注册任务处理函数，需在Start之前调用
+func (w *Worker) Handle(jobType string, handler Handler) {
+	w.handlers[jobType] = handler
+}
+
+// Start 启动worker
+func (w *Worker) Start(ctx context.Context) {
+	ctx, w.cancel = context.WithCancelCause(ctx)
+	for i := 0; i < w.count; i++ {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	logger.Info("job worker started", zap.String("queue", w.queue.cfg.Name), zap.Int("workers", w.count))
+}
+
+// Stop 停止worker，执行中的任务被中断并重新入队
+func (w *Worker) Stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel(ErrWorkerStopped)
+	w.wg.Wait()
+	logger.Info("job worker stopped", zap.String("queue", w.queue.cfg.Name))
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		job, err := w.queue.dequeue(ctx)
+		if err != nil && ctx.Err() == nil {
+			logger.Error("dequeue job failed", zap.Error(err))
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(w.queue.cfg.PollInterval):
+			}
+			continue
+		}
+		w.process(ctx, job)
+	}
+}
+
+// process 执行任务，执行期间定期续期可见性超时并检查取消标记
+func (w *Worker) process(ctx context.Context, job *Job) {
+	// 状态更新使用独立上下文，worker停止时也需要写回
+	stateCtx := context.WithoutCancel(ctx)
+	handler, ok := w.handlers[job.Type]
+	if !ok {
+		logger.Error("no handler for job type", zap.String("jobID", job.ID), zap.String("type", job.Type))
+		job.Attempts = job.MaxAttempts
+		if err := w.queue.fail(stateCtx, job, fmt.Errorf("no handler for job type %s", job.Type)); err != nil {
+			logger.Error("mark job failed error", zap.String("jobID", job.ID), zap.Error(err))
+		}
+		return
+	}
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	stopHeartbeat := make(chan struct{})
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		w.heartbeat(jobCtx, job.ID, cancel, stopHeartbeat)
+	}()
+
+	logger.Info("job started", zap.String("jobID", job.ID), zap.String("type", job.Type), zap.Int("attempt", job.Attempts))
+	err := w.safeHandle(jobCtx, handler, job)
+	close(stopHeartbeat)
+	<-heartbeatDone
+
+	cause := context.Cause(jobCtx)
+	switch {
+	case err == nil:
+		logger.Info("job succeeded", zap.String("jobID", job.ID), zap.String("type", job.Type))
+		err = w.queue.complete(stateCtx, job)
+	case errors.Is(cause, ErrWorkerStopped):
+		logger.Info("job interrupted, requeue", zap.String("jobID", job.ID))
+		err = w.queue.requeue(stateCtx, job)
+	case errors.Is(err, ErrJobCancelled) || errors.Is(cause, ErrJobCancelled):
+		logger.Info("job cancelled", zap.String("jobID", job.ID), zap.String("type", job.Type))
+		err = w.queue.cancelled(stateCtx, job)
+	default:
+		logger.Error("job failed", zap.String("jobID", job.ID), zap.String("type", job.Type), zap.Int("attempt", job.Attempts), zap.Error(err))
+		err = w.queue.fail(stateCtx, job, err)
+	}
+	if err != nil {
+		logger.Error("update job status failed", zap.String("jobID", job.ID), zap.Error(err))
+	}
+}
+
+func (w *Worker) safeHandle(ctx context.Context, handler Handler, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job handler panic: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// heartbeat 续期可见性超时并检查取消标记
+func (w *Worker) heartbeat(ctx context.Context, jobID string, cancel context.CancelCauseFunc, stop <-chan struct{}) {
+	extendTicker := time.NewTicker(w.queue.cfg.VisibilityTimeout / 3)
+	defer extendTicker.Stop()
+	cancelTicker := time.NewTicker(cancelCheckInterval)
+	defer cancelTicker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-extendTicker.C:
+			owned, err := w.queue.extend(ctx, jobID)
+			if err != nil {
+				logger.Warn("extend job visibility failed", zap.String("jobID", jobID), zap.Error(err))
+				continue
+			}
+			if !owned {
+				logger.Warn("job visibility lost", zap.String("jobID", jobID))
+			}
+		case <-cancelTicker.C:
+			requested, err := w.queue.cancelRequested(ctx, jobID)
+			if err != nil {
+				logger.Warn("check job cancel failed", zap.String("jobID", jobID), zap.Error(err))
+				continue
+			}
+			if requested {
+				cancel(ErrJobCancelled)
+				return
+			}
+		}
+	}
+}
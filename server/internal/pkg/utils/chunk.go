@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strings"
+)
+
+// ChunkText 按空行切分文本，相邻段落合并到不超过 size 个字符，超长段落按字符截断
+func ChunkText(content string, size int) []string {
+	var chunks []string
+	var builder strings.Builder
+	length := 0
+	flush := func() {
+		if text := strings.TrimSpace(builder.String()); text != "" {
+			chunks = append(chunks, text)
+		}
+		builder.Reset()
+		length = 0
+	}
+	for _, paragraph := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		runes := []rune(paragraph)
+		if length > 0 && length+len(runes) > size {
+			flush()
+		}
+		for len(runes) > size {
+			chunks = append(chunks, string(runes[:size]))
+			runes = runes[size:]
+		}
+		if length > 0 {
+			builder.WriteString("\n\n")
+		}
+		builder.WriteString(string(runes))
+		length += len(runes)
+	}
+	flush()
+	return chunks
+}
+
+// ChunkCSV 按行切分CSV，每个片段以表头开头，行内容合并到不超过 size 个字符
+func ChunkCSV(content string, size int) ([]string, error) {
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse csv: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	header := strings.Join(records[0], ", ")
+	var chunks []string
+	var builder strings.Builder
+	flush := func() {
+		if builder.Len() > 0 {
+			chunks = append(chunks, header+"\n"+builder.String())
+			builder.Reset()
+		}
+	}
+	for _, record := range records[1:] {
+		row := strings.Join(record, ", ")
+		if builder.Len() > 0 && len([]rune(header))+len([]rune(builder.String()))+len([]rune(row)) > size {
+			flush()
+		}
+		if builder.Len() > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(row)
+	}
+	flush()
+	return chunks, nil
+}
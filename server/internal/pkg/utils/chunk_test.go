@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChunkText(t *testing.T) {
+	content := "first paragraph\n\nsecond paragraph\r\n\r\n" + strings.Repeat("x", 25)
+	chunks := ChunkText(content, 20)
+	want := []string{"first paragraph", "second paragraph", strings.Repeat("x", 20), "xxxxx"}
+	if len(chunks) != len(want) {
+		t.Fatalf("ChunkText() = %q, want %q", chunks, want)
+	}
+	for i := range want {
+		if chunks[i] != want[i] {
+			t.Errorf("chunk %d = %q, want %q", i, chunks[i], want[i])
+		}
+	}
+
+	if chunks := ChunkText("a\n\nb", 100); len(chunks) != 1 || chunks[0] != "a\n\nb" {
+		t.Errorf("ChunkText() = %q, want merged paragraphs", chunks)
+	}
+}
+
+func TestChunkCSV(t *testing.T) {
+	chunks, err := ChunkCSV("name,score\nalice,1\nbob,2\ncarol,3\n", 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("ChunkCSV() = %q, want 2 chunks", chunks)
+	}
+	for _, chunk := range chunks {
+		if !strings.HasPrefix(chunk, "name, score\n") {
+			t.Errorf("chunk %q does not start with header", chunk)
+		}
+	}
+
+	if _, err := ChunkCSV("a,\"b\nc", 30); err == nil {
+		t.Error("ChunkCSV() expected error for malformed csv")
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tokens := Tokenize("Raft 领导者选举, v2")
+	want := []string{"raft", "领导", "导者", "者选", "选举", "v2"}
+	if strings.Join(tokens, "|") != strings.Join(want, "|") {
+		t.Errorf("Tokenize() = %q, want %q", tokens, want)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	cases := map[string]int{
+		"":            0,
+		"思维导图":        4,
+		"hello world": 3,
+		"拆解 task":     4,
+	}
+	for text, want := range cases {
+		if got := EstimateTokens(text); got != want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
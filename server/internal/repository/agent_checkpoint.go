@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type agentCheckpointRepository struct {
+	db *gorm.DB
+}
+
+// NewAgentCheckpointRepository 创建运行检查点仓储实例
+func NewAgentCheckpointRepository(db *gorm.DB) AgentCheckpoint {
+	return &agentCheckpointRepository{db: db}
+}
+
+func (r *agentCheckpointRepository) Create(ctx context.Context, checkpoint *model.AgentCheckpoint) error {
+	return r.db.WithContext(ctx).Create(checkpoint).Error
+}
+
+func (r *agentCheckpointRepository) FindLatestByRunID(ctx context.Context, runID string) (*model.AgentCheckpoint, error) {
+	var checkpoint model.AgentCheckpoint
+	err := r.db.WithContext(ctx).Where("run_id = ?", runID).Order("serial_id DESC").First(&checkpoint).Error
+	if err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+func (r *agentCheckpointRepository) DeleteByRunID(ctx context.Context, runID string) error {
+	return r.db.WithContext(ctx).Where("run_id = ?", runID).Delete(&model.AgentCheckpoint{}).Error
+}
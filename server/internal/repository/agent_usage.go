@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type agentUsageRepository struct {
+	db *gorm.DB
+}
+
+// NewAgentUsageRepository 创建运行用量仓储实例
+func NewAgentUsageRepository(db *gorm.DB) AgentUsage {
+	return &agentUsageRepository{db: db}
+}
+
+func (r *agentUsageRepository) Create(ctx context.Context, usage *model.AgentUsage) error {
+	return r.db.WithContext(ctx).Create(usage).Error
+}
+
+func (r *agentUsageRepository) SumByMapID(ctx context.Context, mapID string, since time.Time) (*model.UsageSum, error) {
+	return r.sum(ctx, "map_id = ?", mapID, since)
+}
+
+func (r *agentUsageRepository) SumByUserID(ctx context.Context, userID string, since time.Time) (*model.UsageSum, error) {
+	return r.sum(ctx, "user_id = ?", userID, since)
+}
+
+func (r *agentUsageRepository) sum(ctx context.Context, query string, arg any, since time.Time) (*model.UsageSum, error) {
+	var sum model.UsageSum
+	db := r.db.WithContext(ctx).Model(&model.AgentUsage{}).
+		Select("COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens, COALESCE(SUM(completion_tokens), 0) AS completion_tokens, "+
+			"COALESCE(SUM(total_tokens), 0) AS total_tokens, COALESCE(SUM(cost), 0) AS cost").
+		Where(query, arg)
+	if !since.IsZero() {
+		db = db.Where("created_at >= ?", since)
+	}
+	if err := db.Scan(&sum).Error; err != nil {
+		return nil, err
+	}
+	return &sum, nil
+}
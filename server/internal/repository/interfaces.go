@@ -49,8 +49,10 @@ type ThinkingNode interface {
 	UpdatePosition(ctx context.Context, id string, position model.JSONB) error
 	UpdateStatus(ctx context.Context, id string, status int) error
 	UpdateIsDecomposed(ctx context.Context, id string, isDecomposed bool) error
+	UpdateConclusionAbstract(ctx context.Context, id string, abstract string) error
 	UpdateInTx(ctx context.Context, tx *gorm.DB, node *model.ThinkingNode) error
 	DeleteByParentID(ctx context.Context, parentID string) error
+	Restore(ctx context.Context, id string) error
 }
 
 // Message 消息仓储接口
@@ -71,4 +73,43 @@ type RAGRecord interface {
 	Update(ctx context.Context, record *model.RAGRecord) error
 	Delete(ctx context.Context, id string) error
 	FindByID(ctx context.Context, id string) (*model.RAGRecord, error)
+	FindLatestByCacheKey(ctx context.Context, cacheKey string, since time.Time) (*model.RAGRecord, error)
+}
+
+// NodeChange 节点变更仓储接口
+type NodeChange interface {
+	Create(ctx context.Context, change *model.NodeChange) error
+	FindByRunID(ctx context.Context, runID string) ([]*model.NodeChange, error)
+	FindByMapID(ctx context.Context, mapID, status string) ([]*model.NodeChange, error)
+	UpdateStatus(ctx context.Context, id, status, errMsg string) error
+}
+
+// NodeOperation 节点操作日志仓储接口
+type NodeOperation interface {
+	Create(ctx context.Context, op *model.NodeOperation) error
+	FindByMapID(ctx context.Context, mapID string) ([]*model.NodeOperation, error)
+}
+
+// AgentCheckpoint 运行检查点仓储接口
+type AgentCheckpoint interface {
+	Create(ctx context.Context, checkpoint *model.AgentCheckpoint) error
+	FindLatestByRunID(ctx context.Context, runID string) (*model.AgentCheckpoint, error)
+	DeleteByRunID(ctx context.Context, runID string) error
+}
+
+// AgentUsage 运行用量仓储接口
+type AgentUsage interface {
+	Create(ctx context.Context, usage *model.AgentUsage) error
+	SumByMapID(ctx context.Context, mapID string, since time.Time) (*model.UsageSum, error)
+	SumByUserID(ctx context.Context, userID string, since time.Time) (*model.UsageSum, error)
+}
+
+// KnowledgeBase 思维导图知识库仓储接口
+type KnowledgeBase interface {
+	CreateDocument(ctx context.Context, doc *model.KBDocument, chunks []*model.KBChunk) error
+	FindDocumentByID(ctx context.Context, id string) (*model.KBDocument, error)
+	ListDocuments(ctx context.Context, mapID string) ([]*model.KBDocument, error)
+	DeleteDocument(ctx context.Context, id string) error
+	SearchChunks(ctx context.Context, mapID string, terms []string, limit int) ([]*model.KBChunkHit, error)
+	FindEmbeddedChunks(ctx context.Context, mapID string, afterSerialID int64, limit int) ([]*model.KBChunkHit, error)
 }
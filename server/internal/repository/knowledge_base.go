@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// chunkHitSelect 片段及所属文档名称，已删除文档的片段不返回
+const chunkHitSelect = "SELECT kb_chunks.*, kb_documents.name AS document_name%s FROM kb_chunks " +
+	"JOIN kb_documents ON kb_documents.id = kb_chunks.document_id AND kb_documents.deleted_at IS NULL "
+
+type knowledgeBaseRepository struct {
+	db *gorm.DB
+}
+
+// NewKnowledgeBaseRepository 创建知识库仓储实例
+func NewKnowledgeBaseRepository(db *gorm.DB) KnowledgeBase {
+	return &knowledgeBaseRepository{db: db}
+}
+
+// CreateKnowledgeBaseIndexes 创建知识库全文检索索引，AutoMigrate之后调用
+func CreateKnowledgeBaseIndexes(db *gorm.DB) error {
+	return db.Exec("CREATE INDEX IF NOT EXISTS idx_kb_chunks_terms ON kb_chunks USING GIN (to_tsvector('simple', terms))").Error
+}
+
+func (r *knowledgeBaseRepository) CreateDocument(ctx context.Context, doc *model.KBDocument, chunks []*model.KBChunk) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(doc).Error; err != nil {
+			return err
+		}
+		if len(chunks) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(chunks, 100).Error
+	})
+}
+
+func (r *knowledgeBaseRepository) FindDocumentByID(ctx context.Context, id string) (*model.KBDocument, error) {
+	var doc model.KBDocument
+	err := r.db.WithContext(ctx).Where(whereID, id).First(&doc).Error
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (r *knowledgeBaseRepository) ListDocuments(ctx context.Context, mapID string) ([]*model.KBDocument, error) {
+	var docs []*model.KBDocument
+	err := r.db.WithContext(ctx).Where("map_id = ?", mapID).Order("created_at DESC").Find(&docs).Error
+	return docs, err
+}
+
+func (r *knowledgeBaseRepository) DeleteDocument(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("document_id = ?", id).Delete(&model.KBChunk{}).Error; err != nil {
+			return err
+		}
+		return tx.Where(whereID, id).Delete(&model.KBDocument{}).Error
+	})
+}
+
+// SearchChunks 全文检索，命中任一分词即返回，按 ts_rank 排序
+func (r *knowledgeBaseRepository) SearchChunks(ctx context.Context, mapID string, terms []string, limit int) ([]*model.KBChunkHit, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	// 分词只包含字母、数字和汉字，可以直接拼接为tsquery
+	query := strings.Join(terms, " | ")
+	var hits []*model.KBChunkHit
+	err := r.db.WithContext(ctx).Raw(
+		fmt.Sprintf(chunkHitSelect, ", ts_rank(to_tsvector('simple', kb_chunks.terms), to_tsquery('simple', @query)) AS rank")+
+			"WHERE kb_chunks.map_id = @mapID AND to_tsvector('simple', kb_chunks.terms) @@ to_tsquery('simple', @query) "+
+			"ORDER BY rank DESC LIMIT @limit",
+		map[string]interface{}{"query": query, "mapID": mapID, "limit": limit},
+	).Scan(&hits).Error
+	return hits, err
+}
+
+// FindEmbeddedChunks 按自增ID分页获取已生成向量的片段，返回自增ID大于afterSerialID的前limit个
+func (r *knowledgeBaseRepository) FindEmbeddedChunks(ctx context.Context, mapID string, afterSerialID int64, limit int) ([]*model.KBChunkHit, error) {
+	var hits []*model.KBChunkHit
+	err := r.db.WithContext(ctx).Raw(
+		fmt.Sprintf(chunkHitSelect, "")+"WHERE kb_chunks.map_id = @mapID AND kb_chunks.embedding IS NOT NULL AND kb_chunks.serial_id > @after "+
+			"ORDER BY kb_chunks.serial_id LIMIT @limit",
+		map[string]interface{}{"mapID": mapID, "after": afterSerialID, "limit": limit},
+	).Scan(&hits).Error
+	return hits, err
+}
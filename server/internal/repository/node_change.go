@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type nodeChangeRepository struct {
+	db *gorm.DB
+}
+
+// NewNodeChangeRepository 创建节点变更仓储实例
+func NewNodeChangeRepository(db *gorm.DB) NodeChange {
+	return &nodeChangeRepository{db: db}
+}
+
+func (r *nodeChangeRepository) Create(ctx context.Context, change *model.NodeChange) error {
+	return r.db.WithContext(ctx).Create(change).Error
+}
+
+// FindByRunID 按提出顺序获取一次运行的全部变更
+func (r *nodeChangeRepository) FindByRunID(ctx context.Context, runID string) ([]*model.NodeChange, error) {
+	var changes []*model.NodeChange
+	err := r.db.WithContext(ctx).Where("run_id = ?", runID).Order("serial_id").Find(&changes).Error
+	return changes, err
+}
+
+// FindByMapID 按提出顺序获取思维导图指定状态的变更，status为空时不过滤
+func (r *nodeChangeRepository) FindByMapID(ctx context.Context, mapID, status string) ([]*model.NodeChange, error) {
+	var changes []*model.NodeChange
+	db := r.db.WithContext(ctx).Where("map_id = ?", mapID)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	err := db.Order("serial_id").Find(&changes).Error
+	return changes, err
+}
+
+func (r *nodeChangeRepository) UpdateStatus(ctx context.Context, id, status, errMsg string) error {
+	return r.db.WithContext(ctx).Model(&model.NodeChange{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     status,
+		"error":      errMsg,
+		"updated_at": time.Now(),
+	}).Error
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type nodeOperationRepository struct {
+	db *gorm.DB
+}
+
+// NewNodeOperationRepository 创建节点操作日志仓储实例
+func NewNodeOperationRepository(db *gorm.DB) NodeOperation {
+	return &nodeOperationRepository{db: db}
+}
+
+func (r *nodeOperationRepository) Create(ctx context.Context, op *model.NodeOperation) error {
+	return r.db.WithContext(ctx).Create(op).Error
+}
+
+// FindByMapID 按记录顺序获取思维导图的全部操作
+func (r *nodeOperationRepository) FindByMapID(ctx context.Context, mapID string) ([]*model.NodeOperation, error) {
+	var ops []*model.NodeOperation
+	err := r.db.WithContext(ctx).Where("map_id = ?", mapID).Order("serial_id").Find(&ops).Error
+	return ops, err
+}
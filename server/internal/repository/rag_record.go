@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/PGshen/thinking-map/server/internal/model"
 
@@ -38,6 +39,19 @@ func (r *ragRecordRepository) FindByID(ctx context.Context, id string) (*model.R
 	return &record, nil
 }
 
+// FindLatestByCacheKey 查找 since 之后创建的、缓存键相同的最新检索记录
+func (r *ragRecordRepository) FindLatestByCacheKey(ctx context.Context, cacheKey string, since time.Time) (*model.RAGRecord, error) {
+	var record model.RAGRecord
+	err := r.db.WithContext(ctx).
+		Where("cache_key = ? AND created_at >= ?", cacheKey, since).
+		Order("created_at DESC").
+		First(&record).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
 func (r *ragRecordRepository) List(ctx context.Context, offset, limit int) ([]*model.RAGRecord, int64, error) {
 	var records []*model.RAGRecord
 	var total int64
@@ -71,6 +71,13 @@ func (r *thinkingNodeRepository) DeleteByParentID(ctx context.Context, parentID
 	return r.db.WithContext(ctx).Where("parent_id = ?", parentID).Delete(&model.ThinkingNode{}).Error
 }
 
+// Restore 恢复已删除的节点
+func (r *thinkingNodeRepository) Restore(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Unscoped().Model(&model.ThinkingNode{}).
+		Where(whereID, id).
+		Update("deleted_at", nil).Error
+}
+
 // FindByIDs retrieves multiple ThinkingNode records by their IDs
 func (r *thinkingNodeRepository) FindByIDs(ctx context.Context, ids []string) ([]*model.ThinkingNode, error) {
 	var nodes []*model.ThinkingNode
@@ -137,3 +144,10 @@ func (r *thinkingNodeRepository) UpdateIsDecomposed(ctx context.Context, id stri
 		UpdateColumn("status", comm.NodeStatusInDecomposition).
 		UpdateColumn("decomposition", gorm.Expr("jsonb_set(decomposition, '{isDecomposed}', ?)", isDecomposed)).Error
 }
+
+// UpdateConclusionAbstract 只更新结论摘要，不影响结论的其他字段和节点状态
+func (r *thinkingNodeRepository) UpdateConclusionAbstract(ctx context.Context, id string, abstract string) error {
+	return r.db.WithContext(ctx).Model(&model.ThinkingNode{}).
+		Where("id = ?", id).
+		UpdateColumn("conclusion", gorm.Expr("jsonb_set(conclusion, '{abstract}', to_jsonb(?::text))", abstract)).Error
+}
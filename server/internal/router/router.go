@@ -10,6 +10,7 @@ import (
 	"github.com/PGshen/thinking-map/server/internal/global"
 	"github.com/PGshen/thinking-map/server/internal/handler"
 	thinkinghandler "github.com/PGshen/thinking-map/server/internal/handler/thinking"
+	"github.com/PGshen/thinking-map/server/internal/mcpserver"
 	"github.com/PGshen/thinking-map/server/internal/middleware"
 	"github.com/PGshen/thinking-map/server/internal/repository"
 	"github.com/PGshen/thinking-map/server/internal/service"
@@ -42,6 +43,8 @@ func SetupRouter(
 	mapRepo := repository.NewThinkingMapRepository(db)
 	nodeRepo := repository.NewThinkingNodeRepository(db)
 	messageRepo := repository.NewMessageRepository(db)
+	nodeChangeRepo := repository.NewNodeChangeRepository(db)
+	nodeOperationRepo := repository.NewNodeOperationRepository(db)
 
 	// Create services
 	authService := service.NewAuthService(db, redisClient, jwtConfig)
@@ -50,7 +53,14 @@ func SetupRouter(
 	contextManager := service.NewContextManager(nodeRepo, mapRepo, messageRepo)
 	understandingService := service.NewUnderstandingService(messageRepo, nodeRepo)
 	decompositionService := service.NewDecompositionService(contextManager, nodeRepo)
-	conclusionService := service.NewConclusionV3Service(contextManager, nodeRepo)
+	conclusionService := service.NewConclusionV3Service(contextManager, nodeRepo, mapRepo)
+	runService := service.NewRunService(nodeRepo)
+	autopilotService := service.NewAutopilotService(decompositionService, conclusionService, nodeRepo, mapRepo)
+	jobService := service.NewJobService()
+	kbService := service.NewKnowledgeBaseService(global.GetKnowledgeBase())
+	branchService := service.NewBranchService(nodeRepo, messageRepo)
+	nodeChangeService := service.NewNodeChangeService(nodeChangeRepo)
+	nodeOperationService := service.NewNodeOperationService(nodeOperationRepo, nodeRepo, redisClient)
 
 	// Create handlers
 	authHandler := handler.NewAuthHandler(authService)
@@ -60,6 +70,14 @@ func SetupRouter(
 	decompositionHandler := thinkinghandler.NewDecompositionHandler(decompositionService)
 	conclusionHandler := thinkinghandler.NewConclusionHandler(conclusionService)
 	repeaterHandler := thinkinghandler.NewRepeaterHandler()
+	runHandler := thinkinghandler.NewRunHandler(runService)
+	jobHandler := thinkinghandler.NewJobHandler(jobService)
+	autopilotHandler := thinkinghandler.NewAutopilotHandler(autopilotService)
+	kbHandler := handler.NewKnowledgeBaseHandler(kbService)
+	branchHandler := handler.NewBranchHandler(branchService)
+	nodeChangeHandler := handler.NewNodeChangeHandler(nodeChangeService)
+	nodeOperationHandler := handler.NewNodeOperationHandler(nodeOperationService)
+	mcpHandler := handler.NewMCPHandler(mcpserver.NewMapServer(mapRepo, nodeRepo))
 
 	// 使用全局 broker
 	sseHandler := handler.NewSSEHandler(global.GetBroker(), mapRepo)
@@ -106,10 +124,52 @@ func SetupRouter(
 				nodes.PUT("/:nodeID/context/reset", middleware.NodeOwnershipMiddleware(nodeRepo, mapRepo), nodeHandler.ResetNodeContext)
 				nodes.PUT("/:nodeID/decomposition/reset", middleware.NodeOwnershipMiddleware(nodeRepo, mapRepo), nodeHandler.ResetDecomposition)
 				nodes.GET("/:nodeID/messages", middleware.NodeOwnershipMiddleware(nodeRepo, mapRepo), nodeHandler.GetNodeMessages)
+				nodes.GET("/:nodeID/branches", middleware.NodeOwnershipMiddleware(nodeRepo, mapRepo), branchHandler.ListBranches)
+				nodes.GET("/:nodeID/branches/:messageID/messages", middleware.NodeOwnershipMiddleware(nodeRepo, mapRepo), branchHandler.GetBranchMessages)
+				nodes.POST("/:nodeID/branches/fork", middleware.NodeOwnershipMiddleware(nodeRepo, mapRepo), branchHandler.Fork)
+				nodes.PUT("/:nodeID/branches/active", middleware.NodeOwnershipMiddleware(nodeRepo, mapRepo), branchHandler.Switch)
+				nodes.PUT("/:nodeID/messages/:messageID", middleware.NodeOwnershipMiddleware(nodeRepo, mapRepo), branchHandler.EditMessage)
+				nodes.POST("/:nodeID/messages/:messageID/regenerate", middleware.NodeOwnershipMiddleware(nodeRepo, mapRepo), branchHandler.Regenerate)
+				nodes.GET("/:nodeID/conclusion", middleware.NodeOwnershipMiddleware(nodeRepo, mapRepo), nodeHandler.GetConclusion)
 				nodes.PUT("/:nodeID/conclusion", middleware.NodeOwnershipMiddleware(nodeRepo, mapRepo), nodeHandler.SaveConclusion)
 				nodes.PUT("/:nodeID/conclusion/reset", middleware.NodeOwnershipMiddleware(nodeRepo, mapRepo), nodeHandler.ResetConclusion)
 			}
 
+			// Knowledge base routes
+			kb := protected.Group("/maps/:mapID/kb", middleware.MapOwnershipMiddleware(mapRepo))
+			{
+				kb.POST("/documents", kbHandler.UploadDocument)
+				kb.GET("/documents", kbHandler.ListDocuments)
+				kb.DELETE("/documents/:documentID", kbHandler.DeleteDocument)
+				kb.GET("/search", kbHandler.Search)
+			}
+
+			// Node changeset routes
+			changesets := protected.Group("/maps/:mapID/changesets", middleware.MapOwnershipMiddleware(mapRepo))
+			{
+				changesets.GET("", nodeChangeHandler.ListChangesets)
+				changesets.POST("/:runID/accept", nodeChangeHandler.AcceptChangeset)
+				changesets.POST("/:runID/reject", nodeChangeHandler.RejectChangeset)
+			}
+
+			// Node operation log routes
+			operations := protected.Group("/maps/:mapID/operations", middleware.MapOwnershipMiddleware(mapRepo))
+			{
+				operations.GET("", nodeOperationHandler.ListOperations)
+				operations.POST("/undo", nodeOperationHandler.Undo)
+				operations.POST("/redo", nodeOperationHandler.Redo)
+			}
+
+			// Autopilot routes
+			autopilot := protected.Group("/maps/:mapID/autopilot", middleware.MapOwnershipMiddleware(mapRepo))
+			{
+				autopilot.POST("", autopilotHandler.Start)
+				autopilot.GET("", autopilotHandler.Get)
+				autopilot.POST("/pause", autopilotHandler.Pause)
+				autopilot.POST("/resume", autopilotHandler.Resume)
+				autopilot.POST("/stop", autopilotHandler.Stop)
+			}
+
 			// Thinking routes
 			thinking := protected.Group("/thinking")
 			{
@@ -118,6 +178,9 @@ func SetupRouter(
 				thinking.POST("/decomposition", decompositionHandler.Handle)
 				thinking.POST("/conclusion", conclusionHandler.Handle)
 				thinking.POST("/repeat", thinkinghandler.NewStreamReply(repeaterHandler))
+				thinking.DELETE("/runs/:runID", runHandler.CancelRun)
+				thinking.POST("/runs/:runID/plan", decompositionHandler.ReviewPlan)
+				thinking.GET("/jobs/:jobID", jobHandler.GetJob)
 			}
 
 			// SSE routes
@@ -126,6 +189,11 @@ func SetupRouter(
 				sse.GET("/connect/:mapID", sseHandler.Connect)
 				sse.POST("/send-event/:mapID", sseHandler.SendEvent)
 			}
+
+			// MCP server (streamable HTTP)
+			protected.POST("/mcp", mcpHandler.Handle)
+			protected.GET("/mcp", mcpHandler.Handle)
+			protected.DELETE("/mcp", mcpHandler.Handle)
 		}
 	}
 
@@ -0,0 +1,761 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/base"
+	"github.com/PGshen/thinking-map/server/internal/agent/callback"
+	"github.com/PGshen/thinking-map/server/internal/agent/decomposition"
+	"github.com/PGshen/thinking-map/server/internal/agent/llmmodel"
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/autopilot"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/PGshen/thinking-map/server/internal/pkg/queue"
+	"github.com/PGshen/thinking-map/server/internal/pkg/sse"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// 自动驾驶的执行动作
+const (
+	autopilotActionExpand   = "expand"   // 待执行节点，由拆解决策决定拆解还是直接总结
+	autopilotActionConclude = "conclude" // 无子节点，直接生成结论
+	autopilotActionRollup   = "rollup"   // 子节点均已完成，汇总子节点结论
+)
+
+// 自动驾驶进度事件的阶段，结束时为自动驾驶的状态（paused | stopped | completed | failed）
+const (
+	autopilotStageDeciding    = "deciding"
+	autopilotStageDecomposing = "decomposing"
+	autopilotStageConcluding  = "concluding"
+	autopilotStageRollingUp   = "rolling_up"
+)
+
+const (
+	autopilotDecisionInstruction   = "请判断当前节点是否需要继续拆解。当前节点深度为%d，最大拆解深度为%d。"
+	autopilotDecomposeInstruction  = "开始将问题（任务）拆解为多个子问题（子任务）。拆解策略：%s。理由：%s"
+	autopilotConclusionInstruction = "请基于上下文信息生成当前节点的结论。"
+	// autopilotBusyWait 可执行的节点上都有其他运行时，等待后再检查
+	autopilotBusyWait = 5 * time.Second
+)
+
+// AutopilotService 自动驾驶：按依赖顺序反复选取可执行节点，决定拆解或总结并执行，直到思维导图完成
+type AutopilotService struct {
+	decompositionService *DecompositionService
+	conclusionService    *ConclusionService
+	nodeService          *NodeService
+	nodeRepo             repository.ThinkingNode
+	mapRepo              repository.ThinkingMap
+}
+
+func NewAutopilotService(decompositionService *DecompositionService, conclusionService *ConclusionService, nodeRepo repository.ThinkingNode, mapRepo repository.ThinkingMap) *AutopilotService {
+	return &AutopilotService{
+		decompositionService: decompositionService,
+		conclusionService:    conclusionService,
+		nodeService:          NewNodeService(nodeRepo, mapRepo),
+		nodeRepo:             nodeRepo,
+		mapRepo:              mapRepo,
+	}
+}
+
+// AutopilotJobPayload 自动驾驶任务参数
+type AutopilotJobPayload struct {
+	AutopilotID string `json:"autopilotID"`
+}
+
+// autopilotStep 自动驾驶的一个执行步骤
+type autopilotStep struct {
+	Action string
+	Node   *model.ThinkingNode
+	Depth  int
+}
+
+// Start 启动自动驾驶，以根节点任务的形式加入队列，同一思维导图同一时间只允许一个自动驾驶
+func (s *AutopilotService) Start(ctx context.Context, mapID, userID string, req dto.StartAutopilotRequest) (*dto.AutopilotResponse, error) {
+	store := global.GetAutopilotStore()
+	nodes, err := s.nodeRepo.FindByMapID(ctx, mapID)
+	if err != nil {
+		return nil, err
+	}
+	root := findRootNode(nodes)
+	if root == nil {
+		return nil, comm.ErrThinkingNodeNotFound
+	}
+	state := &autopilot.State{
+		ID:         uuid.NewString(),
+		MapID:      mapID,
+		RootNodeID: root.ID,
+		UserID:     userID,
+		Status:     autopilot.StatusRunning,
+		Limits: store.DefaultLimits().Merge(autopilot.Limits{
+			MaxDepth:  req.MaxDepth,
+			MaxNodes:  req.MaxNodes,
+			MaxSteps:  req.MaxSteps,
+			MaxTokens: req.MaxTokens,
+			MaxCost:   req.MaxCost,
+		}),
+		CreatedAt: time.Now(),
+	}
+	if err := store.Create(ctx, state); err != nil {
+		if errors.Is(err, autopilot.ErrActive) {
+			return nil, comm.ErrAutopilotConflict
+		}
+		return nil, err
+	}
+	if err := s.enqueue(ctx, state); err != nil {
+		// 未能入队（如根节点上已有运行），结束本次自动驾驶
+		_, _ = s.update(ctx, mapID, state.ID, func(state *autopilot.State) error {
+			state.Finish(autopilot.StatusFailed, err.Error())
+			return nil
+		})
+		return nil, err
+	}
+	return toAutopilotResponse(state), nil
+}
+
+// Get 获取思维导图的自动驾驶状态
+func (s *AutopilotService) Get(ctx context.Context, mapID string) (*dto.AutopilotResponse, error) {
+	state, err := global.GetAutopilotStore().Get(ctx, mapID)
+	if err != nil {
+		if errors.Is(err, autopilot.ErrNotFound) {
+			return nil, comm.ErrAutopilotNotFound
+		}
+		return nil, err
+	}
+	return toAutopilotResponse(state), nil
+}
+
+// Pause 请求暂停，当前步骤结束后暂停
+func (s *AutopilotService) Pause(ctx context.Context, mapID string) (*dto.AutopilotResponse, error) {
+	state, err := s.update(ctx, mapID, "", func(state *autopilot.State) error {
+		if state.Status != autopilot.StatusRunning {
+			return comm.ErrAutopilotInvalidState
+		}
+		state.Status = autopilot.StatusPausing
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toAutopilotResponse(state), nil
+}
+
+// Resume 恢复已暂停的自动驾驶，重新加入队列，步骤数和用量继续累计
+func (s *AutopilotService) Resume(ctx context.Context, mapID, userID string) (*dto.AutopilotResponse, error) {
+	state, err := s.update(ctx, mapID, "", func(state *autopilot.State) error {
+		if state.Status != autopilot.StatusPaused {
+			return comm.ErrAutopilotInvalidState
+		}
+		state.Status = autopilot.StatusRunning
+		state.UserID = userID
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.enqueue(ctx, state); err != nil {
+		_, _ = s.update(ctx, mapID, state.ID, func(state *autopilot.State) error {
+			state.Status = autopilot.StatusPaused
+			return nil
+		})
+		return nil, err
+	}
+	return toAutopilotResponse(state), nil
+}
+
+// Stop 停止自动驾驶：已暂停的直接结束，执行中的同时取消当前运行
+func (s *AutopilotService) Stop(ctx context.Context, mapID string) (*dto.AutopilotResponse, error) {
+	var prevStatus autopilot.Status
+	state, err := s.update(ctx, mapID, "", func(state *autopilot.State) error {
+		if !state.Status.Active() {
+			return comm.ErrAutopilotInvalidState
+		}
+		prevStatus = state.Status
+		state.Finish(autopilot.StatusStopped, "stopped by user")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if prevStatus != autopilot.StatusPaused && state.JobID != "" {
+		// 排队中的任务直接取消；执行中的任务在本实例上立即中断，其他实例上的worker通过取消标记中断
+		if _, err := global.GetJobQueue().Cancel(ctx, state.JobID); err != nil &&
+			!errors.Is(err, queue.ErrJobFinished) && !errors.Is(err, queue.ErrJobNotFound) {
+			return nil, err
+		}
+		_, _ = global.GetRunRegistry().Cancel(state.JobID)
+	}
+	publishAutopilotProgress(state, state.RootNodeID, string(state.Status), 0, state.StopReason)
+	return toAutopilotResponse(state), nil
+}
+
+// enqueue 将自动驾驶任务加入队列，任务占用根节点
+func (s *AutopilotService) enqueue(ctx context.Context, state *autopilot.State) error {
+	run, err := enqueueRun(ctx, queue.EnqueueRequest{
+		Type:    JobTypeAutopilot,
+		MapID:   state.MapID,
+		NodeID:  state.RootNodeID,
+		UserID:  state.UserID,
+		Payload: AutopilotJobPayload{AutopilotID: state.ID},
+	})
+	if err != nil {
+		return err
+	}
+	state.JobID = run.RunID
+	_, err = s.update(ctx, state.MapID, state.ID, func(state *autopilot.State) error {
+		state.JobID = run.RunID
+		return nil
+	})
+	return err
+}
+
+// update 修改思维导图的自动驾驶状态，id不为空时只修改该次自动驾驶
+func (s *AutopilotService) update(ctx context.Context, mapID, id string, fn func(state *autopilot.State) error) (*autopilot.State, error) {
+	state, err := global.GetAutopilotStore().Update(ctx, mapID, func(state *autopilot.State) error {
+		if id != "" && state.ID != id {
+			return comm.ErrAutopilotNotFound
+		}
+		return fn(state)
+	})
+	if errors.Is(err, autopilot.ErrNotFound) {
+		return nil, comm.ErrAutopilotNotFound
+	}
+	return state, err
+}
+
+// HandleJob 执行自动驾驶任务
+func (s *AutopilotService) HandleJob(ctx context.Context, job *queue.Job) error {
+	var payload AutopilotJobPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	state, err := global.GetAutopilotStore().Get(ctx, job.MapID)
+	if err != nil {
+		if errors.Is(err, autopilot.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	// 入队后已停止或被新的自动驾驶替代
+	if state.ID != payload.AutopilotID || !state.Status.Active() {
+		return nil
+	}
+	return runJob(ctx, job, global.RunSpec{
+		Tasks: []global.RunTask{func(runCtx context.Context) error {
+			err := s.run(runCtx, payload.AutopilotID)
+			if err != nil && runCtx.Err() == nil && job.Attempts >= job.MaxAttempts {
+				// 重试次数用尽，结束自动驾驶
+				s.finish(context.Background(), job.MapID, payload.AutopilotID, autopilot.StatusFailed, err.Error())
+			}
+			return err
+		}},
+		OnCancelled: func(ctx context.Context, run *global.Run) {
+			s.restoreAfterCancel(ctx, run, payload.AutopilotID)
+		},
+	})
+}
+
+// restoreAfterCancel 运行取消后结束自动驾驶，已完成的步骤保持不变
+func (s *AutopilotService) restoreAfterCancel(ctx context.Context, run *global.Run, autopilotID string) {
+	s.finish(ctx, run.MapID, autopilotID, autopilot.StatusStopped, "run cancelled")
+	node, err := s.nodeRepo.FindByID(ctx, run.NodeID)
+	if err != nil {
+		logger.Error("find node after cancel failed", zap.String("nodeID", run.NodeID), zap.Error(err))
+		return
+	}
+	publishRunCancelled(run.MapID, runCancelledEvent(run, node.Status))
+}
+
+// run 自动驾驶主循环，每个步骤结束后检查暂停/停止请求和各项限制
+func (s *AutopilotService) run(ctx context.Context, autopilotID string) error {
+	mapID, _ := ctx.Value("mapID").(string)
+	store := global.GetAutopilotStore()
+	state, err := store.Get(ctx, mapID)
+	if err != nil {
+		return err
+	}
+	// 恢复执行时在已有用量上累计
+	baseUsage := base.Usage{
+		PromptTokens:     state.PromptTokens,
+		CompletionTokens: state.CompletionTokens,
+		TotalTokens:      state.TotalTokens,
+		Cost:             state.Cost,
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		state, err = store.Get(ctx, mapID)
+		if err != nil {
+			return err
+		}
+		if state.ID != autopilotID {
+			return nil
+		}
+		switch state.Status {
+		case autopilot.StatusRunning:
+		case autopilot.StatusPausing:
+			state, err = s.update(ctx, mapID, autopilotID, func(state *autopilot.State) error {
+				state.Status = autopilot.StatusPaused
+				state.CurrentNodeID = ""
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			publishAutopilotProgress(state, state.RootNodeID, string(state.Status), 0, "paused by user")
+			return nil
+		default:
+			return nil
+		}
+
+		// 刷新节点状态：依赖完成的节点变为待执行，子节点全部完成的节点进入结论阶段
+		if _, err := s.nodeService.ExecutableNodes(ctx, mapID, ""); err != nil {
+			return err
+		}
+		nodes, err := s.nodeRepo.FindByMapID(ctx, mapID)
+		if err != nil {
+			return err
+		}
+		progress := autopilotProgress(nodes)
+		root := findRootNode(nodes)
+		if root == nil {
+			return comm.ErrThinkingNodeNotFound
+		}
+		if root.Status == comm.NodeStatusCompleted {
+			if err := s.conclusionService.completeMap(ctx, root); err != nil {
+				return err
+			}
+			s.finish(ctx, mapID, autopilotID, autopilot.StatusCompleted, "")
+			return nil
+		}
+		if reason := autopilotLimitReached(state); reason != "" {
+			s.finish(ctx, mapID, autopilotID, autopilot.StatusStopped, reason)
+			return nil
+		}
+		if reason := base.BudgetExceeded(ctx); reason != "" {
+			s.finish(ctx, mapID, autopilotID, autopilot.StatusStopped, reason)
+			return nil
+		}
+
+		step, waiting, err := s.acquireStep(ctx, nodes, root.ID)
+		if err != nil {
+			return err
+		}
+		if step == nil {
+			if !waiting {
+				s.finish(ctx, mapID, autopilotID, autopilot.StatusStopped, "no executable nodes left")
+				return nil
+			}
+			// 可执行的节点上都有用户发起的运行，等待其结束
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(autopilotBusyWait):
+			}
+			continue
+		}
+
+		if _, err := s.update(ctx, mapID, autopilotID, func(state *autopilot.State) error {
+			state.CurrentNodeID = step.Node.ID
+			return nil
+		}); err != nil {
+			s.releaseStep(ctx, step, root.ID)
+			return err
+		}
+		created, err := s.execute(autopilotStepContext(ctx, step), state, step, len(nodes), progress)
+		s.releaseStep(ctx, step, root.ID)
+		if err != nil {
+			return err
+		}
+
+		var usage base.Usage
+		if tracker, ok := base.GetBudgetTracker(ctx); ok {
+			usage = tracker.Usage()
+		}
+		usage.Add(baseUsage)
+		if _, err := s.update(ctx, mapID, autopilotID, func(state *autopilot.State) error {
+			state.Steps++
+			state.NodesCreated += created
+			state.PromptTokens = usage.PromptTokens
+			state.CompletionTokens = usage.CompletionTokens
+			state.TotalTokens = usage.TotalTokens
+			state.Cost = usage.Cost
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+// autopilotStepContext 步骤的运行上下文，工具调用使用上下文中的 nodeID 和 operation，按当前步骤覆盖；
+// 检索记录等按 operation 保存到节点的拆解或结论对话中
+func autopilotStepContext(ctx context.Context, step *autopilotStep) context.Context {
+	return global.WithValues(ctx, map[string]any{
+		"nodeID":    step.Node.ID,
+		"operation": step.Action,
+	})
+}
+
+// execute 执行一个步骤，返回新建的节点数
+func (s *AutopilotService) execute(ctx context.Context, state *autopilot.State, step *autopilotStep, nodeCount, progress int) (int, error) {
+	node := step.Node
+	switch step.Action {
+	case autopilotActionRollup:
+		publishAutopilotProgress(state, node.ID, autopilotStageRollingUp, progress, node.Question)
+		_, err := s.conclusionService.rollup(ctx, node.ID)
+		return 0, err
+	case autopilotActionConclude:
+		publishAutopilotProgress(state, node.ID, autopilotStageConcluding, progress, node.Question)
+		return 0, s.conclude(ctx, node)
+	}
+
+	// 达到深度或节点数限制时不再拆解
+	var decision *dto.DecompositionDecision
+	switch {
+	case state.Limits.MaxDepth > 0 && step.Depth >= state.Limits.MaxDepth:
+		decision = &dto.DecompositionDecision{Reason: "max depth reached"}
+	case state.Limits.MaxNodes > 0 && nodeCount >= state.Limits.MaxNodes:
+		decision = &dto.DecompositionDecision{Reason: "max nodes reached"}
+	default:
+		publishAutopilotProgress(state, node.ID, autopilotStageDeciding, progress, node.Question)
+		var err error
+		if decision, err = s.decide(ctx, node.ID, step.Depth, state.Limits.MaxDepth); err != nil {
+			return 0, err
+		}
+	}
+	if !decision.ShouldDecompose {
+		publishAutopilotProgress(state, node.ID, autopilotStageConcluding, progress, decision.Reason)
+		return 0, s.conclude(ctx, node)
+	}
+
+	publishAutopilotProgress(state, node.ID, autopilotStageDecomposing, progress, decision.Reason)
+	created, err := s.decompose(ctx, node, fmt.Sprintf(autopilotDecomposeInstruction, decision.Strategy, decision.Reason))
+	if err != nil {
+		return created, err
+	}
+	if created == 0 {
+		// 拆解Agent认为无需拆解，直接总结
+		publishAutopilotProgress(state, node.ID, autopilotStageConcluding, progress, node.Question)
+		return 0, s.conclude(ctx, node)
+	}
+	return created, nil
+}
+
+// decide 调用拆解决策Agent判断节点是否需要继续拆解
+func (s *AutopilotService) decide(ctx context.Context, nodeID string, depth, maxDepth int) (*dto.DecompositionDecision, error) {
+	contextInfo, err := s.decompositionService.contextManager.GetContextInfo(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	messages := []*schema.Message{
+		schema.UserMessage(s.decompositionService.contextManager.FormatContextWithinBudget(ctx, contextInfo,
+			llmmodel.ProfileNameForSpecialist(llmmodel.AgentDecomposition, decomposition.DecisionSpecialist))),
+		schema.UserMessage(fmt.Sprintf(autopilotDecisionInstruction, depth, maxDepth)),
+	}
+	agent, err := decomposition.BuildDecisionAgent(ctx)
+	if err != nil {
+		return nil, err
+	}
+	output, err := agent.Invoke(ctx, messages, compose.WithCallbacks(callback.LogCbHandler))
+	if err != nil {
+		return nil, err
+	}
+	return parseDecompositionDecision(output.Content)
+}
+
+// decompose 保存拆解指令并拆解节点，返回新建的子节点数
+func (s *AutopilotService) decompose(ctx context.Context, node *model.ThinkingNode, instruction string) (int, error) {
+	userID, _ := ctx.Value("user_id").(string)
+	before, err := s.nodeRepo.FindByParentID(ctx, node.ID)
+	if err != nil {
+		return 0, err
+	}
+	lastMsgID := node.Decomposition.LastMessageID
+	_, err = s.decompositionService.msgManager.SaveDecompositionMessage(ctx, node.ID, dto.CreateMessageRequest{
+		ID:          uuid.NewString(),
+		ParentID:    lastMsgID,
+		UserID:      userID,
+		MessageType: model.MsgTypeText,
+		Role:        schema.User,
+		Content:     model.MessageContent{Text: instruction},
+	})
+	if err != nil {
+		return 0, err
+	}
+	contextInfo, messages, err := s.decompositionService.buildMessages(ctx, node.ID, lastMsgID, instruction)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.setStatus(ctx, contextInfo.NodeInfo, comm.NodeStatusInDecomposition); err != nil {
+		return 0, err
+	}
+	if err := s.decompositionService.Decompose(ctx, contextInfo, messages); err != nil {
+		return 0, err
+	}
+	after, err := s.nodeRepo.FindByParentID(ctx, node.ID)
+	if err != nil {
+		return 0, err
+	}
+	return len(after) - len(before), nil
+}
+
+// conclude 生成并保存节点结论
+func (s *AutopilotService) conclude(ctx context.Context, node *model.ThinkingNode) error {
+	contextInfo, messages, err := s.conclusionService.buildMessages(ctx, node.ID, autopilotConclusionInstruction)
+	if err != nil {
+		return err
+	}
+	node = contextInfo.NodeInfo
+	if err := s.setStatus(ctx, node, comm.NodeStatusInConclusion); err != nil {
+		return err
+	}
+	content, err := s.conclusionService.generate(ctx, contextInfo, messages)
+	if err != nil {
+		return err
+	}
+	if content == "" {
+		return errors.New("conclusion agent returned empty conclusion")
+	}
+	if err := s.conclusionService.saveConclusion(ctx, node, content); err != nil {
+		return err
+	}
+	publishNodeUpdated(node.MapID, node.ID, map[string]interface{}{
+		"status":     node.Status,
+		"conclusion": node.Conclusion,
+	})
+	return nil
+}
+
+// setStatus 更新节点状态并通知前端
+func (s *AutopilotService) setStatus(ctx context.Context, node *model.ThinkingNode, status string) error {
+	if node.Status == status {
+		return nil
+	}
+	node.Status = status
+	if err := s.nodeRepo.Update(ctx, node); err != nil {
+		return err
+	}
+	publishNodeUpdated(node.MapID, node.ID, map[string]interface{}{"status": status})
+	return nil
+}
+
+// acquireStep 选择下一个步骤并占用步骤节点，占用方式与任务入队相同，用户无法在该节点上发起运行；
+// 节点上有其他未结束的任务时跳过该节点；根节点由自动驾驶任务占用，不再占用
+func (s *AutopilotService) acquireStep(ctx context.Context, nodes []*model.ThinkingNode, rootID string) (*autopilotStep, bool, error) {
+	jobID, _ := ctx.Value("jobID").(string)
+	busy := make(map[string]bool)
+	for {
+		step, waiting := nextAutopilotStep(nodes, busy)
+		if step == nil || step.Node.ID == rootID {
+			return step, waiting, nil
+		}
+		ok, err := global.GetJobQueue().LockNode(ctx, jobID, step.Node.ID)
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			return step, false, nil
+		}
+		busy[step.Node.ID] = true
+	}
+}
+
+// releaseStep 释放步骤节点的占用，任务结束时队列也会释放未释放的占用
+func (s *AutopilotService) releaseStep(ctx context.Context, step *autopilotStep, rootID string) {
+	if step.Node.ID == rootID {
+		return
+	}
+	jobID, _ := ctx.Value("jobID").(string)
+	if err := global.GetJobQueue().UnlockNode(context.WithoutCancel(ctx), jobID, step.Node.ID); err != nil {
+		logger.Error("release autopilot step node failed", zap.String("nodeID", step.Node.ID), zap.Error(err))
+	}
+}
+
+// finish 结束自动驾驶并推送最终进度，已被停止或替代时忽略
+func (s *AutopilotService) finish(ctx context.Context, mapID, autopilotID string, status autopilot.Status, reason string) {
+	state, err := s.update(ctx, mapID, autopilotID, func(state *autopilot.State) error {
+		if !state.Status.Active() {
+			return comm.ErrAutopilotInvalidState
+		}
+		state.Finish(status, reason)
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, comm.ErrAutopilotInvalidState) && !errors.Is(err, comm.ErrAutopilotNotFound) {
+			logger.Error("finish autopilot failed", zap.String("mapID", mapID), zap.Error(err))
+		}
+		return
+	}
+	progress := 0
+	if status == autopilot.StatusCompleted {
+		progress = 100
+	}
+	publishAutopilotProgress(state, state.RootNodeID, string(status), progress, reason)
+	logger.Info("Autopilot finished", zap.String("mapID", mapID), zap.String("status", string(status)), zap.String("reason", reason))
+}
+
+// nextAutopilotStep 选择下一个执行步骤，跳过有其他运行的节点
+// 优先汇总子节点已全部完成的节点，其次按深度优先处理待执行节点，最后总结拆解后没有子节点的节点；
+// 没有可执行步骤但有节点被跳过时waiting为true
+func nextAutopilotStep(nodes []*model.ThinkingNode, busy map[string]bool) (step *autopilotStep, waiting bool) {
+	nodeMap := make(map[string]*model.ThinkingNode, len(nodes))
+	children := make(map[string][]*model.ThinkingNode)
+	for _, node := range nodes {
+		nodeMap[node.ID] = node
+	}
+	for _, node := range nodes {
+		if _, ok := nodeMap[node.ParentID]; ok {
+			children[node.ParentID] = append(children[node.ParentID], node)
+		}
+	}
+	depth := func(node *model.ThinkingNode) int {
+		d := 0
+		for parent, ok := nodeMap[node.ParentID]; ok && d < len(nodes); parent, ok = nodeMap[parent.ParentID] {
+			d++
+		}
+		return d
+	}
+
+	var candidates []*autopilotStep
+	for _, node := range nodes {
+		var action string
+		switch node.Status {
+		case comm.NodeStatusPending:
+			action = autopilotActionExpand
+		case comm.NodeStatusInDecomposition, comm.NodeStatusInConclusion:
+			if len(children[node.ID]) == 0 {
+				action = autopilotActionConclude
+				break
+			}
+			action = autopilotActionRollup
+			for _, child := range children[node.ID] {
+				if child.Status != comm.NodeStatusCompleted {
+					action = ""
+					break
+				}
+			}
+		}
+		if action == "" {
+			continue
+		}
+		if busy[node.ID] {
+			waiting = true
+			continue
+		}
+		candidates = append(candidates, &autopilotStep{Action: action, Node: node, Depth: depth(node)})
+	}
+	if len(candidates) == 0 {
+		return nil, waiting
+	}
+	priority := map[string]int{autopilotActionRollup: 0, autopilotActionExpand: 1, autopilotActionConclude: 2}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if priority[a.Action] != priority[b.Action] {
+			return priority[a.Action] < priority[b.Action]
+		}
+		if a.Depth != b.Depth {
+			return a.Depth > b.Depth
+		}
+		return a.Node.CreatedAt.Before(b.Node.CreatedAt)
+	})
+	return candidates[0], false
+}
+
+// autopilotLimitReached 检查步骤数和累计用量限制，达到时返回原因
+func autopilotLimitReached(state *autopilot.State) string {
+	limits := state.Limits
+	switch {
+	case limits.MaxSteps > 0 && state.Steps >= limits.MaxSteps:
+		return fmt.Sprintf("max steps reached (%d)", limits.MaxSteps)
+	case limits.MaxTokens > 0 && state.TotalTokens >= limits.MaxTokens:
+		return fmt.Sprintf("max tokens reached (%d/%d)", state.TotalTokens, limits.MaxTokens)
+	case limits.MaxCost > 0 && state.Cost >= limits.MaxCost:
+		return fmt.Sprintf("max cost reached (%.4f/%.4f)", state.Cost, limits.MaxCost)
+	}
+	return ""
+}
+
+// autopilotProgress 已完成节点占比（0-100）
+func autopilotProgress(nodes []*model.ThinkingNode) int {
+	if len(nodes) == 0 {
+		return 0
+	}
+	completed := 0
+	for _, node := range nodes {
+		if node.Status == comm.NodeStatusCompleted {
+			completed++
+		}
+	}
+	return completed * 100 / len(nodes)
+}
+
+// parseDecompositionDecision 解析拆解决策结果，兼容模型在json外包裹代码块或说明文字
+func parseDecompositionDecision(content string) (*dto.DecompositionDecision, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("invalid decomposition decision: %s", content)
+	}
+	var decision dto.DecompositionDecision
+	if err := json.Unmarshal([]byte(content[start:end+1]), &decision); err != nil {
+		return nil, fmt.Errorf("invalid decomposition decision: %w", err)
+	}
+	return &decision, nil
+}
+
+// findRootNode 查找根节点
+func findRootNode(nodes []*model.ThinkingNode) *model.ThinkingNode {
+	for _, node := range nodes {
+		if node.ParentID == "" || node.ParentID == uuid.Nil.String() {
+			return node
+		}
+	}
+	return nil
+}
+
+// publishAutopilotProgress 推送自动驾驶进度
+func publishAutopilotProgress(state *autopilot.State, nodeID, stage string, progress int, message string) {
+	global.GetBroker().PublishToSession(state.MapID, sse.Event{
+		ID:   nodeID,
+		Type: dto.ThinkingProgressEventType,
+		Data: dto.ThinkingProgressEvent{
+			NodeID:   nodeID,
+			Stage:    stage,
+			Progress: progress,
+			Message:  message,
+		},
+	})
+}
+
+func toAutopilotResponse(state *autopilot.State) *dto.AutopilotResponse {
+	return &dto.AutopilotResponse{
+		ID:            state.ID,
+		MapID:         state.MapID,
+		RootNodeID:    state.RootNodeID,
+		RunID:         state.JobID,
+		Status:        string(state.Status),
+		Limits:        state.Limits,
+		Steps:         state.Steps,
+		NodesCreated:  state.NodesCreated,
+		TotalTokens:   state.TotalTokens,
+		Cost:          state.Cost,
+		CurrentNodeID: state.CurrentNodeID,
+		StopReason:    state.StopReason,
+		CreatedAt:     state.CreatedAt,
+		UpdatedAt:     state.UpdatedAt,
+		FinishedAt:    state.FinishedAt,
+	}
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/tool/search"
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/autopilot"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAutopilotTestNode(id, parentID, status string, createdAt time.Time) *model.ThinkingNode {
+	return &model.ThinkingNode{ID: id, ParentID: parentID, Status: status, CreatedAt: createdAt}
+}
+
+func TestNextAutopilotStep(t *testing.T) {
+	now := time.Now()
+	root := newAutopilotTestNode("root", uuid.Nil.String(), comm.NodeStatusInDecomposition, now)
+	a := newAutopilotTestNode("a", "root", comm.NodeStatusInDecomposition, now.Add(time.Second))
+	a1 := newAutopilotTestNode("a1", "a", comm.NodeStatusCompleted, now.Add(2*time.Second))
+	a2 := newAutopilotTestNode("a2", "a", comm.NodeStatusPending, now.Add(3*time.Second))
+	b := newAutopilotTestNode("b", "root", comm.NodeStatusPending, now.Add(4*time.Second))
+	c := newAutopilotTestNode("c", "root", comm.NodeStatusInitial, now.Add(5*time.Second))
+	nodes := []*model.ThinkingNode{root, a, a1, a2, b, c}
+
+	// 待执行节点深度优先
+	step, waiting := nextAutopilotStep(nodes, nil)
+	require.NotNil(t, step)
+	assert.False(t, waiting)
+	assert.Equal(t, autopilotActionExpand, step.Action)
+	assert.Equal(t, "a2", step.Node.ID)
+	assert.Equal(t, 2, step.Depth)
+
+	// 有其他运行的节点被跳过
+	step, _ = nextAutopilotStep(nodes, map[string]bool{"a2": true})
+	require.NotNil(t, step)
+	assert.Equal(t, "b", step.Node.ID)
+	assert.Equal(t, 1, step.Depth)
+
+	// 子节点全部完成时优先汇总
+	a2.Status = comm.NodeStatusCompleted
+	step, _ = nextAutopilotStep(nodes, nil)
+	require.NotNil(t, step)
+	assert.Equal(t, autopilotActionRollup, step.Action)
+	assert.Equal(t, "a", step.Node.ID)
+
+	// 拆解后没有子节点的节点直接总结
+	a.Status = comm.NodeStatusCompleted
+	b.Status = comm.NodeStatusInDecomposition
+	step, _ = nextAutopilotStep(nodes, nil)
+	require.NotNil(t, step)
+	assert.Equal(t, autopilotActionConclude, step.Action)
+	assert.Equal(t, "b", step.Node.ID)
+
+	// 可执行的节点都在运行中
+	step, waiting = nextAutopilotStep(nodes, map[string]bool{"b": true})
+	assert.Nil(t, step)
+	assert.True(t, waiting)
+
+	// 剩余节点的依赖未完成，没有可执行步骤
+	b.Status = comm.NodeStatusCompleted
+	step, waiting = nextAutopilotStep(nodes, nil)
+	assert.Nil(t, step)
+	assert.False(t, waiting)
+}
+
+func TestAutopilotLimitReached(t *testing.T) {
+	state := &autopilot.State{Limits: autopilot.Limits{MaxSteps: 10, MaxTokens: 1000, MaxCost: 0.5}}
+	assert.Empty(t, autopilotLimitReached(state))
+
+	state.Steps = 10
+	assert.Contains(t, autopilotLimitReached(state), "max steps")
+
+	state.Steps = 1
+	state.TotalTokens = 1200
+	assert.Contains(t, autopilotLimitReached(state), "max tokens")
+
+	state.TotalTokens = 0
+	state.Cost = 0.5
+	assert.Contains(t, autopilotLimitReached(state), "max cost")
+
+	// 零值不限制
+	assert.Empty(t, autopilotLimitReached(&autopilot.State{Steps: 100, TotalTokens: 1 << 20}))
+}
+
+func TestAutopilotProgress(t *testing.T) {
+	assert.Equal(t, 0, autopilotProgress(nil))
+	nodes := []*model.ThinkingNode{
+		{Status: comm.NodeStatusCompleted},
+		{Status: comm.NodeStatusPending},
+		{Status: comm.NodeStatusCompleted},
+		{Status: comm.NodeStatusInConclusion},
+	}
+	assert.Equal(t, 50, autopilotProgress(nodes))
+}
+
+func TestParseDecompositionDecision(t *testing.T) {
+	decision, err := parseDecompositionDecision(`{"shouldDecompose":true,"strategy":"parallel","reason":"涉及多个维度"}`)
+	require.NoError(t, err)
+	assert.True(t, decision.ShouldDecompose)
+	assert.Equal(t, "parallel", decision.Strategy)
+
+	// 模型在json外包裹代码块
+	decision, err = parseDecompositionDecision("```json\n{\"shouldDecompose\":false,\"strategy\":\"\",\"reason\":\"问题明确\"}\n```")
+	require.NoError(t, err)
+	assert.False(t, decision.ShouldDecompose)
+	assert.Equal(t, "问题明确", decision.Reason)
+
+	_, err = parseDecompositionDecision("无需拆解")
+	assert.Error(t, err)
+}
+
+func TestAutopilotStep_SavesRAGRecord(t *testing.T) {
+	ctx := &gin.Context{}
+	userID := uuid.NewString()
+	mapResp, err := mapSvc.CreateMap(ctx, dto.CreateMapRequest{
+		Problem: "自动驾驶检索记录",
+		Target:  "测试目标",
+	}, userID)
+	require.NoError(t, err)
+
+	tests := []struct {
+		action           string
+		conversationType string
+	}{
+		{autopilotActionExpand, dto.ConversationTypeDecomposition},
+		{autopilotActionConclude, dto.ConversationTypeConclusion},
+		{autopilotActionRollup, dto.ConversationTypeConclusion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			nodeResp, err := nodeSvc.CreateNode(ctx, mapResp.ID, dto.CreateNodeRequest{
+				MapID:    mapResp.ID,
+				NodeType: "analysis",
+				Question: "检索问题",
+				Target:   "检索目标",
+			})
+			require.NoError(t, err)
+			node, err := repository.NewThinkingNodeRepository(testDB).FindByID(ctx, nodeResp.ID)
+			require.NoError(t, err)
+
+			// 按自动驾驶步骤的上下文调用检索工具
+			runCtx := global.WithValues(context.Background(), map[string]any{
+				"mapID":   mapResp.ID,
+				"runID":   uuid.NewString(),
+				"user_id": userID,
+			})
+			stepCtx := autopilotStepContext(runCtx, &autopilotStep{Action: tt.action, Node: node, Depth: 1})
+			_, err = search.KBSearchFunc(stepCtx, &search.KBSearchFuncRequest{Query: "检索问题"})
+			require.NoError(t, err)
+
+			msgs, err := messageManager.GetNodeMessages(ctx, node.ID, tt.conversationType)
+			require.NoError(t, err)
+			var ragID string
+			for _, msg := range msgs {
+				if msg.MessageType == model.MsgTypeRAG && msg.Content.RagRecord != nil {
+					ragID = msg.Content.RagRecord.ID
+				}
+			}
+			require.NotEmpty(t, ragID, "rag record message not saved to %s conversation", tt.conversationType)
+			record, err := global.GetRAGRecordRepository().FindByID(ctx, ragID)
+			require.NoError(t, err)
+			assert.Equal(t, "检索问题", record.Query)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// branchPreviewLength 分支摘要的最大字符数
+const branchPreviewLength = 80
+
+// BranchService 节点对话分支
+// 消息通过ParentID构成树，节点的 Decomposition/Conclusion.LastMessageID 指向当前分支的最后一条消息，
+// 新消息总是接在该消息之后，上下文也沿该消息向上构建
+type BranchService struct {
+	nodeRepo    repository.ThinkingNode
+	messageRepo repository.Message
+	msgManager  *global.MessageManager
+}
+
+func NewBranchService(nodeRepo repository.ThinkingNode, messageRepo repository.Message) *BranchService {
+	return &BranchService{
+		nodeRepo:    nodeRepo,
+		messageRepo: messageRepo,
+		msgManager:  global.GetMessageManager(),
+	}
+}
+
+// ListBranches 列出节点对话的所有分支
+func (s *BranchService) ListBranches(ctx context.Context, nodeID, conversationType string) (*dto.BranchListResponse, error) {
+	node, err := s.nodeRepo.FindByID(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	conversationID, lastMessageID, err := global.NodeConversation(node, conversationType)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := s.conversationMessages(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.BranchListResponse{
+		NodeID:           nodeID,
+		ConversationType: conversationType,
+		ConversationID:   conversationID,
+		ActiveMessageID:  lastMessageID,
+		Branches:         buildBranches(messages, lastMessageID),
+	}, nil
+}
+
+// GetBranchMessages 获取从根消息到指定消息的分支消息
+func (s *BranchService) GetBranchMessages(ctx context.Context, nodeID, conversationType, messageID string) ([]*dto.MessageResponse, error) {
+	conversationID, _, err := s.findMessage(ctx, nodeID, conversationType, messageID)
+	if err != nil {
+		return nil, err
+	}
+	return s.msgManager.GetMessageChain(ctx, messageID, conversationID)
+}
+
+// Fork 从指定消息分叉：将其设为当前消息，之后的新消息作为它的新子消息形成新分支，原有后续消息保留在原分支
+func (s *BranchService) Fork(ctx context.Context, nodeID string, req dto.ForkBranchRequest) (*dto.BranchListResponse, error) {
+	if err := s.activate(ctx, nodeID, req.ConversationType, req.MessageID, false); err != nil {
+		return nil, err
+	}
+	return s.ListBranches(ctx, nodeID, req.ConversationType)
+}
+
+// Switch 切换到指定分支，目标必须是分支的最后一条消息
+func (s *BranchService) Switch(ctx context.Context, nodeID string, req dto.SwitchBranchRequest) (*dto.BranchListResponse, error) {
+	if err := s.activate(ctx, nodeID, req.ConversationType, req.LeafMessageID, true); err != nil {
+		return nil, err
+	}
+	return s.ListBranches(ctx, nodeID, req.ConversationType)
+}
+
+// activate 将消息设为节点对话的当前消息，节点有运行中的任务时不允许切换
+func (s *BranchService) activate(ctx context.Context, nodeID, conversationType, messageID string, leafOnly bool) error {
+	if err := checkNodeIdle(ctx, nodeID); err != nil {
+		return err
+	}
+	conversationID, messages, err := s.findMessage(ctx, nodeID, conversationType, messageID)
+	if err != nil {
+		return err
+	}
+	if leafOnly {
+		for _, msg := range messages {
+			if msg.ParentID == messageID {
+				return comm.ErrNotBranchLeaf
+			}
+		}
+	}
+	return s.msgManager.UpdateNodeLastMessage(ctx, nodeID, messageID, conversationID, conversationType)
+}
+
+// findMessage 检查消息属于节点的对话，返回会话ID及会话中的所有消息
+func (s *BranchService) findMessage(ctx context.Context, nodeID, conversationType, messageID string) (string, []*model.Message, error) {
+	node, err := s.nodeRepo.FindByID(ctx, nodeID)
+	if err != nil {
+		return "", nil, err
+	}
+	conversationID, _, err := global.NodeConversation(node, conversationType)
+	if err != nil {
+		return "", nil, err
+	}
+	messages, err := s.conversationMessages(ctx, conversationID)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, msg := range messages {
+		if msg.ID == messageID {
+			return conversationID, messages, nil
+		}
+	}
+	return "", nil, comm.ErrMessageNotFound
+}
+
+func (s *BranchService) conversationMessages(ctx context.Context, conversationID string) ([]*model.Message, error) {
+	if conversationID == "" {
+		return nil, nil
+	}
+	messages, err := s.messageRepo.FindByConversationID(ctx, conversationID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// buildBranches 根据消息树构建分支列表，每条叶子消息对应一个分支，按叶子消息的创建时间排序
+func buildBranches(messages []*model.Message, activeMessageID string) []dto.MessageBranch {
+	byID := make(map[string]*model.Message, len(messages))
+	for _, msg := range messages {
+		byID[msg.ID] = msg
+	}
+	childCount := make(map[string]int, len(messages))
+	for _, msg := range messages {
+		if _, ok := byID[msg.ParentID]; ok {
+			childCount[msg.ParentID]++
+		}
+	}
+
+	branches := make([]dto.MessageBranch, 0)
+	for _, leaf := range messages {
+		if childCount[leaf.ID] > 0 {
+			continue
+		}
+		branch := dto.MessageBranch{
+			LeafMessageID: leaf.ID,
+			Active:        leaf.ID == activeMessageID,
+			UpdatedAt:     leaf.CreatedAt,
+		}
+		for msg := leaf; msg != nil; msg = byID[msg.ParentID] {
+			branch.Length++
+			if branch.Preview == "" && msg.Content.Text != "" {
+				branch.Preview = truncateRunes(msg.Content.Text, branchPreviewLength)
+			}
+			if parent, ok := byID[msg.ParentID]; ok && branch.ForkMessageID == "" && childCount[parent.ID] > 1 {
+				branch.ForkMessageID = parent.ID
+			}
+			// 数据异常形成环时停止
+			if msg.ParentID == "" || msg.ParentID == uuid.Nil.String() || branch.Length >= len(messages) {
+				break
+			}
+		}
+		branches = append(branches, branch)
+	}
+	sort.SliceStable(branches, func(i, j int) bool {
+		return branches[i].UpdatedAt.Before(branches[j].UpdatedAt)
+	})
+	return branches
+}
+
+// truncateRunes 按字符截断文本
+func truncateRunes(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "..."
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBranchTestMessage(id, parentID, text string, createdAt time.Time) *model.Message {
+	return &model.Message{ID: id, ParentID: parentID, Content: model.MessageContent{Text: text}, CreatedAt: createdAt}
+}
+
+func TestBuildBranches(t *testing.T) {
+	now := time.Now()
+	// m1 -> m2 -> m3
+	//          \-> m4 -> m5（从m2分叉）
+	messages := []*model.Message{
+		newBranchTestMessage("m1", uuid.Nil.String(), "问题", now),
+		newBranchTestMessage("m2", "m1", "回答", now.Add(time.Second)),
+		newBranchTestMessage("m3", "m2", "追问A", now.Add(2*time.Second)),
+		newBranchTestMessage("m4", "m2", "追问B", now.Add(3*time.Second)),
+		newBranchTestMessage("m5", "m4", "", now.Add(4*time.Second)),
+	}
+	branches := buildBranches(messages, "m5")
+	require.Len(t, branches, 2)
+
+	assert.Equal(t, "m3", branches[0].LeafMessageID)
+	assert.Equal(t, "m2", branches[0].ForkMessageID)
+	assert.Equal(t, 3, branches[0].Length)
+	assert.Equal(t, "追问A", branches[0].Preview)
+	assert.False(t, branches[0].Active)
+
+	assert.Equal(t, "m5", branches[1].LeafMessageID)
+	assert.Equal(t, "m2", branches[1].ForkMessageID)
+	assert.Equal(t, 4, branches[1].Length)
+	// 最后一条消息没有文本时向上查找
+	assert.Equal(t, "追问B", branches[1].Preview)
+	assert.True(t, branches[1].Active)
+
+	// 分叉后新消息产生前，当前消息不是叶子，没有分支为当前分支
+	for _, branch := range buildBranches(messages, "m2") {
+		assert.False(t, branch.Active)
+	}
+}
+
+func TestBuildBranchesLinear(t *testing.T) {
+	now := time.Now()
+	messages := []*model.Message{
+		newBranchTestMessage("m1", uuid.Nil.String(), strings.Repeat("长", 100), now),
+		newBranchTestMessage("m2", "m1", "", now.Add(time.Second)),
+	}
+	branches := buildBranches(messages, "m2")
+	require.Len(t, branches, 1)
+	assert.Empty(t, branches[0].ForkMessageID)
+	assert.Equal(t, 2, branches[0].Length)
+	assert.Equal(t, strings.Repeat("长", branchPreviewLength)+"...", branches[0].Preview)
+
+	assert.Empty(t, buildBranches(nil, ""))
+}
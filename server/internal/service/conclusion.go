@@ -6,15 +6,19 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
+	"github.com/PGshen/thinking-map/server/internal/agent/base"
 	"github.com/PGshen/thinking-map/server/internal/agent/base/react"
 	"github.com/PGshen/thinking-map/server/internal/agent/callback"
 	conclusionv3 "github.com/PGshen/thinking-map/server/internal/agent/conclusion"
+	"github.com/PGshen/thinking-map/server/internal/agent/llmmodel"
 	"github.com/PGshen/thinking-map/server/internal/global"
 	"github.com/PGshen/thinking-map/server/internal/model"
 	"github.com/PGshen/thinking-map/server/internal/model/dto"
 	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
 	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/PGshen/thinking-map/server/internal/pkg/queue"
 	"github.com/PGshen/thinking-map/server/internal/pkg/sse"
 	"github.com/PGshen/thinking-map/server/internal/pkg/utils"
 	"github.com/PGshen/thinking-map/server/internal/repository"
@@ -25,83 +29,195 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	// conclusionStructuringTimeout 结论结构化整理的超时时间
+	conclusionStructuringTimeout = 2 * time.Minute
+	// operationConclusionStructuring 不在运行中的结构化整理记录用量时使用的操作名
+	operationConclusionStructuring = "conclusion_structuring"
+)
+
 type ConclusionService struct {
 	contextManager *ContextManager
 	msgManager     *global.MessageManager
 	nodeRepo       repository.ThinkingNode
+	mapRepo        repository.ThinkingMap
 }
 
-func NewConclusionV3Service(contextManager *ContextManager, nodeRepo repository.ThinkingNode) *ConclusionService {
+func NewConclusionV3Service(contextManager *ContextManager, nodeRepo repository.ThinkingNode, mapRepo repository.ThinkingMap) *ConclusionService {
 	return &ConclusionService{
 		contextManager: contextManager,
 		msgManager:     global.GetMessageManager(),
 		nodeRepo:       nodeRepo,
+		mapRepo:        mapRepo,
 	}
 }
 
-func (c *ConclusionService) Conclusion(ctx *gin.Context, req dto.ConclusionRequest) error {
-	// 查询上下文
-	contextInfo, err := c.contextManager.GetNodeContextWithConversation(ctx, req.NodeID, "")
+// ConclusionJobPayload 结论任务参数
+type ConclusionJobPayload struct {
+	Instruction string `json:"instruction"`
+	Reference   string `json:"reference"`
+}
+
+// Conclusion 将结论生成/优化任务加入队列，由worker执行
+func (c *ConclusionService) Conclusion(ctx *gin.Context, req dto.ConclusionRequest) (*dto.RunResponse, error) {
+	// 同一节点不允许同时存在多个运行
+	if err := checkNodeIdle(ctx, req.NodeID); err != nil {
+		return nil, err
+	}
+	node, err := c.nodeRepo.FindByID(ctx, req.NodeID)
+	if err != nil {
+		return nil, err
+	}
+	userID := ctx.GetString("user_id")
+	// 保存用户指令，便于之后编辑指令或重新生成
+	var userMsg *dto.MessageResponse
+	if req.Instruction != "" {
+		userMsg, err = c.msgManager.SaveConclusionMessage(ctx, req.NodeID, dto.CreateMessageRequest{
+			ID:          uuid.NewString(),
+			UserID:      userID,
+			MessageType: model.MsgTypeText,
+			Role:        schema.User,
+			Content:     model.MessageContent{Text: formatInstruction(req.Instruction, req.Reference)},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	resp, err := enqueueRun(ctx, queue.EnqueueRequest{
+		Type:   JobTypeConclusion,
+		MapID:  node.MapID,
+		NodeID: req.NodeID,
+		UserID: userID,
+		Payload: ConclusionJobPayload{
+			Instruction: req.Instruction,
+			Reference:   req.Reference,
+		},
+	})
+	if err != nil && userMsg != nil {
+		// 任务未能入队（预算耗尽、节点已有运行等），删除已保存的用户指令，避免留下没有回复的消息
+		discardUserMessage(ctx, req.NodeID, userMsg, dto.ConversationTypeConclusion)
+	}
+	return resp, err
+}
+
+// HandleJob 执行结论任务
+func (c *ConclusionService) HandleJob(ctx context.Context, job *queue.Job) error {
+	var payload ConclusionJobPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	contextInfo, messages, err := c.buildMessages(ctx, job.NodeID, formatInstruction(payload.Instruction, payload.Reference))
 	if err != nil {
 		return err
 	}
-	ctx.Set("mapID", contextInfo.MapInfo.ID)
-	ctx.Set("nodeID", req.NodeID)
-	ctx.Set("operation", "conclusion")
+	var tasks []global.RunTask
+	if contextInfo.NodeInfo.Conclusion.Content != "" {
+		// 有结论，优化结论
+		tasks = append(tasks, func(runCtx context.Context) error {
+			return c.Optimize(runCtx, messages)
+		})
+	}
+	// 无结论，生成结论
+	tasks = append(tasks, func(runCtx context.Context) error {
+		return c.Generate(runCtx, contextInfo, messages)
+	})
+	originStatus := contextInfo.NodeInfo.Status
+	return runJob(ctx, job, global.RunSpec{
+		Tasks: tasks,
+		OnCancelled: func(ctx context.Context, run *global.Run) {
+			c.restoreAfterCancel(ctx, run, originStatus)
+		},
+	})
+}
+
+// formatInstruction 将引用内容拼接到用户指令中
+func formatInstruction(instruction, reference string) string {
+	if instruction == "" || reference == "" {
+		return instruction
+	}
+	return fmt.Sprintf("引用内容：%s\n指令要求：%s", reference, instruction)
+}
+
+// buildMessages 构建结论Agent的输入消息：节点上下文、子节点列表及用户指令
+func (c *ConclusionService) buildMessages(ctx context.Context, nodeID, instruction string) (*ContextInfo, []*schema.Message, error) {
+	// 查询上下文
+	contextInfo, err := c.contextManager.GetNodeContextWithConversation(ctx, nodeID, "")
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// 2. 构建用户消息
 	//  2.1 上下文消息
-	ctxMsg := schema.UserMessage(c.contextManager.FormatContextForAgent(contextInfo))
+	specialist := conclusionv3.ProfileForNodeType(contextInfo.NodeInfo.NodeType).Specialist
+	ctxMsg := schema.UserMessage(c.contextManager.FormatContextWithinBudget(ctx, contextInfo,
+		llmmodel.ProfileNameForSpecialist(llmmodel.AgentConclusionGeneration, specialist), llmmodel.ProfileNameForAgent(llmmodel.AgentConclusionOptimization)))
 	messages := []*schema.Message{ctxMsg}
 
 	// 2.2 查询当前节点的和子节点列表。作为上下文消息，用于后续操作节点
 	childrenMessages, err := c.msgManager.GetNodeChildren(ctx, contextInfo.NodeInfo.ID)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	messages = append(messages, childrenMessages...)
 	// 2.3 用户指令
-	if req.Instruction != "" {
-		instruction := req.Instruction
-		if req.Reference != "" {
-			instruction = fmt.Sprintf("引用内容：%s\n指令要求：%s", req.Reference, instruction)
-		}
+	if instruction != "" {
 		messages = append(messages, schema.UserMessage(instruction))
 	}
-	if contextInfo.NodeInfo.Conclusion.Content != "" {
-		// 有结论，优化结论
-		go c.Optimize(ctx, messages)
+	return contextInfo, messages, nil
+}
+
+// restoreAfterCancel 运行取消后恢复节点到运行前的状态，未保存的结论不会写入节点
+func (c *ConclusionService) restoreAfterCancel(ctx context.Context, run *global.Run, originStatus string) {
+	node, err := c.nodeRepo.FindByID(ctx, run.NodeID)
+	if err != nil {
+		logger.Error("find node after cancel failed", zap.String("nodeID", run.NodeID), zap.Error(err))
+		return
 	}
-	// 无结论，生成结论
-	go c.Generate(ctx, contextInfo, messages)
-	return nil
+	if node.Status != originStatus {
+		node.Status = originStatus
+		if err := c.nodeRepo.Update(ctx, node); err != nil {
+			logger.Error("restore node status after cancel failed", zap.String("nodeID", run.NodeID), zap.Error(err))
+			return
+		}
+	}
+	publishRunCancelled(run.MapID, runCancelledEvent(run, node.Status))
 }
 
-func (c *ConclusionService) Generate(ctx *gin.Context, contextInfo *ContextInfo, messages []*schema.Message) error {
-	userID := ctx.GetString("user_id")
+func (c *ConclusionService) Generate(ctx context.Context, contextInfo *ContextInfo, messages []*schema.Message) error {
+	_, err := c.generate(ctx, contextInfo, messages)
+	return err
+}
+
+// generate 生成结论并返回Agent的最终回答，结论需要保存后才写入节点
+func (c *ConclusionService) generate(ctx context.Context, contextInfo *ContextInfo, messages []*schema.Message) (string, error) {
+	userID, _ := ctx.Value("user_id").(string)
 	messageHandler := &messageHandler{
 		mapID:      contextInfo.MapInfo.ID,
 		nodeID:     contextInfo.NodeInfo.ID,
 		userID:     userID,
 		msgManager: c.msgManager,
 	}
-	agent, err := conclusionv3.BuildGenerationAgent(ctx, react.WithMessageHandler(messageHandler))
+	agent, err := conclusionv3.BuildGenerationAgent(ctx, contextInfo.NodeInfo.NodeType, react.WithMessageHandler(messageHandler))
 	if err != nil {
-		return err
+		return "", err
 	}
 	sr, err := agent.Stream(ctx, messages, compose.WithCallbacks(callback.LogCbHandler))
 	if err != nil {
-		return err
+		return "", err
 	}
+	// 运行取消时关闭流，停止后续输出
+	defer sr.Close()
+	var answer strings.Builder
 	for {
 		chunk, err := sr.Recv()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				break
 			}
-			return err
+			return "", err
 		}
 		fmt.Printf("%s", chunk.Content)
+		answer.WriteString(chunk.Content)
 	}
 	global.GetBroker().PublishToSession(contextInfo.MapInfo.ID, sse.Event{
 		ID:   contextInfo.NodeInfo.ID,
@@ -112,10 +228,10 @@ func (c *ConclusionService) Generate(ctx *gin.Context, contextInfo *ContextInfo,
 			Status: "completed",
 		},
 	})
-	return nil
+	return strings.TrimSpace(answer.String()), nil
 }
 
-func (c ConclusionService) Optimize(ctx *gin.Context, messages []*schema.Message) error {
+func (c ConclusionService) Optimize(ctx context.Context, messages []*schema.Message) error {
 	agent, err := conclusionv3.BuildOptimizationAgent(ctx)
 	if err != nil {
 		return err
@@ -124,6 +240,8 @@ func (c ConclusionService) Optimize(ctx *gin.Context, messages []*schema.Message
 	if err != nil {
 		return err
 	}
+	// 运行取消时关闭流，停止后续输出
+	defer sr.Close()
 	for {
 		chunk, err := sr.Recv()
 		if err != nil {
@@ -134,8 +252,8 @@ func (c ConclusionService) Optimize(ctx *gin.Context, messages []*schema.Message
 		}
 		fmt.Printf("%s", chunk.Content)
 	}
-	mapID := ctx.GetString("mapID")
-	nodeID := ctx.GetString("nodeID")
+	mapID, _ := ctx.Value("mapID").(string)
+	nodeID, _ := ctx.Value("nodeID").(string)
 	global.GetBroker().PublishToSession(mapID, sse.Event{
 		ID:   nodeID,
 		Type: dto.ConclusionCompletedEventType,
@@ -253,20 +371,160 @@ func (c *ConclusionService) SaveConclusion(ctx *gin.Context, nodeID string, req
 		return fmt.Errorf("failed to get node: %w", err)
 	}
 
+	if err := c.saveConclusion(ctx, node, req.Content); err != nil {
+		logger.Error("Failed to save conclusion", zap.String("nodeID", nodeID), zap.Error(err))
+		return err
+	}
+
+	logger.Info("Conclusion saved successfully", zap.String("nodeID", nodeID))
+
+	// 自动汇总父节点结论，失败不影响结论保存
+	if err := c.rollupParent(ctx, node, ctx.GetString("user_id")); err != nil {
+		logger.Error("Failed to roll up parent conclusion", zap.String("nodeID", nodeID), zap.Error(err))
+	}
+	return nil
+}
+
+// saveConclusion 校验引用后写入结论，并将节点标记为已完成
+func (c *ConclusionService) saveConclusion(ctx context.Context, node *model.ThinkingNode, content string) error {
+	// 校验结论中的引用是否来自本节点结论对话的检索结果
+	citations, err := c.verifyCitations(ctx, node.ID, content)
+	if err != nil {
+		return fmt.Errorf("failed to verify conclusion citations: %w", err)
+	}
+
 	// 更新结论内容，保留原有的 conversationID 和 lastMessageID
-	node.Conclusion.Content = req.Content
+	before := *node
+	node.Conclusion.Content = content
+	node.Conclusion.Citations = citations
+	node.Conclusion.Structured, node.Conclusion.Payload = c.structureConclusion(ctx, node)
+	node.Conclusion.Abstract = ""
 	node.Status = comm.NodeStatusCompleted
 
 	// 更新数据库
 	if err := c.nodeRepo.Update(ctx, node); err != nil {
-		logger.Error("Failed to update node conclusion", zap.String("nodeID", nodeID), zap.Error(err))
 		return fmt.Errorf("failed to update node conclusion: %w", err)
 	}
-
-	logger.Info("Conclusion saved successfully", zap.String("nodeID", nodeID))
+	global.GetNodeOperationLog().Record(ctx, model.NodeOpConclusion, &before, node)
+	// 摘要在后台按新结论重新生成
+	global.GetNodeAbstractor().Refresh(node.ID)
 	return nil
 }
 
+// structureConclusion 将结论正文整理为结构化结论及节点类型专属的结构化结论
+// 整理失败、超时或预算耗尽时不影响结论保存，只记录日志，此时两者均为空；
+// 运行中的整理计入运行的预算，不在运行中（如用户直接保存结论）时单独检查预算并记录用量
+func (c *ConclusionService) structureConclusion(ctx context.Context, node *model.ThinkingNode) (*model.StructuredConclusion, *model.ConclusionPayload) {
+	if node.Conclusion.Content == "" {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, conclusionStructuringTimeout)
+	defer cancel()
+	if _, ok := base.GetBudgetTracker(ctx); !ok {
+		budgetManager := global.GetBudgetManager()
+		userID, _ := ctx.Value("user_id").(string)
+		if err := budgetManager.Check(ctx, node.MapID, userID); err != nil {
+			logger.Warn("skip conclusion structuring", zap.String("nodeID", node.ID), zap.Error(err))
+			return nil, nil
+		}
+		tracker, err := budgetManager.NewTracker(ctx, node.MapID, userID)
+		if err != nil {
+			logger.Error("create budget tracker failed", zap.String("nodeID", node.ID), zap.Error(err))
+			return nil, nil
+		}
+		ctx = base.WithBudgetTracker(ctx, tracker)
+		defer func() {
+			if err := budgetManager.RecordUsage(context.Background(), uuid.NewString(), node.MapID, userID, operationConclusionStructuring, tracker); err != nil {
+				logger.Error("failed to record conclusion structuring usage", zap.String("nodeID", node.ID), zap.Error(err))
+			}
+		}()
+	} else if reason := base.BudgetExceeded(ctx); reason != "" {
+		logger.Warn("skip conclusion structuring", zap.String("nodeID", node.ID), zap.String("reason", reason))
+		return nil, nil
+	}
+	agent, err := conclusionv3.BuildStructuringAgent(ctx, node.NodeType)
+	if err != nil {
+		logger.Error("build conclusion structuring agent failed", zap.String("nodeID", node.ID), zap.Error(err))
+		return nil, nil
+	}
+	input := fmt.Sprintf("节点问题：%s\n节点目标：%s\n节点类型：%s\n\n结论正文：\n%s", node.Question, node.Target, node.NodeType, node.Conclusion.Content)
+	output, err := agent.Invoke(ctx, []*schema.Message{schema.UserMessage(input)}, compose.WithCallbacks(callback.LogCbHandler))
+	if err != nil {
+		logger.Error("structure conclusion failed", zap.String("nodeID", node.ID), zap.Error(err))
+		return nil, nil
+	}
+	structured, payload, err := conclusionv3.ParseStructuringOutput(node.NodeType, output.Content)
+	if err != nil {
+		logger.Error("parse structured conclusion failed", zap.String("nodeID", node.ID), zap.Error(err))
+		return nil, nil
+	}
+	return structured, payload
+}
+
+// verifyCitations 解析结论中的引用，并与节点结论对话中的检索记录比对
+func (c *ConclusionService) verifyCitations(ctx context.Context, nodeID, content string) ([]model.Citation, error) {
+	citations := conclusionv3.ParseCitations(content)
+	if len(citations) == 0 {
+		return nil, nil
+	}
+	messages, err := c.msgManager.GetNodeMessages(ctx, nodeID, dto.ConversationTypeConclusion)
+	if err != nil {
+		return nil, err
+	}
+	var records []*model.RAGRecord
+	for _, msg := range messages {
+		if msg.Content.RagRecord != nil {
+			records = append(records, msg.Content.RagRecord)
+		}
+	}
+	return conclusionv3.VerifyCitations(citations, records), nil
+}
+
+// GetConclusion 获取节点结论及引用的来源
+func (c *ConclusionService) GetConclusion(ctx context.Context, nodeID string) (*dto.ConclusionResponse, error) {
+	node, err := c.nodeRepo.FindByID(ctx, nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node: %w", err)
+	}
+	resp := &dto.ConclusionResponse{
+		NodeID:     nodeID,
+		NodeType:   node.NodeType,
+		Content:    node.Conclusion.Content,
+		Abstract:   node.Conclusion.Abstract,
+		Structured: node.Conclusion.Structured,
+		Payload:    node.Conclusion.Payload,
+		Citations:  make([]dto.CitationSource, 0, len(node.Conclusion.Citations)),
+	}
+	records := make(map[string]*model.RAGRecord)
+	for _, citation := range node.Conclusion.Citations {
+		source := dto.CitationSource{Citation: citation}
+		if !citation.Verified {
+			resp.Unverified++
+		} else if citation.RagID != "" {
+			record, ok := records[citation.RagID]
+			if !ok {
+				if record, err = global.GetRAGRecordRepository().FindByID(ctx, citation.RagID); err != nil {
+					logger.Warn("find citation rag record failed", zap.String("ragID", citation.RagID), zap.Error(err))
+					record = nil
+				}
+				records[citation.RagID] = record
+			}
+			if record != nil {
+				source.Source = record.Sources
+				for _, result := range record.Results {
+					if strings.TrimRight(result.URL, "/") == strings.TrimRight(citation.URL, "/") {
+						source.Snippet = result.Content
+						source.Favicon = result.Favicon
+						break
+					}
+				}
+			}
+		}
+		resp.Citations = append(resp.Citations, source)
+	}
+	return resp, nil
+}
+
 // ResetConclusion 重置结论
 func (c *ConclusionService) ResetConclusion(ctx *gin.Context, nodeID string) error {
 	// 获取当前节点
@@ -275,6 +533,7 @@ func (c *ConclusionService) ResetConclusion(ctx *gin.Context, nodeID string) err
 		logger.Error("Failed to get node", zap.String("nodeID", nodeID), zap.Error(err))
 		return fmt.Errorf("failed to get node: %w", err)
 	}
+	before := *node
 
 	// 重置结论相关字段
 	node.Conclusion = model.Conclusion{
@@ -289,6 +548,7 @@ func (c *ConclusionService) ResetConclusion(ctx *gin.Context, nodeID string) err
 		logger.Error("Failed to reset node conclusion", zap.String("nodeID", nodeID), zap.Error(err))
 		return fmt.Errorf("failed to reset node conclusion: %w", err)
 	}
+	global.GetNodeOperationLog().Record(ctx, model.NodeOpConclusion, &before, node)
 
 	logger.Info("Conclusion reset successfully", zap.String("nodeID", nodeID))
 	return nil
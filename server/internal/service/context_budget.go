@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/callback"
+	"github.com/PGshen/thinking-map/server/internal/agent/llmmodel"
+	"github.com/PGshen/thinking-map/server/internal/agent/summary"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/PGshen/thinking-map/server/internal/pkg/utils"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
+)
+
+const (
+	// recentConversationMessages 压缩对话历史时原样保留的最近消息数
+	recentConversationMessages = 4
+	// keptAncestors 压缩祖先节点时原样保留的最近祖先数，即父节点
+	keptAncestors = 1
+	// contextSummaryCacheSize 摘要缓存的最大条数，超出时淘汰最早写入的
+	contextSummaryCacheSize = 512
+)
+
+// 上下文条目类型
+const (
+	contextItemConversation = "conversation"
+	contextItemAncestor     = "ancestor"
+	contextItemDependency   = "dependency"
+	contextItemChild        = "child"
+)
+
+// ContextBudgetForProfiles 获取多个模型配置档中最小的上下文预算，同一份上下文会交给这些配置档的模型，0 表示不限制
+func ContextBudgetForProfiles(profileNames ...string) int {
+	budget := 0
+	for _, name := range profileNames {
+		profile, err := llmmodel.GetProfile(name)
+		if err != nil || profile.ContextBudget <= 0 {
+			continue
+		}
+		if budget == 0 || profile.ContextBudget < budget {
+			budget = profile.ContextBudget
+		}
+	}
+	return budget
+}
+
+// FormatContextWithinBudget 按模型配置档的上下文预算裁剪上下文后格式化，供Agent使用
+func (cm *ContextManager) FormatContextWithinBudget(ctx context.Context, contextInfo *ContextInfo, profileNames ...string) string {
+	return cm.FormatContextForAgent(cm.FitContext(ctx, contextInfo, ContextBudgetForProfiles(profileNames...)))
+}
+
+// FitContext 将上下文裁剪到token预算内，返回裁剪后的副本，不修改原上下文：
+// 1. 按相关性从低到高将祖先、依赖和子节点的结论替换为已生成的结论摘要
+// 2. 较早的对话历史压缩为摘要，保留最近几条消息
+// 3. 较远的祖先节点压缩为摘要，保留父节点
+// 4. 仍超出预算时按相关性从低到高省略对话消息、祖先、依赖和子节点
+// 导图和当前节点信息始终保留，预算为0时不裁剪
+func (cm *ContextManager) FitContext(ctx context.Context, contextInfo *ContextInfo, budget int) *ContextInfo {
+	if contextInfo == nil || budget <= 0 {
+		return contextInfo
+	}
+	fitted := *contextInfo
+	if cm.fits(&fitted, budget) {
+		return &fitted
+	}
+
+	if cm.useAbstracts(&fitted, budget) {
+		return &fitted
+	}
+
+	if len(fitted.ConversationContext) > recentConversationMessages {
+		split := len(fitted.ConversationContext) - recentConversationMessages
+		if text, err := cm.summarize(ctx, summary.KindConversation, formatConversationForSummary(fitted.ConversationContext[:split])); err == nil {
+			fitted.ConversationSummary = text
+			fitted.ConversationContext = fitted.ConversationContext[split:]
+		} else {
+			logger.Warn("summarize conversation failed", zap.String("nodeID", fitted.NodeInfo.ID), zap.Error(err))
+		}
+		if cm.fits(&fitted, budget) {
+			return &fitted
+		}
+	}
+
+	if len(fitted.AncestorsContext) > keptAncestors {
+		split := len(fitted.AncestorsContext) - keptAncestors
+		if text, err := cm.summarize(ctx, summary.KindAncestors, formatNodesForSummary(fitted.AncestorsContext[:split])); err == nil {
+			fitted.AncestorsSummary = text
+			fitted.AncestorsContext = fitted.AncestorsContext[split:]
+		} else {
+			logger.Warn("summarize ancestors failed", zap.String("nodeID", fitted.NodeInfo.ID), zap.Error(err))
+		}
+		if cm.fits(&fitted, budget) {
+			return &fitted
+		}
+	}
+
+	omitted := map[string]map[int]bool{}
+	for _, item := range rankContextItems(&fitted) {
+		if omitted[item.kind] == nil {
+			omitted[item.kind] = map[int]bool{}
+		}
+		omitted[item.kind][item.index] = true
+		trimmed := omitContextItems(&fitted, omitted)
+		if cm.fits(trimmed, budget) {
+			return trimmed
+		}
+	}
+	trimmed := omitContextItems(&fitted, omitted)
+	logger.Warn("context exceeds budget after trimming",
+		zap.String("nodeID", fitted.NodeInfo.ID),
+		zap.Int("budget", budget),
+		zap.Int("tokens", utils.EstimateTokens(cm.FormatContextForAgent(trimmed))))
+	return trimmed
+}
+
+// useAbstracts 按相关性从低到高将节点结论替换为摘要，直到上下文放得下；节点列表先复制再修改
+func (cm *ContextManager) useAbstracts(contextInfo *ContextInfo, budget int) bool {
+	contextInfo.AncestorsContext = append([]NodeContextInfo(nil), contextInfo.AncestorsContext...)
+	contextInfo.DependencyContext = append([]NodeContextInfo(nil), contextInfo.DependencyContext...)
+	contextInfo.ChildrenContext = append([]NodeContextInfo(nil), contextInfo.ChildrenContext...)
+	nodes := map[string][]NodeContextInfo{
+		contextItemAncestor:   contextInfo.AncestorsContext,
+		contextItemDependency: contextInfo.DependencyContext,
+		contextItemChild:      contextInfo.ChildrenContext,
+	}
+	for _, item := range rankContextItems(contextInfo) {
+		list, ok := nodes[item.kind]
+		if !ok {
+			continue
+		}
+		node := &list[item.index]
+		if node.Abstract == "" || node.Abstract == node.Conclusion {
+			continue
+		}
+		node.Conclusion = node.Abstract
+		if cm.fits(contextInfo, budget) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cm *ContextManager) fits(contextInfo *ContextInfo, budget int) bool {
+	return utils.EstimateTokens(cm.FormatContextForAgent(contextInfo)) <= budget
+}
+
+func (cm *ContextManager) summarize(ctx context.Context, kind, text string) (string, error) {
+	if cm.summarizer == nil {
+		return "", errors.New("context summarizer not configured")
+	}
+	return cm.summarizer.Summarize(ctx, kind, text)
+}
+
+// contextItem 可省略的上下文条目，score 越低越先省略
+type contextItem struct {
+	kind  string
+	index int
+	score float64
+}
+
+// rankContextItems 按相关性从低到高排列可省略的上下文条目
+// 基础分：最近的对话消息最高，其次是依赖节点、父节点、子节点，祖先越远分数越低；
+// 再按条目与当前节点问题和目标的关键词重合度加分
+func rankContextItems(contextInfo *ContextInfo) []contextItem {
+	query := tokenSet(contextInfo.NodeInfo.Question + " " + contextInfo.NodeInfo.Target)
+	var items []contextItem
+	add := func(kind string, index int, base float64, text string) {
+		items = append(items, contextItem{kind: kind, index: index, score: base + 0.3*overlap(query, text)})
+	}
+	for i, msg := range contextInfo.ConversationContext {
+		add(contextItemConversation, i, 0.6+0.4*float64(i+1)/float64(len(contextInfo.ConversationContext)), msg.Content)
+	}
+	for i, ancestor := range contextInfo.AncestorsContext {
+		add(contextItemAncestor, i, 0.4+0.3*float64(i+1)/float64(len(contextInfo.AncestorsContext)), nodeContextText(ancestor))
+	}
+	for i, dep := range contextInfo.DependencyContext {
+		add(contextItemDependency, i, 0.8, nodeContextText(dep))
+	}
+	for i, child := range contextInfo.ChildrenContext {
+		add(contextItemChild, i, 0.5, nodeContextText(child))
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].score < items[j].score
+	})
+	return items
+}
+
+// omitContextItems 返回省略指定条目后的上下文副本
+func omitContextItems(contextInfo *ContextInfo, omitted map[string]map[int]bool) *ContextInfo {
+	trimmed := *contextInfo
+	count := 0
+	keepNodes := func(kind string, nodes []NodeContextInfo) []NodeContextInfo {
+		var kept []NodeContextInfo
+		for i, node := range nodes {
+			if omitted[kind][i] {
+				count++
+				continue
+			}
+			kept = append(kept, node)
+		}
+		return kept
+	}
+	trimmed.AncestorsContext = keepNodes(contextItemAncestor, contextInfo.AncestorsContext)
+	trimmed.DependencyContext = keepNodes(contextItemDependency, contextInfo.DependencyContext)
+	trimmed.ChildrenContext = keepNodes(contextItemChild, contextInfo.ChildrenContext)
+	trimmed.ConversationContext = nil
+	for i, msg := range contextInfo.ConversationContext {
+		if omitted[contextItemConversation][i] {
+			count++
+			continue
+		}
+		trimmed.ConversationContext = append(trimmed.ConversationContext, msg)
+	}
+	trimmed.OmittedCount = contextInfo.OmittedCount + count
+	return &trimmed
+}
+
+func tokenSet(text string) map[string]bool {
+	set := map[string]bool{}
+	for _, token := range utils.Tokenize(text) {
+		set[token] = true
+	}
+	return set
+}
+
+// overlap 文本覆盖查询关键词的比例
+func overlap(query map[string]bool, text string) float64 {
+	if len(query) == 0 {
+		return 0
+	}
+	hit := 0
+	for token := range tokenSet(text) {
+		if query[token] {
+			hit++
+		}
+	}
+	return float64(hit) / float64(len(query))
+}
+
+func nodeContextText(node NodeContextInfo) string {
+	return node.Question + " " + node.Target + " " + node.Conclusion
+}
+
+func formatConversationForSummary(messages []ConversationMessage) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		b.WriteString(fmt.Sprintf("%s: %s\n\n", msg.Role, msg.Content))
+	}
+	return b.String()
+}
+
+func formatNodesForSummary(nodes []NodeContextInfo) string {
+	var b strings.Builder
+	for i, node := range nodes {
+		b.WriteString(fmt.Sprintf("%d. 问题：%s\n   目标：%s\n   结论：%s\n\n", i+1, node.Question, node.Target, node.Conclusion))
+	}
+	return b.String()
+}
+
+// summarizeFunc 生成指定类型的摘要
+type summarizeFunc func(ctx context.Context, kind, text string) (string, error)
+
+// summarizeWithAgent 调用摘要Agent生成摘要
+func summarizeWithAgent(ctx context.Context, kind, text string) (string, error) {
+	agent, err := summary.BuildSummaryAgent(ctx, kind)
+	if err != nil {
+		return "", err
+	}
+	output, err := agent.Invoke(ctx, []*schema.Message{schema.UserMessage(text)}, compose.WithCallbacks(callback.LogCbHandler))
+	if err != nil {
+		return "", err
+	}
+	content := strings.TrimSpace(output.Content)
+	if content == "" {
+		return "", errors.New("summary agent returned empty content")
+	}
+	return content, nil
+}
+
+// contextSummarizer 带缓存的摘要生成，相同内容只调用一次模型
+type contextSummarizer struct {
+	summarize summarizeFunc
+	mu        sync.Mutex
+	cache     map[string]string
+	keys      []string // 按写入顺序记录，用于淘汰
+}
+
+func newContextSummarizer(summarize summarizeFunc) *contextSummarizer {
+	return &contextSummarizer{
+		summarize: summarize,
+		cache:     make(map[string]string),
+	}
+}
+
+// Summarize 生成摘要，命中缓存时直接返回
+func (s *contextSummarizer) Summarize(ctx context.Context, kind, text string) (string, error) {
+	sum := sha256.Sum256([]byte(kind + "\x00" + text))
+	key := hex.EncodeToString(sum[:])
+	s.mu.Lock()
+	if cached, ok := s.cache[key]; ok {
+		s.mu.Unlock()
+		return cached, nil
+	}
+	s.mu.Unlock()
+
+	result, err := s.summarize(ctx, kind, text)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.cache[key]; !ok {
+		s.keys = append(s.keys, key)
+		if len(s.keys) > contextSummaryCacheSize {
+			delete(s.cache, s.keys[0])
+			s.keys = s.keys[1:]
+		}
+	}
+	s.cache[key] = result
+	return result, nil
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/summary"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/PGshen/thinking-map/server/internal/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newBudgetTestContext() *ContextInfo {
+	info := &ContextInfo{
+		MapInfo:  &model.ThinkingMap{Title: "市场进入策略", Problem: "是否进入东南亚市场", Target: "给出进入决策"},
+		NodeInfo: &model.ThinkingNode{ID: "node", Question: "分析印尼电商竞争格局", Target: "明确主要竞争对手"},
+	}
+	for i := 0; i < 4; i++ {
+		info.AncestorsContext = append(info.AncestorsContext, NodeContextInfo{
+			NodeID:     fmt.Sprintf("a%d", i),
+			Question:   fmt.Sprintf("祖先问题%d", i),
+			Conclusion: strings.Repeat("祖先结论", 50),
+		})
+	}
+	info.DependencyContext = []NodeContextInfo{{NodeID: "dep", Question: "印尼电商市场规模", Conclusion: strings.Repeat("依赖结论", 50)}}
+	info.ChildrenContext = []NodeContextInfo{
+		{NodeID: "c1", Question: "无关子问题", Conclusion: strings.Repeat("子节点结论", 50)},
+		{NodeID: "c2", Question: "印尼电商竞争对手", Conclusion: strings.Repeat("子节点结论", 50)},
+	}
+	for i := 0; i < 10; i++ {
+		info.ConversationContext = append(info.ConversationContext, ConversationMessage{
+			MessageID: fmt.Sprintf("m%d", i),
+			Role:      "user",
+			Content:   strings.Repeat("对话内容", 30),
+		})
+	}
+	return info
+}
+
+func newBudgetTestManager(calls *[]string, err error) *ContextManager {
+	return &ContextManager{summarizer: newContextSummarizer(func(ctx context.Context, kind, text string) (string, error) {
+		*calls = append(*calls, kind)
+		if err != nil {
+			return "", err
+		}
+		return kind + "摘要", nil
+	})}
+}
+
+func TestFitContextWithinBudget(t *testing.T) {
+	var calls []string
+	cm := newBudgetTestManager(&calls, nil)
+	info := newBudgetTestContext()
+	full := utils.EstimateTokens(cm.FormatContextForAgent(info))
+
+	// 预算充足时不裁剪
+	assert.Same(t, info, cm.FitContext(context.Background(), info, 0))
+	fitted := cm.FitContext(context.Background(), info, full)
+	assert.Equal(t, info.ConversationContext, fitted.ConversationContext)
+	assert.Empty(t, calls)
+
+	// 先压缩较早的对话，再压缩较远的祖先
+	fitted = cm.FitContext(context.Background(), info, full-100)
+	assert.Equal(t, []string{summary.KindConversation}, calls)
+	assert.Equal(t, summary.KindConversation+"摘要", fitted.ConversationSummary)
+	assert.Len(t, fitted.ConversationContext, recentConversationMessages)
+	assert.Equal(t, "m6", fitted.ConversationContext[0].MessageID)
+	assert.Len(t, fitted.AncestorsContext, 4)
+
+	fitted = cm.FitContext(context.Background(), info, full-1000)
+	assert.Equal(t, summary.KindAncestors+"摘要", fitted.AncestorsSummary)
+	require.Len(t, fitted.AncestorsContext, keptAncestors)
+	assert.Equal(t, "a3", fitted.AncestorsContext[0].NodeID)
+	assert.LessOrEqual(t, utils.EstimateTokens(cm.FormatContextForAgent(fitted)), full-1000)
+
+	// 原上下文不被修改
+	assert.Len(t, info.ConversationContext, 10)
+	assert.Len(t, info.AncestorsContext, 4)
+	assert.Empty(t, info.ConversationSummary)
+}
+
+func TestFitContextOmitsLowRelevance(t *testing.T) {
+	var calls []string
+	cm := newBudgetTestManager(&calls, nil)
+	info := newBudgetTestContext()
+	fitted := cm.FitContext(context.Background(), info, 1000)
+	assert.LessOrEqual(t, utils.EstimateTokens(cm.FormatContextForAgent(fitted)), 1000)
+	assert.Greater(t, fitted.OmittedCount, 0)
+	assert.Contains(t, cm.FormatContextForAgent(fitted), fmt.Sprintf("已省略 %d 条", fitted.OmittedCount))
+	// 与当前节点相关的子节点比无关子节点保留得更久
+	if len(fitted.ChildrenContext) == 1 {
+		assert.Equal(t, "c2", fitted.ChildrenContext[0].NodeID)
+	}
+	// 导图和当前节点信息始终保留
+	assert.Contains(t, cm.FormatContextForAgent(fitted), "分析印尼电商竞争格局")
+}
+
+func TestFitContextUsesAbstracts(t *testing.T) {
+	var calls []string
+	cm := newBudgetTestManager(&calls, nil)
+	info := newBudgetTestContext()
+	info.ChildrenContext[0].Abstract = "无关子问题摘要"
+	info.DependencyContext[0].Abstract = "依赖摘要"
+	full := utils.EstimateTokens(cm.FormatContextForAgent(info))
+
+	// 相关性最低的节点先换成摘要，放得下时不再压缩对话
+	fitted := cm.FitContext(context.Background(), info, full-100)
+	assert.Empty(t, calls)
+	assert.Equal(t, "无关子问题摘要", fitted.ChildrenContext[0].Conclusion)
+	assert.Equal(t, strings.Repeat("依赖结论", 50), fitted.DependencyContext[0].Conclusion)
+	assert.Len(t, fitted.ConversationContext, 10)
+
+	// 原上下文不受影响
+	assert.Equal(t, strings.Repeat("子节点结论", 50), info.ChildrenContext[0].Conclusion)
+}
+
+func TestFitContextSummaryFailure(t *testing.T) {
+	if logger.Log == nil {
+		logger.Log = zap.NewNop()
+	}
+	var calls []string
+	cm := newBudgetTestManager(&calls, errors.New("model unavailable"))
+	info := newBudgetTestContext()
+	fitted := cm.FitContext(context.Background(), info, 1500)
+	assert.Empty(t, fitted.ConversationSummary)
+	assert.Empty(t, fitted.AncestorsSummary)
+	assert.LessOrEqual(t, utils.EstimateTokens(cm.FormatContextForAgent(fitted)), 1500)
+}
+
+func TestRankContextItems(t *testing.T) {
+	info := newBudgetTestContext()
+	items := rankContextItems(info)
+	require.Len(t, items, 4+1+2+10)
+	index := func(kind string, i int) int {
+		for pos, item := range items {
+			if item.kind == kind && item.index == i {
+				return pos
+			}
+		}
+		return -1
+	}
+	// 远祖先最先省略，最近的对话消息最后省略
+	assert.Equal(t, contextItemAncestor, items[0].kind)
+	assert.Equal(t, 0, items[0].index)
+	assert.Equal(t, contextItemConversation, items[len(items)-1].kind)
+	assert.Equal(t, 9, items[len(items)-1].index)
+	assert.Less(t, index(contextItemChild, 0), index(contextItemChild, 1))
+	assert.Less(t, index(contextItemAncestor, 3), index(contextItemDependency, 0))
+}
+
+func TestContextSummarizerCache(t *testing.T) {
+	count := 0
+	s := newContextSummarizer(func(ctx context.Context, kind, text string) (string, error) {
+		count++
+		return "摘要" + text, nil
+	})
+	for i := 0; i < 3; i++ {
+		result, err := s.Summarize(context.Background(), summary.KindConversation, "对话")
+		require.NoError(t, err)
+		assert.Equal(t, "摘要对话", result)
+	}
+	assert.Equal(t, 1, count)
+	_, err := s.Summarize(context.Background(), summary.KindAncestors, "对话")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	for i := 0; i < contextSummaryCacheSize+10; i++ {
+		_, err := s.Summarize(context.Background(), summary.KindConversation, fmt.Sprint(i))
+		require.NoError(t, err)
+	}
+	assert.Len(t, s.cache, contextSummaryCacheSize)
+	assert.Len(t, s.keys, contextSummaryCacheSize)
+}
@@ -24,6 +24,7 @@ type ContextManager struct {
 	nodeRepo    repository.ThinkingNode
 	mapRepo     repository.ThinkingMap
 	messageRepo repository.Message
+	summarizer  *contextSummarizer // 上下文超出预算时压缩内容
 }
 
 // NewContextManager 创建新的上下文管理器实例
@@ -32,6 +33,7 @@ func NewContextManager(nodeRepo repository.ThinkingNode, mapRepo repository.Thin
 		nodeRepo:    nodeRepo,
 		mapRepo:     mapRepo,
 		messageRepo: messageRepo,
+		summarizer:  newContextSummarizer(summarizeWithAgent),
 	}
 }
 
@@ -44,6 +46,10 @@ type ContextInfo struct {
 	ChildrenContext     []NodeContextInfo      `json:"childrenContext,omitempty"`
 	ConversationContext []ConversationMessage  `json:"conversationContext,omitempty"`
 	UserContext         map[string]interface{} `json:"userContext,omitempty"`
+	// 以下字段由上下文预算裁剪时填充
+	AncestorsSummary    string `json:"ancestorsSummary,omitempty"`    // 较远祖先节点的摘要
+	ConversationSummary string `json:"conversationSummary,omitempty"` // 较早对话历史的摘要
+	OmittedCount        int    `json:"omittedCount,omitempty"`        // 因超出预算被省略的条目数
 }
 
 // NodeContextInfo 节点上下文信息
@@ -52,6 +58,7 @@ type NodeContextInfo struct {
 	Question   string `json:"question"`
 	Target     string `json:"target"`
 	Conclusion string `json:"conclusion,omitempty"`
+	Abstract   string `json:"abstract,omitempty"` // 结论摘要，结论放不下时代替全文
 	Status     string `json:"status"`
 }
 
@@ -152,7 +159,8 @@ func (cm *ContextManager) getAncestorsContext(ctx context.Context, nodeID string
 			NodeID:     parent.ID,
 			Question:   parent.Question,
 			Target:     parent.Target,
-			Conclusion: parent.Conclusion.Content,
+			Conclusion: parent.Conclusion.ContextText(),
+			Abstract:   parent.Conclusion.Abstract,
 			Status:     parent.Status,
 		}}, ancestors...)
 
@@ -186,7 +194,8 @@ func (cm *ContextManager) getDependencyContext(ctx context.Context, nodeID strin
 			NodeID:     depNode.ID,
 			Question:   depNode.Question,
 			Target:     depNode.Target,
-			Conclusion: depNode.Conclusion.Content,
+			Conclusion: depNode.Conclusion.ContextText(),
+			Abstract:   depNode.Conclusion.Abstract,
 			Status:     depNode.Status,
 		})
 	}
@@ -207,7 +216,8 @@ func (cm *ContextManager) getChildrenContext(ctx context.Context, nodeID string)
 			NodeID:     child.ID,
 			Question:   child.Question,
 			Target:     child.Target,
-			Conclusion: child.Conclusion.Content,
+			Conclusion: child.Conclusion.ContextText(),
+			Abstract:   child.Conclusion.Abstract,
 			Status:     child.Status,
 		})
 	}
@@ -323,8 +333,11 @@ func (cm *ContextManager) FormatContextForAgent(contextInfo *ContextInfo) string
 		contextInfo.NodeInfo.Status)
 
 	// 添加祖先节点上下文（问题分解路径）
-	if len(contextInfo.AncestorsContext) > 0 {
+	if len(contextInfo.AncestorsContext) > 0 || contextInfo.AncestorsSummary != "" {
 		prompt += "\n\n## 问题分解路径（祖先节点）\n以下是从根问题到当前问题的分解路径，帮助你理解问题的层次结构：\n"
+		if contextInfo.AncestorsSummary != "" {
+			prompt += fmt.Sprintf("**较早的分解路径摘要**：%s\n\n", contextInfo.AncestorsSummary)
+		}
 		for i, ancestor := range contextInfo.AncestorsContext {
 			prompt += fmt.Sprintf("%d. **问题**：%s\n   **目标**：%s\n   **结论**：%s\n   **状态**：%s\n\n",
 				i+1, ancestor.Question, ancestor.Target, ancestor.Conclusion, ancestor.Status)
@@ -350,13 +363,20 @@ func (cm *ContextManager) FormatContextForAgent(contextInfo *ContextInfo) string
 	}
 
 	// 添加对话历史上下文
-	if len(contextInfo.ConversationContext) > 0 {
+	if len(contextInfo.ConversationContext) > 0 || contextInfo.ConversationSummary != "" {
 		prompt += "\n## 对话历史\n以下是与用户的历史对话，包含重要的讨论内容：\n"
+		if contextInfo.ConversationSummary != "" {
+			prompt += fmt.Sprintf("**较早的对话摘要**：%s\n\n", contextInfo.ConversationSummary)
+		}
 		for _, msg := range contextInfo.ConversationContext {
 			prompt += fmt.Sprintf("**%s**: %s\n\n", msg.Role, msg.Content)
 		}
 	}
 
+	if contextInfo.OmittedCount > 0 {
+		prompt += fmt.Sprintf("\n> 受上下文长度限制，已省略 %d 条相关性较低的节点或对话。\n", contextInfo.OmittedCount)
+	}
+
 	return prompt
 }
 
@@ -406,6 +426,7 @@ func (cm *ContextManager) convertToNodeDependentContext(contextInfo *ContextInfo
 			Question:   ancestor.Question,
 			Target:     ancestor.Target,
 			Conclusion: ancestor.Conclusion,
+			Abstract:   ancestor.Abstract,
 			Status:     ancestor.Status,
 		})
 	}
@@ -416,6 +437,7 @@ func (cm *ContextManager) convertToNodeDependentContext(contextInfo *ContextInfo
 			Question:   dep.Question,
 			Target:     dep.Target,
 			Conclusion: dep.Conclusion,
+			Abstract:   dep.Abstract,
 			Status:     dep.Status,
 		})
 	}
@@ -426,6 +448,7 @@ func (cm *ContextManager) convertToNodeDependentContext(contextInfo *ContextInfo
 			Question:   child.Question,
 			Target:     child.Target,
 			Conclusion: child.Conclusion,
+			Abstract:   child.Abstract,
 			Status:     child.Status,
 		})
 	}
@@ -10,10 +10,12 @@ import (
 	"github.com/PGshen/thinking-map/server/internal/agent/base"
 	"github.com/PGshen/thinking-map/server/internal/agent/base/multiagent"
 	"github.com/PGshen/thinking-map/server/internal/agent/base/react"
+	"github.com/PGshen/thinking-map/server/internal/agent/llmmodel"
 	"github.com/PGshen/thinking-map/server/internal/global"
 	"github.com/PGshen/thinking-map/server/internal/model"
 	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
 	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/PGshen/thinking-map/server/internal/pkg/queue"
 	"github.com/PGshen/thinking-map/server/internal/pkg/sse"
 	"github.com/PGshen/thinking-map/server/internal/pkg/utils"
 	"github.com/PGshen/thinking-map/server/internal/repository"
@@ -44,7 +46,23 @@ func NewDecompositionService(contextManager *ContextManager, nodeRepo repository
 	}
 }
 
-func (s *DecompositionService) Decomposition(ctx *gin.Context, req dto.DecompositionRequest) (err error) {
+// DecompositionJobPayload 拆解任务参数
+type DecompositionJobPayload struct {
+	LastMessageID string `json:"lastMessageID"` // 保存用户消息之前的最后一条消息，用于构建会话上下文
+	Clarification string `json:"clarification"`
+	IsDecompose   bool   `json:"isDecompose"` // 是否执行拆解
+	// RequirePlanApproval 计划生成或更新后中断，等待用户审批
+	RequirePlanApproval bool `json:"requirePlanApproval"`
+	// CheckpointID 非空时从该检查点恢复拆解，即等待审批的运行ID
+	CheckpointID string `json:"checkpointID,omitempty"`
+}
+
+// Decomposition 保存用户消息并将拆解任务加入队列，由worker执行
+func (s *DecompositionService) Decomposition(ctx *gin.Context, req dto.DecompositionRequest) (resp *dto.RunResponse, err error) {
+	// 同一节点不允许同时存在多个运行
+	if err = checkNodeIdle(ctx, req.NodeID); err != nil {
+		return
+	}
 	node, err := s.nodeRepo.FindByID(ctx, req.NodeID)
 	if err != nil {
 		return
@@ -52,50 +70,230 @@ func (s *DecompositionService) Decomposition(ctx *gin.Context, req dto.Decomposi
 	isDecompose := req.IsDecomposed || node.Decomposition.IsDecomposed // 是否执行拆解
 	lastMsgID := node.Decomposition.LastMessageID
 	userID := ctx.GetString("user_id")
+	if req.IsDecomposed && req.Clarification == "" {
+		req.Clarification = "开始将问题（任务）拆解为多个子问题（子任务）"
+	}
+	// 保存用户消息
+	var userMsg *dto.MessageResponse
+	if req.Clarification != "" {
+		userMsg, err = s.msgManager.SaveDecompositionMessage(ctx, req.NodeID, dto.CreateMessageRequest{
+			ID:          uuid.NewString(),
+			ParentID:    lastMsgID,
+			UserID:      userID,
+			MessageType: model.MsgTypeText,
+			Role:        schema.User,
+			Content:     model.MessageContent{Text: req.Clarification},
+		})
+		if err != nil {
+			return
+		}
+	}
+	resp, err = enqueueRun(ctx, queue.EnqueueRequest{
+		Type:   JobTypeDecomposition,
+		MapID:  node.MapID,
+		NodeID: req.NodeID,
+		UserID: userID,
+		Payload: DecompositionJobPayload{
+			LastMessageID:       lastMsgID,
+			Clarification:       req.Clarification,
+			IsDecompose:         isDecompose,
+			RequirePlanApproval: req.RequirePlanApproval,
+		},
+	})
+	if err != nil && userMsg != nil {
+		// 任务未能入队（预算耗尽、节点已有运行等），删除已保存的用户消息，避免留下没有回复的消息
+		discardUserMessage(ctx, req.NodeID, userMsg, dto.ConversationTypeDecomposition)
+	}
+	return
+}
+
+// HandleJob 执行拆解任务
+func (s *DecompositionService) HandleJob(ctx context.Context, job *queue.Job) error {
+	var payload DecompositionJobPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	node, err := s.nodeRepo.FindByID(ctx, job.NodeID)
+	if err != nil {
+		return err
+	}
+	contextInfo, messages, err := s.buildMessages(ctx, job.NodeID, payload.LastMessageID, payload.Clarification)
+	if err != nil {
+		return err
+	}
+	// 以任务ID作为检查点ID，任务重试时从上次的检查点继续执行；审批后恢复时沿用原检查点
+	checkpointID := payload.CheckpointID
+	resume := checkpointID != ""
+	if !resume {
+		checkpointID = job.ID
+	}
+	var tasks []global.RunTask
+	// 拆解
+	if payload.IsDecompose || resume {
+		tasks = append(tasks, func(runCtx context.Context) error {
+			runCtx = multiagent.WithCheckpointID(runCtx, checkpointID)
+			if payload.RequirePlanApproval {
+				runCtx = multiagent.WithPlanApproval(runCtx)
+			}
+			return s.Decompose(runCtx, contextInfo, messages)
+		})
+	}
+	// 分析，从检查点恢复时不再重复
+	if !resume {
+		tasks = append(tasks, func(runCtx context.Context) error {
+			return s.Analyze(runCtx, contextInfo, messages)
+		})
+	}
+	origin := *node
+	return runJob(ctx, job, global.RunSpec{
+		Tasks: tasks,
+		OnCancelled: func(ctx context.Context, run *global.Run) {
+			s.restoreAfterCancel(ctx, run, &origin, checkpointID)
+		},
+	})
+}
+
+// buildMessages 构建拆解、分析Agent的输入消息：节点上下文、子节点列表及用户提问
+func (s *DecompositionService) buildMessages(ctx context.Context, nodeID, lastMsgID, clarification string) (*ContextInfo, []*schema.Message, error) {
 	// 1. 构建上下文消息
-	contextInfo, err := s.contextManager.GetNodeContextWithConversation(ctx, req.NodeID, lastMsgID)
+	contextInfo, err := s.contextManager.GetNodeContextWithConversation(ctx, nodeID, lastMsgID)
 	if err != nil {
-		return
+		return nil, nil, err
 	}
-	// 将mapID, nodeID传入到ctx
-	ctx.Set("mapID", contextInfo.MapInfo.ID)
-	ctx.Set("nodeID", req.NodeID)
-	ctx.Set("operation", "decomposition")
 
 	// 2. 构建用户消息
 	//  2.1 上下文消息
-	ctxMsg := schema.UserMessage(s.contextManager.FormatContextForAgent(contextInfo))
+	ctxMsg := schema.UserMessage(s.contextManager.FormatContextWithinBudget(ctx, contextInfo,
+		llmmodel.ProfileNameForAgent(llmmodel.AgentDecomposition), llmmodel.ProfileNameForAgent(llmmodel.AgentAnalysis)))
 	messages := []*schema.Message{ctxMsg}
 
 	// 2.2 查询当前节点的和子节点列表。作为上下文消息，用于后续操作节点
 	childrenMessages, err := s.msgManager.GetNodeChildren(ctx, contextInfo.NodeInfo.ID)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	messages = append(messages, childrenMessages...)
-	if req.IsDecomposed && req.Clarification == "" {
-		req.Clarification = "开始将问题（任务）拆解为多个子问题（子任务）"
+	// 2.3 节点类型的拆解指引
+	if guide := decomposition.NodeTypeGuide(contextInfo.NodeInfo.NodeType); guide != "" {
+		messages = append(messages, schema.UserMessage(guide))
 	}
-	// 2.3 用户提问
-	if req.Clarification != "" {
-		messages = append(messages, schema.UserMessage(req.Clarification))
-		// 3. 保存用户消息
-		_, err = s.msgManager.SaveDecompositionMessage(ctx, req.NodeID, dto.CreateMessageRequest{
-			ID:          uuid.NewString(),
-			ParentID:    lastMsgID,
-			UserID:      userID,
-			MessageType: model.MsgTypeText,
-			Role:        schema.User,
-			Content:     model.MessageContent{Text: req.Clarification},
+	// 2.4 用户提问
+	if clarification != "" {
+		messages = append(messages, schema.UserMessage(clarification))
+	}
+	return contextInfo, messages, nil
+}
+
+// ReviewPlan 审批等待中的计划，runID为等待审批的运行ID
+// 通过或编辑后重新加入任务队列，从检查点继续执行；拒绝时结束本次拆解
+func (s *DecompositionService) ReviewPlan(ctx context.Context, runID, userID string, req dto.PlanReviewRequest) (*dto.PlanReviewResponse, error) {
+	job, err := global.GetJobQueue().Get(ctx, runID)
+	if err != nil {
+		if errors.Is(err, queue.ErrJobNotFound) {
+			return nil, comm.ErrRunNotFound
+		}
+		return nil, err
+	}
+	if job.Type != JobTypeDecomposition {
+		return nil, comm.ErrRunNotFound
+	}
+	if job.UserID != "" && job.UserID != userID {
+		return nil, comm.ErrNoPermission
+	}
+	if err := checkNodeIdle(ctx, job.NodeID); err != nil {
+		return nil, err
+	}
+	var payload DecompositionJobPayload
+	if err := job.Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	plan, err := multiagent.ReviewPlan(ctx, global.GetCheckpointStore(), runID, multiagent.PlanReview{
+		Action:     multiagent.PlanReviewAction(req.Action),
+		Operations: req.Operations,
+		Reason:     req.Reason,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, multiagent.ErrCheckpointNotFound), errors.Is(err, multiagent.ErrPlanNotAwaitingApproval):
+			return nil, comm.ErrPlanNotAwaitingApproval
+		case errors.Is(err, multiagent.ErrInvalidPlanReview):
+			return nil, fmt.Errorf("%w: %v", comm.ErrInvalidPlanReview, err)
+		}
+		return nil, err
+	}
+
+	resp := &dto.PlanReviewResponse{
+		Status: string(multiagent.PlanApprovalApproved),
+		Plan:   *plan,
+	}
+	if req.Action == string(multiagent.PlanReviewReject) {
+		resp.Status = string(multiagent.PlanApprovalRejected)
+	} else {
+		resp.Run, err = enqueueRun(ctx, queue.EnqueueRequest{
+			Type:   JobTypeDecomposition,
+			MapID:  job.MapID,
+			NodeID: job.NodeID,
+			UserID: userID,
+			Payload: DecompositionJobPayload{
+				LastMessageID:       payload.LastMessageID,
+				IsDecompose:         true,
+				RequirePlanApproval: payload.RequirePlanApproval,
+				CheckpointID:        runID,
+			},
 		})
+		if err != nil {
+			return nil, err
+		}
 	}
-	// 拆解
-	if isDecompose {
-		go s.Decompose(ctx, contextInfo, messages)
+	publishPlanReview(job.MapID, job.NodeID, runID, resp.Status, plan)
+	return resp, nil
+}
+
+// publishPlanReview 推送计划审批状态
+func publishPlanReview(mapID, nodeID, runID, status string, plan *multiagent.TaskPlan) {
+	global.GetBroker().PublishToSession(mapID, sse.Event{
+		ID:   nodeID,
+		Type: dto.MessagePlanEventType,
+		Data: dto.MessagePlanEvent{
+			NodeID:    nodeID,
+			MessageID: uuid.NewString(),
+			Plan:      *plan,
+			IsEnd:     true,
+			RunID:     runID,
+			Status:    status,
+		},
+	})
+}
+
+// restoreAfterCancel 运行取消后恢复节点状态：已拆解出子节点则保持拆解中，否则恢复到运行前的状态
+func (s *DecompositionService) restoreAfterCancel(ctx context.Context, run *global.Run, origin *model.ThinkingNode, checkpointID string) {
+	node, err := s.nodeRepo.FindByID(ctx, run.NodeID)
+	if err != nil {
+		logger.Error("find node after cancel failed", zap.String("nodeID", run.NodeID), zap.Error(err))
+		return
 	}
-	// 分析
-	go s.Analyze(ctx, contextInfo, messages)
-	return nil
+	children, err := s.nodeRepo.FindByParentID(ctx, run.NodeID)
+	if err != nil {
+		logger.Error("find children after cancel failed", zap.String("nodeID", run.NodeID), zap.Error(err))
+		return
+	}
+	if len(children) > 0 {
+		node.Status = comm.NodeStatusInDecomposition
+		node.Decomposition.IsDecomposed = true
+	} else {
+		node.Status = origin.Status
+		node.Decomposition.IsDecomposed = origin.Decomposition.IsDecomposed
+	}
+	if err := s.nodeRepo.Update(ctx, node); err != nil {
+		logger.Error("restore node status after cancel failed", zap.String("nodeID", run.NodeID), zap.Error(err))
+		return
+	}
+	// 取消后不再恢复，清理检查点
+	if err := global.GetCheckpointStore().Delete(ctx, checkpointID); err != nil {
+		logger.Warn("delete checkpoint after cancel failed", zap.String("runID", checkpointID), zap.Error(err))
+	}
+	publishRunCancelled(run.MapID, runCancelledEvent(run, node.Status))
 }
 
 // ResetDecomposition resets the decomposition of a node.
@@ -106,13 +304,22 @@ func (s *DecompositionService) ResetDecomposition(ctx context.Context, nodeID st
 		return fmt.Errorf("failed to find node by id %s: %w", nodeID, err)
 	}
 
-	// 2. 删除所有子节点
+	// 2. 删除所有子节点，与节点的重置一起作为一批操作记录到操作日志，一次撤销即可还原
+	children, err := s.nodeRepo.FindByParentID(ctx, nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to find child nodes: %w", err)
+	}
 	if err := s.nodeRepo.DeleteByParentID(ctx, nodeID); err != nil {
 		return fmt.Errorf("failed to delete child nodes: %w", err)
 	}
+	changes := make([]global.NodeOpChange, 0, len(children)+1)
+	for _, child := range children {
+		changes = append(changes, global.NodeOpChange{Op: model.NodeOpDelete, Before: child})
+	}
 
 	// 4. 重置当前节点的拆解信息
 	// 重置结论相关字段
+	before := *node
 	node.Decomposition = model.Decomposition{
 		IsDecomposed:   false,
 		ConversationID: "",
@@ -125,14 +332,16 @@ func (s *DecompositionService) ResetDecomposition(ctx context.Context, nodeID st
 		logger.Error("Failed to reset node decomposition", zap.String("nodeID", nodeID), zap.Error(err))
 		return fmt.Errorf("failed to reset node decomposition: %w", err)
 	}
+	changes = append(changes, global.NodeOpChange{Op: model.NodeOpDecomposition, Before: &before, After: node})
+	global.GetNodeOperationLog().RecordBatch(ctx, changes...)
 
 	logger.Info("Decomposition reset successfully", zap.String("nodeID", nodeID))
 	return nil
 }
 
 // Analyze performs intent analysis for a given node
-func (s *DecompositionService) Analyze(ctx *gin.Context, contextInfo *ContextInfo, messages []*schema.Message) (err error) {
-	userID := ctx.GetString("user_id")
+func (s *DecompositionService) Analyze(ctx context.Context, contextInfo *ContextInfo, messages []*schema.Message) (err error) {
+	userID, _ := ctx.Value("user_id").(string)
 	defer func() {
 		if err != nil {
 			logger.Error("Analyze failed", zap.Error(err))
@@ -156,6 +365,8 @@ func (s *DecompositionService) Analyze(ctx *gin.Context, contextInfo *ContextInf
 	if err != nil {
 		return
 	}
+	// 运行取消时关闭流，停止后续输出
+	defer sr.Close()
 	for {
 		_, err := sr.Recv()
 		if err != nil {
@@ -294,8 +505,8 @@ outer:
 }
 
 // Decompose 拆解节点
-func (s *DecompositionService) Decompose(ctx *gin.Context, contextInfo *ContextInfo, messages []*schema.Message) (err error) {
-	userID := ctx.GetString("user_id")
+func (s *DecompositionService) Decompose(ctx context.Context, contextInfo *ContextInfo, messages []*schema.Message) (err error) {
+	userID, _ := ctx.Value("user_id").(string)
 	defer func() {
 		if err != nil {
 			logger.Error("Decompose failed", zap.Error(err))
@@ -331,9 +542,6 @@ func (s *DecompositionService) Decompose(ctx *gin.Context, contextInfo *ContextI
 		messageID:  uuid.NewString(),
 		msgManager: s.msgManager,
 	}
-	// 将mapID, nodeID保存至ctx, 工具调用时会用到
-	ctx.Set("mapID", contextInfo.MapInfo.ID)
-	ctx.Set("nodeID", contextInfo.NodeInfo.ID)
 	// 4. 调用分析Agent
 	agent, err := decomposition.BuildDecompositionAgent(ctx)
 	if err != nil {
@@ -354,15 +562,17 @@ func (s *DecompositionService) Decompose(ctx *gin.Context, contextInfo *ContextI
 	opts = append(opts, compose.WithCallbacks(callback.LogCbHandler))
 	sr, err := agent.Stream(ctx, messages, opts...)
 	if err != nil {
-		return
+		return s.awaitPlanApproval(contextInfo, err)
 	}
+	// 运行取消时关闭流，停止后续输出
+	defer sr.Close()
 	for {
 		chunk, err := sr.Recv()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				break
 			}
-			return err
+			return s.awaitPlanApproval(contextInfo, err)
 		}
 		fmt.Printf("%s", chunk.Content)
 	}
@@ -378,6 +588,16 @@ func (s *DecompositionService) Decompose(ctx *gin.Context, contextInfo *ContextI
 	return
 }
 
+// awaitPlanApproval 计划等待审批时中断拆解并推送待审批的计划，其他错误原样返回
+func (s *DecompositionService) awaitPlanApproval(contextInfo *ContextInfo, err error) error {
+	var approvalErr *multiagent.PlanApprovalError
+	if !errors.As(err, &approvalErr) {
+		return err
+	}
+	publishPlanReview(contextInfo.MapInfo.ID, contextInfo.NodeInfo.ID, approvalErr.RunID, string(multiagent.PlanApprovalAwaiting), approvalErr.Plan)
+	return nil
+}
+
 type analyzerMessageHandler struct {
 	mapID      string
 	nodeID     string
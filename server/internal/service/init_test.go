@@ -51,6 +51,7 @@ func TestMain(m *testing.M) {
 	mapSvc = NewMapService(mapRepo)
 
 	nodeRepo := repository.NewThinkingNodeRepository(testDB)
+	global.InitNodeOperationLog(repository.NewNodeOperationRepository(testDB))
 	nodeSvc = NewNodeService(nodeRepo, mapRepo)
 	dependencyChecker = NewDependencyChecker(nodeRepo)
 
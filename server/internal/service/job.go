@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/base"
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/PGshen/thinking-map/server/internal/pkg/queue"
+	"github.com/PGshen/thinking-map/server/internal/pkg/sse"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 任务类型，与运行的operation一致
+const (
+	JobTypeDecomposition = "decomposition"
+	JobTypeConclusion    = "conclusion"
+	JobTypeRollup        = "rollup"
+	JobTypeAutopilot     = "autopilot"
+)
+
+// NewJobWorker 创建消费Agent运行任务的worker，count为并发数
+func NewJobWorker(db *gorm.DB, count int) *queue.Worker {
+	nodeRepo := repository.NewThinkingNodeRepository(db)
+	mapRepo := repository.NewThinkingMapRepository(db)
+	contextManager := NewContextManager(nodeRepo, mapRepo, repository.NewMessageRepository(db))
+	decompositionService := NewDecompositionService(contextManager, nodeRepo)
+	conclusionService := NewConclusionV3Service(contextManager, nodeRepo, mapRepo)
+	autopilotService := NewAutopilotService(decompositionService, conclusionService, nodeRepo, mapRepo)
+
+	worker := queue.NewWorker(global.GetJobQueue(), count)
+	worker.Handle(JobTypeDecomposition, decompositionService.HandleJob)
+	worker.Handle(JobTypeConclusion, conclusionService.HandleJob)
+	worker.Handle(JobTypeRollup, conclusionService.HandleRollupJob)
+	worker.Handle(JobTypeAutopilot, autopilotService.HandleJob)
+	return worker
+}
+
+// JobService 任务状态查询
+type JobService struct{}
+
+func NewJobService() *JobService {
+	return &JobService{}
+}
+
+// GetJob 查询任务状态，只允许发起者查询
+func (s *JobService) GetJob(ctx context.Context, jobID, userID string) (*dto.JobResponse, error) {
+	job, err := global.GetJobQueue().Get(ctx, jobID)
+	if err != nil {
+		if errors.Is(err, queue.ErrJobNotFound) {
+			return nil, comm.ErrJobNotFound
+		}
+		return nil, err
+	}
+	if job.UserID != "" && job.UserID != userID {
+		return nil, comm.ErrNoPermission
+	}
+	return toJobResponse(job), nil
+}
+
+// enqueueRun 将运行加入任务队列，同一节点同一时间只允许一个未结束的任务
+// 思维导图或用户的累计预算耗尽时拒绝入队
+func enqueueRun(ctx context.Context, req queue.EnqueueRequest) (*dto.RunResponse, error) {
+	if err := global.GetBudgetManager().Check(ctx, req.MapID, req.UserID); err != nil {
+		return nil, err
+	}
+	job, err := global.GetJobQueue().Enqueue(ctx, req)
+	if err != nil {
+		if errors.Is(err, queue.ErrJobConflict) {
+			return nil, comm.ErrRunConflict
+		}
+		return nil, err
+	}
+	return &dto.RunResponse{
+		RunID:     job.ID,
+		MapID:     job.MapID,
+		NodeID:    job.NodeID,
+		Operation: job.Type,
+		Status:    string(job.Status),
+		CreatedAt: job.CreatedAt,
+	}, nil
+}
+
+// checkNodeIdle 检查节点上是否有未结束的任务
+func checkNodeIdle(ctx context.Context, nodeID string) error {
+	_, active, err := global.GetJobQueue().ActiveJobID(ctx, nodeID)
+	if err != nil {
+		return err
+	}
+	if active {
+		return comm.ErrRunConflict
+	}
+	return nil
+}
+
+// discardUserMessage 删除入队前保存的用户消息，删除失败只记录日志，不覆盖入队的错误
+func discardUserMessage(ctx context.Context, nodeID string, msg *dto.MessageResponse, conversationType string) {
+	if err := global.GetMessageManager().DiscardNodeMessage(ctx, nodeID, msg, conversationType); err != nil {
+		logger.Error("discard user message failed", zap.String("nodeID", nodeID), zap.String("messageID", msg.ID), zap.Error(err))
+	}
+}
+
+// runJob 在运行注册表中启动任务对应的运行，并等待运行结束
+// 任务被取消时取消运行，由OnCancelled恢复节点状态；
+// worker停止时同样中断运行，但节点状态保持不变，任务重新入队后继续执行
+// 运行中的模型调用用量计入预算跟踪器，预算耗尽时通知前端，运行结束后记录用量
+func runJob(ctx context.Context, job *queue.Job, spec global.RunSpec) error {
+	registry := global.GetRunRegistry()
+	budgetManager := global.GetBudgetManager()
+	tracker, err := budgetManager.NewTracker(ctx, job.MapID, job.UserID)
+	if err != nil {
+		return err
+	}
+	tracker.OnExceeded(func(reason string) {
+		publishBudgetExceeded(job.MapID, job.NodeID, reason)
+	})
+	for i, task := range spec.Tasks {
+		spec.Tasks[i] = func(c context.Context) error {
+			return task(base.WithBudgetTracker(c, tracker))
+		}
+	}
+	spec.ID = job.ID
+	spec.MapID = job.MapID
+	spec.NodeID = job.NodeID
+	spec.Operation = job.Type
+	spec.Values = map[string]any{"user_id": job.UserID, "jobID": job.ID}
+	onCancelled := spec.OnCancelled
+	spec.OnCancelled = func(c context.Context, run *global.Run) {
+		if errors.Is(context.Cause(ctx), queue.ErrWorkerStopped) {
+			return
+		}
+		if onCancelled != nil {
+			onCancelled(c, run)
+		}
+	}
+	run, err := registry.Start(nil, spec)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-run.Done():
+	case <-ctx.Done():
+		_, _ = registry.Cancel(run.ID)
+		<-run.Done()
+	}
+	if err := budgetManager.Record(context.Background(), run, tracker); err != nil {
+		logger.Error("failed to record run usage", zap.String("runID", run.ID), zap.Error(err))
+	}
+	if run.Cancelled() {
+		return queue.ErrJobCancelled
+	}
+	return run.Err()
+}
+
+// publishBudgetExceeded 通知前端运行预算耗尽，Agent将尽快给出最终回答
+func publishBudgetExceeded(mapID, nodeID, reason string) {
+	global.GetBroker().PublishToSession(mapID, sse.Event{
+		ID:   uuid.NewString(),
+		Type: dto.MessageNoticeEventType,
+		Data: dto.MessageNoticeEvent{
+			NodeID:    nodeID,
+			MessageID: uuid.NewString(),
+			Notice: model.Notice{
+				Type:    model.NoticeTypeWarning,
+				Name:    "预算耗尽",
+				Content: reason,
+			},
+		},
+	})
+}
+
+func toJobResponse(job *queue.Job) *dto.JobResponse {
+	return &dto.JobResponse{
+		JobID:       job.ID,
+		Type:        job.Type,
+		MapID:       job.MapID,
+		NodeID:      job.NodeID,
+		Status:      string(job.Status),
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		LastError:   job.LastError,
+		CreatedAt:   job.CreatedAt,
+		UpdatedAt:   job.UpdatedAt,
+		StartedAt:   job.StartedAt,
+		FinishedAt:  job.FinishedAt,
+		NextRunAt:   job.NextRunAt,
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+)
+
+// MaxKBDocumentSize 单个知识库文档的最大字节数
+const MaxKBDocumentSize = 10 << 20
+
+// kbContentTypes 按扩展名识别文档类型，PDF等需要先转换为文本再上传
+var kbContentTypes = map[string]string{
+	".txt":      model.KBContentText,
+	".text":     model.KBContentText,
+	".md":       model.KBContentMarkdown,
+	".markdown": model.KBContentMarkdown,
+	".csv":      model.KBContentCSV,
+}
+
+type KnowledgeBaseService struct {
+	kb *global.KnowledgeBase
+}
+
+func NewKnowledgeBaseService(kb *global.KnowledgeBase) *KnowledgeBaseService {
+	return &KnowledgeBaseService{kb: kb}
+}
+
+// UploadDocument 上传文档到思维导图知识库，切分并建立索引
+func (s *KnowledgeBaseService) UploadDocument(ctx context.Context, mapID, userID, filename string, content []byte) (*dto.KBDocumentResponse, error) {
+	name := filepath.Base(filename)
+	contentType, ok := kbContentTypes[strings.ToLower(filepath.Ext(name))]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", comm.ErrUnsupportedDocument, name)
+	}
+	if !utf8.Valid(content) {
+		return nil, fmt.Errorf("%w: %s is not utf-8 text", comm.ErrUnsupportedDocument, name)
+	}
+	doc := &model.KBDocument{
+		MapID:       mapID,
+		UserID:      userID,
+		Name:        name,
+		ContentType: contentType,
+	}
+	if err := s.kb.AddDocument(ctx, doc, string(content)); err != nil {
+		return nil, err
+	}
+	resp := dto.ToKBDocumentResponse(doc)
+	return &resp, nil
+}
+
+// ListDocuments 思维导图知识库中的文档
+func (s *KnowledgeBaseService) ListDocuments(ctx context.Context, mapID string) ([]dto.KBDocumentResponse, error) {
+	docs, err := s.kb.ListDocuments(ctx, mapID)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]dto.KBDocumentResponse, len(docs))
+	for i, doc := range docs {
+		items[i] = dto.ToKBDocumentResponse(doc)
+	}
+	return items, nil
+}
+
+// DeleteDocument 删除知识库文档
+func (s *KnowledgeBaseService) DeleteDocument(ctx context.Context, mapID, documentID string) error {
+	return s.kb.DeleteDocument(ctx, mapID, documentID)
+}
+
+// Search 检索思维导图知识库
+func (s *KnowledgeBaseService) Search(ctx context.Context, mapID string, query dto.KBSearchQuery) ([]dto.KBSearchHit, error) {
+	hits, err := s.kb.Search(ctx, mapID, query.Query, query.Limit)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]dto.KBSearchHit, len(hits))
+	for i, hit := range hits {
+		items[i] = dto.KBSearchHit{
+			DocumentID:   hit.DocumentID,
+			DocumentName: hit.DocumentName,
+			Seq:          hit.Seq,
+			Content:      hit.Content,
+			Score:        hit.Rank,
+		}
+	}
+	return items, nil
+}
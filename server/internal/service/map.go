@@ -39,6 +39,16 @@ func (s *MapService) CreateMap(ctx context.Context, req dto.CreateMapRequest, us
 		Metadata:    datatypes.JSON{},
 		Status:      comm.MapStatusInitial,
 	}
+	if req.SearchProvider != "" {
+		if err := thinkingMap.SetMeta(model.MapMetaSearchProvider, req.SearchProvider); err != nil {
+			return nil, err
+		}
+	}
+	if req.AutoRollup {
+		if err := thinkingMap.SetMeta(model.MapMetaAutoRollup, true); err != nil {
+			return nil, err
+		}
+	}
 
 	rootNodeID := uuid.NewString()
 	rootNode := &model.ThinkingNode{
@@ -151,6 +161,28 @@ func (s *MapService) UpdateMap(ctx context.Context, mapID string, req dto.Update
 	if req.Conclusion != "" {
 		updates["conclusion"] = req.Conclusion
 	}
+	if req.SearchProvider != "" || req.AutoRollup != nil || req.NodeDryRun != nil {
+		thinkingMap, err := s.mapRepo.FindByID(ctx, mapID)
+		if err != nil {
+			return nil, err
+		}
+		if req.SearchProvider != "" {
+			if err := thinkingMap.SetMeta(model.MapMetaSearchProvider, req.SearchProvider); err != nil {
+				return nil, err
+			}
+		}
+		if req.AutoRollup != nil {
+			if err := thinkingMap.SetMeta(model.MapMetaAutoRollup, *req.AutoRollup); err != nil {
+				return nil, err
+			}
+		}
+		if req.NodeDryRun != nil {
+			if err := thinkingMap.SetMeta(model.MapMetaNodeDryRun, *req.NodeDryRun); err != nil {
+				return nil, err
+			}
+		}
+		updates["metadata"] = thinkingMap.Metadata
+	}
 	if err := s.mapRepo.Update(ctx, mapID, updates); err != nil {
 		return nil, err
 	}
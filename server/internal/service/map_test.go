@@ -13,6 +13,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/PGshen/thinking-map/server/internal/model"
 	"github.com/PGshen/thinking-map/server/internal/model/dto"
 )
 
@@ -87,3 +88,28 @@ func TestMapService_CRUD(t *testing.T) {
 	_, err = mapSvc.GetMap(ctx, mapID)
 	assert.Error(t, err)
 }
+
+func TestMapService_AutoRollup(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.NewString()
+
+	mapResp, err := mapSvc.CreateMap(ctx, dto.CreateMapRequest{
+		Problem:    "测试自动汇总",
+		AutoRollup: true,
+	}, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, true, mapResp.Metadata[model.MapMetaAutoRollup])
+
+	// 关闭自动汇总，其他设置保持不变
+	disabled := false
+	updatedMap, err := mapSvc.UpdateMap(ctx, mapResp.ID, dto.UpdateMapRequest{AutoRollup: &disabled}, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, false, updatedMap.Metadata[model.MapMetaAutoRollup])
+
+	// 未设置时不修改
+	updatedMap, err = mapSvc.UpdateMap(ctx, mapResp.ID, dto.UpdateMapRequest{Title: "新标题"}, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, false, updatedMap.Metadata[model.MapMetaAutoRollup])
+
+	assert.NoError(t, mapSvc.DeleteMap(ctx, mapResp.ID, userID))
+}
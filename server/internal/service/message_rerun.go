@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/PGshen/thinking-map/server/internal/pkg/queue"
+	"github.com/PGshen/thinking-map/server/internal/pkg/sse"
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	rerunModeEdit       = "edit"
+	rerunModeRegenerate = "regenerate"
+)
+
+// EditMessage 编辑历史用户消息：编辑后的消息作为原消息的兄弟消息保存，并以此前的上下文重新运行拆解或结论，
+// 原消息及其后续消息保留在原分支，可通过分支切换选择
+func (s *BranchService) EditMessage(ctx context.Context, nodeID, userID, messageID string, req dto.EditMessageRequest) (*dto.RerunMessageResponse, error) {
+	if err := checkNodeIdle(ctx, nodeID); err != nil {
+		return nil, err
+	}
+	node, conversationID, messages, err := s.rerunTarget(ctx, nodeID, req.ConversationType, messageID)
+	if err != nil {
+		return nil, err
+	}
+	msg := findByID(messages, messageID)
+	if msg.Role != schema.User || msg.MessageType != model.MsgTypeText {
+		return nil, comm.ErrMessageNotRerunnable
+	}
+	_, originLastMessageID, err := global.NodeConversation(node, req.ConversationType)
+	if err != nil {
+		return nil, err
+	}
+
+	forkID := rootAsEmpty(msg.ParentID)
+	if err := s.msgManager.UpdateNodeLastMessage(ctx, nodeID, forkID, conversationID, req.ConversationType); err != nil {
+		return nil, err
+	}
+	edited, err := s.saveNodeMessage(ctx, nodeID, req.ConversationType, dto.CreateMessageRequest{
+		ID:          uuid.NewString(),
+		UserID:      userID,
+		MessageType: model.MsgTypeText,
+		Role:        schema.User,
+		Content:     model.MessageContent{Text: req.Text},
+	})
+	if err != nil {
+		s.restoreLastMessage(ctx, nodeID, originLastMessageID, conversationID, req.ConversationType)
+		return nil, err
+	}
+	// 拆解的上下文沿分叉点向上构建，编辑后的消息作为本次提问
+	run, err := s.enqueueRerun(ctx, node, userID, req.ConversationType, forkID, req.Text)
+	if err != nil {
+		// 删除编辑后的消息，避免在分叉点下留下没有回复的分支
+		discardUserMessage(ctx, nodeID, edited, req.ConversationType)
+		s.restoreLastMessage(ctx, nodeID, originLastMessageID, conversationID, req.ConversationType)
+		return nil, err
+	}
+	resp := &dto.RerunMessageResponse{
+		RunResponse:         *run,
+		SupersededMessageID: msg.ID,
+		ForkMessageID:       forkID,
+		MessageID:           edited.ID,
+	}
+	publishMessageSuperseded(node, req.ConversationType, rerunModeEdit, resp)
+	return resp, nil
+}
+
+// Regenerate 重新生成助手消息：从该消息所属回复之前的用户消息处分叉，以相同的提问重新运行拆解或结论，
+// 新的回复作为原回复的兄弟消息产生，原回复保留在原分支
+func (s *BranchService) Regenerate(ctx context.Context, nodeID, userID, messageID string, req dto.RegenerateMessageRequest) (*dto.RerunMessageResponse, error) {
+	if err := checkNodeIdle(ctx, nodeID); err != nil {
+		return nil, err
+	}
+	node, conversationID, messages, err := s.rerunTarget(ctx, nodeID, req.ConversationType, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if findByID(messages, messageID).Role != schema.Assistant {
+		return nil, comm.ErrMessageNotRerunnable
+	}
+	_, originLastMessageID, err := global.NodeConversation(node, req.ConversationType)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt, head := replyHead(messages, messageID)
+	var forkID, lastMessageID, text string
+	if prompt != nil {
+		// 回复接在用户消息之后，上下文构建到用户消息之前，用户消息作为本次提问
+		forkID = prompt.ID
+		lastMessageID = rootAsEmpty(prompt.ParentID)
+		text = prompt.Content.Text
+	}
+	if err := s.msgManager.UpdateNodeLastMessage(ctx, nodeID, forkID, conversationID, req.ConversationType); err != nil {
+		return nil, err
+	}
+	run, err := s.enqueueRerun(ctx, node, userID, req.ConversationType, lastMessageID, text)
+	if err != nil {
+		s.restoreLastMessage(ctx, nodeID, originLastMessageID, conversationID, req.ConversationType)
+		return nil, err
+	}
+	resp := &dto.RerunMessageResponse{
+		RunResponse:         *run,
+		SupersededMessageID: head.ID,
+		ForkMessageID:       forkID,
+	}
+	publishMessageSuperseded(node, req.ConversationType, rerunModeRegenerate, resp)
+	return resp, nil
+}
+
+// rerunTarget 查询节点并检查消息属于节点的对话
+func (s *BranchService) rerunTarget(ctx context.Context, nodeID, conversationType, messageID string) (*model.ThinkingNode, string, []*model.Message, error) {
+	node, err := s.nodeRepo.FindByID(ctx, nodeID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	conversationID, messages, err := s.findMessage(ctx, nodeID, conversationType, messageID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return node, conversationID, messages, nil
+}
+
+// saveNodeMessage 将消息保存到节点对话的当前消息之后
+func (s *BranchService) saveNodeMessage(ctx context.Context, nodeID, conversationType string, req dto.CreateMessageRequest) (*dto.MessageResponse, error) {
+	if conversationType == dto.ConversationTypeConclusion {
+		return s.msgManager.SaveConclusionMessage(ctx, nodeID, req)
+	}
+	return s.msgManager.SaveDecompositionMessage(ctx, nodeID, req)
+}
+
+// enqueueRerun 将重新运行的拆解或结论任务加入队列，lastMessageID 为构建会话上下文的最后一条消息
+func (s *BranchService) enqueueRerun(ctx context.Context, node *model.ThinkingNode, userID, conversationType, lastMessageID, text string) (*dto.RunResponse, error) {
+	req := queue.EnqueueRequest{
+		MapID:  node.MapID,
+		NodeID: node.ID,
+		UserID: userID,
+	}
+	if conversationType == dto.ConversationTypeConclusion {
+		req.Type = JobTypeConclusion
+		req.Payload = ConclusionJobPayload{Instruction: text}
+	} else {
+		req.Type = JobTypeDecomposition
+		req.Payload = DecompositionJobPayload{
+			LastMessageID: lastMessageID,
+			Clarification: text,
+			IsDecompose:   node.Decomposition.IsDecomposed,
+		}
+	}
+	return enqueueRun(ctx, req)
+}
+
+// restoreLastMessage 任务未能加入队列时恢复节点对话的当前消息
+func (s *BranchService) restoreLastMessage(ctx context.Context, nodeID, messageID, conversationID, conversationType string) {
+	if err := s.msgManager.UpdateNodeLastMessage(ctx, nodeID, messageID, conversationID, conversationType); err != nil {
+		logger.Error("restore node last message failed", zap.String("nodeID", nodeID), zap.Error(err))
+	}
+}
+
+// publishMessageSuperseded 通知前端消息已被新版本替代
+func publishMessageSuperseded(node *model.ThinkingNode, conversationType, mode string, resp *dto.RerunMessageResponse) {
+	global.GetBroker().PublishToSession(node.MapID, sse.Event{
+		ID:   node.ID,
+		Type: dto.MessageSupersededEventType,
+		Data: dto.MessageSupersededEvent{
+			NodeID:           node.ID,
+			ConversationType: conversationType,
+			MessageID:        resp.SupersededMessageID,
+			ParentID:         resp.ForkMessageID,
+			NewMessageID:     resp.MessageID,
+			Mode:             mode,
+			RunID:            resp.RunID,
+		},
+	})
+}
+
+// replyHead 找到助手消息所属回复之前最近的用户文本消息，以及回复中紧接该用户消息的第一条消息；
+// 没有用户消息时prompt为nil，head为根消息
+func replyHead(messages []*model.Message, messageID string) (prompt, head *model.Message) {
+	byID := make(map[string]*model.Message, len(messages))
+	for _, msg := range messages {
+		byID[msg.ID] = msg
+	}
+	head = byID[messageID]
+	// 数据异常形成环时最多遍历全部消息
+	for i := 0; head != nil && i < len(messages); i++ {
+		parent, ok := byID[head.ParentID]
+		if !ok {
+			return nil, head
+		}
+		if parent.Role == schema.User && parent.MessageType == model.MsgTypeText {
+			return parent, head
+		}
+		head = parent
+	}
+	return nil, head
+}
+
+func findByID(messages []*model.Message, messageID string) *model.Message {
+	for _, msg := range messages {
+		if msg.ID == messageID {
+			return msg
+		}
+	}
+	return nil
+}
+
+// rootAsEmpty 根消息的ParentID为空UUID，节点对话中以空字符串表示
+func rootAsEmpty(parentID string) string {
+	if parentID == uuid.Nil.String() {
+		return ""
+	}
+	return parentID
+}
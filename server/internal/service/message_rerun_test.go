@@ -0,0 +1,59 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRerunTestMessage(id, parentID string, role schema.RoleType, msgType model.MsgType) *model.Message {
+	return &model.Message{ID: id, ParentID: parentID, Role: role, MessageType: msgType}
+}
+
+func TestReplyHead(t *testing.T) {
+	// a0 -> u1 -> a1 -> a2 -> u2(action) -> a3
+	messages := []*model.Message{
+		newRerunTestMessage("a0", uuid.Nil.String(), schema.Assistant, model.MsgTypeText),
+		newRerunTestMessage("u1", "a0", schema.User, model.MsgTypeText),
+		newRerunTestMessage("a1", "u1", schema.Assistant, model.MsgTypeText),
+		newRerunTestMessage("a2", "a1", schema.Assistant, model.MsgTypeAction),
+		newRerunTestMessage("u2", "a2", schema.User, model.MsgTypeAction),
+		newRerunTestMessage("a3", "u2", schema.Assistant, model.MsgTypeText),
+	}
+
+	// 非文本的用户消息不作为提问
+	prompt, head := replyHead(messages, "a3")
+	require.NotNil(t, prompt)
+	assert.Equal(t, "u1", prompt.ID)
+	assert.Equal(t, "a1", head.ID)
+
+	prompt, head = replyHead(messages, "a1")
+	require.NotNil(t, prompt)
+	assert.Equal(t, "u1", prompt.ID)
+	assert.Equal(t, "a1", head.ID)
+
+	// 没有用户消息时从根消息重新生成
+	prompt, head = replyHead(messages, "a0")
+	assert.Nil(t, prompt)
+	assert.Equal(t, "a0", head.ID)
+}
+
+func TestReplyHeadCycle(t *testing.T) {
+	messages := []*model.Message{
+		newRerunTestMessage("a1", "a2", schema.Assistant, model.MsgTypeText),
+		newRerunTestMessage("a2", "a1", schema.Assistant, model.MsgTypeText),
+	}
+	prompt, head := replyHead(messages, "a1")
+	assert.Nil(t, prompt)
+	assert.NotNil(t, head)
+}
+
+func TestFormatInstruction(t *testing.T) {
+	assert.Equal(t, "精简结论", formatInstruction("精简结论", ""))
+	assert.Equal(t, "", formatInstruction("", "引用"))
+	assert.Equal(t, "引用内容：第一段\n指令要求：精简结论", formatInstruction("精简结论", "第一段"))
+}
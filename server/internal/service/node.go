@@ -4,15 +4,20 @@ import (
 	"context"
 	"time"
 
+	"github.com/PGshen/thinking-map/server/internal/global"
 	"github.com/PGshen/thinking-map/server/internal/model"
 	"github.com/PGshen/thinking-map/server/internal/model/dto"
 	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/pkg/utils"
 	"github.com/PGshen/thinking-map/server/internal/repository"
 	"github.com/gin-gonic/gin"
 
 	"github.com/google/uuid"
 )
 
+// nodeContextConclusionTokens 节点上下文中结论全文的长度上限，超出时使用结论摘要
+const nodeContextConclusionTokens = 800
+
 type NodeService struct {
 	nodeRepo repository.ThinkingNode
 	mapRepo  repository.ThinkingMap
@@ -205,7 +210,7 @@ func (s *NodeService) ExecutableNodes(ctx context.Context, mapID, nodeID string)
 	}, nil
 }
 
-func (s *NodeService) GetNodeContext(ctx *gin.Context, node *model.ThinkingNode) model.DependentContext {
+func (s *NodeService) GetNodeContext(ctx context.Context, node *model.ThinkingNode) model.DependentContext {
 	// 获取节点上下文，parentProblem是所有祖先节点的问题和目标，subProblem是所有直接子节点的问题、目标和结论
 	ancestor := node.Context.Ancestor
 	prevSibling := node.Context.PrevSibling
@@ -304,9 +309,14 @@ func (s *NodeService) CreateNode(ctx context.Context, mapID string, req dto.Crea
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
+	// 用户创建的节点不能被Agent删除
+	if err := node.SetMeta(model.NodeMetaUserEdited, true); err != nil {
+		return nil, err
+	}
 	if err := s.nodeRepo.Create(ctx, node); err != nil {
 		return nil, err
 	}
+	global.GetNodeOperationLog().Record(ctx, model.NodeOpCreate, nil, node)
 	resp := dto.ToNodeResponse(node)
 	return &resp, nil
 }
@@ -317,6 +327,7 @@ func (s *NodeService) UpdateNode(ctx context.Context, nodeID string, req dto.Upd
 	if err != nil {
 		return nil, err
 	}
+	before := *node
 	if req.Question != "" {
 		node.Question = req.Question
 	}
@@ -329,21 +340,34 @@ func (s *NodeService) UpdateNode(ctx context.Context, nodeID string, req dto.Upd
 			Y: req.Position.Y,
 		}
 	}
+	// 用户编辑过的节点不能被Agent删除
+	if err := node.SetMeta(model.NodeMetaUserEdited, true); err != nil {
+		return nil, err
+	}
 	node.UpdatedAt = time.Now()
 	if err := s.nodeRepo.Update(ctx, node); err != nil {
 		return nil, err
 	}
+	global.GetNodeOperationLog().Record(ctx, model.NodeOpUpdate, &before, node)
 	resp := dto.ToNodeResponse(node)
 	return &resp, nil
 }
 
 // DeleteNode 删除节点
 func (s *NodeService) DeleteNode(ctx context.Context, nodeID string) error {
-	return s.nodeRepo.Delete(ctx, nodeID)
+	node, err := s.nodeRepo.FindByID(ctx, nodeID)
+	if err != nil {
+		return err
+	}
+	if err := s.nodeRepo.Delete(ctx, nodeID); err != nil {
+		return err
+	}
+	global.GetNodeOperationLog().Record(ctx, model.NodeOpDelete, node, nil)
+	return nil
 }
 
 // getAncestorProblems 递归获取所有祖先节点的问题和目标
-func (s *NodeService) getAncestor(ctx *gin.Context, nodeID string) []model.NodeContext {
+func (s *NodeService) getAncestor(ctx context.Context, nodeID string) []model.NodeContext {
 	var nodeContexts []model.NodeContext
 
 	// 获取当前节点
@@ -362,7 +386,7 @@ func (s *NodeService) getAncestor(ctx *gin.Context, nodeID string) []model.NodeC
 	nodeContext := model.NodeContext{
 		Question: parentNode.Question,
 		Target:   parentNode.Target,
-		Abstract: "", // 可以根据需要添加摘要逻辑
+		Abstract: parentNode.Conclusion.Abstract,
 		Status:   parentNode.Status,
 	}
 	// 递归获取更上层的祖先节点
@@ -373,7 +397,7 @@ func (s *NodeService) getAncestor(ctx *gin.Context, nodeID string) []model.NodeC
 }
 
 // getPreSibling 获取所有前一个兄弟节点的问题、目标和结论
-func (s *NodeService) getPreSibling(ctx *gin.Context, node *model.ThinkingNode) []model.NodeContext {
+func (s *NodeService) getPreSibling(ctx context.Context, node *model.ThinkingNode) []model.NodeContext {
 	var nodeContexts []model.NodeContext
 
 	// node.Dependencies 是当前节点依赖的节点id，通过这个查询依赖节点的问题、目标和结论
@@ -391,8 +415,8 @@ func (s *NodeService) getPreSibling(ctx *gin.Context, node *model.ThinkingNode)
 		nodeContext := model.NodeContext{
 			Question:   depNode.Question,
 			Target:     depNode.Target,
-			Conclusion: depNode.Conclusion.Content,
-			Abstract:   "", // 可以根据需要添加摘要逻辑
+			Conclusion: contextConclusion(depNode.Conclusion),
+			Abstract:   depNode.Conclusion.Abstract,
 			Status:     depNode.Status,
 		}
 		nodeContexts = append(nodeContexts, nodeContext)
@@ -402,7 +426,7 @@ func (s *NodeService) getPreSibling(ctx *gin.Context, node *model.ThinkingNode)
 }
 
 // getChildren 获取所有直接子节点的问题、目标和结论
-func (s *NodeService) getChildren(ctx *gin.Context, nodeID string) []model.NodeContext {
+func (s *NodeService) getChildren(ctx context.Context, nodeID string) []model.NodeContext {
 	var nodeContexts []model.NodeContext
 
 	// 获取所有直接子节点
@@ -415,8 +439,8 @@ func (s *NodeService) getChildren(ctx *gin.Context, nodeID string) []model.NodeC
 		nodeContext := model.NodeContext{
 			Question:   childNode.Question,
 			Target:     childNode.Target,
-			Conclusion: childNode.Conclusion.Content,
-			Abstract:   "", // 可以根据需要添加摘要逻辑
+			Conclusion: contextConclusion(childNode.Conclusion),
+			Abstract:   childNode.Conclusion.Abstract,
 			Status:     childNode.Status,
 		}
 		nodeContexts = append(nodeContexts, nodeContext)
@@ -424,3 +448,12 @@ func (s *NodeService) getChildren(ctx *gin.Context, nodeID string) []model.NodeC
 
 	return nodeContexts
 }
+
+// contextConclusion 节点上下文中的结论，全文超出长度限制且已有摘要时使用摘要
+func contextConclusion(conclusion model.Conclusion) string {
+	text := conclusion.ContextText()
+	if conclusion.Abstract != "" && utils.EstimateTokens(text) > nodeContextConclusionTokens {
+		return conclusion.Abstract
+	}
+	return text
+}
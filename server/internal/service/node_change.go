@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	nodetool "github.com/PGshen/thinking-map/server/internal/agent/tool/node"
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/PGshen/thinking-map/server/internal/pkg/queue"
+	"github.com/PGshen/thinking-map/server/internal/pkg/sse"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+	"go.uber.org/zap"
+)
+
+// NodeChangeService 试运行模式下Agent提出的节点变更集，由用户整体接受或拒绝
+type NodeChangeService struct {
+	changeRepo repository.NodeChange
+}
+
+func NewNodeChangeService(changeRepo repository.NodeChange) *NodeChangeService {
+	return &NodeChangeService{
+		changeRepo: changeRepo,
+	}
+}
+
+// ListChangesets 列出思维导图中等待确认的变更集，按提出时间排序
+func (s *NodeChangeService) ListChangesets(ctx context.Context, mapID string) ([]dto.NodeChangesetResponse, error) {
+	changes, err := s.changeRepo.FindByMapID(ctx, mapID, model.NodeChangeStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	return groupChangesets(changes), nil
+}
+
+// AcceptChangeset 接受变更集，按提出顺序执行所有等待确认的变更；
+// 单项变更执行失败时标记为失败并继续执行后续变更；运行结束前变更集可能还在增加，不能接受
+func (s *NodeChangeService) AcceptChangeset(ctx context.Context, mapID, runID string) (*dto.NodeChangesetResponse, error) {
+	if err := checkRunFinished(ctx, runID); err != nil {
+		return nil, err
+	}
+	changes, err := s.pendingChanges(ctx, mapID, runID)
+	if err != nil {
+		return nil, err
+	}
+	applied, failed := 0, 0
+	for _, change := range changes {
+		change.Status, change.Error = model.NodeChangeStatusApplied, ""
+		if err := nodetool.ApplyChange(ctx, change); err != nil {
+			logger.Warn("apply node change failed", zap.String("changeID", change.ID), zap.Error(err))
+			change.Status, change.Error = model.NodeChangeStatusFailed, err.Error()
+			failed++
+		} else {
+			applied++
+		}
+		if err := s.changeRepo.UpdateStatus(ctx, change.ID, change.Status, change.Error); err != nil {
+			return nil, err
+		}
+	}
+	publishChangesetResolved(mapID, runID, model.NodeChangeStatusApplied, applied, failed)
+	return &groupChangesets(changes)[0], nil
+}
+
+// RejectChangeset 拒绝变更集中所有等待确认的变更
+func (s *NodeChangeService) RejectChangeset(ctx context.Context, mapID, runID string) (*dto.NodeChangesetResponse, error) {
+	changes, err := s.pendingChanges(ctx, mapID, runID)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		if err := s.changeRepo.UpdateStatus(ctx, change.ID, model.NodeChangeStatusRejected, ""); err != nil {
+			return nil, err
+		}
+		change.Status = model.NodeChangeStatusRejected
+	}
+	publishChangesetResolved(mapID, runID, model.NodeChangeStatusRejected, 0, 0)
+	return &groupChangesets(changes)[0], nil
+}
+
+// checkRunFinished 检查运行对应的任务是否已结束，任务记录已过期时视为已结束
+func checkRunFinished(ctx context.Context, runID string) error {
+	job, err := global.GetJobQueue().Get(ctx, runID)
+	if err != nil {
+		if errors.Is(err, queue.ErrJobNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !job.Status.Finished() {
+		return comm.ErrChangesetRunning
+	}
+	return nil
+}
+
+// pendingChanges 获取运行在思维导图中提出的等待确认的变更
+func (s *NodeChangeService) pendingChanges(ctx context.Context, mapID, runID string) ([]*model.NodeChange, error) {
+	changes, err := s.changeRepo.FindByRunID(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	var pending []*model.NodeChange
+	for _, change := range changes {
+		if change.MapID == mapID && change.Status == model.NodeChangeStatusPending {
+			pending = append(pending, change)
+		}
+	}
+	if len(pending) == 0 {
+		return nil, comm.ErrChangesetNotFound
+	}
+	return pending, nil
+}
+
+// groupChangesets 按运行分组，保持变更的提出顺序
+func groupChangesets(changes []*model.NodeChange) []dto.NodeChangesetResponse {
+	changesets := []dto.NodeChangesetResponse{}
+	index := map[string]int{}
+	for _, change := range changes {
+		i, ok := index[change.RunID]
+		if !ok {
+			i = len(changesets)
+			index[change.RunID] = i
+			changesets = append(changesets, dto.NodeChangesetResponse{
+				RunID:     change.RunID,
+				MapID:     change.MapID,
+				NodeID:    change.NodeID,
+				CreatedAt: change.CreatedAt,
+			})
+		}
+		changesets[i].Changes = append(changesets[i].Changes, change)
+	}
+	return changesets
+}
+
+func publishChangesetResolved(mapID, runID, status string, applied, failed int) {
+	global.GetBroker().PublishToSession(mapID, sse.Event{
+		ID:   runID,
+		Type: dto.NodeChangesetResolvedEventType,
+		Data: dto.NodeChangesetResolvedEvent{
+			RunID:   runID,
+			Status:  status,
+			Applied: applied,
+			Failed:  failed,
+		},
+	})
+}
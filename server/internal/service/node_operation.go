@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/pkg/sse"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	// operationLockTTL 撤销、重做锁的过期时间，持有者异常退出时自动释放
+	operationLockTTL = 30 * time.Second
+	// operationLockWait 等待其他撤销、重做结束的最长时间
+	operationLockWait = 5 * time.Second
+)
+
+// unlockScript 只释放自己持有的锁
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// NodeOperationService 按思维导图撤销和重做节点操作，撤销和重做本身也追加到操作日志
+type NodeOperationService struct {
+	opRepo   repository.NodeOperation
+	nodeRepo repository.ThinkingNode
+	redis    *redis.Client // 同一思维导图的撤销和重做在所有实例间依次执行，避免基于同一份日志重复还原
+}
+
+func NewNodeOperationService(opRepo repository.NodeOperation, nodeRepo repository.ThinkingNode, redisClient *redis.Client) *NodeOperationService {
+	return &NodeOperationService{
+		opRepo:   opRepo,
+		nodeRepo: nodeRepo,
+		redis:    redisClient,
+	}
+}
+
+// History 获取思维导图的操作日志及当前可撤销、可重做的操作
+func (s *NodeOperationService) History(ctx context.Context, mapID string) (*dto.NodeOperationHistoryResponse, error) {
+	ops, err := s.opRepo.FindByMapID(ctx, mapID)
+	if err != nil {
+		return nil, err
+	}
+	done, undone := operationStacks(ops)
+	resp := &dto.NodeOperationHistoryResponse{Operations: ops}
+	if len(done) > 0 {
+		resp.Undo = done[len(done)-1]
+	}
+	if len(undone) > 0 {
+		resp.Redo = undone[len(undone)-1]
+	}
+	return resp, nil
+}
+
+// Undo 撤销最近一次未撤销的操作，将节点还原到操作前的快照
+func (s *NodeOperationService) Undo(ctx context.Context, mapID string) (*model.NodeOperation, error) {
+	return s.revert(ctx, mapID, model.NodeOpActionUndo)
+}
+
+// Redo 重做最近一次撤销的操作，将节点还原到操作后的快照；撤销后有新的操作时不能重做
+func (s *NodeOperationService) Redo(ctx context.Context, mapID string) (*model.NodeOperation, error) {
+	return s.revert(ctx, mapID, model.NodeOpActionRedo)
+}
+
+// revert 按操作的前后快照执行撤销或重做，并记录为一条引用原操作的操作
+func (s *NodeOperationService) revert(ctx context.Context, mapID, action string) (*model.NodeOperation, error) {
+	unlock, err := s.lock(ctx, mapID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	ops, err := s.opRepo.FindByMapID(ctx, mapID)
+	if err != nil {
+		return nil, err
+	}
+	done, undone := operationStacks(ops)
+	var target *model.NodeOperation
+	if action == model.NodeOpActionUndo {
+		if len(done) == 0 {
+			return nil, comm.ErrNothingToUndo
+		}
+		target = done[len(done)-1]
+	} else {
+		if len(undone) == 0 {
+			return nil, comm.ErrNothingToRedo
+		}
+		target = undone[len(undone)-1]
+	}
+
+	// 批量操作整体还原：撤销按记录的相反顺序，重做按记录顺序
+	batch := operationBatch(ops, target)
+	if action == model.NodeOpActionUndo {
+		slices.Reverse(batch)
+	}
+	var batchID string
+	if len(batch) > 1 {
+		batchID = uuid.NewString()
+	}
+	var first *model.NodeOperation
+	for _, op := range batch {
+		snapshot := op.After
+		if action == model.NodeOpActionUndo {
+			snapshot = op.Before
+		}
+		state, err := model.ParseNodeSnapshot(snapshot)
+		if err != nil {
+			return nil, err
+		}
+		before, after, err := s.apply(ctx, op, state)
+		if err != nil {
+			return nil, err
+		}
+		entry := &model.NodeOperation{
+			MapID:   mapID,
+			NodeID:  op.NodeID,
+			Op:      op.Op,
+			Action:  action,
+			RefID:   target.ID,
+			BatchID: batchID,
+		}
+		if err := global.GetNodeOperationLog().Append(ctx, entry, before, after); err != nil {
+			return nil, err
+		}
+		if first == nil {
+			first = entry
+		}
+	}
+	return first, nil
+}
+
+// lock 获取思维导图的撤销、重做锁，其他撤销或重做在 operationLockWait 内未结束时返回 comm.ErrOperationBusy
+func (s *NodeOperationService) lock(ctx context.Context, mapID string) (func(), error) {
+	key := fmt.Sprintf("node_operation:lock:%s", mapID)
+	token := uuid.NewString()
+	deadline := time.Now().Add(operationLockWait)
+	for {
+		ok, err := s.redis.SetNX(ctx, key, token, operationLockTTL).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire operation lock: %w", err)
+		}
+		if ok {
+			return func() {
+				unlockScript.Run(context.Background(), s.redis, []string{key}, token)
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, comm.ErrOperationBusy
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// apply 将节点还原到快照状态并通知前端，state为空表示节点不应存在；
+// 节点当前状态与操作不符（如要恢复的节点已存在、要删除的节点有子节点）时返回冲突
+func (s *NodeOperationService) apply(ctx context.Context, op *model.NodeOperation, state *model.ThinkingNode) (before, after *model.ThinkingNode, err error) {
+	current, err := s.findNode(ctx, op.NodeID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case state == nil:
+		if current == nil {
+			return nil, nil, comm.ErrOperationConflict
+		}
+		children, err := s.nodeRepo.FindByParentID(ctx, current.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(children) > 0 {
+			return nil, nil, comm.ErrOperationConflict
+		}
+		if err := s.nodeRepo.Delete(ctx, current.ID); err != nil {
+			return nil, nil, err
+		}
+		publishNodeDeleted(current)
+		return current, nil, nil
+
+	case current == nil:
+		// 父节点已被删除时不能恢复
+		if state.ParentID != "" && state.ParentID != uuid.Nil.String() {
+			parent, err := s.findNode(ctx, state.ParentID)
+			if err != nil {
+				return nil, nil, err
+			}
+			if parent == nil {
+				return nil, nil, comm.ErrOperationConflict
+			}
+		}
+		if err := s.nodeRepo.Restore(ctx, op.NodeID); err != nil {
+			return nil, nil, err
+		}
+		restored, err := s.findNode(ctx, op.NodeID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if restored == nil {
+			return nil, nil, comm.ErrOperationConflict
+		}
+		publishNodeCreated(restored)
+		return nil, restored, nil
+
+	case op.Op == model.NodeOpCreate || op.Op == model.NodeOpDelete:
+		// 要恢复的节点已存在
+		return nil, nil, comm.ErrOperationConflict
+
+	default:
+		prev := *current
+		model.CopyNodeOpFields(op.Op, current, state)
+		current.UpdatedAt = time.Now()
+		if err := s.nodeRepo.Update(ctx, current); err != nil {
+			return nil, nil, err
+		}
+		publishNodeUpdated(current.MapID, current.ID, operationUpdates(op.Op, current))
+		return &prev, current, nil
+	}
+}
+
+// findNode 查找节点，节点不存在或已删除时返回nil
+func (s *NodeOperationService) findNode(ctx context.Context, nodeID string) (*model.ThinkingNode, error) {
+	node, err := s.nodeRepo.FindByID(ctx, nodeID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return node, err
+}
+
+// operationStacks 按记录顺序重放操作日志，得到可撤销的操作（done）和可重做的操作（undone），栈顶在末尾；
+// 新的操作会清空可重做的操作。同一批次的操作以批次中的第一条代表
+func operationStacks(ops []*model.NodeOperation) (done, undone []*model.NodeOperation) {
+	batches := map[string]bool{}
+	for _, op := range ops {
+		if op.BatchID != "" {
+			if batches[op.BatchID] {
+				continue
+			}
+			batches[op.BatchID] = true
+		}
+		switch op.Action {
+		case model.NodeOpActionUndo:
+			if n := len(done); n > 0 && done[n-1].ID == op.RefID {
+				undone = append(undone, done[n-1])
+				done = done[:n-1]
+			}
+		case model.NodeOpActionRedo:
+			if n := len(undone); n > 0 && undone[n-1].ID == op.RefID {
+				done = append(done, undone[n-1])
+				undone = undone[:n-1]
+			}
+		default:
+			done = append(done, op)
+			undone = nil
+		}
+	}
+	return done, undone
+}
+
+// operationBatch 操作所在批次的全部操作，按记录顺序；不属于批次的操作只包含其本身
+func operationBatch(ops []*model.NodeOperation, head *model.NodeOperation) []*model.NodeOperation {
+	if head.BatchID == "" {
+		return []*model.NodeOperation{head}
+	}
+	var batch []*model.NodeOperation
+	for _, op := range ops {
+		if op.BatchID == head.BatchID {
+			batch = append(batch, op)
+		}
+	}
+	return batch
+}
+
+// operationUpdates 撤销或重做后节点更新事件中的字段
+func operationUpdates(op string, node *model.ThinkingNode) map[string]interface{} {
+	switch op {
+	case model.NodeOpDependencies:
+		return map[string]interface{}{"dependencies": node.Dependencies}
+	case model.NodeOpConclusion:
+		return map[string]interface{}{
+			"status":     node.Status,
+			"conclusion": node.Conclusion,
+		}
+	case model.NodeOpDecomposition:
+		return map[string]interface{}{
+			"status":        node.Status,
+			"decomposition": node.Decomposition,
+		}
+	default:
+		return map[string]interface{}{
+			"question": node.Question,
+			"target":   node.Target,
+			"position": node.Position,
+			"parentID": node.ParentID,
+		}
+	}
+}
+
+func publishNodeCreated(node *model.ThinkingNode) {
+	global.GetBroker().PublishToSession(node.MapID, sse.Event{
+		ID:   node.ID,
+		Type: dto.NodeCreatedEventType,
+		Data: dto.NodeCreatedEvent{
+			NodeID:   node.ID,
+			ParentID: node.ParentID,
+			NodeType: node.NodeType,
+			Question: node.Question,
+			Target:   node.Target,
+			Position: node.Position,
+		},
+	})
+}
+
+func publishNodeDeleted(node *model.ThinkingNode) {
+	global.GetBroker().PublishToSession(node.MapID, sse.Event{
+		ID:   node.ID,
+		Type: dto.NodeDeletedEventType,
+		Data: dto.NodeDeletedEvent{
+			NodeID:   node.ID,
+			Question: node.Question,
+		},
+	})
+}
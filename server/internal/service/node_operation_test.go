@@ -0,0 +1,59 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperationStacks(t *testing.T) {
+	a := &model.NodeOperation{ID: "a", Action: model.NodeOpActionApply}
+	b := &model.NodeOperation{ID: "b", Action: model.NodeOpActionApply}
+	c := &model.NodeOperation{ID: "c", Action: model.NodeOpActionApply}
+	undo := func(ref string) *model.NodeOperation {
+		return &model.NodeOperation{Action: model.NodeOpActionUndo, RefID: ref}
+	}
+	redo := func(ref string) *model.NodeOperation {
+		return &model.NodeOperation{Action: model.NodeOpActionRedo, RefID: ref}
+	}
+
+	done, undone := operationStacks([]*model.NodeOperation{a, b, undo("b"), undo("a")})
+	assert.Empty(t, done)
+	assert.Equal(t, []*model.NodeOperation{b, a}, undone)
+
+	// 重做按撤销的相反顺序
+	done, undone = operationStacks([]*model.NodeOperation{a, b, undo("b"), undo("a"), redo("a")})
+	assert.Equal(t, []*model.NodeOperation{a}, done)
+	assert.Equal(t, []*model.NodeOperation{b}, undone)
+
+	// 撤销后有新的操作时不能再重做
+	done, undone = operationStacks([]*model.NodeOperation{a, b, undo("b"), c})
+	assert.Equal(t, []*model.NodeOperation{a, c}, done)
+	assert.Empty(t, undone)
+}
+
+func TestOperationStacks_Batch(t *testing.T) {
+	a := &model.NodeOperation{ID: "a", Action: model.NodeOpActionApply}
+	child1 := &model.NodeOperation{ID: "c1", Op: model.NodeOpDelete, Action: model.NodeOpActionApply, BatchID: "reset"}
+	child2 := &model.NodeOperation{ID: "c2", Op: model.NodeOpDelete, Action: model.NodeOpActionApply, BatchID: "reset"}
+	parent := &model.NodeOperation{ID: "p", Op: model.NodeOpDecomposition, Action: model.NodeOpActionApply, BatchID: "reset"}
+	ops := []*model.NodeOperation{a, child1, child2, parent}
+
+	// 同一批次以第一条代表，撤销时还原整个批次
+	done, undone := operationStacks(ops)
+	assert.Equal(t, []*model.NodeOperation{a, child1}, done)
+	assert.Empty(t, undone)
+	assert.Equal(t, []*model.NodeOperation{child1, child2, parent}, operationBatch(ops, child1))
+	assert.Equal(t, []*model.NodeOperation{a}, operationBatch(ops, a))
+
+	// 批次的撤销记录同样成批，一次撤销后整个批次可以重做
+	ops = append(ops,
+		&model.NodeOperation{ID: "u1", Action: model.NodeOpActionUndo, RefID: "c1", BatchID: "undo"},
+		&model.NodeOperation{ID: "u2", Action: model.NodeOpActionUndo, RefID: "c1", BatchID: "undo"},
+		&model.NodeOperation{ID: "u3", Action: model.NodeOpActionUndo, RefID: "c1", BatchID: "undo"},
+	)
+	done, undone = operationStacks(ops)
+	assert.Equal(t, []*model.NodeOperation{a}, done)
+	assert.Equal(t, []*model.NodeOperation{child1}, undone)
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PGshen/thinking-map/server/internal/agent/callback"
+	conclusionv3 "github.com/PGshen/thinking-map/server/internal/agent/conclusion"
+	"github.com/PGshen/thinking-map/server/internal/agent/llmmodel"
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/model"
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/pkg/logger"
+	"github.com/PGshen/thinking-map/server/internal/pkg/queue"
+	"github.com/PGshen/thinking-map/server/internal/pkg/sse"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// rollupInstruction 结论汇总的用户指令
+const rollupInstruction = "当前节点的所有子节点均已完成，请汇总子节点结论，生成当前节点的结论。"
+
+// HandleRollupJob 执行结论汇总任务
+func (c *ConclusionService) HandleRollupJob(ctx context.Context, job *queue.Job) error {
+	node, err := c.nodeRepo.FindByID(ctx, job.NodeID)
+	if err != nil {
+		return err
+	}
+	// 入队后用户已手动保存了结论，不再汇总
+	if node.Status == comm.NodeStatusCompleted {
+		return nil
+	}
+	originStatus := node.Status
+	return runJob(ctx, job, global.RunSpec{
+		Tasks: []global.RunTask{func(runCtx context.Context) error {
+			return c.Rollup(runCtx, job.NodeID)
+		}},
+		OnCancelled: func(ctx context.Context, run *global.Run) {
+			c.restoreAfterCancel(ctx, run, originStatus)
+		},
+	})
+}
+
+// Rollup 根据子节点结论生成节点结论并标记为完成，然后继续向上汇总
+func (c *ConclusionService) Rollup(ctx context.Context, nodeID string) error {
+	node, err := c.rollup(ctx, nodeID)
+	if err != nil {
+		return err
+	}
+	userID, _ := ctx.Value("user_id").(string)
+	return c.rollupParent(ctx, node, userID)
+}
+
+// rollup 根据子节点结论生成节点结论并标记为完成，不向上汇总
+func (c *ConclusionService) rollup(ctx context.Context, nodeID string) (*model.ThinkingNode, error) {
+	contextInfo, err := c.contextManager.GetContextInfo(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	node := contextInfo.NodeInfo
+	before := *node
+	children, err := c.nodeRepo.FindByParentID(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if node.Status != comm.NodeStatusInConclusion {
+		node.Status = comm.NodeStatusInConclusion
+		if err := c.nodeRepo.Update(ctx, node); err != nil {
+			return nil, err
+		}
+		publishNodeUpdated(node.MapID, node.ID, map[string]interface{}{"status": node.Status})
+	}
+
+	messages := []*schema.Message{schema.UserMessage(c.contextManager.FormatContextWithinBudget(ctx, contextInfo, llmmodel.ProfileNameForAgent(llmmodel.AgentConclusionRollup)))}
+	childrenMessages, err := c.msgManager.GetNodeChildren(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	messages = append(messages, childrenMessages...)
+	messages = append(messages, schema.UserMessage(rollupInstruction))
+
+	agent, err := conclusionv3.BuildRollupAgent(ctx)
+	if err != nil {
+		return nil, err
+	}
+	output, err := agent.Invoke(ctx, messages, compose.WithCallbacks(callback.LogCbHandler))
+	if err != nil {
+		return nil, err
+	}
+	content := strings.TrimSpace(output.Content)
+	if content == "" {
+		return nil, errors.New("rollup agent returned empty conclusion")
+	}
+
+	// 汇总结论只能沿用子节点结论中已校验的来源
+	node.Conclusion.Content = content
+	node.Conclusion.Citations = conclusionv3.VerifyCitations(conclusionv3.ParseCitations(content), c.childrenRAGRecords(ctx, children))
+	node.Conclusion.Structured, node.Conclusion.Payload = c.structureConclusion(ctx, node)
+	node.Conclusion.Abstract = ""
+	node.Status = comm.NodeStatusCompleted
+	if err := c.nodeRepo.Update(ctx, node); err != nil {
+		return nil, fmt.Errorf("failed to update node conclusion: %w", err)
+	}
+	global.GetNodeOperationLog().Record(ctx, model.NodeOpConclusion, &before, node)
+	global.GetNodeAbstractor().Refresh(node.ID)
+	publishNodeUpdated(node.MapID, node.ID, map[string]interface{}{
+		"status":     node.Status,
+		"conclusion": node.Conclusion,
+	})
+	publishConclusionCompleted(node.MapID, node.ID, "rollup")
+	logger.Info("Conclusion rolled up", zap.String("nodeID", nodeID), zap.Int("children", len(children)))
+	return node, nil
+}
+
+// rollupParent 自动汇总模式下，节点完成后检查父节点的子节点是否全部完成，是则将父节点的汇总加入任务队列；
+// 根节点完成时写入思维导图结论并将思维导图标记为已完成
+func (c *ConclusionService) rollupParent(ctx context.Context, node *model.ThinkingNode, userID string) error {
+	thinkingMap, err := c.mapRepo.FindByID(ctx, node.MapID)
+	if err != nil {
+		return err
+	}
+	if !thinkingMap.MetaBool(model.MapMetaAutoRollup) {
+		return nil
+	}
+	if node.ParentID == "" || node.ParentID == uuid.Nil.String() {
+		return c.completeMap(ctx, node)
+	}
+
+	siblings, err := c.nodeRepo.FindByParentID(ctx, node.ParentID)
+	if err != nil {
+		return err
+	}
+	for _, sibling := range siblings {
+		if sibling.Status != comm.NodeStatusCompleted {
+			return nil
+		}
+	}
+	_, err = enqueueRun(ctx, queue.EnqueueRequest{
+		Type:   JobTypeRollup,
+		MapID:  node.MapID,
+		NodeID: node.ParentID,
+		UserID: userID,
+	})
+	if errors.Is(err, comm.ErrRunConflict) {
+		// 父节点上已有运行（如用户正在手动生成结论），由该运行负责父节点结论
+		logger.Info("Skip rollup, parent node is running", zap.String("nodeID", node.ParentID))
+		return nil
+	}
+	return err
+}
+
+// completeMap 以根节点结论作为思维导图结论，并将思维导图标记为已完成
+func (c *ConclusionService) completeMap(ctx context.Context, root *model.ThinkingNode) error {
+	if err := c.mapRepo.Update(ctx, root.MapID, map[string]interface{}{
+		"conclusion": root.Conclusion.Content,
+		"status":     comm.MapStatusCompleted,
+	}); err != nil {
+		return fmt.Errorf("failed to update map conclusion: %w", err)
+	}
+	publishConclusionCompleted(root.MapID, root.ID, "map")
+	logger.Info("Map conclusion rolled up", zap.String("mapID", root.MapID))
+	return nil
+}
+
+// childrenRAGRecords 查询子节点结论中已校验引用对应的检索记录
+func (c *ConclusionService) childrenRAGRecords(ctx context.Context, children []*model.ThinkingNode) []*model.RAGRecord {
+	var records []*model.RAGRecord
+	seen := make(map[string]bool)
+	for _, child := range children {
+		for _, citation := range child.Conclusion.Citations {
+			if !citation.Verified || citation.RagID == "" || seen[citation.RagID] {
+				continue
+			}
+			seen[citation.RagID] = true
+			record, err := global.GetRAGRecordRepository().FindByID(ctx, citation.RagID)
+			if err != nil {
+				logger.Warn("find citation rag record failed", zap.String("ragID", citation.RagID), zap.Error(err))
+				continue
+			}
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// publishConclusionCompleted 推送结论完成事件
+func publishConclusionCompleted(mapID, nodeID, mode string) {
+	global.GetBroker().PublishToSession(mapID, sse.Event{
+		ID:   nodeID,
+		Type: dto.ConclusionCompletedEventType,
+		Data: dto.ConclusionCompletedEvent{
+			NodeID: nodeID,
+			Mode:   mode,
+			Status: "completed",
+		},
+	})
+}
+
+// publishNodeUpdated 推送节点字段变更
+func publishNodeUpdated(mapID, nodeID string, updates map[string]interface{}) {
+	global.GetBroker().PublishToSession(mapID, sse.Event{
+		ID:   nodeID,
+		Type: dto.NodeUpdatedEventType,
+		Data: dto.NodeUpdatedEvent{
+			NodeID:  nodeID,
+			Mode:    "replace",
+			Updates: updates,
+		},
+	})
+}
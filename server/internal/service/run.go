@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/PGshen/thinking-map/server/internal/global"
+	"github.com/PGshen/thinking-map/server/internal/model/dto"
+	"github.com/PGshen/thinking-map/server/internal/pkg/comm"
+	"github.com/PGshen/thinking-map/server/internal/pkg/queue"
+	"github.com/PGshen/thinking-map/server/internal/pkg/sse"
+	"github.com/PGshen/thinking-map/server/internal/repository"
+)
+
+// RunService Agent运行管理
+type RunService struct {
+	nodeRepo repository.ThinkingNode
+}
+
+func NewRunService(nodeRepo repository.ThinkingNode) *RunService {
+	return &RunService{
+		nodeRepo: nodeRepo,
+	}
+}
+
+// CancelRun 取消运行，只允许发起者取消
+// 运行ID即任务ID：排队中的任务直接取消；执行中的任务由执行它的worker中断，
+// 各任务退出、节点状态恢复完成后推送 runCancelled 事件
+func (s *RunService) CancelRun(ctx context.Context, runID, userID string) (*dto.RunResponse, error) {
+	q := global.GetJobQueue()
+	job, err := q.Get(ctx, runID)
+	if err != nil {
+		if errors.Is(err, queue.ErrJobNotFound) {
+			return nil, comm.ErrRunNotFound
+		}
+		return nil, err
+	}
+	if job.UserID != "" && job.UserID != userID {
+		return nil, comm.ErrNoPermission
+	}
+	job, err = q.Cancel(ctx, runID)
+	if err != nil {
+		if errors.Is(err, queue.ErrJobFinished) {
+			return nil, comm.ErrRunNotFound
+		}
+		return nil, err
+	}
+	if job.Status == queue.StatusCancelled {
+		// 尚未开始执行，节点状态未变化
+		node, err := s.nodeRepo.FindByID(ctx, job.NodeID)
+		if err != nil {
+			return nil, err
+		}
+		publishRunCancelled(job.MapID, dto.RunCancelledEvent{
+			RunID:     job.ID,
+			NodeID:    job.NodeID,
+			Operation: job.Type,
+			Status:    node.Status,
+		})
+	} else {
+		// 在本实例执行时立即中断，其他实例上的worker通过取消标记中断
+		_, _ = global.GetRunRegistry().Cancel(runID)
+	}
+	resp := &dto.RunResponse{
+		RunID:     job.ID,
+		MapID:     job.MapID,
+		NodeID:    job.NodeID,
+		Operation: job.Type,
+		Status:    string(job.Status),
+		CreatedAt: job.CreatedAt,
+		StartedAt: job.StartedAt,
+	}
+	return resp, nil
+}
+
+// publishRunCancelled 推送运行取消事件及节点状态变更
+func publishRunCancelled(mapID string, event dto.RunCancelledEvent) {
+	broker := global.GetBroker()
+	broker.PublishToSession(mapID, sse.Event{
+		ID:   event.NodeID,
+		Type: dto.NodeUpdatedEventType,
+		Data: dto.NodeUpdatedEvent{
+			NodeID: event.NodeID,
+			Mode:   "replace",
+			Updates: map[string]interface{}{
+				"status": event.Status,
+			},
+		},
+	})
+	broker.PublishToSession(mapID, sse.Event{
+		ID:   event.NodeID,
+		Type: dto.RunCancelledEventType,
+		Data: event,
+	})
+}
+
+// runCancelledEvent 运行取消事件
+func runCancelledEvent(run *global.Run, status string) dto.RunCancelledEvent {
+	return dto.RunCancelledEvent{
+		RunID:     run.ID,
+		NodeID:    run.NodeID,
+		Operation: run.Operation,
+		Status:    status,
+	}
+}